@@ -0,0 +1,164 @@
+package ansi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Profile describes what a client terminal can render.
+type Profile struct {
+	ANSI  bool // understands ANSI escape sequences and colors
+	CP437 bool // expects raw CP437 bytes instead of UTF-8
+}
+
+// ProfileFor derives a rendering profile from the terminal type reported by
+// the client, e.g. the TERM value of an SSH pty-req.
+func ProfileFor(termType string) Profile {
+	switch t := strings.ToLower(termType); {
+	case t == "", t == "dumb":
+		return Profile{}
+	case t == "ansi", t == "ansi-bbs", t == "pcansi", strings.HasPrefix(t, "syncterm"):
+		return Profile{ANSI: true, CP437: true}
+	default:
+		return Profile{ANSI: true}
+	}
+}
+
+type Screen struct {
+	Data  []byte
+	Sauce *Sauce
+	CP437 bool
+}
+
+func Load(path string) (*Screen, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, sauce := ParseSauce(data)
+	return &Screen{
+		Data:  content,
+		Sauce: sauce,
+		CP437: isCP437(content),
+	}, nil
+}
+
+// Render converts the screen for the given profile. Line endings are
+// normalized to LF; the terminal adds the carriage returns.
+func (s *Screen) Render(p Profile) string {
+	var text string
+	if s.CP437 && !p.CP437 {
+		text = DecodeCP437(s.Data)
+	} else if !s.CP437 && p.CP437 {
+		text = string(EncodeCP437(string(s.Data)))
+	} else {
+		text = string(s.Data)
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "")
+
+	if !p.ANSI {
+		return StripANSI(text)
+	}
+
+	if strings.Contains(text, "\033[") {
+		text += "\033[0m"
+	}
+	return text
+}
+
+// StripANSI removes escape sequences for terminals without ANSI support.
+// Cursor-forward sequences are expanded to spaces because art editors use
+// them to compress runs of blanks.
+func StripANSI(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != 0x1b {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 >= len(s) || s[i+1] != '[' {
+			i++
+			continue
+		}
+
+		j := i + 2
+		for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+			j++
+		}
+		if j >= len(s) {
+			break
+		}
+
+		if s[j] == 'C' {
+			n, err := strconv.Atoi(s[i+2 : j])
+			if err != nil || n < 1 {
+				n = 1
+			}
+			b.WriteString(strings.Repeat(" ", min(n, 255)))
+		}
+		i = j
+	}
+
+	return b.String()
+}
+
+// Loader finds screens in the sysop's art directory.
+type Loader struct {
+	dir string
+}
+
+func NewLoader(dir string) *Loader {
+	return &Loader{dir: dir}
+}
+
+// Load returns the named screen rendered for the profile. A name without an
+// extension is looked up as .ans, then .asc and .txt; terminals without ANSI
+// support prefer the .asc variant when one exists. A missing screen returns
+// an error satisfying os.IsNotExist.
+func (l *Loader) Load(name string, p Profile) (string, error) {
+	if name == "" || l.dir == "" {
+		return "", os.ErrNotExist
+	}
+
+	for _, candidate := range l.candidates(name, p) {
+		screen, err := Load(filepath.Join(l.dir, candidate))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return screen.Render(p), nil
+	}
+
+	return "", os.ErrNotExist
+}
+
+func (l *Loader) candidates(name string, p Profile) []string {
+	name = filepath.Clean("/" + name)[1:]
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	switch strings.ToLower(ext) {
+	case "":
+		if p.ANSI {
+			return []string{base + ".ans", base + ".asc", base + ".txt"}
+		}
+		return []string{base + ".asc", base + ".txt", base + ".ans"}
+	case ".ans":
+		if !p.ANSI {
+			return []string{base + ".asc", name}
+		}
+	}
+	return []string{name}
+}
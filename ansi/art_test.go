@@ -0,0 +1,81 @@
+package ansi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileFor(t *testing.T) {
+	tests := []struct {
+		termType string
+		expected Profile
+	}{
+		{"", Profile{}},
+		{"dumb", Profile{}},
+		{"xterm-256color", Profile{ANSI: true}},
+		{"syncterm", Profile{ANSI: true, CP437: true}},
+		{"ANSI-BBS", Profile{ANSI: true, CP437: true}},
+	}
+
+	for _, tt := range tests {
+		if got := ProfileFor(tt.termType); got != tt.expected {
+			t.Errorf("ProfileFor(%q) = %+v, expected %+v", tt.termType, got, tt.expected)
+		}
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	input := "\033[1;33mA\033[3CB\033[0m\033[CC"
+	if got := StripANSI(input); got != "A   B C" {
+		t.Errorf("Expected escapes stripped and cursor moves expanded, got %q", got)
+	}
+}
+
+func TestScreenRender(t *testing.T) {
+	screen := &Screen{Data: []byte{0x1b, '[', '3', '1', 'm', 0xdb, '\r', '\n'}, CP437: true}
+
+	if got := screen.Render(Profile{ANSI: true}); got != "\033[31m█\n\033[0m" {
+		t.Errorf("Unexpected UTF-8 render %q", got)
+	}
+
+	if got := screen.Render(Profile{ANSI: true, CP437: true}); got != "\033[31m\xdb\n\033[0m" {
+		t.Errorf("Unexpected CP437 render %q", got)
+	}
+
+	if got := screen.Render(Profile{}); got != "█\n" {
+		t.Errorf("Unexpected plain render %q", got)
+	}
+}
+
+func TestLoaderLoad(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "logon.ans"), []byte("\033[32mcolor\033[0m"), 0644)
+	os.WriteFile(filepath.Join(dir, "logon.asc"), []byte("plain"), 0644)
+	os.WriteFile(filepath.Join(dir, "news.txt"), []byte("news"), 0644)
+
+	loader := NewLoader(dir)
+
+	got, err := loader.Load("logon", Profile{ANSI: true})
+	if err != nil || got != "\033[32mcolor\033[0m\033[0m" {
+		t.Errorf("Expected ANSI screen, got %q (%v)", got, err)
+	}
+
+	got, err = loader.Load("logon.ans", Profile{})
+	if err != nil || got != "plain" {
+		t.Errorf("Expected ASCII fallback, got %q (%v)", got, err)
+	}
+
+	got, err = loader.Load("news", Profile{ANSI: true})
+	if err != nil || got != "news" {
+		t.Errorf("Expected text bulletin, got %q (%v)", got, err)
+	}
+
+	if _, err := loader.Load("missing", Profile{ANSI: true}); !os.IsNotExist(err) {
+		t.Errorf("Expected not-exist error, got %v", err)
+	}
+
+	if _, err := loader.Load("../../etc/passwd", Profile{ANSI: true}); !os.IsNotExist(err) {
+		t.Errorf("Expected paths to stay inside the art directory, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package ansi
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// cp437High maps bytes 0x80-0xFF of code page 437 to Unicode.
+var cp437High = [128]rune{
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç', 'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù', 'ÿ', 'Ö', 'Ü', '¢', '£', '¥', '₧', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º', '¿', '⌐', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', '╡', '╢', '╖', '╕', '╣', '║', '╗', '╝', '╜', '╛', '┐',
+	'└', '┴', '┬', '├', '─', '┼', '╞', '╟', '╚', '╔', '╩', '╦', '╠', '═', '╬', '╧',
+	'╨', '╤', '╥', '╙', '╘', '╒', '╓', '╫', '╪', '┘', '┌', '█', '▄', '▌', '▐', '▀',
+	'α', 'ß', 'Γ', 'π', 'Σ', 'σ', 'µ', 'τ', 'Φ', 'Θ', 'Ω', 'δ', '∞', 'φ', 'ε', '∩',
+	'≡', '±', '≥', '≤', '⌠', '⌡', '÷', '≈', '°', '∙', '·', '√', 'ⁿ', '²', '■', ' ',
+}
+
+var cp437Reverse = func() map[rune]byte {
+	m := make(map[rune]byte, len(cp437High))
+	for i, r := range cp437High {
+		m[r] = byte(0x80 + i)
+	}
+	return m
+}()
+
+// DecodeCP437 converts CP437 bytes to a UTF-8 string. Control characters are
+// kept as controls since ANSI art relies on ESC, CR and LF.
+func DecodeCP437(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+
+	for _, c := range data {
+		switch {
+		case c < 0x7f:
+			b.WriteByte(c)
+		case c == 0x7f:
+			b.WriteRune('⌂')
+		default:
+			b.WriteRune(cp437High[c-0x80])
+		}
+	}
+
+	return b.String()
+}
+
+// EncodeCP437 converts a UTF-8 string to CP437 bytes. Runes without a CP437
+// equivalent become '?'.
+func EncodeCP437(s string) []byte {
+	out := make([]byte, 0, len(s))
+
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case r == '⌂':
+			out = append(out, 0x7f)
+		default:
+			if c, ok := cp437Reverse[r]; ok {
+				out = append(out, c)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+
+	return out
+}
+
+// isCP437 guesses whether data is CP437 rather than UTF-8. Art saved by DOS
+// editors is rarely valid UTF-8 once it uses any block or line characters.
+func isCP437(data []byte) bool {
+	return !utf8.Valid(data)
+}
@@ -0,0 +1,45 @@
+package ansi
+
+import "testing"
+
+func TestDecodeCP437(t *testing.T) {
+	data := []byte{'A', 0xb0, 0xb1, 0xb2, 0xdb, 0xc9, 0xcd, 0xbb, 0x1b, '[', 'm'}
+
+	got := DecodeCP437(data)
+	expected := "A░▒▓█╔═╗\033[m"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestEncodeCP437RoundTrip(t *testing.T) {
+	data := make([]byte, 0, 256)
+	for i := 0x20; i < 0x100; i++ {
+		data = append(data, byte(i))
+	}
+
+	encoded := EncodeCP437(DecodeCP437(data))
+	if string(encoded) != string(data) {
+		t.Error("CP437 round trip should preserve all printable bytes")
+	}
+}
+
+func TestEncodeCP437Unknown(t *testing.T) {
+	if got := string(EncodeCP437("a€b")); got != "a?b" {
+		t.Errorf("Expected unknown runes to become '?', got %q", got)
+	}
+}
+
+func TestIsCP437(t *testing.T) {
+	if isCP437([]byte("plain ascii")) {
+		t.Error("ASCII should not be detected as CP437")
+	}
+
+	if isCP437([]byte("räksmörgås")) {
+		t.Error("Valid UTF-8 should not be detected as CP437")
+	}
+
+	if !isCP437([]byte{0xdb, 0xdb, 0xb2}) {
+		t.Error("Block characters should be detected as CP437")
+	}
+}
@@ -0,0 +1,92 @@
+package ansi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+const (
+	sauceRecordSize  = 128
+	sauceCommentSize = 64
+	sauceEOF         = 0x1a
+)
+
+// Sauce holds the metadata record appended to ANSI art files.
+// See https://www.acid.org/info/sauce/sauce.htm
+type Sauce struct {
+	Title    string
+	Author   string
+	Group    string
+	Date     time.Time
+	FileSize int
+	DataType byte
+	FileType byte
+	Width    int
+	Height   int
+	Flags    byte
+	Font     string
+	Comments []string
+}
+
+// ICEColors reports whether the blink bit selects bright backgrounds.
+func (s *Sauce) ICEColors() bool {
+	return s.Flags&0x01 != 0
+}
+
+// ParseSauce splits data into its content and SAUCE record. If there is no
+// record, data is returned unchanged and the record is nil.
+func ParseSauce(data []byte) ([]byte, *Sauce) {
+	if len(data) < sauceRecordSize {
+		return data, nil
+	}
+
+	record := data[len(data)-sauceRecordSize:]
+	if !bytes.HasPrefix(record, []byte("SAUCE00")) {
+		return data, nil
+	}
+
+	sauce := &Sauce{
+		Title:    sauceString(record[7:42]),
+		Author:   sauceString(record[42:62]),
+		Group:    sauceString(record[62:82]),
+		FileSize: int(binary.LittleEndian.Uint32(record[90:94])),
+		DataType: record[94],
+		FileType: record[95],
+		Width:    int(binary.LittleEndian.Uint16(record[96:98])),
+		Height:   int(binary.LittleEndian.Uint16(record[98:100])),
+		Flags:    record[105],
+		Font:     sauceString(record[106:128]),
+	}
+
+	if date, err := time.Parse("20060102", string(record[82:90])); err == nil {
+		sauce.Date = date
+	}
+
+	content := data[:len(data)-sauceRecordSize]
+
+	if n := int(record[104]); n > 0 {
+		blockSize := 5 + n*sauceCommentSize
+		if len(content) >= blockSize {
+			block := content[len(content)-blockSize:]
+			if bytes.HasPrefix(block, []byte("COMNT")) {
+				for i := 0; i < n; i++ {
+					start := 5 + i*sauceCommentSize
+					sauce.Comments = append(sauce.Comments, sauceString(block[start:start+sauceCommentSize]))
+				}
+				content = content[:len(content)-blockSize]
+			}
+		}
+	}
+
+	if len(content) > 0 && content[len(content)-1] == sauceEOF {
+		content = content[:len(content)-1]
+	}
+
+	return content, sauce
+}
+
+func sauceString(b []byte) string {
+	return strings.TrimRight(DecodeCP437(b), " \x00")
+}
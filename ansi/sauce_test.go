@@ -0,0 +1,96 @@
+package ansi
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func buildSauce(title, author string, width, height int, comments []string) []byte {
+	var block []byte
+	if len(comments) > 0 {
+		block = append(block, []byte("COMNT")...)
+		for _, c := range comments {
+			line := make([]byte, sauceCommentSize)
+			copy(line, padded(c, sauceCommentSize))
+			block = append(block, line...)
+		}
+	}
+
+	record := make([]byte, sauceRecordSize)
+	copy(record, "SAUCE00")
+	copy(record[7:], padded(title, 35))
+	copy(record[42:], padded(author, 20))
+	copy(record[62:], padded("group", 20))
+	copy(record[82:], "19960704")
+	record[94] = 1
+	record[95] = 1
+	binary.LittleEndian.PutUint16(record[96:], uint16(width))
+	binary.LittleEndian.PutUint16(record[98:], uint16(height))
+	record[104] = byte(len(comments))
+	record[105] = 0x01
+	copy(record[106:], "IBM VGA")
+
+	out := []byte{sauceEOF}
+	out = append(out, block...)
+	return append(out, record...)
+}
+
+func padded(s string, n int) []byte {
+	b := []byte(s)
+	for len(b) < n {
+		b = append(b, ' ')
+	}
+	return b
+}
+
+func TestParseSauce(t *testing.T) {
+	art := []byte("\033[1;31mHELLO\033[0m")
+	data := append(append([]byte{}, art...), buildSauce("Hello", "sysop", 80, 25, []string{"first", "second"})...)
+
+	content, sauce := ParseSauce(data)
+	if sauce == nil {
+		t.Fatal("Expected SAUCE record to be parsed")
+	}
+
+	if string(content) != string(art) {
+		t.Errorf("Expected content without SAUCE, got %q", content)
+	}
+
+	if sauce.Title != "Hello" || sauce.Author != "sysop" || sauce.Group != "group" {
+		t.Errorf("Unexpected SAUCE strings: %+v", sauce)
+	}
+
+	if sauce.Width != 80 || sauce.Height != 25 {
+		t.Errorf("Expected 80x25, got %dx%d", sauce.Width, sauce.Height)
+	}
+
+	if !sauce.Date.Equal(time.Date(1996, 7, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %v", sauce.Date)
+	}
+
+	if len(sauce.Comments) != 2 || sauce.Comments[1] != "second" {
+		t.Errorf("Unexpected comments %v", sauce.Comments)
+	}
+
+	if !sauce.ICEColors() {
+		t.Error("Expected iCE colors flag to be set")
+	}
+
+	if sauce.Font != "IBM VGA" {
+		t.Errorf("Expected font IBM VGA, got %q", sauce.Font)
+	}
+}
+
+func TestParseSauceWithoutRecord(t *testing.T) {
+	data := []byte("just some text")
+
+	content, sauce := ParseSauce(data)
+	if sauce != nil {
+		t.Error("Expected no SAUCE record")
+	}
+
+	if string(content) != string(data) {
+		t.Error("Content should be unchanged without a SAUCE record")
+	}
+}
@@ -0,0 +1,112 @@
+// Package auth checks login attempts for both the SSH server and the login
+// menu, so throttling, failure logging and second factors behave the same on
+// either path.
+package auth
+
+import (
+	"errors"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCode        = errors.New("invalid authentication code")
+)
+
+type Authenticator struct {
+	repos    *repository.Manager
+	throttle *domain.LoginThrottle
+	now      func() time.Time
+	// checks bounds concurrent password checks. The throttle only sees
+	// failures once they finish, so without it a burst of parallel
+	// connections could still keep every core busy with bcrypt.
+	checks chan struct{}
+}
+
+func NewAuthenticator(repos *repository.Manager, throttle *domain.LoginThrottle) *Authenticator {
+	return &Authenticator{
+		repos:    repos,
+		throttle: throttle,
+		now:      time.Now,
+		checks:   make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+func (a *Authenticator) Throttle() *domain.LoginThrottle {
+	return a.throttle
+}
+
+// Result is a password check that succeeded. If the user has two-factor
+// authentication, the login is not complete until SecondFactor succeeds.
+type Result struct {
+	User *domain.User
+	// PasswordResetID is set when a reset token was used instead of the
+	// password; the user must choose a new password.
+	PasswordResetID int
+}
+
+func (r *Result) NeedsSecondFactor() bool {
+	return r.User.TOTPEnabled
+}
+
+// Password checks a password, or a sysop-issued reset token, for username.
+// Throttled attempts are refused with a *domain.ThrottledError before any
+// bcrypt work is done.
+func (a *Authenticator) Password(username, password, remoteAddr string) (*Result, error) {
+	if wait := a.throttle.Wait(username, remoteAddr, a.now()); wait > 0 {
+		return nil, &domain.ThrottledError{Wait: wait}
+	}
+
+	a.checks <- struct{}{}
+	defer func() { <-a.checks }()
+
+	result := &Result{}
+	user, err := a.repos.User.Authenticate(username, password)
+	if err != nil {
+		var reset *domain.PasswordReset
+		user, reset, err = a.repos.CheckPasswordReset(username, password)
+		if err != nil {
+			a.fail(username, remoteAddr, domain.FailedLoginPassword)
+			return nil, ErrInvalidCredentials
+		}
+		result.PasswordResetID = reset.ID
+	}
+
+	result.User = user
+	if !result.NeedsSecondFactor() {
+		a.throttle.Success(user.Username)
+	}
+	return result, nil
+}
+
+// SecondFactor checks an authenticator or recovery code for a user whose
+// password has already been accepted.
+func (a *Authenticator) SecondFactor(user *domain.User, code, remoteAddr string) error {
+	if wait := a.throttle.Wait(user.Username, remoteAddr, a.now()); wait > 0 {
+		return &domain.ThrottledError{Wait: wait}
+	}
+
+	if !a.repos.VerifySecondFactor(user, code) {
+		a.fail(user.Username, remoteAddr, domain.FailedLoginCode)
+		return ErrInvalidCode
+	}
+
+	a.throttle.Success(user.Username)
+	return nil
+}
+
+func (a *Authenticator) fail(username, remoteAddr, reason string) {
+	a.throttle.Failure(username, remoteAddr, a.now())
+
+	attempt := domain.NewFailedLogin(username, remoteAddr, reason)
+	if err := a.repos.FailedLogin.Create(attempt); err != nil {
+		log.Printf("Failed to record failed login: %v", err)
+	}
+	a.repos.RecordAudit(domain.NewAuditEntry(nil, domain.AuditLoginFailed, username, remoteAddr,
+		map[string]interface{}{"reason": reason}))
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/test/mocks"
+	"github.com/leinonen/bbs/totp"
+)
+
+func newTestAuthenticator(t *testing.T) (*Authenticator, *repository.Manager, *time.Time) {
+	repos := &repository.Manager{
+		User:          mocks.NewUserRepository(),
+		PasswordReset: mocks.NewPasswordResetRepository(),
+		RecoveryCode:  mocks.NewRecoveryCodeRepository(),
+		FailedLogin:   mocks.NewFailedLoginRepository(),
+		Audit:         mocks.NewAuditRepository(),
+	}
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	if err := repos.User.Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	now := time.Now()
+	a := NewAuthenticator(repos, domain.NewLoginThrottle(domain.DefaultThrottlePolicy(), domain.DefaultThrottlePolicy()))
+	a.now = func() time.Time { return now }
+	return a, repos, &now
+}
+
+func TestAuthenticator_Password(t *testing.T) {
+	a, repos, _ := newTestAuthenticator(t)
+
+	result, err := a.Password("testuser", "password123", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Password should accept the right password: %v", err)
+	}
+
+	if result.User.Username != "testuser" || result.PasswordResetID != 0 || result.NeedsSecondFactor() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if _, err := a.Password("testuser", "wrong", "192.0.2.1"); err != ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	attempts, _ := repos.FailedLogin.GetRecent(10)
+	if len(attempts) != 1 || attempts[0].Username != "testuser" || attempts[0].RemoteAddr != "192.0.2.1" ||
+		attempts[0].Reason != domain.FailedLoginPassword {
+		t.Errorf("Expected the failure to be logged, got %+v", attempts)
+	}
+
+	entries, _ := repos.Audit.GetRecent(10)
+	if len(entries) != 1 || entries[0].Action != domain.AuditLoginFailed || entries[0].Target != "testuser" {
+		t.Errorf("Expected the failure in the audit log, got %+v", entries)
+	}
+}
+
+func TestAuthenticator_Throttle(t *testing.T) {
+	a, repos, now := newTestAuthenticator(t)
+	policy := domain.DefaultThrottlePolicy()
+
+	for i := 0; i <= policy.FreeAttempts; i++ {
+		if _, err := a.Password("testuser", "wrong", "192.0.2.1"); err != ErrInvalidCredentials {
+			t.Fatalf("Attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+	}
+
+	// even the right password is refused while throttled, without a check
+	_, err := a.Password("testuser", "password123", "192.0.2.1")
+	var throttled *domain.ThrottledError
+	if !errors.As(err, &throttled) || throttled.Wait <= 0 {
+		t.Fatalf("Expected a ThrottledError, got %v", err)
+	}
+
+	attempts, _ := repos.FailedLogin.GetRecent(50)
+	if len(attempts) != policy.FreeAttempts+1 {
+		t.Errorf("Throttled attempts should not be logged as failures, got %d", len(attempts))
+	}
+
+	// the account is throttled from other addresses too
+	if _, err := a.Password("testuser", "password123", "198.51.100.1"); !errors.As(err, &throttled) {
+		t.Errorf("Expected the account to be throttled from any address, got %v", err)
+	}
+
+	*now = now.Add(throttled.Wait)
+	if _, err := a.Password("testuser", "password123", "192.0.2.1"); err != nil {
+		t.Fatalf("Password should be accepted once the wait has passed: %v", err)
+	}
+
+	// success clears the account
+	if wait := a.Throttle().Wait("testuser", "", *now); wait != 0 {
+		t.Errorf("Success should clear the account's failures, still waiting %v", wait)
+	}
+}
+
+func TestAuthenticator_PasswordReset(t *testing.T) {
+	a, repos, _ := newTestAuthenticator(t)
+
+	user, _ := repos.User.GetByUsername("testuser")
+	reset, token, _ := domain.NewPasswordReset(user.ID, user.ID)
+	repos.PasswordReset.Create(reset)
+
+	result, err := a.Password("testuser", token, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Password should accept a reset token: %v", err)
+	}
+
+	if result.PasswordResetID != reset.ID {
+		t.Errorf("Expected reset ID %d, got %d", reset.ID, result.PasswordResetID)
+	}
+}
+
+func TestAuthenticator_SecondFactor(t *testing.T) {
+	a, repos, now := newTestAuthenticator(t)
+
+	user, _ := repos.User.GetByUsername("testuser")
+	secret, _ := totp.GenerateSecret()
+	repos.User.SetTOTP(user.ID, secret, true)
+
+	result, err := a.Password("testuser", "password123", "192.0.2.1")
+	if err != nil || !result.NeedsSecondFactor() {
+		t.Fatalf("Expected a second factor to be needed, got %v", err)
+	}
+
+	if err := a.SecondFactor(result.User, "000000x", "192.0.2.1"); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+
+	attempts, _ := repos.FailedLogin.GetRecent(10)
+	if len(attempts) != 1 || attempts[0].Reason != domain.FailedLoginCode {
+		t.Errorf("Expected the failed code to be logged, got %+v", attempts)
+	}
+
+	code, _ := totp.Code(secret, *now)
+	if err := a.SecondFactor(result.User, code, "192.0.2.1"); err != nil {
+		t.Errorf("SecondFactor should accept a current code: %v", err)
+	}
+
+	if wait := a.Throttle().Wait("testuser", "", *now); wait != 0 {
+		t.Errorf("Success should clear the account's failures, still waiting %v", wait)
+	}
+}
@@ -2,18 +2,156 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
+
+	"github.com/leinonen/bbs/ftn"
 )
 
 type Config struct {
-	ListenAddr     string `json:"listen_addr"`
-	DatabasePath   string `json:"database_path"`
-	ServerName     string `json:"server_name"`
-	HostKeyPath    string `json:"host_key_path"`
-	AllowAnonymous bool   `json:"allow_anonymous"`
-	MaxUsers       int    `json:"max_users"`
+	ListenAddr     string            `json:"listen_addr"`
+	TelnetAddr     string            `json:"telnet_addr"`
+	WebAddr        string            `json:"web_addr"`
+	APIAddr        string            `json:"api_addr"`
+	FeedAddr       string            `json:"feed_addr"`
+	FeedURL        string            `json:"feed_url"`
+	NNTPAddr       string            `json:"nntp_addr"`
+	GopherAddr     string            `json:"gopher_addr"`
+	GopherHost     string            `json:"gopher_host"`
+	QWKID          string            `json:"qwk_id"`
+	DatabasePath   string            `json:"database_path"`
+	ServerName     string            `json:"server_name"`
+	HostKeyPath    string            `json:"host_key_path"`
+	AllowAnonymous bool              `json:"allow_anonymous"`
+	MaxUsers       int               `json:"max_users"`
+	ArtDir         string            `json:"art_dir"`
+	LogonArt       string            `json:"logon_art"`
+	NewsBulletin   string            `json:"news_bulletin"`
+	LogoffArt      string            `json:"logoff_art"`
+	BoardArt       map[string]string `json:"board_art"`
+
+	MailGatewayAddr   string `json:"mail_gateway_addr"`
+	MailGatewayDomain string `json:"mail_gateway_domain"`
+	// MailGatewayTrustedRelays are the addresses or CIDR ranges of the mail
+	// servers allowed to hand messages to the gateway, and
+	// MailGatewayAuthservID is the name they give themselves in the
+	// Authentication-Results headers they add.
+	MailGatewayTrustedRelays []string `json:"mail_gateway_trusted_relays"`
+	MailGatewayAuthservID    string   `json:"mail_gateway_authserv_id"`
+
+	PasswordMinLength      int  `json:"password_min_length"`
+	PasswordRejectUsername bool `json:"password_reject_username"`
+	PasswordCheckBreached  bool `json:"password_check_breached"`
+
+	LoginLockoutAttempts   int `json:"login_lockout_attempts"`
+	LoginIPLockoutAttempts int `json:"login_ip_lockout_attempts"`
+	LoginLockoutMinutes    int `json:"login_lockout_minutes"`
+
+	RegistrationMode string `json:"registration_mode"`
+	UserInvites      int    `json:"user_invites"`
+
+	MailFrom     string `json:"mail_from"`
+	Maildir      string `json:"maildir"`
+	SMTPAddr     string `json:"smtp_addr"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+
+	RecordingDir           string `json:"recording_dir"`
+	RecordInput            bool   `json:"record_input"`
+	RecordingRetentionDays int    `json:"recording_retention_days"`
+	RecordingMaxMB         int    `json:"recording_max_mb"`
+
+	Webhooks []Webhook `json:"webhooks"`
+
+	FTN FTN `json:"ftn"`
 }
 
+// FTN links boards to echo areas of a FidoNet-style network. Packets are
+// exchanged with the uplink through the inbound and outbound directories,
+// which a mailer such as binkd polls; the BBS is off the network if Address
+// is empty.
+type FTN struct {
+	Address  string            `json:"address"` // this node, e.g. "21:1/100"
+	Uplink   string            `json:"uplink"`
+	Password string            `json:"password"` // packet password, up to 8 characters
+	Inbound  string            `json:"inbound"`
+	Outbound string            `json:"outbound"`
+	Origin   string            `json:"origin"` // server_name if empty
+	User     string            `json:"user"`   // the account imported posts belong to
+	Areas    map[string]string `json:"areas"`  // board name to AREA tag
+}
+
+// BoardFor returns the board carrying an echo area, or "".
+func (f *FTN) BoardFor(area string) string {
+	for board, tag := range f.Areas {
+		if strings.EqualFold(tag, area) {
+			return board
+		}
+	}
+	return ""
+}
+
+// Webhook is a URL told about events on the BBS. Each request is signed with
+// Secret so the receiver can tell it came from the BBS.
+type Webhook struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"` // all events if empty
+}
+
+// Webhook events.
+const (
+	EventThreadCreated  = "thread.created"
+	EventReplyCreated   = "reply.created"
+	EventUserRegistered = "user.registered"
+	EventModeration     = "moderation"
+)
+
+var WebhookEvents = []string{
+	EventThreadCreated, EventReplyCreated, EventUserRegistered, EventModeration,
+}
+
+// Wants reports whether the webhook is told about event.
+func (w *Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook returns the configured webhook for url, or nil.
+func (c *Config) Webhook(url string) *Webhook {
+	for i := range c.Webhooks {
+		if c.Webhooks[i].URL == url {
+			return &c.Webhooks[i]
+		}
+	}
+	return nil
+}
+
+// Registration modes.
+const (
+	RegistrationOpen     = "open"     // accounts are active straight away
+	RegistrationEmail    = "email"    // a code is emailed to verify the address
+	RegistrationInvite   = "invite"   // an invite code is needed to register
+	RegistrationApproval = "approval" // a sysop approves each new account
+)
+
+var RegistrationModes = []string{
+	RegistrationOpen, RegistrationEmail, RegistrationInvite, RegistrationApproval,
+}
+
+var qwkIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,8}$`)
+
 func Default() *Config {
 	return &Config{
 		ListenAddr:     ":2222",
@@ -22,6 +160,29 @@ func Default() *Config {
 		HostKeyPath:    "host_key",
 		AllowAnonymous: true,
 		MaxUsers:       100,
+		ArtDir:         "art",
+		LogonArt:       "logon",
+		NewsBulletin:   "news",
+		LogoffArt:      "logoff",
+		QWKID:          "GOBBS",
+
+		MailGatewayTrustedRelays: []string{"127.0.0.1", "::1"},
+
+		PasswordMinLength:      8,
+		PasswordRejectUsername: true,
+		PasswordCheckBreached:  true,
+
+		LoginLockoutAttempts:   10,
+		LoginIPLockoutAttempts: 50,
+		LoginLockoutMinutes:    15,
+
+		RegistrationMode: RegistrationOpen,
+		UserInvites:      3,
+		MailFrom:         "bbs@localhost",
+
+		RecordingRetentionDays: 30,
+
+		FTN: FTN{User: "echomail"},
 	}
 }
 
@@ -32,12 +193,16 @@ func Load(path string) (*Config, error) {
 	}
 	defer file.Close()
 
-	cfg := &Config{}
+	cfg := Default()
 	decoder := json.NewDecoder(file)
 	if err := decoder.Decode(cfg); err != nil {
 		return nil, err
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -52,3 +217,161 @@ func (c *Config) Save(path string) error {
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(c)
 }
+
+// BoardArtFor returns the header art for a board, falling back to a
+// "board-<name>" screen in the art directory.
+func (c *Config) BoardArtFor(boardName string) string {
+	if name, ok := c.BoardArt[boardName]; ok {
+		return name
+	}
+	return "board-" + boardName
+}
+
+// FeedBaseURL returns the address feed readers reach feeds at: feed_url, or
+// else an address made from feed_addr.
+func (c *Config) FeedBaseURL() string {
+	if c.FeedURL != "" {
+		return strings.TrimSuffix(c.FeedURL, "/")
+	}
+	host, port, err := net.SplitHostPort(c.FeedAddr)
+	if err != nil {
+		return "http://" + c.FeedAddr
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// GopherMenuHost returns the host and port Gopher menus point clients at:
+// gopher_host, or else the host of gopher_addr, with the port of gopher_addr.
+func (c *Config) GopherMenuHost() (string, string) {
+	host, port, err := net.SplitHostPort(c.GopherAddr)
+	if err != nil {
+		host, port = "", "70"
+	}
+	if c.GopherHost != "" {
+		host = c.GopherHost
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return host, port
+}
+
+func (c *Config) Validate() error {
+	valid := false
+	for _, mode := range RegistrationModes {
+		valid = valid || c.RegistrationMode == mode
+	}
+	if !valid {
+		return fmt.Errorf("unknown registration_mode %q", c.RegistrationMode)
+	}
+
+	if c.RegistrationMode == RegistrationEmail && c.SMTPAddr == "" && c.Maildir == "" {
+		return fmt.Errorf("registration_mode %q needs smtp_addr or maildir to send codes", RegistrationEmail)
+	}
+
+	if c.RecordingRetentionDays < 0 || c.RecordingMaxMB < 0 {
+		return fmt.Errorf("recording_retention_days and recording_max_mb cannot be negative")
+	}
+
+	if c.FeedURL != "" {
+		u, err := url.Parse(c.FeedURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("feed_url must be an http or https URL")
+		}
+	}
+
+	// Gopher has no logins, so it can only show boards guests may read
+	if c.GopherAddr != "" && !c.AllowAnonymous {
+		return fmt.Errorf("gopher_addr needs allow_anonymous, since Gopher clients cannot log in")
+	}
+
+	if c.MailGatewayAddr != "" {
+		// without it, no sender could be told from someone forging their From
+		if c.MailGatewayAuthservID == "" {
+			return fmt.Errorf("mail_gateway_addr needs mail_gateway_authserv_id to know which Authentication-Results to trust")
+		}
+		for _, relay := range c.MailGatewayTrustedRelays {
+			if _, _, err := net.ParseCIDR(relay); err != nil && net.ParseIP(relay) == nil {
+				return fmt.Errorf("mail_gateway_trusted_relays: %q is not an IP address or CIDR range", relay)
+			}
+		}
+	}
+
+	// readers name packets and their replies after the ID
+	if !qwkIDPattern.MatchString(c.QWKID) {
+		return fmt.Errorf("qwk_id must be 1 to 8 letters and digits")
+	}
+
+	if c.FTN.Address != "" {
+		if err := c.FTN.validate(); err != nil {
+			return fmt.Errorf("ftn: %v", err)
+		}
+	}
+
+	// deliveries find their webhook again by URL
+	seen := make(map[string]bool)
+	for _, hook := range c.Webhooks {
+		if err := hook.validate(); err != nil {
+			return fmt.Errorf("webhook %q: %v", hook.URL, err)
+		}
+		if seen[hook.URL] {
+			return fmt.Errorf("webhook %q is configured twice", hook.URL)
+		}
+		seen[hook.URL] = true
+	}
+
+	return nil
+}
+
+func (f *FTN) validate() error {
+	for _, addr := range []string{f.Address, f.Uplink} {
+		if _, err := ftn.ParseAddress(addr); err != nil {
+			return err
+		}
+	}
+	if len(f.Password) > 8 {
+		return fmt.Errorf("password cannot be longer than 8 characters")
+	}
+	if f.Inbound == "" || f.Outbound == "" {
+		return fmt.Errorf("inbound and outbound directories are needed")
+	}
+	if f.User == "" {
+		return fmt.Errorf("user is needed to own imported posts")
+	}
+	seen := make(map[string]bool)
+	for board, tag := range f.Areas {
+		tag = strings.ToUpper(tag)
+		if tag == "" || strings.ContainsAny(tag, " \t") {
+			return fmt.Errorf("board %q has an invalid area tag", board)
+		}
+		if seen[tag] {
+			return fmt.Errorf("area %q is given to two boards", tag)
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+func (w *Webhook) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if w.Secret == "" {
+		return fmt.Errorf("a secret is needed to sign requests")
+	}
+	for _, event := range w.Events {
+		known := false
+		for _, e := range WebhookEvents {
+			known = known || event == e
+		}
+		if !known {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	return nil
+}
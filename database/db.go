@@ -29,7 +29,20 @@ func Migrate(db *sql.DB) error {
 		email TEXT UNIQUE NOT NULL,
 		created_at DATETIME NOT NULL,
 		last_login DATETIME NOT NULL,
-		is_admin BOOLEAN DEFAULT 0
+		is_admin BOOLEAN DEFAULT 0,
+		location TEXT NOT NULL DEFAULT '',
+		bio TEXT NOT NULL DEFAULT '',
+		homepage TEXT NOT NULL DEFAULT '',
+		signature TEXT NOT NULL DEFAULT '',
+		timezone TEXT NOT NULL DEFAULT '',
+		date_format TEXT NOT NULL DEFAULT '',
+		totp_secret TEXT NOT NULL DEFAULT '',
+		totp_enabled BOOLEAN NOT NULL DEFAULT 0,
+		totp_last_step INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'active',
+		invited_by INTEGER NOT NULL DEFAULT 0,
+		email_verified BOOLEAN NOT NULL DEFAULT 0,
+		recording_opt_out BOOLEAN NOT NULL DEFAULT 0
 	);
 
 	CREATE TABLE IF NOT EXISTS boards (
@@ -43,6 +56,7 @@ func Migrate(db *sql.DB) error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		board_id INTEGER NOT NULL,
 		user_id INTEGER NOT NULL,
+		author TEXT NOT NULL DEFAULT '',
 		title TEXT,
 		content TEXT NOT NULL,
 		created_at DATETIME NOT NULL,
@@ -58,6 +72,253 @@ func Migrate(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_posts_reply ON posts(reply_to);
 	CREATE INDEX IF NOT EXISTS idx_posts_updated ON posts(updated_at);
 
+	CREATE TABLE IF NOT EXISTS motd (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		content TEXT NOT NULL,
+		updated_by INTEGER,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS motd_seen (
+		user_id INTEGER PRIMARY KEY,
+		seen_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS oneliners (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_oneliners_created ON oneliners(created_at);
+
+	CREATE TABLE IF NOT EXISTS logins (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		remote_addr TEXT NOT NULL,
+		client_version TEXT,
+		login_at DATETIME NOT NULL,
+		logout_at DATETIME,
+		logout_reason TEXT,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_logins_user ON logins(user_id);
+	CREATE INDEX IF NOT EXISTS idx_logins_remote ON logins(remote_addr);
+	CREATE INDEX IF NOT EXISTS idx_logins_login_at ON logins(login_at);
+
+	CREATE TABLE IF NOT EXISTS password_resets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		created_by INTEGER,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		code_hash TEXT NOT NULL,
+		used_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id);
+
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS failed_logins (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL,
+		remote_addr TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		attempted_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_failed_logins_attempted_at ON failed_logins(attempted_at);
+	CREATE INDEX IF NOT EXISTS idx_failed_logins_username ON failed_logins(username);
+	CREATE INDEX IF NOT EXISTS idx_failed_logins_remote_addr ON failed_logins(remote_addr);
+
+	CREATE TABLE IF NOT EXISTS email_verifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER UNIQUE NOT NULL,
+		email TEXT NOT NULL,
+		code_hash TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS invites (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		code TEXT UNIQUE NOT NULL,
+		created_by INTEGER NOT NULL,
+		max_uses INTEGER NOT NULL,
+		uses INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_invites_created_by ON invites(created_by);
+
+	CREATE TABLE IF NOT EXISTS private_messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_user_id INTEGER NOT NULL,
+		from_username TEXT NOT NULL,
+		to_user_id INTEGER NOT NULL,
+		to_username TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		read_at DATETIME,
+		FOREIGN KEY (from_user_id) REFERENCES users(id),
+		FOREIGN KEY (to_user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_private_messages_to ON private_messages(to_user_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_private_messages_from ON private_messages(from_user_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS notification_settings (
+		user_id INTEGER PRIMARY KEY,
+		email_replies BOOLEAN NOT NULL DEFAULT 0,
+		email_messages BOOLEAN NOT NULL DEFAULT 0,
+		email_digest BOOLEAN NOT NULL DEFAULT 0,
+		digest_sent_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS board_subscriptions (
+		user_id INTEGER NOT NULL,
+		board_id INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, board_id),
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (board_id) REFERENCES boards(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS email_queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		to_addr TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		next_attempt_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		sent_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_email_queue_due ON email_queue(status, next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS thread_watches (
+		user_id INTEGER NOT NULL,
+		post_id INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, post_id),
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (post_id) REFERENCES posts(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_thread_watches_post ON thread_watches(post_id);
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		post_id INTEGER NOT NULL,
+		thread_id INTEGER NOT NULL,
+		actor_id INTEGER NOT NULL,
+		actor_username TEXT NOT NULL,
+		title TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		read_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
+
+	CREATE INDEX IF NOT EXISTS idx_board_subscriptions_board ON board_subscriptions(board_id);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER NOT NULL DEFAULT 0,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL,
+		remote_addr TEXT NOT NULL,
+		details TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_used_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+
+	CREATE TABLE IF NOT EXISTS feed_tokens (
+		user_id INTEGER PRIMARY KEY,
+		token_hash TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_code INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		next_attempt_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		delivered_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS read_pointers (
+		user_id INTEGER NOT NULL,
+		board_id INTEGER NOT NULL,
+		post_id INTEGER NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, board_id),
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (board_id) REFERENCES boards(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS echomail (
+		msgid TEXT PRIMARY KEY,
+		post_id INTEGER NOT NULL,
+		area TEXT NOT NULL,
+		imported BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (post_id) REFERENCES posts(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_echomail_post ON echomail(post_id);
+
 	INSERT OR IGNORE INTO boards (id, name, description, created_at)
 	VALUES
 		(1, 'general', 'General discussion', datetime('now')),
@@ -69,5 +330,79 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create schema: %v", err)
 	}
 
+	if err := addMissingColumns(db); err != nil {
+		return fmt.Errorf("failed to upgrade schema: %v", err)
+	}
+
 	return nil
 }
+
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addedColumns lists columns introduced after a table was first created.
+// CREATE TABLE IF NOT EXISTS leaves older databases alone, so these are
+// added with ALTER TABLE when missing.
+var addedColumns = []column{
+	{"users", "location", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "bio", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "homepage", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "signature", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "timezone", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "date_format", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "totp_secret", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "totp_enabled", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"users", "totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "status", "TEXT NOT NULL DEFAULT 'active'"},
+	{"users", "invited_by", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "email_verified", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"users", "recording_opt_out", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"posts", "author", "TEXT NOT NULL DEFAULT ''"},
+}
+
+func addMissingColumns(db *sql.DB) error {
+	for _, c := range addedColumns {
+		exists, err := hasColumn(db, c.table, c.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %v", c.table, c.name, err)
+		}
+	}
+	return nil
+}
+
+func hasColumn(db *sql.DB, table, name string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			columnName string
+			columnType string
+			notNull    bool
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &columnName, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return false, err
+		}
+		if columnName == name {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxAPITokens is how many API tokens one user may hold at a time.
+	MaxAPITokens          = 10
+	MaxAPITokenNameLength = 40
+
+	// APITokenPrefix makes tokens easy to spot, for example in a leaked
+	// config file.
+	APITokenPrefix = "bbs_"
+)
+
+// APIToken lets a program use the HTTP API as the user who created it. Only
+// a hash of the token is stored, so it can be shown just once.
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	TokenHash  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// NewAPIToken creates a token for the user and returns it together with the
+// plaintext token to hand to them.
+func NewAPIToken(userID int, name string) (*APIToken, string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := APITokenPrefix + hex.EncodeToString(b)
+
+	apiToken := &APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: HashToken(token),
+		CreatedAt: time.Now(),
+	}
+	apiToken.Sanitize()
+	if err := apiToken.Validate(); err != nil {
+		return nil, "", err
+	}
+	return apiToken, token, nil
+}
+
+func (t *APIToken) Sanitize() {
+	t.Name = strings.TrimSpace(SanitizeLine(t.Name))
+}
+
+func (t *APIToken) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("token name cannot be empty")
+	}
+	if len([]rune(t.Name)) > MaxAPITokenNameLength {
+		return fmt.Errorf("token name must be at most %d characters", MaxAPITokenNameLength)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAPIToken(t *testing.T) {
+	apiToken, token, err := NewAPIToken(5, "  backup script ")
+	if err != nil {
+		t.Fatalf("NewAPIToken failed: %v", err)
+	}
+
+	if !strings.HasPrefix(token, "bbs_") || len(token) != 44 {
+		t.Errorf("Expected a prefixed 40 digit token, got %q", token)
+	}
+	if apiToken.TokenHash != HashToken(token) {
+		t.Error("Expected stored hash to match the token")
+	}
+	if apiToken.UserID != 5 || apiToken.Name != "backup script" {
+		t.Errorf("Expected the user and trimmed name to be set, got %d %q", apiToken.UserID, apiToken.Name)
+	}
+
+	_, other, _ := NewAPIToken(5, "other")
+	if other == token {
+		t.Error("Expected tokens to be random")
+	}
+}
+
+func TestNewAPIToken_InvalidName(t *testing.T) {
+	for _, name := range []string{"", "   ", strings.Repeat("x", MaxAPITokenNameLength+1)} {
+		if _, _, err := NewAPIToken(1, name); err == nil {
+			t.Errorf("Expected name %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Audit actions are named "<category>.<event>", so a filter can ask for a
+// whole category such as "auth".
+const (
+	AuditLogin             = "auth.login"
+	AuditLoginFailed       = "auth.login_failed"
+	AuditRegister          = "user.register"
+	AuditUserApprove       = "user.approve"
+	AuditUserReject        = "user.reject"
+	AuditUserRole          = "user.role"
+	AuditUserBan           = "user.ban"
+	AuditUserUnban         = "user.unban"
+	AuditPasswordReset     = "user.password_reset"
+	AuditBoardCreate       = "board.create"
+	AuditPostEdit          = "post.edit"
+	AuditPostDelete        = "post.delete"
+	AuditSessionSnoop      = "session.snoop"
+	AuditSessionMessage    = "session.message"
+	AuditSessionLogout     = "session.logout"
+	AuditSessionDisconnect = "session.disconnect"
+	AuditRecordingPlay     = "recording.play"
+	AuditRecordingDelete   = "recording.delete"
+	AuditExport            = "audit.export"
+	AuditAPITokenCreate    = "api_token.create"
+	AuditAPITokenRevoke    = "api_token.revoke"
+	AuditFeedTokenCreate   = "feed_token.create"
+	AuditFeedTokenRevoke   = "feed_token.revoke"
+)
+
+// moderationActions are the audited actions a sysop takes on other users
+// and the boards. Edits and deletions of posts count only when the post is
+// someone else's, which the caller knows.
+var moderationActions = map[string]bool{
+	AuditUserApprove:       true,
+	AuditUserReject:        true,
+	AuditUserRole:          true,
+	AuditUserBan:           true,
+	AuditUserUnban:         true,
+	AuditPasswordReset:     true,
+	AuditBoardCreate:       true,
+	AuditSessionLogout:     true,
+	AuditSessionDisconnect: true,
+}
+
+func IsModerationAction(action string) bool {
+	return moderationActions[action]
+}
+
+// AuditEntry records a security or moderation event: who did what to whom.
+type AuditEntry struct {
+	ID         int
+	ActorID    int
+	Actor      string
+	Action     string
+	Target     string
+	RemoteAddr string
+	Details    string // a JSON object
+	CreatedAt  time.Time
+}
+
+// NewAuditEntry records actor doing action to target. actor may be nil for
+// events with nobody logged in.
+func NewAuditEntry(actor *User, action, target, remoteAddr string, details map[string]interface{}) *AuditEntry {
+	entry := &AuditEntry{
+		Action:     action,
+		Target:     target,
+		RemoteAddr: remoteAddr,
+		Details:    "{}",
+		CreatedAt:  time.Now(),
+	}
+
+	if actor != nil {
+		entry.ActorID = actor.ID
+		entry.Actor = actor.Username
+	}
+
+	if len(details) > 0 {
+		if data, err := json.Marshal(details); err == nil {
+			entry.Details = string(data)
+		}
+	}
+	return entry
+}
+
+func (e *AuditEntry) Sanitize() {
+	e.Actor = SanitizeLine(e.Actor)
+	e.Target = SanitizeLine(e.Target)
+}
+
+// AuditFilter narrows down the audit log. Empty fields match everything.
+type AuditFilter struct {
+	Actor  string // exact username, ignoring case
+	Action string // an action, or a category such as "auth"
+	Target string // part of the target, ignoring case
+	Since  time.Time
+	Until  time.Time
+}
+
+// Matches reports whether entry passes the filter.
+func (f AuditFilter) Matches(entry *AuditEntry) bool {
+	if f.Actor != "" && !strings.EqualFold(entry.Actor, f.Actor) {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action && !strings.HasPrefix(entry.Action, f.Action+".") {
+		return false
+	}
+	if f.Target != "" && !strings.Contains(strings.ToLower(entry.Target), strings.ToLower(f.Target)) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.CreatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// auditJSON is how an entry is exported, one per line.
+type auditJSON struct {
+	ID         int             `json:"id"`
+	Time       time.Time       `json:"time"`
+	ActorID    int             `json:"actor_id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Target     string          `json:"target"`
+	RemoteAddr string          `json:"remote_addr"`
+	Details    json.RawMessage `json:"details"`
+}
+
+func (e *AuditEntry) MarshalJSON() ([]byte, error) {
+	details := json.RawMessage(e.Details)
+	if !json.Valid(details) {
+		details = json.RawMessage("{}")
+	}
+	return json.Marshal(auditJSON{
+		ID:         e.ID,
+		Time:       e.CreatedAt.UTC(),
+		ActorID:    e.ActorID,
+		Actor:      e.Actor,
+		Action:     e.Action,
+		Target:     e.Target,
+		RemoteAddr: e.RemoteAddr,
+		Details:    details,
+	})
+}
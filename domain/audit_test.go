@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAuditFilter_Matches(t *testing.T) {
+	now := time.Now()
+	entry := &AuditEntry{Actor: "Carol", Action: AuditLoginFailed, Target: "node 2 (Dave)", CreatedAt: now}
+
+	tests := []struct {
+		filter AuditFilter
+		want   bool
+	}{
+		{AuditFilter{}, true},
+		{AuditFilter{Actor: "carol"}, true},
+		{AuditFilter{Actor: "car"}, false},
+		{AuditFilter{Action: "auth"}, true},
+		{AuditFilter{Action: AuditLoginFailed}, true},
+		{AuditFilter{Action: AuditLogin}, false},
+		{AuditFilter{Action: "au"}, false},
+		{AuditFilter{Target: "dave"}, true},
+		{AuditFilter{Target: "erin"}, false},
+		{AuditFilter{Since: now.Add(-time.Hour)}, true},
+		{AuditFilter{Since: now.Add(time.Hour)}, false},
+		{AuditFilter{Until: now}, false},
+		{AuditFilter{Until: now.Add(time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Matches(entry); got != tt.want {
+			t.Errorf("%+v: expected %v, got %v", tt.filter, tt.want, got)
+		}
+	}
+}
+
+func TestAuditEntry_MarshalJSON(t *testing.T) {
+	entry := NewAuditEntry(&User{ID: 3, Username: "carol"}, AuditUserBan, "dave", "192.0.2.1",
+		map[string]interface{}{"reason": "spam"})
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+	details, _ := got["details"].(map[string]interface{})
+	if got["actor"] != "carol" || got["action"] != AuditUserBan || details["reason"] != "spam" {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+
+	entry.Details = "not json"
+	if data, err := json.Marshal(entry); err != nil || !json.Valid(data) {
+		t.Errorf("Expected valid JSON for bad details, got %s, %v", data, err)
+	}
+}
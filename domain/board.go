@@ -18,3 +18,8 @@ func NewBoard(name, description string) *Board {
 		PostCount:   0,
 	}
 }
+
+func (b *Board) Sanitize() {
+	b.Name = SanitizeLine(b.Name)
+	b.Description = SanitizeLine(b.Description)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// EchomailMessage links a post to the echomail message it came in as or went
+// out as. Messages are known by MSGID, which spots duplicates and lets
+// replies find their thread.
+type EchomailMessage struct {
+	MSGID     string
+	PostID    int
+	Area      string
+	Imported  bool // tossed in from the network rather than written here
+	CreatedAt time.Time
+}
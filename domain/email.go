@@ -0,0 +1,97 @@
+package domain
+
+import "time"
+
+// NotificationSettings are a user's choices about which email they get.
+// Everything is off until the user turns it on.
+type NotificationSettings struct {
+	UserID        int
+	EmailReplies  bool // a reply to one of the user's threads
+	EmailMessages bool // a new private message
+	EmailDigest   bool // a daily digest of new threads on subscribed boards
+	// DigestSentAt is when the last digest was sent; the next one covers
+	// threads started since.
+	DigestSentAt time.Time
+}
+
+func (s *NotificationSettings) Any() bool {
+	return s.EmailReplies || s.EmailMessages || s.EmailDigest
+}
+
+const DigestInterval = 24 * time.Hour
+
+func (s *NotificationSettings) DigestDue(now time.Time) bool {
+	return s.EmailDigest && !now.Before(s.DigestSentAt.Add(DigestInterval))
+}
+
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed" // gave up after MaxEmailAttempts
+)
+
+const (
+	MaxEmailAttempts = 8
+	emailRetryBase   = time.Minute
+	emailRetryMax    = 6 * time.Hour
+)
+
+// QueuedEmail is an outgoing email waiting in the database to be sent, so it
+// survives restarts and is retried when the mail server is unavailable.
+type QueuedEmail struct {
+	ID            int
+	To            string
+	Subject       string
+	Body          string
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+func NewQueuedEmail(to, subject, body string) *QueuedEmail {
+	now := time.Now()
+	return &QueuedEmail{
+		To:            to,
+		Subject:       subject,
+		Body:          body,
+		Status:        EmailPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+// Failed records a failed delivery attempt and schedules the next one,
+// doubling the wait each time, or gives up after MaxEmailAttempts.
+func (e *QueuedEmail) Failed(err error, now time.Time) {
+	e.Attempts++
+	e.LastError = err.Error()
+	if e.Attempts >= MaxEmailAttempts {
+		e.Status = EmailFailed
+		return
+	}
+
+	e.NextAttemptAt = now.Add(retryDelay(e.Attempts, emailRetryBase, emailRetryMax))
+}
+
+// retryDelay is how long to wait after the given number of failed attempts:
+// base, doubling each time, up to max.
+func retryDelay(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+func (e *QueuedEmail) Sent(now time.Time) {
+	e.Attempts++
+	e.Status = EmailSent
+	e.LastError = ""
+	e.SentAt = &now
+}
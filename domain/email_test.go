@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueuedEmail_Failed(t *testing.T) {
+	email := NewQueuedEmail("user@example.com", "Hello", "Hi")
+	now := time.Now()
+
+	email.Failed(errors.New("connection refused"), now)
+	if email.Status != EmailPending || email.Attempts != 1 || email.LastError != "connection refused" {
+		t.Errorf("Unexpected email after first failure: %+v", email)
+	}
+
+	if got := email.NextAttemptAt.Sub(now); got != time.Minute {
+		t.Errorf("Expected a 1m retry delay, got %v", got)
+	}
+
+	email.Failed(errors.New("again"), now)
+	if got := email.NextAttemptAt.Sub(now); got != 2*time.Minute {
+		t.Errorf("Expected the retry delay to double to 2m, got %v", got)
+	}
+
+	for email.Status == EmailPending {
+		email.Failed(errors.New("still down"), now)
+	}
+
+	if email.Attempts != MaxEmailAttempts || email.Status != EmailFailed {
+		t.Errorf("Expected to give up after %d attempts, got %d (%s)", MaxEmailAttempts, email.Attempts, email.Status)
+	}
+}
+
+func TestQueuedEmail_Sent(t *testing.T) {
+	email := NewQueuedEmail("user@example.com", "Hello", "Hi")
+	email.Failed(errors.New("down"), time.Now())
+	email.Sent(time.Now())
+
+	if email.Status != EmailSent || email.SentAt == nil || email.LastError != "" || email.Attempts != 2 {
+		t.Errorf("Unexpected email after sending: %+v", email)
+	}
+}
+
+func TestNotificationSettings_DigestDue(t *testing.T) {
+	now := time.Now()
+	settings := &NotificationSettings{DigestSentAt: now.Add(-25 * time.Hour)}
+
+	if settings.DigestDue(now) {
+		t.Error("Digest should not be due when it is turned off")
+	}
+
+	settings.EmailDigest = true
+	if !settings.DigestDue(now) {
+		t.Error("Digest should be due a day after the last one")
+	}
+
+	settings.DigestSentAt = now.Add(-time.Hour)
+	if settings.DigestDue(now) {
+		t.Error("Digest should not be due an hour after the last one")
+	}
+}
+
+func TestPrivateMessage_Validate(t *testing.T) {
+	alice := &User{ID: 1, Username: "alice"}
+	bob := &User{ID: 2, Username: "bob"}
+
+	if err := NewPrivateMessage(alice, bob, "Hi", "Hello bob").Validate(); err != nil {
+		t.Errorf("Expected a valid message, got %v", err)
+	}
+
+	if err := NewPrivateMessage(alice, alice, "Hi", "Hello me").Validate(); err == nil {
+		t.Error("Messages to yourself should be rejected")
+	}
+
+	if err := NewPrivateMessage(alice, bob, " ", "Hello").Validate(); err == nil {
+		t.Error("An empty subject should be rejected")
+	}
+
+	if err := NewPrivateMessage(alice, bob, "Hi", "\n").Validate(); err == nil {
+		t.Error("An empty body should be rejected")
+	}
+
+	if ReplySubject("Hi") != "Re: Hi" || ReplySubject("RE: Hi") != "RE: Hi" {
+		t.Error("ReplySubject should add a single Re: prefix")
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const feedTokenPrefix = "feed_"
+
+// FeedToken lets a feed reader fetch feeds as its user, without the power of
+// an API token. A user has at most one, and only a hash of it is stored.
+type FeedToken struct {
+	UserID    int
+	TokenHash string
+	CreatedAt time.Time
+}
+
+// NewFeedToken creates a token for the user and returns it together with the
+// plaintext token to hand to them.
+func NewFeedToken(userID int) (*FeedToken, string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := feedTokenPrefix + hex.EncodeToString(b)
+
+	return &FeedToken{
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		CreatedAt: time.Now(),
+	}, token, nil
+}
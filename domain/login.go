@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+const (
+	LogoutReasonLogout     = "logout"
+	LogoutReasonExit       = "exit"
+	LogoutReasonDisconnect = "disconnect"
+	LogoutReasonKicked     = "kicked" // disconnected by a sysop
+	LogoutReasonForced     = "forced" // logged out by a sysop, still connected
+)
+
+type Login struct {
+	ID            int
+	UserID        int
+	Username      string
+	RemoteAddr    string
+	ClientVersion string
+	LoginAt       time.Time
+	LogoutAt      *time.Time // nil while the session is still open
+	LogoutReason  string
+}
+
+func NewLogin(userID int, username, remoteAddr, clientVersion string) *Login {
+	return &Login{
+		UserID:        userID,
+		Username:      username,
+		RemoteAddr:    remoteAddr,
+		ClientVersion: clientVersion,
+		LoginAt:       time.Now(),
+	}
+}
+
+// Duration returns the session length, measured up to now for sessions
+// that are still open.
+func (l *Login) Duration(now time.Time) time.Duration {
+	if l.LogoutAt != nil {
+		return l.LogoutAt.Sub(l.LoginAt)
+	}
+	return now.Sub(l.LoginAt)
+}
+
+func (l *Login) Sanitize() {
+	l.ClientVersion = SanitizeLine(l.ClientVersion)
+}
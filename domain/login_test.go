@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLogin(t *testing.T) {
+	login := NewLogin(3, "testuser", "192.0.2.1", "SSH-2.0-OpenSSH_9.6")
+
+	if login.UserID != 3 {
+		t.Errorf("Expected UserID 3, got %d", login.UserID)
+	}
+
+	if login.RemoteAddr != "192.0.2.1" {
+		t.Errorf("Expected remote address 192.0.2.1, got %s", login.RemoteAddr)
+	}
+
+	if login.ClientVersion != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("Expected client version to be set, got %s", login.ClientVersion)
+	}
+
+	if login.LogoutAt != nil {
+		t.Error("Expected new login to have no logout time")
+	}
+
+	now := time.Now()
+	if login.LoginAt.After(now) || login.LoginAt.Before(now.Add(-time.Second)) {
+		t.Error("LoginAt timestamp should be recent")
+	}
+}
+
+func TestLoginDuration(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	login := &Login{LoginAt: start}
+
+	if got := login.Duration(start.Add(5 * time.Minute)); got != 5*time.Minute {
+		t.Errorf("Expected open session duration 5m, got %v", got)
+	}
+
+	logout := start.Add(30 * time.Minute)
+	login.LogoutAt = &logout
+
+	if got := login.Duration(start.Add(time.Hour)); got != 30*time.Minute {
+		t.Errorf("Expected closed session duration 30m, got %v", got)
+	}
+}
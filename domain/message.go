@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	MaxMessageSubjectLength = 72
+	MaxMessageBodyLength    = 10000
+)
+
+// PrivateMessage is a message from one user to another, outside the boards.
+type PrivateMessage struct {
+	ID           int
+	FromUserID   int
+	FromUsername string
+	ToUserID     int
+	ToUsername   string
+	Subject      string
+	Body         string
+	CreatedAt    time.Time
+	ReadAt       *time.Time
+}
+
+func NewPrivateMessage(from, to *User, subject, body string) *PrivateMessage {
+	return &PrivateMessage{
+		FromUserID:   from.ID,
+		FromUsername: from.Username,
+		ToUserID:     to.ID,
+		ToUsername:   to.Username,
+		Subject:      subject,
+		Body:         body,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func (m *PrivateMessage) Sanitize() {
+	m.FromUsername = SanitizeLine(m.FromUsername)
+	m.ToUsername = SanitizeLine(m.ToUsername)
+	m.Subject = SanitizeLine(m.Subject)
+	m.Body = SanitizeText(m.Body)
+}
+
+func (m *PrivateMessage) Validate() error {
+	if m.FromUserID == m.ToUserID {
+		return errors.New("you cannot send a message to yourself")
+	}
+
+	if strings.TrimSpace(m.Subject) == "" {
+		return errors.New("subject cannot be empty")
+	}
+
+	if len([]rune(m.Subject)) > MaxMessageSubjectLength {
+		return fmt.Errorf("subject is limited to %d characters", MaxMessageSubjectLength)
+	}
+
+	if strings.TrimSpace(m.Body) == "" {
+		return errors.New("message cannot be empty")
+	}
+
+	if len([]rune(m.Body)) > MaxMessageBodyLength {
+		return fmt.Errorf("message is limited to %d characters", MaxMessageBodyLength)
+	}
+
+	return nil
+}
+
+func (m *PrivateMessage) IsRead() bool {
+	return m.ReadAt != nil
+}
+
+// ReplySubject prefixes subject with "Re: " unless it already has one.
+func ReplySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+type Motd struct {
+	Content   string
+	UpdatedBy int
+	UpdatedAt time.Time
+}
+
+func NewMotd(content string, updatedBy int) *Motd {
+	return &Motd{
+		Content:   content,
+		UpdatedBy: updatedBy,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// ShouldShow reports whether a user who last saw the message at lastSeen
+// should see it again at now: once per calendar day, or straight away when
+// the sysop has changed it since.
+func (m *Motd) ShouldShow(lastSeen, now time.Time) bool {
+	if m == nil || strings.TrimSpace(m.Content) == "" {
+		return false
+	}
+
+	if lastSeen.IsZero() || m.UpdatedAt.After(lastSeen) {
+		return true
+	}
+
+	y1, m1, d1 := lastSeen.Date()
+	y2, m2, d2 := now.In(lastSeen.Location()).Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
+
+func (m *Motd) Sanitize() {
+	m.Content = SanitizeText(m.Content)
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewMotd(t *testing.T) {
+	motd := NewMotd("Welcome!", 1)
+
+	if motd.Content != "Welcome!" {
+		t.Errorf("Expected content Welcome!, got %s", motd.Content)
+	}
+
+	if motd.UpdatedBy != 1 {
+		t.Errorf("Expected UpdatedBy 1, got %d", motd.UpdatedBy)
+	}
+
+	now := time.Now()
+	if motd.UpdatedAt.After(now) || motd.UpdatedAt.Before(now.Add(-time.Second)) {
+		t.Error("UpdatedAt timestamp should be recent")
+	}
+}
+
+func TestMotdShouldShow(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	motd := &Motd{Content: "Hello", UpdatedAt: updated}
+
+	if !motd.ShouldShow(time.Time{}, updated.Add(time.Hour)) {
+		t.Error("MOTD should show to a user who has never seen it")
+	}
+
+	seen := updated.Add(time.Hour)
+	if motd.ShouldShow(seen, seen.Add(2*time.Hour)) {
+		t.Error("MOTD should not show twice on the same day")
+	}
+
+	if !motd.ShouldShow(seen, seen.Add(24*time.Hour)) {
+		t.Error("MOTD should show again the next day")
+	}
+
+	motd.UpdatedAt = seen.Add(time.Minute)
+	if !motd.ShouldShow(seen, seen.Add(time.Hour)) {
+		t.Error("MOTD should show again after it has been updated")
+	}
+
+	empty := &Motd{Content: "  ", UpdatedAt: updated}
+	if empty.ShouldShow(time.Time{}, updated) {
+		t.Error("Empty MOTD should never show")
+	}
+
+	var missing *Motd
+	if missing.ShouldShow(time.Time{}, updated) {
+		t.Error("Nil MOTD should never show")
+	}
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	NotificationMention = "mention" // someone @mentioned the user
+	NotificationReply   = "reply"   // a reply in a thread the user watches
+	NotificationThread  = "thread"  // a new thread on a board the user watches
+)
+
+// MaxMentions bounds how many users one post can notify by mentioning them.
+const MaxMentions = 10
+
+// Notification tells a user about a post, and is shown in their inbox on the
+// BBS until they read it.
+type Notification struct {
+	ID            int
+	UserID        int
+	Kind          string
+	PostID        int // the post that caused the notification
+	ThreadID      int // the thread it is in, opened from the inbox
+	ActorID       int
+	ActorUsername string
+	Title         string // the thread's title when the post was made
+	CreatedAt     time.Time
+	ReadAt        *time.Time
+}
+
+func NewNotification(userID int, kind string, post *Post, thread *Post) *Notification {
+	return &Notification{
+		UserID:        userID,
+		Kind:          kind,
+		PostID:        post.ID,
+		ThreadID:      thread.ID,
+		ActorID:       post.UserID,
+		ActorUsername: post.Username,
+		Title:         thread.Title,
+		CreatedAt:     time.Now(),
+	}
+}
+
+func (n *Notification) Sanitize() {
+	n.ActorUsername = SanitizeLine(n.ActorUsername)
+	n.Title = SanitizeLine(n.Title)
+}
+
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// Summary describes the notification in one line.
+func (n *Notification) Summary() string {
+	switch n.Kind {
+	case NotificationMention:
+		return fmt.Sprintf("%s mentioned you in \"%s\"", n.ActorUsername, n.Title)
+	case NotificationReply:
+		return fmt.Sprintf("%s replied to \"%s\"", n.ActorUsername, n.Title)
+	case NotificationThread:
+		return fmt.Sprintf("%s started \"%s\"", n.ActorUsername, n.Title)
+	}
+	return fmt.Sprintf("%s posted in \"%s\"", n.ActorUsername, n.Title)
+}
+
+// mentionPattern matches @username where the name follows ValidateUsername's
+// rules; punctuation ending a sentence is trimmed afterwards.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9][A-Za-z0-9_.\-]{2,19})`)
+
+// Mentions returns the usernames @mentioned in text, each once and in the
+// order they first appear, up to MaxMentions.
+func Mentions(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimRight(match[1], ".-")
+		if len(name) < 3 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == MaxMentions {
+			break
+		}
+	}
+	return names
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"no mentions here", nil},
+		{"@alice what do you think?", []string{"alice"}},
+		{"Thanks @bob.", []string{"bob"}},
+		{"cc @bob, @carol_1 and @bob again", []string{"bob", "carol_1"}},
+		{"mail me at dave@example.com", nil},
+		{"@@alice and @ab are not mentions", nil},
+		{"(@dave.smith)", []string{"dave.smith"}},
+	}
+
+	for _, tt := range tests {
+		if got := Mentions(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Mentions(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMentions_Limit(t *testing.T) {
+	var text strings.Builder
+	for i := 0; i < MaxMentions+5; i++ {
+		fmt.Fprintf(&text, "@user%d ", i)
+	}
+
+	if got := Mentions(text.String()); len(got) != MaxMentions {
+		t.Errorf("Expected %d mentions, got %d", MaxMentions, len(got))
+	}
+}
+
+func TestNotification_Summary(t *testing.T) {
+	thread := &Post{ID: 1, UserID: 1, Username: "alice", Title: "Hello"}
+	reply := &Post{ID: 2, UserID: 2, Username: "bob"}
+
+	n := NewNotification(3, NotificationReply, reply, thread)
+	if n.PostID != 2 || n.ThreadID != 1 || n.ActorUsername != "bob" || n.IsRead() {
+		t.Errorf("Unexpected notification: %+v", n)
+	}
+
+	if got := n.Summary(); got != `bob replied to "Hello"` {
+		t.Errorf("Unexpected summary: %s", got)
+	}
+}
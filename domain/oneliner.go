@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+const MaxOneLinerLength = 72
+
+type OneLiner struct {
+	ID        int
+	UserID    int
+	Username  string
+	Text      string
+	CreatedAt time.Time
+}
+
+func NewOneLiner(userID int, username, text string) *OneLiner {
+	return &OneLiner{
+		UserID:    userID,
+		Username:  username,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (o *OneLiner) Sanitize() {
+	o.Username = SanitizeLine(o.Username)
+	o.Text = SanitizeLine(o.Text)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewOneLiner(t *testing.T) {
+	oneLiner := NewOneLiner(7, "testuser", "Hello from the wall")
+
+	if oneLiner.UserID != 7 {
+		t.Errorf("Expected UserID 7, got %d", oneLiner.UserID)
+	}
+
+	if oneLiner.Username != "testuser" {
+		t.Errorf("Expected username testuser, got %s", oneLiner.Username)
+	}
+
+	if oneLiner.Text != "Hello from the wall" {
+		t.Errorf("Expected text to be set, got %s", oneLiner.Text)
+	}
+
+	if oneLiner.ID != 0 {
+		t.Errorf("Expected new one-liner ID to be 0, got %d", oneLiner.ID)
+	}
+
+	now := time.Now()
+	if oneLiner.CreatedAt.After(now) || oneLiner.CreatedAt.Before(now.Add(-time.Second)) {
+		t.Error("CreatedAt timestamp should be recent")
+	}
+}
+
+func TestOneLinerSanitize(t *testing.T) {
+	oneLiner := NewOneLiner(1, "user", "multi\nline\033[2J")
+	oneLiner.Sanitize()
+
+	if oneLiner.Text != "multi line" {
+		t.Errorf("Expected sanitized single line, got %q", oneLiner.Text)
+	}
+}
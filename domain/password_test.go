@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, RejectUsername: true, CheckBreached: true}
+
+	if err := policy.Validate("alice", "correct horse battery"); err != nil {
+		t.Errorf("Expected strong password to pass, got %v", err)
+	}
+
+	rejected := map[string]string{
+		"empty":      "",
+		"too short":  "abc12",
+		"username":   "xxALICExx99",
+		"breached":   "Password123",
+		"breached 2": "qwertyuiop",
+	}
+
+	for name, password := range rejected {
+		if err := policy.Validate("alice", password); err == nil {
+			t.Errorf("Expected %s password %q to be rejected", name, password)
+		}
+	}
+
+	lenient := PasswordPolicy{MinLength: 1}
+	if err := lenient.Validate("alice", "alice"); err != nil {
+		t.Errorf("Expected lenient policy to allow username, got %v", err)
+	}
+}
+
+func TestIsBreachedPassword(t *testing.T) {
+	if !IsBreachedPassword("LetMeIn") {
+		t.Error("Expected breached check to be case-insensitive")
+	}
+
+	if IsBreachedPassword("# Commonly breached passwords, one per line, compared case-insensitively.") {
+		t.Error("Comment lines should not be treated as passwords")
+	}
+}
+
+func TestNewPasswordReset(t *testing.T) {
+	reset, token, err := NewPasswordReset(5, 1)
+	if err != nil {
+		t.Fatalf("NewPasswordReset failed: %v", err)
+	}
+
+	if token == "" {
+		t.Error("Expected a plaintext token")
+	}
+
+	if reset.TokenHash != HashToken(token) {
+		t.Error("Expected stored hash to match the token")
+	}
+
+	if reset.TokenHash == token {
+		t.Error("Token must not be stored in plaintext")
+	}
+
+	if reset.UserID != 5 || reset.CreatedBy != 1 {
+		t.Errorf("Unexpected reset %+v", reset)
+	}
+
+	now := time.Now()
+	if !reset.IsValid(now) {
+		t.Error("New reset should be valid")
+	}
+
+	if reset.IsValid(now.Add(PasswordResetTTL + time.Minute)) {
+		t.Error("Reset should expire")
+	}
+
+	reset.UsedAt = &now
+	if reset.IsValid(now) {
+		t.Error("Used reset should not be valid")
+	}
+}
+
+func TestHashTokenNormalizes(t *testing.T) {
+	if HashToken(" ABCdef ") != HashToken("abcdef") {
+		t.Error("HashToken should ignore case and surrounding whitespace")
+	}
+}
@@ -3,10 +3,14 @@ package domain
 import "time"
 
 type Post struct {
-	ID        int
-	BoardID   int
-	UserID    int
-	Username  string
+	ID       int
+	BoardID  int
+	UserID   int
+	Username string
+	// Author is the name of a poster on another system, who has no account
+	// here, as name@address. The post belongs to UserID, and Username shows
+	// Author.
+	Author    string
 	Title     string
 	Content   string
 	CreatedAt time.Time
@@ -42,3 +46,10 @@ func NewReply(boardID, userID int, username, content string, replyTo int) *Post
 		Replies:   0,
 	}
 }
+
+func (p *Post) Sanitize() {
+	p.Username = SanitizeLine(p.Username)
+	p.Author = SanitizeLine(p.Author)
+	p.Title = SanitizeLine(p.Title)
+	p.Content = SanitizeText(p.Content)
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Account statuses. Only active users can use the BBS; the others are
+// waiting on a step of registration, or were banned by a sysop.
+const (
+	UserStatusActive     = "active"
+	UserStatusUnverified = "unverified" // must enter the code emailed to them
+	UserStatusPending    = "pending"    // waiting for a sysop to approve them
+	UserStatusBanned     = "banned"
+)
+
+func (u *User) IsActive() bool {
+	return u.Status == "" || u.Status == UserStatusActive
+}
+
+const (
+	VerificationCodeLength = 8
+	VerificationTTL        = 24 * time.Hour
+	// MaxVerificationAttempts bounds guesses at a code; after that a new one
+	// has to be sent.
+	MaxVerificationAttempts = 5
+)
+
+// EmailVerification is a code sent to a new user's email address. Only a
+// hash of the code is stored.
+type EmailVerification struct {
+	ID        int
+	UserID    int
+	Email     string
+	CodeHash  string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewEmailVerification returns a verification for email and the code to send.
+func NewEmailVerification(userID int, email string) (*EmailVerification, string, error) {
+	code, err := randomDigits(VerificationCodeLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &EmailVerification{
+		UserID:    userID,
+		Email:     email,
+		CodeHash:  HashToken(code),
+		ExpiresAt: now.Add(VerificationTTL),
+		CreatedAt: now,
+	}, code, nil
+}
+
+// Check compares code against the stored hash. It reports why a code was
+// refused so the user knows whether to ask for a new one.
+func (v *EmailVerification) Check(code string, now time.Time) error {
+	if !now.Before(v.ExpiresAt) {
+		return errors.New("verification code has expired, request a new one")
+	}
+	if v.Attempts >= MaxVerificationAttempts {
+		return errors.New("too many wrong codes, request a new one")
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if subtle.ConstantTimeCompare([]byte(HashToken(code)), []byte(v.CodeHash)) != 1 {
+		return errors.New("verification code is not valid")
+	}
+	return nil
+}
+
+func randomDigits(n int) (string, error) {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + d.Int64()))
+	}
+	return b.String(), nil
+}
+
+const MaxInviteUses = 100
+
+// Invite is a code that lets someone register when registration is
+// invite-only. The code is kept in the clear so its creator can look it up
+// and pass it on again.
+type Invite struct {
+	ID        int
+	Code      string
+	CreatedBy int
+	MaxUses   int
+	Uses      int
+	CreatedAt time.Time
+}
+
+func NewInvite(createdBy, maxUses int) (*Invite, error) {
+	if maxUses < 1 || maxUses > MaxInviteUses {
+		return nil, fmt.Errorf("an invite can be used 1 to %d times", MaxInviteUses)
+	}
+
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	code := hex.EncodeToString(b)
+
+	return &Invite{
+		Code:      code[:6] + "-" + code[6:],
+		CreatedBy: createdBy,
+		MaxUses:   maxUses,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (i *Invite) Remaining() int {
+	if i.Uses >= i.MaxUses {
+		return 0
+	}
+	return i.MaxUses - i.Uses
+}
+
+// NormalizeInviteCode lower-cases a code and restores the separator if it
+// was left out.
+func NormalizeInviteCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	if len(code) == 12 && !strings.Contains(code, "-") {
+		code = code[:6] + "-" + code[6:]
+	}
+	return code
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEmailVerification_Check(t *testing.T) {
+	v, code, err := NewEmailVerification(1, "user@example.com")
+	if err != nil {
+		t.Fatalf("NewEmailVerification failed: %v", err)
+	}
+
+	if len(code) != VerificationCodeLength || v.CodeHash == code {
+		t.Errorf("Expected a %d digit code stored as a hash, got %q", VerificationCodeLength, code)
+	}
+
+	now := time.Now()
+	if err := v.Check(" "+code+" ", now); err != nil {
+		t.Errorf("Check should accept the code: %v", err)
+	}
+
+	if err := v.Check("00000000x", now); err == nil {
+		t.Error("Check should reject a wrong code")
+	}
+
+	if err := v.Check(code, v.ExpiresAt); err == nil {
+		t.Error("Check should reject an expired code")
+	}
+
+	v.Attempts = MaxVerificationAttempts
+	if err := v.Check(code, now); err == nil {
+		t.Error("Check should reject codes after too many attempts")
+	}
+}
+
+func TestNewInvite(t *testing.T) {
+	invite, err := NewInvite(1, 3)
+	if err != nil {
+		t.Fatalf("NewInvite failed: %v", err)
+	}
+
+	if len(invite.Code) != 13 || invite.Remaining() != 3 {
+		t.Errorf("Unexpected invite: %+v", invite)
+	}
+
+	if _, err := NewInvite(1, 0); err == nil {
+		t.Error("NewInvite should reject zero uses")
+	}
+
+	if _, err := NewInvite(1, MaxInviteUses+1); err == nil {
+		t.Error("NewInvite should reject too many uses")
+	}
+
+	invite.Uses = 5
+	if invite.Remaining() != 0 {
+		t.Errorf("Expected 0 remaining, got %d", invite.Remaining())
+	}
+}
+
+func TestNormalizeInviteCode(t *testing.T) {
+	tests := map[string]string{
+		"abc123-def456":   "abc123-def456",
+		" ABC123DEF456 ":  "abc123-def456",
+		"abc 123-def 456": "abc123-def456",
+	}
+	for in, want := range tests {
+		if got := NormalizeInviteCode(in); got != want {
+			t.Errorf("NormalizeInviteCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const esc = 0x1b
+
+// SanitizeText removes terminal control characters and escape sequences from
+// untrusted text. Newlines and tabs are kept so multi-line content survives.
+func SanitizeText(s string) string {
+	return sanitize(s, true)
+}
+
+// SanitizeLine is like SanitizeText but also folds newlines and tabs into
+// spaces, for single-line fields such as usernames and titles.
+func SanitizeLine(s string) string {
+	return sanitize(s, false)
+}
+
+func sanitize(s string, multiline bool) string {
+	if isClean(s, multiline) {
+		return s
+	}
+
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		if r == esc {
+			i += escapeSequenceLength(s[i:])
+			continue
+		}
+
+		switch {
+		case r == '\n' || r == '\t':
+			if multiline {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte(' ')
+			}
+		case isUnsafeRune(r):
+			// dropped
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+
+	return b.String()
+}
+
+func isClean(s string, multiline bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x80 {
+			return false
+		}
+		if c < 0x20 || c == 0x7f {
+			if multiline && (c == '\n' || c == '\t') {
+				continue
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func isUnsafeRune(r rune) bool {
+	switch {
+	case r < 0x20, r == 0x7f:
+		return true
+	case r >= 0x80 && r <= 0x9f:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		// bidi overrides can be used to disguise text
+		return true
+	}
+	return false
+}
+
+// escapeSequenceLength returns the number of bytes taken by the escape
+// sequence at the start of s, which must begin with ESC.
+func escapeSequenceLength(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes, then a final byte
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x7e {
+				return i
+			}
+		}
+		return len(s)
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS, SOS, PM, APC: terminated by BEL or ST (ESC \)
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == esc {
+				if i+1 < len(s) && s[i+1] == '\\' {
+					return i + 2
+				}
+				return i
+			}
+		}
+		return len(s)
+	default:
+		// two-byte sequences such as ESC c (reset) and ESC 7/8; the next byte
+		// is consumed only if it is a valid final byte
+		if s[1] >= 0x20 && s[1] <= 0x7e {
+			return 2
+		}
+		return 1
+	}
+}
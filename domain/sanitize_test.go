@@ -0,0 +1,63 @@
+package domain
+
+import "testing"
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain text", "Hello, world!", "Hello, world!"},
+		{"keeps newlines and tabs", "line one\n\tline two", "line one\n\tline two"},
+		{"normalizes CRLF", "line one\r\nline two", "line one\nline two"},
+		{"strips carriage return", "real\rfake", "realfake"},
+		{"strips clear screen", "before\033[2J\033[Hafter", "beforeafter"},
+		{"strips colors", "\033[31mred\033[0m", "red"},
+		{"strips window title", "\033]0;pwned\007text", "text"},
+		{"strips OSC with ST", "\033]8;;http://evil\033\\link", "link"},
+		{"strips DCS", "\033Pq#0;2;0;0;0\033\\ok", "ok"},
+		{"strips reset", "\033cok", "ok"},
+		{"strips bell and backspace", "a\007b\bc", "abc"},
+		{"strips C1 CSI", "a\u009b2Jb", "a2Jb"},
+		{"strips bidi override", "abc‮dcba", "abcdcba"},
+		{"replaces invalid UTF-8", "a\x9bb", "a�b"},
+		{"keeps unicode", "räksmörgås ─ ✓", "räksmörgås ─ ✓"},
+		{"trailing escape", "text\033", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeText(tt.input); got != tt.expected {
+				t.Errorf("SanitizeText(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizeLine(t *testing.T) {
+	if got := SanitizeLine("two\nlines\there"); got != "two lines here" {
+		t.Errorf("Expected newlines and tabs to become spaces, got %q", got)
+	}
+
+	if got := SanitizeLine("\033[1mbold\033[0m"); got != "bold" {
+		t.Errorf("Expected escape sequences to be removed, got %q", got)
+	}
+}
+
+func TestPostSanitize(t *testing.T) {
+	post := NewPost(1, 1, "evil\033[2J", "Title\033]0;x\007", "Body\n\033[31mred\033[0m")
+	post.Sanitize()
+
+	if post.Username != "evil" {
+		t.Errorf("Expected sanitized username, got %q", post.Username)
+	}
+
+	if post.Title != "Title" {
+		t.Errorf("Expected sanitized title, got %q", post.Title)
+	}
+
+	if post.Content != "Body\nred" {
+		t.Errorf("Expected sanitized content, got %q", post.Content)
+	}
+}
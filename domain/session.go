@@ -1,15 +1,235 @@
 package domain
 
 import (
+	"io"
+	"sync"
 	"time"
-
-	"golang.org/x/term"
 )
 
 type Session struct {
-	ID           string
-	User         *User
-	Terminal     *term.Terminal
+	ID            string
+	Node          int
+	Terminal      Terminal
+	TermType      string
+	RemoteAddr    string
+	ClientVersion string
+	// Transport is how the caller connected, such as "SSH" or "Telnet".
+	Transport    string
 	CreatedAt    time.Time
 	LastActivity time.Time
+	// PasswordResetID is set when the user logged in with a reset token
+	// and must choose a new password before doing anything else.
+	PasswordResetID int
+	// Notices carries short messages to show above the user's prompt, such
+	// as a reply to their thread or a sysop broadcast.
+	Notices chan string
+	// Conn is closed to drop the connection.
+	Conn io.Closer
+	// Recording is set while the session is being recorded.
+	Recording Recording
+
+	mu          sync.Mutex
+	user        *User
+	activity    string
+	kickReason  string
+	forceLogout bool
+	snoopers    map[chan []byte]struct{}
+}
+
+// Terminal is a line-editing terminal on whatever transport the caller
+// connected with. *term.Terminal from golang.org/x/term satisfies it.
+type Terminal interface {
+	io.Writer
+	ReadLine() (string, error)
+	ReadPassword(prompt string) (string, error)
+	SetPrompt(prompt string)
+}
+
+// Recording is a recording of what a session's client sees, made by package
+// recording.
+type Recording interface {
+	// Login notes that user logged in partway through the session. A
+	// different user from the one recorded so far gets a new recording, and
+	// nothing is kept of users who opted out of being recorded.
+	Login(user *User)
+	// HideInput stops recording keystrokes, and the terminal's echo of
+	// them, while hide is true, so that passwords and one-time codes are
+	// never saved.
+	HideInput(hide bool)
+}
+
+// noticeBuffer is how many notices can wait for a busy session before more
+// are dropped.
+const noticeBuffer = 16
+
+// snoopBuffer is how many writes a slow snooper can fall behind by before
+// output is dropped for it.
+const snoopBuffer = 256
+
+// Notify queues text for the session without blocking, and reports whether
+// there was room for it.
+func (s *Session) Notify(text string) bool {
+	select {
+	case s.Notices <- text:
+		return true
+	default:
+		return false
+	}
+}
+
+// CurrentUser returns who is logged in on the session, or nil before anyone
+// has. The user is swapped rather than changed in place, so the one
+// returned can be read without holding the lock.
+func (s *Session) CurrentUser() *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.user
+}
+
+// SetUser records who is logged in on the session; nil logs them out.
+func (s *Session) SetUser(user *User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.user = user
+}
+
+// SetActivity records what the user is doing, for the node list.
+func (s *Session) SetActivity(activity string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.activity = activity
+}
+
+// Touch records that the user just did something.
+func (s *Session) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.LastActivity = time.Now()
+}
+
+// Status returns what the user is doing and when they last did something.
+func (s *Session) Status() (string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.activity, s.LastActivity
+}
+
+// Kick closes the connection on behalf of a sysop. reason is recorded when
+// the call ends.
+func (s *Session) Kick(reason string) error {
+	s.mu.Lock()
+	s.kickReason = reason
+	s.mu.Unlock()
+
+	if s.Conn == nil {
+		return nil
+	}
+	return s.Conn.Close()
+}
+
+// ForceLogout asks the session's UI to log its user out at their next
+// prompt, leaving the connection open.
+func (s *Session) ForceLogout() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forceLogout = true
+}
+
+// LogoutForced reports whether ForceLogout was called since it was last
+// asked, and clears the request.
+func (s *Session) LogoutForced() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forced := s.forceLogout
+	s.forceLogout = false
+	return forced
+}
+
+// KickReason returns the reason given to Kick, if the session was kicked.
+func (s *Session) KickReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.kickReason
+}
+
+// Output copies what the session sends to its client to every snooper.
+// Snoopers that fall behind miss output rather than slow the session down.
+func (s *Session) Output(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.snoopers) == 0 {
+		return
+	}
+
+	data := append([]byte(nil), p...)
+	for ch := range s.snoopers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// Snoop returns a read-only copy of the session's output from now on. The
+// channel is closed by stop, or when the session ends.
+func (s *Session) Snoop() (output <-chan []byte, stop func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan []byte, snoopBuffer)
+	if s.snoopers == nil {
+		s.snoopers = make(map[chan []byte]struct{})
+	}
+	s.snoopers[ch] = struct{}{}
+
+	return ch, func() { s.stopSnoop(ch) }
+}
+
+func (s *Session) stopSnoop(ch chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.snoopers[ch]; ok {
+		delete(s.snoopers, ch)
+		close(ch)
+	}
+}
+
+// end closes the snoopers' channels when the session goes away.
+func (s *Session) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.snoopers {
+		close(ch)
+	}
+	s.snoopers = nil
+}
+
+// Writer wraps w so everything written through it is also sent to the
+// session's snoopers.
+func (s *Session) Writer(rw io.ReadWriter) io.ReadWriter {
+	return &sessionWriter{ReadWriter: rw, session: s}
+}
+
+type sessionWriter struct {
+	io.ReadWriter
+	session *Session
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	n, err := w.ReadWriter.Write(p)
+	if n > 0 {
+		w.session.Output(p[:n])
+	}
+	return n, err
 }
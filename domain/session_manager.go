@@ -3,12 +3,15 @@ package domain
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"sort"
 	"sync"
 	"time"
-
-	"golang.org/x/term"
 )
 
+// ErrNodesBusy is returned by OpenSession when every node is taken.
+var ErrNodesBusy = errors.New("all nodes are busy")
+
 type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
@@ -20,23 +23,68 @@ func NewSessionManager() *SessionManager {
 	}
 }
 
-func (sm *SessionManager) CreateSession(user *User, term *term.Terminal) *Session {
+func (sm *SessionManager) CreateSession(user *User, term Terminal) *Session {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	return sm.create(user, term)
+}
+
+// OpenSession creates a session unless maxNodes sessions are already open,
+// whichever transport they came in on. maxNodes <= 0 means no limit.
+func (sm *SessionManager) OpenSession(user *User, term Terminal, maxNodes int) (*Session, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	if maxNodes > 0 && len(sm.sessions) >= maxNodes {
+		return nil, ErrNodesBusy
+	}
+	return sm.create(user, term), nil
+}
+
+func (sm *SessionManager) create(user *User, term Terminal) *Session {
 	sessionID := generateSessionID()
 	session := &Session{
 		ID:           sessionID,
-		User:         user,
+		Node:         sm.freeNode(),
 		Terminal:     term,
 		CreatedAt:    time.Now(),
 		LastActivity: time.Now(),
+		Notices:      make(chan string, noticeBuffer),
+		user:         user,
 	}
 
 	sm.sessions[sessionID] = session
 	return session
 }
 
+// freeNode returns the lowest node number not in use, like the numbered
+// lines of a dial-up BBS.
+func (sm *SessionManager) freeNode() int {
+	used := make(map[int]bool, len(sm.sessions))
+	for _, session := range sm.sessions {
+		used[session.Node] = true
+	}
+
+	node := 1
+	for used[node] {
+		node++
+	}
+	return node
+}
+
+func (sm *SessionManager) GetByNode(node int) (*Session, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for _, session := range sm.sessions {
+		if session.Node == node {
+			return session, true
+		}
+	}
+	return nil, false
+}
+
 func (sm *SessionManager) GetSession(id string) (*Session, bool) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
@@ -49,7 +97,10 @@ func (sm *SessionManager) RemoveSession(id string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	delete(sm.sessions, id)
+	if session, exists := sm.sessions[id]; exists {
+		session.end()
+		delete(sm.sessions, id)
+	}
 }
 
 func (sm *SessionManager) GetActiveSessions() []*Session {
@@ -60,9 +111,46 @@ func (sm *SessionManager) GetActiveSessions() []*Session {
 	for _, session := range sm.sessions {
 		sessions = append(sessions, session)
 	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Node < sessions[j].Node
+	})
 	return sessions
 }
 
+// Notify sends text to every session userID is logged in on, and returns
+// how many got it.
+func (sm *SessionManager) Notify(userID int, text string) int {
+	if userID == 0 {
+		return 0
+	}
+	return sm.notify(text, func(user *User) bool { return user.ID == userID })
+}
+
+// NotifyAdmins sends text to every session with a sysop logged in, and
+// returns how many got it.
+func (sm *SessionManager) NotifyAdmins(text string) int {
+	return sm.notify(text, func(user *User) bool { return user.IsAdmin })
+}
+
+// Broadcast sends text to every session, and returns how many got it.
+func (sm *SessionManager) Broadcast(text string) int {
+	return sm.notify(text, func(user *User) bool { return true })
+}
+
+func (sm *SessionManager) notify(text string, match func(*User) bool) int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	sent := 0
+	for _, session := range sm.sessions {
+		if user := session.CurrentUser(); user != nil && match(user) && session.Notify(text) {
+			sent++
+		}
+	}
+	return sent
+}
+
 func generateSessionID() string {
 	b := make([]byte, 16)
 	rand.Read(b)
@@ -38,7 +38,7 @@ func TestCreateSession(t *testing.T) {
 		t.Error("Session should have a non-empty ID")
 	}
 
-	if session.User != user {
+	if session.CurrentUser() != user {
 		t.Error("Session should reference the provided user")
 	}
 
@@ -85,7 +85,7 @@ func TestGetSession(t *testing.T) {
 		t.Error("Retrieved session should have same ID as created session")
 	}
 
-	if retrievedSession.User.Username != user.Username {
+	if retrievedSession.CurrentUser().Username != user.Username {
 		t.Error("Retrieved session should have same user as created session")
 	}
 }
@@ -216,3 +216,205 @@ func TestConcurrentAccess(t *testing.T) {
 		t.Errorf("Expected 10 concurrent sessions, got %d", len(sessions))
 	}
 }
+
+func TestSessionManager_Notify(t *testing.T) {
+	sm := NewSessionManager()
+	alice := sm.CreateSession(&User{ID: 1, Username: "alice"}, nil)
+	aliceAgain := sm.CreateSession(&User{ID: 1, Username: "alice"}, nil)
+	sysop := sm.CreateSession(&User{ID: 2, Username: "sysop", IsAdmin: true}, nil)
+	guest := sm.CreateSession(&User{ID: 0, Username: "anonymous"}, nil)
+
+	if sent := sm.Notify(1, "hello alice"); sent != 2 {
+		t.Errorf("Expected alice's 2 sessions to get the notice, got %d", sent)
+	}
+	if <-alice.Notices != "hello alice" || <-aliceAgain.Notices != "hello alice" {
+		t.Error("Expected both of alice's sessions to get the notice")
+	}
+
+	if sent := sm.Notify(0, "hello guest"); sent != 0 {
+		t.Errorf("Notify should not reach anonymous sessions, got %d", sent)
+	}
+
+	if sent := sm.NotifyAdmins("page"); sent != 1 || <-sysop.Notices != "page" {
+		t.Errorf("Expected only the sysop to be paged, got %d", sent)
+	}
+
+	if sent := sm.Broadcast("everyone"); sent != 4 || <-guest.Notices != "everyone" {
+		t.Errorf("Expected a broadcast to reach all 4 sessions, got %d", sent)
+	}
+}
+
+// TestSessionManager_NotifyWhileLoggingIn is meant for -race: users log in
+// and out on their sessions while notices go out to every session.
+func TestSessionManager_NotifyWhileLoggingIn(t *testing.T) {
+	sm := NewSessionManager()
+	var sessions []*Session
+	for i := 0; i < 4; i++ {
+		sessions = append(sessions, sm.CreateSession(nil, nil))
+	}
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 200; i++ {
+			sm.Broadcast("everyone")
+			sm.NotifyAdmins("page")
+			sm.Notify(1, "hello alice")
+			for _, session := range sessions {
+				select {
+				case <-session.Notices:
+				default:
+				}
+			}
+		}
+		done <- true
+	}()
+
+	for i := 0; i < 200; i++ {
+		session := sessions[i%len(sessions)]
+		session.SetUser(&User{ID: 1, Username: "alice", IsAdmin: i%2 == 0})
+		session.SetUser(nil)
+	}
+	<-done
+
+	sessions[0].SetUser(&User{ID: 1, Username: "alice"})
+	if sent := sm.Notify(1, "hello alice"); sent != 1 || sessions[0].CurrentUser().Username != "alice" {
+		t.Errorf("Expected alice's session to get the notice once she logged in, got %d", sent)
+	}
+}
+
+func TestSession_NotifyDoesNotBlock(t *testing.T) {
+	sm := NewSessionManager()
+	session := sm.CreateSession(&User{ID: 1, Username: "alice"}, nil)
+
+	for i := 0; i < noticeBuffer; i++ {
+		if !session.Notify("notice") {
+			t.Fatalf("Notice %d should fit in the buffer", i+1)
+		}
+	}
+
+	if session.Notify("one too many") {
+		t.Error("Notify should drop notices when the session is backed up")
+	}
+}
+
+func TestSessionManager_Nodes(t *testing.T) {
+	sm := NewSessionManager()
+	first := sm.CreateSession(&User{ID: 1}, nil)
+	second := sm.CreateSession(&User{ID: 2}, nil)
+
+	if first.Node != 1 || second.Node != 2 {
+		t.Errorf("Expected nodes 1 and 2, got %d and %d", first.Node, second.Node)
+	}
+
+	sm.RemoveSession(first.ID)
+	third := sm.CreateSession(&User{ID: 3}, nil)
+	if third.Node != 1 {
+		t.Errorf("Expected the free node 1 to be reused, got %d", third.Node)
+	}
+
+	if session, ok := sm.GetByNode(2); !ok || session != second {
+		t.Error("GetByNode should find node 2")
+	}
+
+	sessions := sm.GetActiveSessions()
+	if len(sessions) != 2 || sessions[0] != third || sessions[1] != second {
+		t.Error("Expected active sessions in node order")
+	}
+}
+
+func TestSessionManager_OpenSession(t *testing.T) {
+	sm := NewSessionManager()
+	first, err := sm.OpenSession(&User{ID: 1}, nil, 2)
+	if err != nil {
+		t.Fatalf("OpenSession failed: %v", err)
+	}
+	if _, err := sm.OpenSession(&User{ID: 2}, nil, 2); err != nil {
+		t.Fatalf("OpenSession failed: %v", err)
+	}
+
+	if _, err := sm.OpenSession(&User{ID: 3}, nil, 2); err != ErrNodesBusy {
+		t.Errorf("Expected ErrNodesBusy with every node taken, got %v", err)
+	}
+
+	sm.RemoveSession(first.ID)
+	if session, err := sm.OpenSession(&User{ID: 3}, nil, 2); err != nil || session.Node != 1 {
+		t.Errorf("Expected node 1 to be free again, got %v", err)
+	}
+
+	if _, err := sm.OpenSession(&User{ID: 4}, nil, 0); err != nil {
+		t.Errorf("Expected no limit with maxNodes 0, got %v", err)
+	}
+}
+
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSession_Kick(t *testing.T) {
+	sm := NewSessionManager()
+	session := sm.CreateSession(&User{ID: 1}, nil)
+	conn := &closeRecorder{}
+	session.Conn = conn
+
+	if session.KickReason() != "" {
+		t.Error("A new session should not be kicked")
+	}
+
+	session.Kick(LogoutReasonKicked)
+	if !conn.closed || session.KickReason() != LogoutReasonKicked {
+		t.Error("Kick should close the connection and record the reason")
+	}
+}
+
+func TestSession_ForceLogout(t *testing.T) {
+	sm := NewSessionManager()
+	session := sm.CreateSession(&User{ID: 1}, nil)
+	conn := &closeRecorder{}
+	session.Conn = conn
+
+	if session.LogoutForced() {
+		t.Error("A new session should not be logged out")
+	}
+
+	session.ForceLogout()
+	if !session.LogoutForced() || conn.closed {
+		t.Error("ForceLogout should be reported without closing the connection")
+	}
+	if session.LogoutForced() {
+		t.Error("LogoutForced should clear the request")
+	}
+}
+
+type discard struct{}
+
+func (discard) Read(p []byte) (int, error)  { return 0, nil }
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestSession_Snoop(t *testing.T) {
+	sm := NewSessionManager()
+	session := sm.CreateSession(&User{ID: 1}, nil)
+	w := session.Writer(discard{})
+
+	w.Write([]byte("before"))
+	output, stop := session.Snoop()
+	w.Write([]byte("after"))
+
+	if got := string(<-output); got != "after" {
+		t.Errorf("Expected only output after snooping started, got %q", got)
+	}
+
+	stop()
+	if _, ok := <-output; ok {
+		t.Error("Expected stop to close the output")
+	}
+	stop()
+
+	output, _ = session.Snoop()
+	sm.RemoveSession(session.ID)
+	if _, ok := <-output; ok {
+		t.Error("Expected the output to close when the session ends")
+	}
+}
@@ -0,0 +1,265 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FailedLoginPassword = "password"
+	FailedLoginCode     = "code"
+)
+
+// FailedLogin records a rejected login attempt for the sysop.
+type FailedLogin struct {
+	ID          int
+	Username    string
+	RemoteAddr  string
+	Reason      string
+	AttemptedAt time.Time
+}
+
+func NewFailedLogin(username, remoteAddr, reason string) *FailedLogin {
+	return &FailedLogin{
+		Username:    username,
+		RemoteAddr:  remoteAddr,
+		Reason:      reason,
+		AttemptedAt: time.Now(),
+	}
+}
+
+// Sanitize cleans the username, which is whatever the client sent.
+func (f *FailedLogin) Sanitize() {
+	f.Username = SanitizeLine(f.Username)
+	if runes := []rune(f.Username); len(runes) > 64 {
+		f.Username = string(runes[:64])
+	}
+}
+
+type ThrottlePolicy struct {
+	FreeAttempts    int           // failures allowed before backoff starts
+	BaseDelay       time.Duration // wait after the first failure past FreeAttempts
+	MaxDelay        time.Duration // backoff doubles up to this
+	LockoutAttempts int           // failures that lock the key out entirely
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration // failures are forgotten after this long without one
+}
+
+func DefaultThrottlePolicy() ThrottlePolicy {
+	return ThrottlePolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAttempts: 10,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+}
+
+const (
+	ThrottleAccount = "account"
+	ThrottleIP      = "ip"
+)
+
+type throttleEntry struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// wait returns how long until the next attempt is allowed.
+func (e *throttleEntry) wait(p ThrottlePolicy, now time.Time) time.Duration {
+	if now.Before(e.lockedUntil) {
+		return e.lockedUntil.Sub(now)
+	}
+	if e.failures <= p.FreeAttempts || now.Sub(e.last) > p.ResetAfter {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < e.failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if next := e.last.Add(delay); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+func (e *throttleEntry) fail(p ThrottlePolicy, now time.Time) {
+	// start over once a lockout has been served or the failures are stale
+	if !e.lockedUntil.IsZero() && !now.Before(e.lockedUntil) || now.Sub(e.last) > p.ResetAfter {
+		*e = throttleEntry{}
+	}
+
+	e.failures++
+	e.last = now
+	if e.failures >= p.LockoutAttempts {
+		e.lockedUntil = now.Add(p.LockoutDuration)
+	}
+}
+
+// ThrottleStatus describes a throttled account or address, for the sysop.
+type ThrottleStatus struct {
+	Kind     string
+	Key      string
+	Failures int
+	Wait     time.Duration
+	Locked   bool
+}
+
+// LoginThrottle tracks failed logins per account and per remote address and
+// tells callers how long to make the next attempt wait. It is kept in memory;
+// a restart forgets everything, which is acceptable for temporary lockouts.
+type LoginThrottle struct {
+	mu            sync.Mutex
+	accountPolicy ThrottlePolicy
+	ipPolicy      ThrottlePolicy
+	accounts      map[string]*throttleEntry
+	ips           map[string]*throttleEntry
+}
+
+func NewLoginThrottle(accountPolicy, ipPolicy ThrottlePolicy) *LoginThrottle {
+	return &LoginThrottle{
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+		accounts:      make(map[string]*throttleEntry),
+		ips:           make(map[string]*throttleEntry),
+	}
+}
+
+func accountKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Wait returns how long the caller must wait before another attempt for
+// username from remoteAddr is allowed; zero means go ahead.
+func (t *LoginThrottle) Wait(username, remoteAddr string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var wait time.Duration
+	if e, ok := t.accounts[accountKey(username)]; ok {
+		wait = e.wait(t.accountPolicy, now)
+	}
+	if e, ok := t.ips[remoteAddr]; ok && remoteAddr != "" {
+		if w := e.wait(t.ipPolicy, now); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+func (t *LoginThrottle) Failure(username, remoteAddr string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(now)
+
+	if key := accountKey(username); key != "" {
+		if _, ok := t.accounts[key]; !ok {
+			t.accounts[key] = &throttleEntry{}
+		}
+		t.accounts[key].fail(t.accountPolicy, now)
+	}
+
+	if remoteAddr != "" {
+		if _, ok := t.ips[remoteAddr]; !ok {
+			t.ips[remoteAddr] = &throttleEntry{}
+		}
+		t.ips[remoteAddr].fail(t.ipPolicy, now)
+	}
+}
+
+// Success clears the account's failures. The address keeps its count, so an
+// attacker cannot reset it by logging into an account of their own.
+func (t *LoginThrottle) Success(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.accounts, accountKey(username))
+}
+
+// Clear forgets a throttled account or address, for sysops unlocking a user.
+func (t *LoginThrottle) Clear(kind, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if kind == ThrottleAccount {
+		delete(t.accounts, accountKey(key))
+	} else {
+		delete(t.ips, key)
+	}
+}
+
+// Throttled lists accounts and addresses that currently have to wait,
+// longest wait first.
+func (t *LoginThrottle) Throttled(now time.Time) []ThrottleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var statuses []ThrottleStatus
+	collect := func(kind string, entries map[string]*throttleEntry, p ThrottlePolicy) {
+		for key, e := range entries {
+			if wait := e.wait(p, now); wait > 0 {
+				statuses = append(statuses, ThrottleStatus{
+					Kind:     kind,
+					Key:      key,
+					Failures: e.failures,
+					Wait:     wait,
+					Locked:   now.Before(e.lockedUntil),
+				})
+			}
+		}
+	}
+	collect(ThrottleAccount, t.accounts, t.accountPolicy)
+	collect(ThrottleIP, t.ips, t.ipPolicy)
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Wait > statuses[j].Wait
+	})
+	return statuses
+}
+
+// maxThrottleEntries bounds memory when someone sprays random usernames.
+const maxThrottleEntries = 10000
+
+func (t *LoginThrottle) prune(now time.Time) {
+	if len(t.accounts)+len(t.ips) < maxThrottleEntries {
+		return
+	}
+	pruneEntries(t.accounts, t.accountPolicy, now)
+	pruneEntries(t.ips, t.ipPolicy, now)
+}
+
+// pruneEntries drops entries that no longer slow anyone down: stale ones,
+// and ones still within their free attempts, which is what spraying random
+// usernames produces.
+func pruneEntries(entries map[string]*throttleEntry, p ThrottlePolicy, now time.Time) {
+	for key, e := range entries {
+		if e.wait(p, now) == 0 && (e.failures <= p.FreeAttempts || now.Sub(e.last) > p.ResetAfter) {
+			delete(entries, key)
+		}
+	}
+}
+
+// ThrottledError is returned when a login is refused without checking the
+// password because of earlier failures.
+type ThrottledError struct {
+	Wait time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	wait := e.Wait.Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return fmt.Sprintf("too many failed attempts, try again in %s", wait)
+}
@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPolicy() ThrottlePolicy {
+	return ThrottlePolicy{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        8 * time.Second,
+		LockoutAttempts: 8,
+		LockoutDuration: time.Minute,
+		ResetAfter:      time.Hour,
+	}
+}
+
+func TestLoginThrottle_Backoff(t *testing.T) {
+	throttle := NewLoginThrottle(testPolicy(), testPolicy())
+	now := time.Unix(1700000000, 0)
+
+	// The free attempts do not slow anyone down
+	for i := 0; i < 2; i++ {
+		throttle.Failure("alice", "", now)
+		if wait := throttle.Wait("alice", "", now); wait != 0 {
+			t.Fatalf("Expected no wait after %d failures, got %s", i+1, wait)
+		}
+	}
+
+	// After that the wait doubles: 1s, 2s, 4s, 8s, 8s
+	expected := []time.Duration{1, 2, 4, 8, 8}
+	for i, seconds := range expected {
+		throttle.Failure("alice", "", now)
+		if wait := throttle.Wait("alice", "", now); wait != seconds*time.Second {
+			t.Errorf("Failure %d: expected wait %ds, got %s", i+3, seconds, wait)
+		}
+	}
+
+	// Waiting out the delay allows another attempt
+	if wait := throttle.Wait("alice", "", now.Add(8*time.Second)); wait != 0 {
+		t.Errorf("Expected no wait once the delay passed, got %s", wait)
+	}
+
+	// Usernames are not case sensitive
+	if wait := throttle.Wait("ALICE", "", now); wait == 0 {
+		t.Error("Throttle should apply regardless of username case")
+	}
+
+	if wait := throttle.Wait("bob", "", now); wait != 0 {
+		t.Errorf("Other accounts should not be throttled, got %s", wait)
+	}
+}
+
+func TestLoginThrottle_Lockout(t *testing.T) {
+	throttle := NewLoginThrottle(testPolicy(), testPolicy())
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 8; i++ {
+		throttle.Failure("alice", "", now)
+	}
+
+	if wait := throttle.Wait("alice", "", now); wait != time.Minute {
+		t.Errorf("Expected a one minute lockout, got %s", wait)
+	}
+
+	statuses := throttle.Throttled(now)
+	if len(statuses) != 1 || !statuses[0].Locked || statuses[0].Key != "alice" {
+		t.Errorf("Expected alice to be listed as locked, got %+v", statuses)
+	}
+
+	// Once the lockout is served the count starts over
+	later := now.Add(time.Minute)
+	if wait := throttle.Wait("alice", "", later); wait != 0 {
+		t.Errorf("Expected lockout to expire, got %s", wait)
+	}
+	throttle.Failure("alice", "", later)
+	if wait := throttle.Wait("alice", "", later); wait != 0 {
+		t.Errorf("Expected a fresh start after the lockout, got %s", wait)
+	}
+}
+
+func TestLoginThrottle_PerIP(t *testing.T) {
+	throttle := NewLoginThrottle(testPolicy(), testPolicy())
+	now := time.Unix(1700000000, 0)
+
+	// Guessing across many accounts from one address still adds up
+	for i := 0; i < 3; i++ {
+		throttle.Failure(strings.Repeat("u", i+1), "10.0.0.1", now)
+	}
+
+	if wait := throttle.Wait("newuser", "10.0.0.1", now); wait != time.Second {
+		t.Errorf("Expected the address to be throttled, got %s", wait)
+	}
+
+	if wait := throttle.Wait("newuser", "10.0.0.2", now); wait != 0 {
+		t.Errorf("Other addresses should not be throttled, got %s", wait)
+	}
+
+	// Logging in successfully clears the account but not the address
+	throttle.Success("u")
+	if wait := throttle.Wait("u", "10.0.0.1", now); wait == 0 {
+		t.Error("A successful login should not clear the address")
+	}
+
+	throttle.Clear(ThrottleIP, "10.0.0.1")
+	if wait := throttle.Wait("newuser", "10.0.0.1", now); wait != 0 {
+		t.Errorf("Clear should remove the address, got %s", wait)
+	}
+}
+
+func TestLoginThrottle_Success(t *testing.T) {
+	throttle := NewLoginThrottle(testPolicy(), testPolicy())
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 4; i++ {
+		throttle.Failure("alice", "", now)
+	}
+	throttle.Success("Alice")
+
+	if wait := throttle.Wait("alice", "", now); wait != 0 {
+		t.Errorf("Success should clear the account, got %s", wait)
+	}
+}
+
+func TestLoginThrottle_ResetAfter(t *testing.T) {
+	throttle := NewLoginThrottle(testPolicy(), testPolicy())
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 5; i++ {
+		throttle.Failure("alice", "", now)
+	}
+
+	later := now.Add(2 * time.Hour)
+	throttle.Failure("alice", "", later)
+	if wait := throttle.Wait("alice", "", later); wait != 0 {
+		t.Errorf("Stale failures should be forgotten, got %s", wait)
+	}
+}
+
+func TestThrottledError(t *testing.T) {
+	err := &ThrottledError{Wait: 1500 * time.Millisecond}
+	if err.Error() != "too many failed attempts, try again in 2s" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+}
+
+func TestFailedLogin_Sanitize(t *testing.T) {
+	attempt := NewFailedLogin("evil\x1b[2Jname"+strings.Repeat("x", 100), "10.0.0.1", FailedLoginPassword)
+	attempt.Sanitize()
+
+	if strings.Contains(attempt.Username, "\x1b") {
+		t.Error("Username should be sanitized")
+	}
+	if len(attempt.Username) > 64 {
+		t.Errorf("Username should be truncated, got %d bytes", len(attempt.Username))
+	}
+}
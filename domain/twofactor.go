@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Roles lists every role, in the order they are shown to sysops.
+var Roles = []string{RoleUser, RoleAdmin}
+
+func (u *User) Role() string {
+	if u.IsAdmin {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+const RecoveryCodeCount = 10
+
+// GenerateRecoveryCodes returns n one-time codes of the form xxxxx-xxxxx.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the separator and whitespace users may or may
+// not type, so "ABCDE-12345" and "abcde12345" are the same code.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// TwoFactorPolicy lists the roles whose members must enrol in two-factor
+// authentication.
+type TwoFactorPolicy struct {
+	RequiredRoles []string
+}
+
+// ParseTwoFactorPolicy reads a policy stored as a comma-separated role list.
+func ParseTwoFactorPolicy(value string) TwoFactorPolicy {
+	var policy TwoFactorPolicy
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			policy.RequiredRoles = append(policy.RequiredRoles, role)
+		}
+	}
+	return policy
+}
+
+func (p TwoFactorPolicy) String() string {
+	roles := append([]string(nil), p.RequiredRoles...)
+	sort.Strings(roles)
+	return strings.Join(roles, ",")
+}
+
+func (p TwoFactorPolicy) RoleRequired(role string) bool {
+	for _, r := range p.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Requires reports whether user must have two-factor authentication.
+func (p TwoFactorPolicy) Requires(user *User) bool {
+	return user != nil && user.ID != 0 && p.RoleRequired(user.Role())
+}
+
+// Toggle turns the requirement for role on or off.
+func (p TwoFactorPolicy) Toggle(role string) TwoFactorPolicy {
+	var roles []string
+	for _, r := range p.RequiredRoles {
+		if r != role {
+			roles = append(roles, r)
+		}
+	}
+	if !p.RoleRequired(role) {
+		roles = append(roles, role)
+	}
+	return TwoFactorPolicy{RequiredRoles: roles}
+}
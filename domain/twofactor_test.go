@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestUser_Role(t *testing.T) {
+	user := NewUser("testuser", "test@example.com")
+	if user.Role() != RoleUser {
+		t.Errorf("Expected role %s, got %s", RoleUser, user.Role())
+	}
+
+	user.IsAdmin = true
+	if user.Role() != RoleAdmin {
+		t.Errorf("Expected role %s, got %s", RoleAdmin, user.Role())
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+
+	if len(codes) != RecoveryCodeCount {
+		t.Errorf("Expected %d codes, got %d", RecoveryCodeCount, len(codes))
+	}
+
+	format := regexp.MustCompile(`^[0-9a-f]{5}-[0-9a-f]{5}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("Unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	for _, input := range []string{"abcde-12345", " ABCDE-12345 ", "abcde12345", "abcde 12345"} {
+		if got := NormalizeRecoveryCode(input); got != "abcde12345" {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q", input, got)
+		}
+	}
+}
+
+func TestTwoFactorPolicy(t *testing.T) {
+	policy := ParseTwoFactorPolicy(" admin , ")
+	if !policy.RoleRequired(RoleAdmin) || policy.RoleRequired(RoleUser) {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+
+	admin := NewUser("admin", "admin@example.com")
+	admin.ID = 1
+	admin.IsAdmin = true
+	user := NewUser("user", "user@example.com")
+	user.ID = 2
+
+	if !policy.Requires(admin) {
+		t.Error("Policy should require 2FA for admins")
+	}
+	if policy.Requires(user) {
+		t.Error("Policy should not require 2FA for users")
+	}
+	if policy.Requires(&User{Username: "guest"}) {
+		t.Error("Policy should never apply to guests")
+	}
+
+	policy = policy.Toggle(RoleUser)
+	if policy.String() != "admin,user" {
+		t.Errorf("Expected admin,user, got %q", policy.String())
+	}
+
+	policy = policy.Toggle(RoleAdmin)
+	if policy.String() != "user" {
+		t.Errorf("Expected user, got %q", policy.String())
+	}
+
+	if ParseTwoFactorPolicy("").String() != "" {
+		t.Error("Empty policy should require nothing")
+	}
+}
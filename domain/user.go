@@ -1,15 +1,56 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	MaxLocationLength  = 64
+	MaxHomepageLength  = 200
+	MaxBioLength       = 1000
+	MaxSignatureLength = 240
+	MaxSignatureLines  = 4
+	DefaultDateFormat  = "us"
+)
+
+// ErrUserExists is returned by UserRepository.Create when the username or
+// email address is already registered.
+var ErrUserExists = errors.New("that username or email is already registered")
+
+// DateFormats maps the date format names users can pick to Go layouts.
+var DateFormats = map[string]string{
+	"us":  "Jan 02, 2006",
+	"iso": "2006-01-02",
+	"eu":  "02.01.2006",
+}
 
 type User struct {
-	ID        int
-	Username  string
-	Password  string // This will be hashed
-	Email     string
-	CreatedAt time.Time
-	LastLogin time.Time
-	IsAdmin   bool
+	ID         int
+	Username   string
+	Password   string // This will be hashed
+	Email      string
+	CreatedAt  time.Time
+	LastLogin  time.Time
+	IsAdmin    bool
+	Location   string
+	Bio        string
+	Homepage   string
+	Signature  string
+	TimeZone   string
+	DateFormat string
+	Status     string
+	InvitedBy  int
+
+	EmailVerified   bool // the user has entered a code sent to Email
+	RecordingOptOut bool // the user's sessions are never recorded
+
+	TOTPSecret   string
+	TOTPEnabled  bool
+	TOTPLastStep int64 // last accepted step, so a code cannot be replayed
 }
 
 func NewUser(username, email string) *User {
@@ -20,5 +61,84 @@ func NewUser(username, email string) *User {
 		CreatedAt: now,
 		LastLogin: now,
 		IsAdmin:   false,
+		Status:    UserStatusActive,
+	}
+}
+
+func (u *User) Sanitize() {
+	u.Username = SanitizeLine(u.Username)
+	u.Email = SanitizeLine(u.Email)
+	u.Location = SanitizeLine(u.Location)
+	u.Bio = SanitizeText(u.Bio)
+	u.Homepage = SanitizeLine(u.Homepage)
+	u.Signature = SanitizeText(u.Signature)
+	u.TimeZone = SanitizeLine(u.TimeZone)
+	u.DateFormat = SanitizeLine(u.DateFormat)
+}
+
+// ValidateProfile checks the user-editable profile fields.
+func (u *User) ValidateProfile() error {
+	if len([]rune(u.Location)) > MaxLocationLength {
+		return fmt.Errorf("location is limited to %d characters", MaxLocationLength)
+	}
+
+	if len([]rune(u.Bio)) > MaxBioLength {
+		return fmt.Errorf("bio is limited to %d characters", MaxBioLength)
+	}
+
+	if len([]rune(u.Signature)) > MaxSignatureLength {
+		return fmt.Errorf("signature is limited to %d characters", MaxSignatureLength)
+	}
+
+	if strings.Count(u.Signature, "\n")+1 > MaxSignatureLines {
+		return fmt.Errorf("signature is limited to %d lines", MaxSignatureLines)
+	}
+
+	if u.Homepage != "" {
+		if len(u.Homepage) > MaxHomepageLength {
+			return fmt.Errorf("homepage is limited to %d characters", MaxHomepageLength)
+		}
+		parsed, err := url.Parse(u.Homepage)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("homepage must be an http or https URL")
+		}
+	}
+
+	if u.TimeZone != "" {
+		if _, err := time.LoadLocation(u.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q", u.TimeZone)
+		}
+	}
+
+	if u.DateFormat != "" {
+		if _, ok := DateFormats[u.DateFormat]; !ok {
+			return fmt.Errorf("unknown date format %q", u.DateFormat)
+		}
+	}
+
+	return nil
+}
+
+// FormatDate formats t in the user's time zone and preferred date format.
+func (u *User) FormatDate(t time.Time) string {
+	layout, ok := DateFormats[u.DateFormat]
+	if !ok {
+		layout = DateFormats[DefaultDateFormat]
+	}
+
+	if u.TimeZone != "" {
+		if loc, err := time.LoadLocation(u.TimeZone); err == nil {
+			t = t.In(loc)
+		}
+	}
+
+	return t.Format(layout)
+}
+
+// Sign appends the user's signature to post content.
+func (u *User) Sign(content string) string {
+	if strings.TrimSpace(u.Signature) == "" {
+		return content
 	}
+	return content + "\n-- \n" + u.Signature
 }
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -66,3 +67,62 @@ func TestUserFields(t *testing.T) {
 		t.Errorf("Expected CreatedAt %v, got %v", expectedCreated, user.CreatedAt)
 	}
 }
+
+func TestUserValidateProfile(t *testing.T) {
+	user := NewUser("testuser", "test@example.com")
+	user.Location = "Helsinki"
+	user.Homepage = "https://example.com/~test"
+	user.TimeZone = "Europe/Helsinki"
+	user.DateFormat = "iso"
+	user.Signature = "line one\nline two"
+
+	if err := user.ValidateProfile(); err != nil {
+		t.Errorf("Expected valid profile, got %v", err)
+	}
+
+	invalid := []func(u *User){
+		func(u *User) { u.Homepage = "javascript:alert(1)" },
+		func(u *User) { u.Homepage = "ftp://example.com" },
+		func(u *User) { u.TimeZone = "Mars/Olympus_Mons" },
+		func(u *User) { u.DateFormat = "klingon" },
+		func(u *User) { u.Signature = "1\n2\n3\n4\n5" },
+		func(u *User) { u.Location = strings.Repeat("x", MaxLocationLength+1) },
+		func(u *User) { u.Bio = strings.Repeat("x", MaxBioLength+1) },
+	}
+
+	for i, mutate := range invalid {
+		u := *user
+		mutate(&u)
+		if err := u.ValidateProfile(); err == nil {
+			t.Errorf("Case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestUserFormatDate(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	user := NewUser("testuser", "test@example.com")
+
+	if got := user.FormatDate(ts); got != "Mar 01, 2024" {
+		t.Errorf("Expected default format, got %s", got)
+	}
+
+	user.DateFormat = "iso"
+	user.TimeZone = "Europe/Helsinki"
+	if got := user.FormatDate(ts); got != "2024-03-02" {
+		t.Errorf("Expected ISO date in Helsinki time, got %s", got)
+	}
+}
+
+func TestUserSign(t *testing.T) {
+	user := NewUser("testuser", "test@example.com")
+
+	if got := user.Sign("hello"); got != "hello" {
+		t.Errorf("Expected content unchanged without signature, got %q", got)
+	}
+
+	user.Signature = "-- testuser"
+	if got := user.Sign("hello"); got != "hello\n-- \n-- testuser" {
+		t.Errorf("Expected signature appended, got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 20
+	MaxEmailLength    = 254
+)
+
+// reservedUsernames are names the BBS itself uses or that would mislead other
+// callers. They are compared case-insensitively.
+var reservedUsernames = map[string]bool{
+	"anonymous": true,
+	"guest":     true,
+	"all":       true,
+	"sysop":     true,
+	"system":    true,
+}
+
+// ValidateUsername checks a username chosen at registration. Usernames are
+// letters, digits, '_', '-' and '.', starting with a letter or digit.
+func ValidateUsername(username string) error {
+	length := len([]rune(username))
+	if length < MinUsernameLength || length > MaxUsernameLength {
+		return fmt.Errorf("username must be %d to %d characters", MinUsernameLength, MaxUsernameLength)
+	}
+
+	for i, r := range username {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case i > 0 && (r == '_' || r == '-' || r == '.'):
+		default:
+			return errors.New("username may only contain letters, digits, '_', '-' and '.', and must start with a letter or digit")
+		}
+	}
+
+	if reservedUsernames[strings.ToLower(username)] {
+		return errors.New("that username is reserved")
+	}
+
+	return nil
+}
+
+// ValidateEmail checks that email is a single bare address such as
+// "user@example.com", with no display name or comments.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return errors.New("email cannot be empty")
+	}
+
+	if len(email) > MaxEmailLength {
+		return fmt.Errorf("email is limited to %d characters", MaxEmailLength)
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return errors.New("email address is not valid")
+	}
+
+	_, domain, _ := strings.Cut(email, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return errors.New("email address is not valid")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUsername(t *testing.T) {
+	valid := []string{"bob", "alice_2", "j.doe", "Äiti", "user-name", "abc1234567890123456x"}
+	for _, username := range valid {
+		if err := ValidateUsername(username); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", username, err)
+		}
+	}
+
+	invalid := []string{"", "ab", strings.Repeat("a", 21), "_bob", "bob smith", "bob\x1b", "bob@example", "Anonymous", "SYSOP"}
+	for _, username := range invalid {
+		if err := ValidateUsername(username); err == nil {
+			t.Errorf("Expected %q to be rejected", username)
+		}
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	valid := []string{"user@example.com", "first.last+tag@mail.example.org"}
+	for _, email := range valid {
+		if err := ValidateEmail(email); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", email, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"user",
+		"user@localhost",
+		"user@.com",
+		"Bob <bob@example.com>",
+		"bob@example.com\r\nBcc: eve@example.com",
+		"a@b@example.com",
+		strings.Repeat("a", 250) + "@example.com",
+	}
+	for _, email := range invalid {
+		if err := ValidateEmail(email); err == nil {
+			t.Errorf("Expected %q to be rejected", email)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed" // gave up after MaxWebhookAttempts
+)
+
+const (
+	MaxWebhookAttempts = 8
+	webhookRetryBase   = 30 * time.Second
+	webhookRetryMax    = time.Hour
+)
+
+// WebhookDelivery is an event waiting in the database to be posted to a
+// webhook, so it survives restarts and is retried while the receiver is
+// down. The payload is kept exactly as it will be signed and sent.
+type WebhookDelivery struct {
+	ID            int
+	URL           string
+	Event         string
+	Payload       string
+	Status        string
+	Attempts      int
+	ResponseCode  int // HTTP status of the last attempt, 0 if there was none
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+func NewWebhookDelivery(url, event, payload string) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		URL:           url,
+		Event:         event,
+		Payload:       payload,
+		Status:        WebhookPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+// Failed records a failed attempt and schedules the next one, doubling the
+// wait each time, or gives up after MaxWebhookAttempts.
+func (d *WebhookDelivery) Failed(responseCode int, err error, now time.Time) {
+	d.Attempts++
+	d.ResponseCode = responseCode
+	d.LastError = err.Error()
+	if d.Attempts >= MaxWebhookAttempts {
+		d.Status = WebhookFailed
+		return
+	}
+	d.NextAttemptAt = now.Add(retryDelay(d.Attempts, webhookRetryBase, webhookRetryMax))
+}
+
+func (d *WebhookDelivery) Delivered(responseCode int, now time.Time) {
+	d.Attempts++
+	d.Status = WebhookDelivered
+	d.ResponseCode = responseCode
+	d.LastError = ""
+	d.DeliveredAt = &now
+}
+
+// Outcome describes the last attempt for the sysop.
+func (d *WebhookDelivery) Outcome() string {
+	switch {
+	case d.Attempts == 0:
+		return "not tried yet"
+	case d.ResponseCode != 0 && d.LastError == "":
+		return fmt.Sprintf("HTTP %d", d.ResponseCode)
+	default:
+		return d.LastError
+	}
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWebhookDelivery_Failed(t *testing.T) {
+	delivery := NewWebhookDelivery("http://localhost/hook", "thread.created", "{}")
+	now := time.Now()
+
+	delivery.Failed(503, errors.New("HTTP 503"), now)
+	if delivery.Status != WebhookPending || delivery.Attempts != 1 || delivery.ResponseCode != 503 {
+		t.Errorf("Unexpected delivery after first failure: %+v", delivery)
+	}
+	if got := delivery.NextAttemptAt.Sub(now); got != 30*time.Second {
+		t.Errorf("Expected a 30s retry delay, got %v", got)
+	}
+
+	delivery.Failed(0, errors.New("connection refused"), now)
+	if got := delivery.NextAttemptAt.Sub(now); got != time.Minute {
+		t.Errorf("Expected the retry delay to double to 1m, got %v", got)
+	}
+	if delivery.Outcome() != "connection refused" {
+		t.Errorf("Expected the last error as the outcome, got %q", delivery.Outcome())
+	}
+
+	for delivery.Status == WebhookPending {
+		delivery.Failed(0, errors.New("still down"), now)
+	}
+	if delivery.Attempts != MaxWebhookAttempts || delivery.Status != WebhookFailed {
+		t.Errorf("Expected to give up after %d attempts, got %d (%s)", MaxWebhookAttempts, delivery.Attempts, delivery.Status)
+	}
+	if delivery.NextAttemptAt.Sub(now) > time.Hour {
+		t.Errorf("Expected the retry delay to stay within an hour, got %v", delivery.NextAttemptAt.Sub(now))
+	}
+}
+
+func TestWebhookDelivery_Delivered(t *testing.T) {
+	delivery := NewWebhookDelivery("http://localhost/hook", "thread.created", "{}")
+	if delivery.Outcome() != "not tried yet" {
+		t.Errorf("Unexpected outcome before the first attempt: %q", delivery.Outcome())
+	}
+
+	delivery.Failed(500, errors.New("HTTP 500"), time.Now())
+	delivery.Delivered(204, time.Now())
+
+	if delivery.Status != WebhookDelivered || delivery.DeliveredAt == nil || delivery.LastError != "" || delivery.Attempts != 2 {
+		t.Errorf("Unexpected delivery after success: %+v", delivery)
+	}
+	if delivery.Outcome() != "HTTP 204" {
+		t.Errorf("Expected the response as the outcome, got %q", delivery.Outcome())
+	}
+}
@@ -0,0 +1,58 @@
+package ftn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Address is a FidoNet-style node address, zone:net/node.point.
+type Address struct {
+	Zone  int
+	Net   int
+	Node  int
+	Point int
+}
+
+// ParseAddress parses "zone:net/node", with an optional ".point" and
+// "@domain", which is ignored.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	text, _, _ := strings.Cut(strings.TrimSpace(s), "@")
+
+	zone, rest, ok := strings.Cut(text, ":")
+	net, rest, ok2 := strings.Cut(rest, "/")
+	node, point, hasPoint := strings.Cut(rest, ".")
+	if !ok || !ok2 {
+		return a, fmt.Errorf("invalid address %q, use zone:net/node", s)
+	}
+
+	parts := []string{zone, net, node}
+	if hasPoint {
+		parts = append(parts, point)
+	}
+	dests := []*int{&a.Zone, &a.Net, &a.Node, &a.Point}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 0xFFFF {
+			return a, fmt.Errorf("invalid address %q, use zone:net/node", s)
+		}
+		*dests[i] = n
+	}
+	if a.Zone == 0 {
+		return a, fmt.Errorf("invalid address %q, the zone cannot be 0", s)
+	}
+	return a, nil
+}
+
+func (a Address) String() string {
+	if a.Point != 0 {
+		return fmt.Sprintf("%d:%d/%d.%d", a.Zone, a.Net, a.Node, a.Point)
+	}
+	return fmt.Sprintf("%d:%d/%d", a.Zone, a.Net, a.Node)
+}
+
+// Short is the net/node form used in SEEN-BY and PATH lines.
+func (a Address) Short() string {
+	return fmt.Sprintf("%d/%d", a.Net, a.Node)
+}
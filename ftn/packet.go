@@ -0,0 +1,357 @@
+// Package ftn reads and writes FidoNet-style type 2+ mail packets (FTS-0001
+// and FSC-0039/FSC-0048), and the control lines of echomail messages:
+// AREA, the MSGID and REPLY kludges, SEEN-BY and PATH.
+package ftn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/leinonen/bbs/ansi"
+)
+
+const (
+	headerSize = 58
+	// capabilities is the capability word of a type 2+ packet.
+	capabilities = 0x0001
+	// productCode is the FTSC code for products without their own.
+	productCode = 0xFE
+	// maxTextSize bounds a message's text when reading.
+	maxTextSize = 64 << 10
+
+	maxNameSize    = 35
+	maxSubjectSize = 71
+
+	dateFormat = "02 Jan 06  15:04:05"
+)
+
+// Packet is a mail packet from one node to another.
+type Packet struct {
+	From     Address
+	To       Address
+	Password string
+	Created  time.Time
+	Messages []*Message
+}
+
+// Message is one message in a packet. For echomail, Area names the echo; it
+// is empty for netmail.
+type Message struct {
+	From      Address
+	To        Address
+	Attribute uint16
+	Date      time.Time
+	FromName  string
+	ToName    string
+	Subject   string
+
+	Area  string
+	MSGID string
+	Reply string
+	// Body is the text without control lines, with "\n" line endings. For
+	// echomail it ends with the tear and origin lines.
+	Body   string
+	SeenBy []string
+	Path   []string
+}
+
+// OriginLines returns the tear and origin lines that end an echomail message
+// from addr.
+func OriginLines(origin string, addr Address) string {
+	suffix := " (" + addr.String() + ")"
+	// the origin line should fit in 79 characters
+	if max := 79 - len(" * Origin: ") - len(suffix); len(origin) > max {
+		origin = truncate(origin, max)
+	}
+	return "--- gobbs\n * Origin: " + origin + suffix
+}
+
+// ReadPacket reads a packet.
+func ReadPacket(r io.Reader) (*Packet, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("packet header too short: %v", err)
+	}
+	word := func(offset int) int {
+		return int(binary.LittleEndian.Uint16(header[offset:]))
+	}
+	if word(18) != 2 {
+		return nil, fmt.Errorf("not a type 2 packet")
+	}
+
+	p := &Packet{
+		From:     Address{Zone: word(34), Net: word(20), Node: word(0)},
+		To:       Address{Zone: word(36), Net: word(22), Node: word(2)},
+		Password: strings.TrimRight(string(header[26:34]), "\x00 "),
+	}
+	p.Created = time.Date(word(4), time.Month(word(6)+1), word(8), word(10), word(12), word(14), 0, time.UTC)
+
+	// a type 2+ packet repeats its capability word byte-swapped
+	capWord := word(44)
+	if capWord&capabilities != 0 && capWord == int(header[40])<<8|int(header[41]) {
+		p.From.Zone, p.To.Zone = word(46), word(48)
+		p.From.Point, p.To.Point = word(50), word(52)
+		if p.From.Point != 0 && p.From.Net == 0xFFFF {
+			p.From.Net = word(38)
+		}
+	}
+
+	for {
+		var msgType uint16
+		if err := binary.Read(br, binary.LittleEndian, &msgType); err != nil {
+			return nil, fmt.Errorf("packet ends without a terminator: %v", err)
+		}
+		if msgType == 0 {
+			return p, nil
+		}
+		if msgType != 2 {
+			return nil, fmt.Errorf("message %d has unknown type %d", len(p.Messages)+1, msgType)
+		}
+		msg, err := readMessage(br, p)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %v", len(p.Messages)+1, err)
+		}
+		p.Messages = append(p.Messages, msg)
+	}
+}
+
+func readMessage(br *bufio.Reader, p *Packet) (*Message, error) {
+	var fields [6]uint16
+	if err := binary.Read(br, binary.LittleEndian, &fields); err != nil {
+		return nil, err
+	}
+	msg := &Message{
+		From:      Address{Zone: p.From.Zone, Net: int(fields[2]), Node: int(fields[0])},
+		To:        Address{Zone: p.To.Zone, Net: int(fields[3]), Node: int(fields[1])},
+		Attribute: fields[4],
+	}
+
+	var strs [5][]byte
+	for i, max := range []int{20, 36, 36, 72, maxTextSize} {
+		s, err := readString(br, max)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	date, to, from, subject, text := strs[0], strs[1], strs[2], strs[3], strs[4]
+
+	charset := charsetOf(text)
+	msg.ToName = strings.TrimSpace(decode(to, charset))
+	msg.FromName = strings.TrimSpace(decode(from, charset))
+	msg.Subject = strings.TrimSpace(decode(subject, charset))
+	zone := msg.parseText(decode(text, charset))
+	msg.Date = parseDate(string(date), zone, p.Created)
+	return msg, nil
+}
+
+// readString reads a null-terminated string of at most max bytes.
+func readString(br *bufio.Reader, max int) ([]byte, error) {
+	var s []byte
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == 0 {
+			return s, nil
+		}
+		if len(s) == max {
+			return nil, errors.New("field too long")
+		}
+		s = append(s, c)
+	}
+}
+
+// parseText splits the text into the control lines and body, and returns the
+// time zone from the TZUTC kludge, or nil.
+func (m *Message) parseText(text string) *time.Location {
+	var zone *time.Location
+	var body []string
+	lines := strings.Split(strings.ReplaceAll(text, "\n", ""), "\r")
+	for i, line := range lines {
+		switch {
+		case i == 0 && strings.HasPrefix(line, "AREA:"):
+			m.Area = strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(line, "AREA:")))
+		case strings.HasPrefix(line, "\x01"):
+			name, value, _ := strings.Cut(line[1:], ":")
+			value = strings.TrimSpace(value)
+			switch strings.ToUpper(strings.TrimSpace(name)) {
+			case "MSGID":
+				m.MSGID = value
+			case "REPLY":
+				m.Reply = value
+			case "PATH":
+				m.Path = append(m.Path, value)
+			case "TZUTC":
+				zone = parseZone(value)
+			}
+		case strings.HasPrefix(line, "SEEN-BY:"):
+			m.SeenBy = append(m.SeenBy, strings.TrimSpace(strings.TrimPrefix(line, "SEEN-BY:")))
+		default:
+			body = append(body, line)
+		}
+	}
+	m.Body = strings.TrimRight(strings.Join(body, "\n"), " \n")
+	return zone
+}
+
+// charsetOf returns the charset named by a CHRS kludge, in upper case.
+func charsetOf(text []byte) string {
+	for _, line := range bytes.Split(text, []byte("\r")) {
+		line = bytes.TrimLeft(line, "\n")
+		if name, ok := bytes.CutPrefix(line, []byte("\x01CHRS:")); ok {
+			fields := strings.Fields(string(name))
+			if len(fields) > 0 {
+				return strings.ToUpper(fields[0])
+			}
+		}
+	}
+	return ""
+}
+
+// decode converts text in a charset to a string. Without a CHRS kludge, text
+// that is valid UTF-8 is taken as such, and anything else as code page 437.
+func decode(text []byte, charset string) string {
+	switch charset {
+	case "UTF-8":
+		return strings.ToValidUTF8(string(text), "?")
+	case "LATIN-1", "ISO-8859-1":
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case "":
+		if utf8.Valid(text) {
+			return string(text)
+		}
+	}
+	return ansi.DecodeCP437(text)
+}
+
+func parseZone(value string) *time.Location {
+	offset, err := strconv.Atoi(value)
+	if err != nil || len(strings.TrimPrefix(value, "-")) != 4 {
+		return nil
+	}
+	sign := 1
+	if offset < 0 {
+		sign, offset = -1, -offset
+	}
+	return time.FixedZone("", sign*(offset/100*3600+offset%100*60))
+}
+
+// parseDate parses an FTS-0001 date, falling back to fallback.
+func parseDate(value string, zone *time.Location, fallback time.Time) time.Time {
+	if zone == nil {
+		zone = time.UTC
+	}
+	for _, layout := range []string{dateFormat, "Mon  2 Jan 06 15:04", "Mon 2 Jan 06 15:04"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), zone); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
+
+// Write writes the packet in type 2+ format. Message text is UTF-8.
+func (p *Packet) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, headerSize)
+	put := func(values ...int) {
+		for _, v := range values {
+			header = binary.LittleEndian.AppendUint16(header, uint16(v))
+		}
+	}
+	created := p.Created.UTC()
+	put(p.From.Node, p.To.Node, created.Year(), int(created.Month())-1, created.Day(),
+		created.Hour(), created.Minute(), created.Second(), 0, 2, p.From.Net, p.To.Net)
+	header = append(header, productCode, 0)
+	password := make([]byte, 8)
+	copy(password, strings.ToUpper(p.Password))
+	header = append(header, password...)
+	put(p.From.Zone, p.To.Zone, 0)
+	header = append(header, capabilities>>8, capabilities&0xFF, 0, 0)
+	put(capabilities, p.From.Zone, p.To.Zone, p.From.Point, p.To.Point)
+	header = append(header, 0, 0, 0, 0)
+	bw.Write(header)
+
+	for _, msg := range p.Messages {
+		var fields []byte
+		for _, v := range []int{2, msg.From.Node, msg.To.Node, msg.From.Net, msg.To.Net, int(msg.Attribute), 0} {
+			fields = binary.LittleEndian.AppendUint16(fields, uint16(v))
+		}
+		bw.Write(fields)
+		for _, s := range []string{
+			msg.Date.Format(dateFormat),
+			truncate(msg.ToName, maxNameSize),
+			truncate(msg.FromName, maxNameSize),
+			truncate(msg.Subject, maxSubjectSize),
+			msg.text(),
+		} {
+			bw.WriteString(strings.ReplaceAll(s, "\x00", ""))
+			bw.WriteByte(0)
+		}
+	}
+	bw.Write([]byte{0, 0})
+	return bw.Flush()
+}
+
+// text returns the message text with its control lines, each line ended by
+// a carriage return.
+func (m *Message) text() string {
+	var lines []string
+	if m.Area != "" {
+		lines = append(lines, "AREA:"+m.Area)
+	}
+	if m.MSGID != "" {
+		lines = append(lines, "\x01MSGID: "+m.MSGID)
+	}
+	if m.Reply != "" {
+		lines = append(lines, "\x01REPLY: "+m.Reply)
+	}
+	lines = append(lines, "\x01CHRS: UTF-8 4")
+	_, offset := m.Date.Zone()
+	sign := ""
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	lines = append(lines, fmt.Sprintf("\x01TZUTC: %s%02d%02d", sign, offset/3600, offset%3600/60))
+
+	for _, line := range strings.Split(strings.ReplaceAll(m.Body, "\r", ""), "\n") {
+		// a body line must not pass for a control line
+		if strings.HasPrefix(line, "\x01") || strings.HasPrefix(line, "SEEN-BY:") {
+			line = " " + line
+		}
+		lines = append(lines, line)
+	}
+	for _, seenBy := range m.SeenBy {
+		lines = append(lines, "SEEN-BY: "+seenBy)
+	}
+	for _, path := range m.Path {
+		lines = append(lines, "\x01PATH: "+path)
+	}
+	return strings.Join(lines, "\r") + "\r"
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
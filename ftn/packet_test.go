@@ -0,0 +1,159 @@
+package ftn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAddress(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Address
+	}{
+		{"21:1/100", Address{21, 1, 100, 0}},
+		{"2:280/464.7", Address{2, 280, 464, 7}},
+		{" 1:103/705@fidonet ", Address{1, 103, 705, 0}},
+	} {
+		got, err := ParseAddress(test.in)
+		if err != nil || got != test.want {
+			t.Errorf("ParseAddress(%q) = %v, %v; want %v", test.in, got, err, test.want)
+		}
+	}
+	for _, in := range []string{"", "1/100", "21:1", "21:x/100", "0:1/100", "21:1/70000"} {
+		if _, err := ParseAddress(in); err == nil {
+			t.Errorf("Expected %q to fail", in)
+		}
+	}
+
+	if a := (Address{2, 280, 464, 7}); a.String() != "2:280/464.7" || a.Short() != "280/464" {
+		t.Errorf("Unexpected forms %s and %s", a.String(), a.Short())
+	}
+}
+
+func TestPacket_RoundTrip(t *testing.T) {
+	from := Address{21, 1, 100, 0}
+	to := Address{21, 1, 1, 0}
+	date := time.Date(2026, 3, 14, 15, 9, 26, 0, time.FixedZone("", -5*3600))
+	packet := &Packet{
+		From:     from,
+		To:       to,
+		Password: "secret",
+		Created:  time.Date(2026, 3, 14, 20, 10, 0, 0, time.UTC),
+		Messages: []*Message{{
+			From:     from,
+			To:       to,
+			Date:     date,
+			FromName: "alice",
+			ToName:   "All",
+			Subject:  "Héllo",
+			Area:     "GOBBS_GENERAL",
+			MSGID:    "21:1/100 69b5a0e6",
+			Reply:    "21:1/1 00000001",
+			Body:     "First line\n\x01Not a kludge\n\n" + OriginLines("Go BBS", from),
+			SeenBy:   []string{"1/1 100"},
+			Path:     []string{"1/100"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := packet.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if capWord := binary.LittleEndian.Uint16(buf.Bytes()[44:]); capWord != capabilities {
+		t.Errorf("Unexpected capability word %04x", capWord)
+	}
+
+	data := buf.Bytes()
+	got, err := ReadPacket(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if got.From != from || got.To != to || got.Password != "SECRET" || !got.Created.Equal(packet.Created) {
+		t.Errorf("Unexpected packet header %+v", got)
+	}
+	if len(got.Messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(got.Messages))
+	}
+
+	msg := got.Messages[0]
+	if msg.From != from || msg.To != to || msg.FromName != "alice" || msg.ToName != "All" || msg.Subject != "Héllo" {
+		t.Errorf("Unexpected message header %+v", msg)
+	}
+	if !msg.Date.Equal(date) {
+		t.Errorf("Expected date %v, got %v", date, msg.Date)
+	}
+	if msg.Area != "GOBBS_GENERAL" || msg.MSGID != "21:1/100 69b5a0e6" || msg.Reply != "21:1/1 00000001" {
+		t.Errorf("Unexpected control lines %q %q %q", msg.Area, msg.MSGID, msg.Reply)
+	}
+	want := "First line\n \x01Not a kludge\n\n--- gobbs\n * Origin: Go BBS (21:1/100)"
+	if msg.Body != want {
+		t.Errorf("Expected body %q, got %q", want, msg.Body)
+	}
+	if len(msg.SeenBy) != 1 || msg.SeenBy[0] != "1/1 100" || len(msg.Path) != 1 || msg.Path[0] != "1/100" {
+		t.Errorf("Unexpected SEEN-BY %q or PATH %q", msg.SeenBy, msg.Path)
+	}
+
+	if _, err := ReadPacket(bytes.NewReader(data[:headerSize])); err == nil {
+		t.Error("Expected a packet without a terminator to fail")
+	}
+}
+
+// rawPacket makes a type 2 packet with one message of the given text, the
+// way older software writes them.
+func rawPacket(text []byte) []byte {
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint16(header[0:], 1)
+	binary.LittleEndian.PutUint16(header[18:], 2)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[34:], 21)
+	binary.LittleEndian.PutUint16(header[36:], 21)
+
+	msg := []byte{2, 0, 1, 0, 100, 0, 1, 0, 1, 0, 0, 0, 0, 0}
+	msg = append(msg, "Sat 14 Mar 26 15:09\x00All\x00Bj\x94rn\x00Hi\x00"...)
+	msg = append(msg, text...)
+	msg = append(msg, 0, 0, 0)
+	return append(header, msg...)
+}
+
+func TestReadPacket_Charsets(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want string
+		from string
+	}{
+		{"AREA:TEST\r\nK\x94ln\r", "Köln", "Björn"},
+		{"AREA:TEST\r\x01CHRS: LATIN-1 2\rK\xf6ln\r", "Köln", "Bj\u0094rn"},
+		{"AREA:TEST\r\x01CHRS: CP437 2\rK\x94ln\r", "Köln", "Björn"},
+		{"AREA:TEST\rK\xc3\xb6ln\r", "Köln", "Björn"},
+	} {
+		packet, err := ReadPacket(bytes.NewReader(rawPacket([]byte(test.text))))
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		msg := packet.Messages[0]
+		if msg.Body != test.want || msg.Area != "TEST" {
+			t.Errorf("Expected %q in TEST from %q, got %q in %q", test.want, test.text, msg.Body, msg.Area)
+		}
+		if msg.From != (Address{21, 1, 1, 0}) || msg.FromName != test.from {
+			t.Errorf("Unexpected sender %v %q", msg.From, msg.FromName)
+		}
+		if msg.Date.Year() != 2026 || msg.Date.Day() != 14 {
+			t.Errorf("Unexpected date %v", msg.Date)
+		}
+	}
+
+	if _, err := ReadPacket(bytes.NewReader(make([]byte, headerSize+2))); err == nil {
+		t.Error("Expected a packet of another type to fail")
+	}
+}
+
+func TestOriginLines(t *testing.T) {
+	lines := OriginLines(strings.Repeat("x", 100), Address{21, 1, 100, 0})
+	origin := lines[strings.Index(lines, "\n")+1:]
+	if len(origin) != 79 || !strings.HasSuffix(origin, "x (21:1/100)") {
+		t.Errorf("Expected the origin line cut to 79 characters, got %q", origin)
+	}
+}
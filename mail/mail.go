@@ -0,0 +1,143 @@
+// Package mail sends the BBS's outgoing email, such as verification codes.
+// Delivery is pluggable: SMTP for real use, or a local maildir for testing.
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// New returns the mailer configured in cfg: SMTP if smtp_addr is set,
+// otherwise a maildir if maildir is set. It returns nil if neither is.
+func New(cfg *config.Config) Mailer {
+	switch {
+	case cfg.SMTPAddr != "":
+		return NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case cfg.Maildir != "":
+		return NewMaildirMailer(cfg.Maildir, cfg.MailFrom)
+	}
+	return nil
+}
+
+// headerValue keeps header fields on one line, so a value cannot add
+// headers of its own.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+}
+
+// Format renders msg as an RFC 5322 message with CRLF line endings.
+func (msg *Message) Format(from string, now time.Time) []byte {
+	id := make([]byte, 12)
+	rand.Read(id)
+	_, host, _ := strings.Cut(from, "@")
+	if host == "" {
+		host = "localhost"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), headerValue(host))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if !strings.HasSuffix(body, "\n") {
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
+
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer sends through the server at addr (host:port), authenticating
+// with PLAIN when a username is given. net/smtp upgrades to TLS with
+// STARTTLS when the server offers it, and refuses PLAIN auth without TLS
+// except to localhost.
+func NewSMTPMailer(addr, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, msg.Format(m.from, time.Now()))
+}
+
+// MaildirMailer delivers every message into a local maildir instead of
+// sending it, which is handy for testing and for single-machine setups
+// where the sysop reads the mail with a local client.
+type MaildirMailer struct {
+	dir  string
+	from string
+}
+
+func NewMaildirMailer(dir, from string) *MaildirMailer {
+	return &MaildirMailer{dir: dir, from: from}
+}
+
+func (m *MaildirMailer) Send(msg *Message) error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(m.dir, sub), 0700); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	unique := make([]byte, 8)
+	rand.Read(unique)
+	hostname, _ := os.Hostname()
+	hostname = strings.NewReplacer("/", "_", ":", "_").Replace(hostname)
+	name := fmt.Sprintf("%d.%d_%s.%s", now.Unix(), os.Getpid(), hex.EncodeToString(unique), hostname)
+
+	// maildir files use plain newlines
+	data := bytes.ReplaceAll(msg.Format(m.from, now), []byte("\r\n"), []byte("\n"))
+
+	// write into tmp and then move into new, so readers never see a
+	// half-written message
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(m.dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
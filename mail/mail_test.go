@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessage_Format(t *testing.T) {
+	msg := &Message{
+		To:      "user@example.com\r\nBcc: eve@example.com",
+		Subject: "Hyvää päivää",
+		Body:    "line one\nline two",
+	}
+
+	data := string(msg.Format("bbs@example.com", time.Now()))
+	header, body, ok := strings.Cut(data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("Expected a blank line after the headers, got %q", data)
+	}
+
+	for _, line := range strings.Split(header, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Error("A header value should not be able to add headers")
+		}
+	}
+
+	if !strings.Contains(header, "Subject: =?utf-8?q?") {
+		t.Errorf("Expected an encoded subject, got %q", header)
+	}
+
+	if !strings.Contains(header, "@example.com>") {
+		t.Errorf("Expected a Message-ID on the sender's domain, got %q", header)
+	}
+
+	if body != "line one\r\nline two\r\n" {
+		t.Errorf("Expected CRLF line endings in the body, got %q", body)
+	}
+}
+
+func TestMaildirMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewMaildirMailer(dir, "bbs@example.com")
+
+	if err := mailer.Send(&Message{To: "user@example.com", Subject: "Hello", Body: "Hi there"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	files, _ := os.ReadDir(filepath.Join(dir, "new"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 message in new/, got %d", len(files))
+	}
+
+	if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+		t.Errorf("Expected tmp/ to be empty, got %d files", len(tmp))
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if !strings.Contains(string(data), "To: user@example.com\n") || strings.Contains(string(data), "\r") {
+		t.Errorf("Unexpected message: %q", data)
+	}
+}
+
+// fakeSMTPServer accepts one connection, speaks just enough SMTP for
+// net/smtp and records what it was sent. A reject code fails RCPT TO.
+type fakeSMTPServer struct {
+	listener net.Listener
+	reject   string
+	done     chan struct{}
+
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTPServer(t *testing.T, reject string) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, reject: reject, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = line[len("MAIL FROM:"):]
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.reject != "" {
+				tp.PrintfLine("%s mailbox unavailable", s.reject)
+				continue
+			}
+			s.to = append(s.to, line[len("RCPT TO:"):])
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			tp.PrintfLine("250 Queued")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server := newFakeSMTPServer(t, "")
+	mailer := NewSMTPMailer(server.listener.Addr().String(), "", "", "bbs@example.com")
+
+	err := mailer.Send(&Message{To: "user@example.com", Subject: "Hello", Body: "Hi there\n.leading dot"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	<-server.done
+
+	if !strings.HasPrefix(server.from, "<bbs@example.com>") {
+		t.Errorf("Expected sender <bbs@example.com>, got %s", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "<user@example.com>" {
+		t.Errorf("Expected recipient <user@example.com>, got %v", server.to)
+	}
+	if !strings.Contains(server.data, "Subject: Hello\n") {
+		t.Errorf("Expected the subject header, got %q", server.data)
+	}
+	if !strings.Contains(server.data, "Hi there\n.leading dot\n") {
+		t.Errorf("Expected the body to survive dot-stuffing, got %q", server.data)
+	}
+}
+
+func TestSMTPMailer_SendRejected(t *testing.T) {
+	server := newFakeSMTPServer(t, "550")
+	mailer := NewSMTPMailer(server.listener.Addr().String(), "", "", "bbs@example.com")
+
+	if err := mailer.Send(&Message{To: "nobody@example.com", Subject: "Hello", Body: "Hi"}); err == nil {
+		t.Error("Expected an error when the server rejects the recipient")
+	}
+}
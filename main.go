@@ -23,6 +23,9 @@ func main() {
 
 	cfg, err := config.Load(*configFile)
 	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("Invalid config file: %v", err)
+		}
 		log.Printf("Warning: Could not load config file: %v. Using defaults.", err)
 		cfg = config.Default()
 	}
@@ -43,7 +46,11 @@ func main() {
 		return
 	}
 
-	sshServer := server.NewSSHServer(cfg, repos)
+	services := server.NewServices(cfg, repos)
+	stop := make(chan struct{})
+	go server.RunJobs(services, stop)
+
+	sshServer := server.NewSSHServer(services)
 
 	go func() {
 		log.Printf("Starting BBS SSH server on %s", cfg.ListenAddr)
@@ -52,10 +59,116 @@ func main() {
 		}
 	}()
 
+	var telnetServer *server.TelnetServer
+	if cfg.TelnetAddr != "" {
+		telnetServer = server.NewTelnetServer(services)
+
+		go func() {
+			log.Printf("Starting BBS telnet server on %s", cfg.TelnetAddr)
+			if err := telnetServer.Start(); err != nil {
+				log.Fatalf("Telnet server error: %v", err)
+			}
+		}()
+	}
+
+	var webServer *server.WebServer
+	if cfg.WebAddr != "" {
+		webServer = server.NewWebServer(services)
+
+		go func() {
+			log.Printf("Starting BBS web terminal on %s", cfg.WebAddr)
+			if err := webServer.Start(); err != nil {
+				log.Fatalf("Web server error: %v", err)
+			}
+		}()
+	}
+
+	var apiServer *server.APIServer
+	if cfg.APIAddr != "" {
+		apiServer = server.NewAPIServer(services)
+
+		go func() {
+			log.Printf("Starting BBS API server on %s", cfg.APIAddr)
+			if err := apiServer.Start(); err != nil {
+				log.Fatalf("API server error: %v", err)
+			}
+		}()
+	}
+
+	var nntpServer *server.NNTPServer
+	if cfg.NNTPAddr != "" {
+		nntpServer = server.NewNNTPServer(services)
+
+		go func() {
+			log.Printf("Starting BBS NNTP server on %s", cfg.NNTPAddr)
+			if err := nntpServer.Start(); err != nil {
+				log.Fatalf("NNTP server error: %v", err)
+			}
+		}()
+	}
+
+	var feedServer *server.FeedServer
+	if cfg.FeedAddr != "" {
+		feedServer = server.NewFeedServer(services)
+
+		go func() {
+			log.Printf("Starting BBS feed server on %s", cfg.FeedAddr)
+			if err := feedServer.Start(); err != nil {
+				log.Fatalf("Feed server error: %v", err)
+			}
+		}()
+	}
+
+	var gopherServer *server.GopherServer
+	if cfg.GopherAddr != "" {
+		gopherServer = server.NewGopherServer(services)
+
+		go func() {
+			log.Printf("Starting BBS Gopher server on %s", cfg.GopherAddr)
+			if err := gopherServer.Start(); err != nil {
+				log.Fatalf("Gopher server error: %v", err)
+			}
+		}()
+	}
+
+	var mailGateway *server.MailGatewayServer
+	if cfg.MailGatewayAddr != "" {
+		mailGateway = server.NewMailGatewayServer(services)
+
+		go func() {
+			log.Printf("Starting BBS mail gateway on %s", cfg.MailGatewayAddr)
+			if err := mailGateway.Start(); err != nil {
+				log.Fatalf("Mail gateway error: %v", err)
+			}
+		}()
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
 	log.Println("Shutting down server...")
+	close(stop)
 	sshServer.Stop()
+	if telnetServer != nil {
+		telnetServer.Stop()
+	}
+	if webServer != nil {
+		webServer.Stop()
+	}
+	if apiServer != nil {
+		apiServer.Stop()
+	}
+	if nntpServer != nil {
+		nntpServer.Stop()
+	}
+	if feedServer != nil {
+		feedServer.Stop()
+	}
+	if gopherServer != nil {
+		gopherServer.Stop()
+	}
+	if mailGateway != nil {
+		mailGateway.Stop()
+	}
 }
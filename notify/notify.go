@@ -0,0 +1,318 @@
+// Package notify tells users about things that happened while they were
+// away. Watched threads and boards and @mentions fill a notification inbox on
+// the BBS. By email, users can get replies to their threads, new private
+// messages and a daily digest of new threads. Mail is queued in the database
+// and sent by Run, so it is retried if the mail server is down and survives
+// restarts.
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/mail"
+	"github.com/leinonen/bbs/repository"
+)
+
+const (
+	// Interval is how often Run delivers queued mail and checks for digests.
+	Interval = time.Minute
+	// batchSize bounds the emails sent per delivery round.
+	batchSize = 50
+)
+
+type Dispatcher struct {
+	repos      *repository.Manager
+	sessions   *domain.SessionManager
+	mailer     mail.Mailer
+	serverName string
+	now        func() time.Time
+}
+
+// NewDispatcher returns a dispatcher that shows notices to users online in
+// sessions and sends email through mailer. With a nil mailer no email is
+// queued or sent.
+func NewDispatcher(repos *repository.Manager, sessions *domain.SessionManager, mailer mail.Mailer, serverName string) *Dispatcher {
+	return &Dispatcher{
+		repos:      repos,
+		sessions:   sessions,
+		mailer:     mailer,
+		serverName: serverName,
+		now:        time.Now,
+	}
+}
+
+// Enabled reports whether email can be sent at all.
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && d.mailer != nil
+}
+
+// recipient returns user's settings if they can be sent email: they want
+// it, and their address has been verified so the BBS cannot be used to mail
+// strangers.
+func (d *Dispatcher) recipient(userID int) (*domain.User, *domain.NotificationSettings, bool) {
+	user, err := d.repos.User.GetByID(userID)
+	if err != nil || !user.EmailVerified || !user.IsActive() {
+		return nil, nil, false
+	}
+
+	settings, err := d.repos.NotificationSettings.Get(userID)
+	if err != nil {
+		log.Printf("Failed to load notification settings for %s: %v", user.Username, err)
+		return nil, nil, false
+	}
+	return user, settings, true
+}
+
+func (d *Dispatcher) enqueue(to, subject, body string) {
+	email := domain.NewQueuedEmail(to, subject, body)
+	email.NextAttemptAt = d.now()
+	if err := d.repos.EmailQueue.Enqueue(email); err != nil {
+		log.Printf("Failed to queue email to %s: %v", to, err)
+	}
+}
+
+// threadRoot follows a reply up to the post that started its thread.
+func (d *Dispatcher) threadRoot(post *domain.Post) (*domain.Post, error) {
+	for depth := 0; post.ReplyTo != nil; depth++ {
+		if depth > 100 {
+			return nil, fmt.Errorf("thread of post %d is too deep", post.ID)
+		}
+		parent, err := d.repos.Post.GetByID(*post.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		post = parent
+	}
+	return post, nil
+}
+
+// PostCreated tells users about a new post. Anyone it @mentions, and the
+// watchers of its thread or board, get a notification on the BBS, and the
+// thread's author gets an email if they asked for one. Posting in a thread
+// also watches it.
+func (d *Dispatcher) PostCreated(post *domain.Post) {
+	thread, err := d.threadRoot(post)
+	if err != nil {
+		log.Printf("Failed to find thread of post %d: %v", post.ID, err)
+		return
+	}
+
+	if err := d.repos.Subscription.WatchThread(post.UserID, thread.ID); err != nil {
+		log.Printf("Failed to watch thread %d for user %d: %v", thread.ID, post.UserID, err)
+	}
+	d.notifyUsers(post, thread)
+
+	if d.Enabled() && post.ReplyTo != nil && thread.UserID != post.UserID {
+		d.emailReply(post, thread)
+	}
+}
+
+// notifyUsers creates one notification per user for post, preferring a
+// mention over a watched thread or board.
+func (d *Dispatcher) notifyUsers(post, thread *domain.Post) {
+	notified := map[int]bool{post.UserID: true}
+	notify := func(userID int, kind string) {
+		if notified[userID] {
+			return
+		}
+		notified[userID] = true
+
+		notification := domain.NewNotification(userID, kind, post, thread)
+		if err := d.repos.Notification.Create(notification); err != nil {
+			log.Printf("Failed to notify user %d about post %d: %v", userID, post.ID, err)
+			return
+		}
+		d.sessions.Notify(userID, notification.Summary())
+	}
+
+	for _, username := range domain.Mentions(post.Title + "\n" + post.Content) {
+		if user, err := d.repos.User.GetByUsername(username); err == nil && user.IsActive() {
+			notify(user.ID, domain.NotificationMention)
+		}
+	}
+
+	var userIDs []int
+	var err error
+	kind := domain.NotificationReply
+	if post.ReplyTo != nil {
+		userIDs, err = d.repos.Subscription.GetWatchers(thread.ID)
+	} else {
+		kind = domain.NotificationThread
+		userIDs, err = d.repos.Subscription.GetSubscribers(post.BoardID)
+	}
+	if err != nil {
+		log.Printf("Failed to load watchers for post %d: %v", post.ID, err)
+	}
+	for _, userID := range userIDs {
+		notify(userID, kind)
+	}
+}
+
+func (d *Dispatcher) emailReply(post, thread *domain.Post) {
+	user, settings, ok := d.recipient(thread.UserID)
+	if !ok || !settings.EmailReplies {
+		return
+	}
+
+	board := ""
+	if b, err := d.repos.Board.GetByID(post.BoardID); err == nil {
+		board = b.Name
+	}
+
+	subject := fmt.Sprintf("[%s] %s replied to %s", d.serverName, post.Username, thread.Title)
+	body := fmt.Sprintf("%s replied to your thread \"%s\" in %s:\n\n%s\n\n-- \n%s",
+		post.Username, thread.Title, board, post.Content, d.footer())
+	d.enqueue(user.Email, subject, body)
+}
+
+// MessageSent tells the recipient of a private message about it if they are
+// online, and mails them if they asked for it.
+func (d *Dispatcher) MessageSent(message *domain.PrivateMessage) {
+	d.sessions.Notify(message.ToUserID, fmt.Sprintf("New private message from %s: %s", message.FromUsername, message.Subject))
+	if !d.Enabled() {
+		return
+	}
+
+	user, settings, ok := d.recipient(message.ToUserID)
+	if !ok || !settings.EmailMessages {
+		return
+	}
+
+	subject := fmt.Sprintf("[%s] Message from %s: %s", d.serverName, message.FromUsername, message.Subject)
+	body := fmt.Sprintf("%s sent you a private message:\n\n%s\n\n%s\n\n-- \n%s",
+		message.FromUsername, message.Subject, message.Body, d.footer())
+	d.enqueue(user.Email, subject, body)
+}
+
+func (d *Dispatcher) footer() string {
+	return fmt.Sprintf("You get this email because of your notification settings on %s.\n"+
+		"Change them from your profile when you next call.", d.serverName)
+}
+
+// SendDigests queues a digest of new threads on subscribed boards for every
+// user whose digest is due. Users with nothing new get no email, but their
+// next digest still starts from now.
+func (d *Dispatcher) SendDigests() error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	now := d.now()
+	due, err := d.repos.NotificationSettings.GetDigestDue(now)
+	if err != nil {
+		return err
+	}
+
+	for _, settings := range due {
+		if user, _, ok := d.recipient(settings.UserID); ok {
+			if body, ok := d.digest(settings); ok {
+				d.enqueue(user.Email, fmt.Sprintf("[%s] Daily digest", d.serverName), body)
+			}
+		}
+
+		settings.DigestSentAt = now
+		if err := d.repos.NotificationSettings.Save(settings); err != nil {
+			log.Printf("Failed to update digest time for user %d: %v", settings.UserID, err)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) digest(settings *domain.NotificationSettings) (string, bool) {
+	boardIDs, err := d.repos.Subscription.GetBoardIDs(settings.UserID)
+	if err != nil {
+		log.Printf("Failed to load subscriptions for user %d: %v", settings.UserID, err)
+		return "", false
+	}
+
+	var b strings.Builder
+	count := 0
+	for _, boardID := range boardIDs {
+		board, err := d.repos.Board.GetByID(boardID)
+		if err != nil {
+			continue
+		}
+
+		threads, err := d.repos.Post.GetThreadsSince(boardID, settings.DigestSentAt)
+		if err != nil {
+			log.Printf("Failed to load new threads on %s: %v", board.Name, err)
+			continue
+		}
+		if len(threads) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s\n", board.Name)
+		for _, thread := range threads {
+			fmt.Fprintf(&b, "  %s - by %s, %s\n", thread.Title, thread.Username, thread.CreatedAt.Format("Jan 02 15:04"))
+		}
+		b.WriteString("\n")
+		count += len(threads)
+	}
+
+	if count == 0 {
+		return "", false
+	}
+
+	return fmt.Sprintf("New threads on your subscribed boards since %s:\n\n%s-- \n%s",
+		settings.DigestSentAt.Format("Jan 02 15:04"), b.String(), d.footer()), true
+}
+
+// Deliver sends queued emails that are due, rescheduling failures with
+// backoff.
+func (d *Dispatcher) Deliver() error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	emails, err := d.repos.EmailQueue.GetDue(d.now(), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		err := d.mailer.Send(&mail.Message{To: email.To, Subject: email.Subject, Body: email.Body})
+		if err != nil {
+			email.Failed(err, d.now())
+			if email.Status == domain.EmailFailed {
+				log.Printf("Giving up on email to %s after %d attempts: %v", email.To, email.Attempts, err)
+			}
+		} else {
+			email.Sent(d.now())
+		}
+
+		if err := d.repos.EmailQueue.Update(email); err != nil {
+			log.Printf("Failed to update queued email %d: %v", email.ID, err)
+		}
+	}
+	return nil
+}
+
+// Run delivers mail and digests every Interval until stop is closed.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	if !d.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.SendDigests(); err != nil {
+			log.Printf("Failed to send digests: %v", err)
+		}
+		if err := d.Deliver(); err != nil {
+			log.Printf("Failed to deliver email: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/mail"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []*mail.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(msg *mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type fixture struct {
+	dispatcher *Dispatcher
+	repos      *repository.Manager
+	sessions   *domain.SessionManager
+	mailer     *recordingMailer
+	now        *time.Time
+	board      *domain.Board
+	alice      *domain.User
+	bob        *domain.User
+}
+
+func newFixture(t *testing.T) *fixture {
+	repos := &repository.Manager{
+		User:                 mocks.NewUserRepository(),
+		Board:                mocks.NewBoardRepository(),
+		Post:                 mocks.NewPostRepository(),
+		Message:              mocks.NewPrivateMessageRepository(),
+		NotificationSettings: mocks.NewNotificationSettingsRepository(),
+		Subscription:         mocks.NewSubscriptionRepository(),
+		EmailQueue:           mocks.NewEmailQueueRepository(),
+		Notification:         mocks.NewNotificationRepository(),
+	}
+
+	f := &fixture{repos: repos, mailer: &recordingMailer{}}
+	f.alice = f.createUser(t, "alice", true)
+	f.bob = f.createUser(t, "bob", true)
+
+	f.board = domain.NewBoard("General", "General discussion")
+	if err := repos.Board.Create(f.board); err != nil {
+		t.Fatalf("Failed to create board: %v", err)
+	}
+
+	now := time.Now()
+	f.now = &now
+	f.sessions = domain.NewSessionManager()
+	f.dispatcher = NewDispatcher(repos, f.sessions, f.mailer, "Test BBS")
+	f.dispatcher.now = func() time.Time { return *f.now }
+	return f
+}
+
+func (f *fixture) createUser(t *testing.T, username string, verified bool) *domain.User {
+	user := domain.NewUser(username, username+"@example.com")
+	user.Password = "password123"
+	user.EmailVerified = verified
+	if err := f.repos.User.Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+func (f *fixture) settings(t *testing.T, user *domain.User, replies, messages, digest bool) {
+	err := f.repos.NotificationSettings.Save(&domain.NotificationSettings{
+		UserID:        user.ID,
+		EmailReplies:  replies,
+		EmailMessages: messages,
+		EmailDigest:   digest,
+		DigestSentAt:  *f.now,
+	})
+	if err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+}
+
+func (f *fixture) post(t *testing.T, post *domain.Post) *domain.Post {
+	if err := f.repos.Post.Create(post); err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	return post
+}
+
+func (f *fixture) deliver(t *testing.T) []*mail.Message {
+	if err := f.dispatcher.Deliver(); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	sent := f.mailer.sent
+	f.mailer.sent = nil
+	return sent
+}
+
+func TestDispatcher_PostCreated(t *testing.T) {
+	f := newFixture(t)
+	f.settings(t, f.alice, true, false, false)
+
+	thread := f.post(t, domain.NewPost(f.board.ID, f.alice.ID, "alice", "Hello", "First post"))
+	reply := f.post(t, domain.NewReply(f.board.ID, f.bob.ID, "bob", "Welcome!", thread.ID))
+	nested := f.post(t, domain.NewReply(f.board.ID, f.bob.ID, "bob", "Again", reply.ID))
+	own := f.post(t, domain.NewReply(f.board.ID, f.alice.ID, "alice", "Thanks", thread.ID))
+
+	f.dispatcher.PostCreated(thread)
+	f.dispatcher.PostCreated(reply)
+	f.dispatcher.PostCreated(nested)
+	f.dispatcher.PostCreated(own)
+
+	sent := f.deliver(t)
+	if len(sent) != 2 {
+		t.Fatalf("Expected 2 emails, got %d", len(sent))
+	}
+
+	msg := sent[0]
+	if msg.To != "alice@example.com" {
+		t.Errorf("Expected email to alice@example.com, got %s", msg.To)
+	}
+	if !strings.Contains(msg.Subject, "bob replied to Hello") {
+		t.Errorf("Unexpected subject: %s", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "Welcome!") || !strings.Contains(msg.Body, "General") {
+		t.Errorf("Unexpected body: %s", msg.Body)
+	}
+	if !strings.Contains(sent[1].Body, "Again") {
+		t.Errorf("Expected a reply to a reply to notify the thread's author, got %s", sent[1].Body)
+	}
+}
+
+func TestDispatcher_Notifications(t *testing.T) {
+	f := newFixture(t)
+	carol := f.createUser(t, "carol", true)
+	dave := f.createUser(t, "dave", true)
+	f.repos.Subscription.Subscribe(carol.ID, f.board.ID)
+
+	// carol watches the board, so she hears about the new thread
+	thread := f.post(t, domain.NewPost(f.board.ID, f.alice.ID, "alice", "Hello", "First post"))
+	f.dispatcher.PostCreated(thread)
+
+	// alice watches her own thread; dave is mentioned; carol only watches
+	// the board, so she does not hear about replies
+	reply := f.post(t, domain.NewReply(f.board.ID, f.bob.ID, "bob", "What do you think, @dave? @bob", thread.ID))
+	f.dispatcher.PostCreated(reply)
+
+	// bob is now watching the thread he replied to, and a mention beats
+	// the watch for alice
+	again := f.post(t, domain.NewReply(f.board.ID, dave.ID, "dave", "Agreed, @alice", reply.ID))
+	f.dispatcher.PostCreated(again)
+
+	expect := func(user *domain.User, kinds ...string) {
+		t.Helper()
+		notifications, _ := f.repos.Notification.GetByUser(user.ID, 10)
+		var got []string
+		for _, n := range notifications {
+			got = append(got, n.Kind)
+			if n.ThreadID != thread.ID || n.Title != "Hello" {
+				t.Errorf("Expected %s's notification to link to the thread, got %+v", user.Username, n)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(kinds, ",") {
+			t.Errorf("Expected %s to get %v, got %v", user.Username, kinds, got)
+		}
+	}
+
+	expect(f.alice, domain.NotificationMention, domain.NotificationReply)
+	expect(f.bob, domain.NotificationReply)
+	expect(carol, domain.NotificationThread)
+	expect(dave, domain.NotificationMention)
+
+	if watching, _ := f.repos.Subscription.IsWatching(dave.ID, thread.ID); !watching {
+		t.Error("Expected replying to watch the thread")
+	}
+}
+
+func TestDispatcher_MessageSent(t *testing.T) {
+	f := newFixture(t)
+	f.settings(t, f.bob, false, true, false)
+
+	message := domain.NewPrivateMessage(f.alice, f.bob, "Lunch?", "Are you free tomorrow?")
+	f.dispatcher.MessageSent(message)
+
+	sent := f.deliver(t)
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(sent))
+	}
+	if sent[0].To != "bob@example.com" || !strings.Contains(sent[0].Subject, "Lunch?") ||
+		!strings.Contains(sent[0].Body, "Are you free tomorrow?") {
+		t.Errorf("Unexpected email: %+v", sent[0])
+	}
+}
+
+func TestDispatcher_RespectsSettings(t *testing.T) {
+	f := newFixture(t)
+	carol := f.createUser(t, "carol", false)
+	f.settings(t, carol, true, true, false)
+
+	// alice has no settings, so everything is off
+	thread := f.post(t, domain.NewPost(f.board.ID, f.alice.ID, "alice", "Hello", "First post"))
+	f.dispatcher.PostCreated(f.post(t, domain.NewReply(f.board.ID, f.bob.ID, "bob", "Hi", thread.ID)))
+	f.dispatcher.MessageSent(domain.NewPrivateMessage(f.bob, f.alice, "Hi", "Hello alice"))
+
+	// carol wants email but has not verified her address
+	f.dispatcher.MessageSent(domain.NewPrivateMessage(f.bob, carol, "Hi", "Hello carol"))
+
+	if sent := f.deliver(t); len(sent) != 0 {
+		t.Errorf("Expected no email, got %d", len(sent))
+	}
+}
+
+func TestDispatcher_WithoutMailer(t *testing.T) {
+	f := newFixture(t)
+	f.settings(t, f.bob, false, true, false)
+	f.dispatcher.mailer = nil
+
+	f.dispatcher.MessageSent(domain.NewPrivateMessage(f.alice, f.bob, "Hi", "Hello"))
+
+	if emails, _ := f.repos.EmailQueue.GetRecent(10); len(emails) != 0 {
+		t.Errorf("Expected nothing to be queued without a mailer, got %d", len(emails))
+	}
+
+	f.dispatcher.PostCreated(f.post(t, domain.NewPost(f.board.ID, f.alice.ID, "alice", "Hi @bob", "Hello")))
+	if unread, _ := f.repos.Notification.CountUnread(f.bob.ID); unread != 1 {
+		t.Errorf("Expected notifications on the BBS without a mailer, got %d", unread)
+	}
+}
+
+func TestDispatcher_DeliverRetries(t *testing.T) {
+	f := newFixture(t)
+	f.settings(t, f.bob, false, true, false)
+	f.mailer.err = errors.New("connection refused")
+
+	f.dispatcher.MessageSent(domain.NewPrivateMessage(f.alice, f.bob, "Hi", "Hello"))
+
+	f.deliver(t)
+	emails, _ := f.repos.EmailQueue.GetRecent(10)
+	if len(emails) != 1 || emails[0].Status != domain.EmailPending || emails[0].Attempts != 1 ||
+		emails[0].LastError != "connection refused" {
+		t.Fatalf("Expected the email to be kept for a retry, got %+v", emails)
+	}
+
+	// not due again until the backoff has passed
+	f.mailer.err = nil
+	if sent := f.deliver(t); len(sent) != 0 {
+		t.Errorf("Expected no retry before the backoff, got %d", len(sent))
+	}
+
+	*f.now = emails[0].NextAttemptAt
+	if sent := f.deliver(t); len(sent) != 1 {
+		t.Fatalf("Expected the email to be retried, got %d", len(sent))
+	}
+
+	emails, _ = f.repos.EmailQueue.GetRecent(10)
+	if emails[0].Status != domain.EmailSent || emails[0].SentAt == nil {
+		t.Errorf("Expected the email to be marked sent, got %+v", emails[0])
+	}
+}
+
+func TestDispatcher_SendDigests(t *testing.T) {
+	f := newFixture(t)
+	f.settings(t, f.alice, false, false, true)
+	f.settings(t, f.bob, false, false, true)
+
+	other := domain.NewBoard("Other", "Not subscribed")
+	f.repos.Board.Create(other)
+	f.repos.Subscription.Subscribe(f.alice.ID, f.board.ID)
+
+	*f.now = f.now.Add(time.Hour)
+	f.post(t, domain.NewPost(f.board.ID, f.bob.ID, "bob", "Subscribed thread", "Hello"))
+	f.post(t, domain.NewPost(other.ID, f.bob.ID, "bob", "Other thread", "Hello"))
+
+	// not a day since the last digest yet
+	if err := f.dispatcher.SendDigests(); err != nil {
+		t.Fatalf("SendDigests failed: %v", err)
+	}
+	if sent := f.deliver(t); len(sent) != 0 {
+		t.Fatalf("Expected no digest before it is due, got %d", len(sent))
+	}
+
+	*f.now = f.now.Add(domain.DigestInterval)
+	if err := f.dispatcher.SendDigests(); err != nil {
+		t.Fatalf("SendDigests failed: %v", err)
+	}
+
+	// bob subscribes to nothing, so only alice gets a digest
+	sent := f.deliver(t)
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 digest, got %d", len(sent))
+	}
+	if sent[0].To != "alice@example.com" || !strings.Contains(sent[0].Body, "Subscribed thread") ||
+		strings.Contains(sent[0].Body, "Other thread") {
+		t.Errorf("Unexpected digest: %+v", sent[0])
+	}
+
+	for _, user := range []*domain.User{f.alice, f.bob} {
+		settings, _ := f.repos.NotificationSettings.Get(user.ID)
+		if !settings.DigestSentAt.Equal(*f.now) {
+			t.Errorf("Expected %s's digest time to be updated, got %v", user.Username, settings.DigestSentAt)
+		}
+	}
+
+	if err := f.dispatcher.SendDigests(); err != nil {
+		t.Fatalf("SendDigests failed: %v", err)
+	}
+	if sent := f.deliver(t); len(sent) != 0 {
+		t.Errorf("Expected no second digest on the same day, got %d", len(sent))
+	}
+}
+
+func TestDispatcher_LiveNotices(t *testing.T) {
+	f := newFixture(t)
+	online := f.sessions.CreateSession(f.alice, nil)
+
+	thread := f.post(t, domain.NewPost(f.board.ID, f.alice.ID, "alice", "Hello", "First post"))
+	f.dispatcher.PostCreated(thread)
+	f.dispatcher.PostCreated(f.post(t, domain.NewReply(f.board.ID, f.bob.ID, "bob", "Hi", thread.ID)))
+	f.dispatcher.MessageSent(domain.NewPrivateMessage(f.bob, f.alice, "Lunch?", "Are you free?"))
+
+	for _, want := range []string{`bob replied to "Hello"`, "New private message from bob: Lunch?"} {
+		select {
+		case got := <-online.Notices:
+			if got != want {
+				t.Errorf("Expected notice %q, got %q", want, got)
+			}
+		default:
+			t.Errorf("Expected notice %q", want)
+		}
+	}
+
+	if len(online.Notices) != 0 {
+		t.Errorf("Expected no more notices, got %d", len(online.Notices))
+	}
+}
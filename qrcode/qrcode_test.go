@@ -0,0 +1,188 @@
+package qrcode
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The worked "HELLO WORLD" 1-M example from the standard's annex.
+func TestRSRemainder(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := rsRemainder(data, rsDivisor(10))
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestEncodeData_Padding(t *testing.T) {
+	layout := layouts[Medium][0]
+	data := encodeData(1, layout, []byte("A"))
+
+	if len(data) != layout.dataCodewords() {
+		t.Fatalf("Expected %d codewords, got %d", layout.dataCodewords(), len(data))
+	}
+
+	// mode 0100, length 00000001, 'A' 01000001, terminator 0000
+	expected := []byte{0x40, 0x14, 0x10, 0xec, 0x11, 0xec}
+	if !bytes.Equal(data[:6], expected) {
+		t.Errorf("Expected prefix %x, got %x", expected, data[:6])
+	}
+}
+
+func TestEncode_VersionSelection(t *testing.T) {
+	tests := []struct {
+		length  int
+		level   Level
+		version int
+	}{
+		{17, Low, 1},
+		{18, Low, 2},
+		{14, Medium, 1},
+		{15, Medium, 2},
+		{106, Medium, 6},
+		{271, Low, 10},
+	}
+
+	for _, tt := range tests {
+		code, err := Encode(strings.Repeat("x", tt.length), tt.level)
+		if err != nil {
+			t.Fatalf("Encode(%d) failed: %v", tt.length, err)
+		}
+		if code.Version != tt.version {
+			t.Errorf("Expected version %d for %d bytes, got %d", tt.version, tt.length, code.Version)
+		}
+		if code.Size != tt.version*4+17 {
+			t.Errorf("Expected size %d, got %d", tt.version*4+17, code.Size)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("x", 272), Low); err != ErrTooLong {
+		t.Errorf("Expected ErrTooLong, got %v", err)
+	}
+}
+
+func TestEncode_FunctionPatterns(t *testing.T) {
+	code, err := Encode("otpauth://totp/Go%20BBS:alice?issuer=Go+BBS&secret=JBSWY3DPEHPK3PXP", Medium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	// finder pattern corners and centres
+	for _, p := range [][2]int{{0, 0}, {6, 6}, {3, 3}, {code.Size - 1, 0}, {0, code.Size - 1}} {
+		if !code.Black(p[0], p[1]) {
+			t.Errorf("Expected dark module at %v", p)
+		}
+	}
+	if code.Black(7, 7) || code.Black(1, 1) {
+		t.Error("Expected separator and finder ring to be light")
+	}
+
+	// timing pattern alternates
+	for i := 8; i < code.Size-8; i++ {
+		if code.Black(i, 6) != (i%2 == 0) || code.Black(6, i) != (i%2 == 0) {
+			t.Fatalf("Timing pattern broken at %d", i)
+		}
+	}
+
+	// dark module
+	if !code.Black(8, code.Size-8) {
+		t.Error("Expected the always-dark module")
+	}
+}
+
+// The golden matrices in testdata were made with the ZXing encoder, and
+// decode back to the input with its reader. '#' is a dark module.
+func TestEncode_Golden(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+	}{
+		{"hello-1M.txt", "hello"},
+		{"otpauth-6M.txt", "otpauth://totp/Go%20BBS%20System:alice?issuer=Go+BBS+System&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"},
+		{"otpauth-8M.txt", "otpauth://totp/Retro%20Computing%20Club%20BBS:a_rather_long_name?issuer=Retro+Computing+Club+BBS&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"},
+	}
+
+	for _, tt := range tests {
+		golden, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.file, err)
+		}
+		code, err := Encode(tt.data, Medium)
+		if err != nil {
+			t.Fatalf("Encode(%q) failed: %v", tt.data, err)
+		}
+
+		var b strings.Builder
+		for y := 0; y < code.Size; y++ {
+			for x := 0; x < code.Size; x++ {
+				if code.Black(x, y) {
+					b.WriteByte('#')
+				} else {
+					b.WriteByte('.')
+				}
+			}
+			b.WriteByte('\n')
+		}
+		if b.String() != string(golden) {
+			t.Errorf("%s: version %d mask %d does not match the golden matrix:\n%s", tt.file, code.Version, code.Mask, b.String())
+		}
+	}
+}
+
+func TestEncode_FormatInfoCopiesMatch(t *testing.T) {
+	code, _ := Encode("hello", Low)
+
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= bit(code.Black(8, i)) << i
+	}
+	first |= bit(code.Black(8, 7))<<6 | bit(code.Black(8, 8))<<7 | bit(code.Black(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(code.Black(14-i, 8)) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(code.Black(code.Size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(code.Black(8, code.Size-15+i)) << i
+	}
+
+	if first != second {
+		t.Fatalf("Format copies differ: %015b vs %015b", first, second)
+	}
+
+	format := (first ^ 0x5412) >> 10
+	if format>>3 != formatBits[Low] || format&7 != code.Mask {
+		t.Errorf("Format info %05b does not match level L, mask %d", format, code.Mask)
+	}
+}
+
+func TestRender(t *testing.T) {
+	code, _ := Encode("hi", Low)
+	width := code.Size + 2*QuietZone
+
+	lines := strings.Split(strings.TrimSuffix(code.HalfBlocks(), "\n"), "\n")
+	if len(lines) != (width+1)/2 {
+		t.Errorf("Expected %d half-block lines, got %d", (width+1)/2, len(lines))
+	}
+	if len([]rune(lines[0])) != width {
+		t.Errorf("Expected width %d, got %d", width, len([]rune(lines[0])))
+	}
+
+	lines = strings.Split(strings.TrimSuffix(code.ASCII(), "\n"), "\n")
+	if len(lines) != width || len(lines[0]) != 2*width {
+		t.Errorf("Unexpected ASCII dimensions %dx%d", len(lines[0]), len(lines))
+	}
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
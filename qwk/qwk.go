@@ -0,0 +1,217 @@
+// Package qwk reads and writes QWK offline mail packets. A packet is a ZIP
+// archive with CONTROL.DAT describing the BBS and its conferences,
+// MESSAGES.DAT holding the messages in 128-byte blocks, and an NDX index per
+// conference. A reader sends replies back in a REP packet: a ZIP archive
+// holding BBSID.MSG in the same block format.
+package qwk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/ansi"
+)
+
+const (
+	blockSize = 128
+	// lineEnd separates lines in a message body.
+	lineEnd = 0xE3
+	// activeFlag marks a message that has not been killed.
+	activeFlag = 0xE1
+
+	// Field sizes in a message header.
+	subjectSize = 25
+	nameSize    = 25
+)
+
+// Conference is a message area in a packet; the BBS's boards.
+type Conference struct {
+	Number int
+	Name   string
+}
+
+// Message is one message in a packet, or a reply in a REP packet. In a
+// packet, Number identifies the message for replies to refer to.
+type Message struct {
+	Number     int
+	Conference int
+	Date       time.Time
+	To         string
+	From       string
+	Subject    string
+	Reference  int
+	Body       string
+}
+
+// Packet is the contents of a QWK packet.
+type Packet struct {
+	// BBSID names the packet and its replies, up to 8 letters and digits.
+	BBSID       string
+	BBSName     string
+	Sysop       string
+	User        string
+	Created     time.Time
+	Conferences []Conference
+	Messages    []*Message
+}
+
+// Write writes the packet as a ZIP archive. The packet needs at least one
+// conference.
+func (p *Packet) Write(w io.Writer) error {
+	if len(p.Conferences) == 0 {
+		return fmt.Errorf("a packet needs a conference")
+	}
+
+	var messages bytes.Buffer
+	messages.Write(pad([]byte("Produced by gobbs"), blockSize))
+	indexes := make(map[int]*bytes.Buffer)
+	for _, msg := range p.Messages {
+		// NDX records are the block number of the header, counting from 1
+		block := messages.Len()/blockSize + 1
+		messages.Write(msg.encode())
+
+		index, ok := indexes[msg.Conference]
+		if !ok {
+			index = new(bytes.Buffer)
+			indexes[msg.Conference] = index
+		}
+		binary.Write(index, binary.LittleEndian, msbin(block))
+		index.WriteByte(byte(msg.Conference))
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"CONTROL.DAT", p.control()},
+		{"MESSAGES.DAT", messages.Bytes()},
+		{"DOOR.ID", crlf([]string{"DOOR = gobbs", "VERSION = 1.0", "SYSTEM = gobbs", "MIXEDCASE = YES"})},
+	}
+	for _, conference := range p.Conferences {
+		if index, ok := indexes[conference.Number]; ok {
+			files = append(files, struct {
+				name string
+				data []byte
+			}{fmt.Sprintf("%03d.NDX", conference.Number), index.Bytes()})
+		}
+	}
+
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: p.Created})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (p *Packet) control() []byte {
+	lines := []string{
+		p.BBSName,
+		"Internet",
+		"000-000-0000",
+		p.Sysop + ", Sysop",
+		"0," + p.BBSID,
+		p.Created.Format("01-02-2006,15:04:05"),
+		strings.ToUpper(p.User),
+		"",
+		"0",
+		strconv.Itoa(len(p.Messages)),
+		strconv.Itoa(len(p.Conferences) - 1),
+	}
+	for _, conference := range p.Conferences {
+		lines = append(lines, strconv.Itoa(conference.Number), conference.Name)
+	}
+	lines = append(lines, "HELLO", "NEWS", "GOODBYE")
+
+	for i, line := range lines {
+		lines[i] = string(encodeText(line))
+	}
+	return crlf(lines)
+}
+
+// encode returns the message's header block and body blocks.
+func (m *Message) encode() []byte {
+	body := m.Body
+	// QWKE readers take a long subject from a kludge line at the top
+	if len([]rune(m.Subject)) > subjectSize {
+		body = "Subject: " + m.Subject + "\n" + body
+	}
+	var text []byte
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		text = append(text, encodeText(line)...)
+		text = append(text, lineEnd)
+	}
+	text = pad(text, (len(text)+blockSize-1)/blockSize*blockSize)
+
+	header := make([]byte, 0, blockSize)
+	header = append(header, ' ')
+	header = append(header, field(strconv.Itoa(m.Number), 7)...)
+	header = append(header, m.Date.Format("01-02-06")...)
+	header = append(header, m.Date.Format("15:04")...)
+	header = append(header, field(m.To, nameSize)...)
+	header = append(header, field(m.From, nameSize)...)
+	header = append(header, field(m.Subject, subjectSize)...)
+	header = append(header, field("", 12)...)
+	reference := ""
+	if m.Reference != 0 {
+		reference = strconv.Itoa(m.Reference)
+	}
+	header = append(header, field(reference, 8)...)
+	header = append(header, field(strconv.Itoa(1+len(text)/blockSize), 6)...)
+	header = append(header, activeFlag)
+	header = binary.LittleEndian.AppendUint16(header, uint16(m.Conference))
+	header = binary.LittleEndian.AppendUint16(header, 0)
+	header = append(header, ' ')
+
+	return append(header, text...)
+}
+
+// field encodes text into a fixed-size field, cut or padded with spaces.
+func field(text string, size int) []byte {
+	encoded := encodeText(text)
+	if len(encoded) > size {
+		encoded = encoded[:size]
+	}
+	return pad(encoded, size)
+}
+
+func pad(data []byte, size int) []byte {
+	for len(data) < size {
+		data = append(data, ' ')
+	}
+	return data
+}
+
+// encodeText converts text to code page 437. π is the line separator there,
+// so it cannot be sent.
+func encodeText(text string) []byte {
+	return ansi.EncodeCP437(strings.NewReplacer("\r", "", "π", "?").Replace(text))
+}
+
+func crlf(lines []string) []byte {
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// msbin converts n to the Microsoft Binary Format single used by NDX files.
+func msbin(n int) uint32 {
+	if n == 0 {
+		return 0
+	}
+	bits := math.Float32bits(float32(n))
+	sign := bits >> 31
+	exponent := (bits>>23)&0xFF + 2
+	mantissa := bits & 0x7FFFFF
+	return exponent<<24 | sign<<23 | mantissa
+}
@@ -0,0 +1,208 @@
+package qwk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readPacket(t *testing.T, packet *Packet) map[string][]byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := packet.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("The packet is not a ZIP archive: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		f, _ := file.Open()
+		files[file.Name], _ = io.ReadAll(f)
+		f.Close()
+	}
+	return files
+}
+
+func testPacket() *Packet {
+	date := time.Date(2026, 3, 14, 15, 9, 26, 0, time.Local)
+	return &Packet{
+		BBSID:   "GOBBS",
+		BBSName: "Go BBS System",
+		Sysop:   "Sysop",
+		User:    "alice",
+		Created: date,
+		Conferences: []Conference{
+			{Number: 1, Name: "general"},
+			{Number: 2, Name: "tech"},
+			{Number: 3, Name: "random"},
+		},
+		Messages: []*Message{
+			{Number: 7, Conference: 1, Date: date, To: "All", From: "bob", Subject: "Hello", Body: "First line\nCafé π"},
+			{Number: 9, Conference: 2, Date: date, To: "All", From: "carol", Subject: "Compilers", Body: strings.Repeat("x", 200)},
+			{Number: 12, Conference: 1, Date: date, To: "bob", From: "carol", Subject: "Re: " + strings.Repeat("long ", 10),
+				Reference: 7, Body: "A reply"},
+		},
+	}
+}
+
+func TestPacket_Write(t *testing.T) {
+	files := readPacket(t, testPacket())
+
+	control := strings.Split(string(files["CONTROL.DAT"]), "\r\n")
+	want := []string{
+		"Go BBS System", "Internet", "000-000-0000", "Sysop, Sysop", "0,GOBBS", "03-14-2026,15:09:26", "ALICE", "", "0",
+		"3", "2", "1", "general", "2", "tech", "3", "random", "HELLO", "NEWS", "GOODBYE", "",
+	}
+	if strings.Join(control, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected CONTROL.DAT:\n%q", control)
+	}
+
+	messages := files["MESSAGES.DAT"]
+	if len(messages)%blockSize != 0 || !strings.HasPrefix(string(messages), "Produced by gobbs") {
+		t.Fatalf("Unexpected MESSAGES.DAT of %d bytes", len(messages))
+	}
+
+	header := messages[blockSize : 2*blockSize]
+	if got := string(header[:46]); got != " 7      03-14-2615:09All                      " {
+		t.Errorf("Unexpected header start %q", got)
+	}
+	if from, subject := string(header[46:71]), string(header[71:96]); from != "bob"+strings.Repeat(" ", 22) ||
+		subject != "Hello                    " {
+		t.Errorf("Unexpected from %q or subject %q", from, subject)
+	}
+	if blocks := string(header[116:122]); blocks != "2     " {
+		t.Errorf("Expected the message to take 2 blocks, got %q", blocks)
+	}
+	if header[122] != activeFlag || binary.LittleEndian.Uint16(header[123:125]) != 1 {
+		t.Errorf("Unexpected flag %x or conference", header[122])
+	}
+	body := string(bytes.TrimRight(messages[2*blockSize:3*blockSize], " "))
+	if body != "First line\xe3Caf\x82 ?\xe3" {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	// the long body takes two blocks, after which comes the reply
+	reply := messages[6*blockSize : 7*blockSize]
+	if string(reply[1:8]) != "12     " || string(reply[108:116]) != "7       " {
+		t.Errorf("Unexpected reply header %q", reply)
+	}
+	if body := string(messages[7*blockSize : 8*blockSize]); !strings.HasPrefix(body, "Subject: Re: long long") {
+		t.Errorf("Expected the long subject in a kludge line, got %q", body)
+	}
+
+	// the messages start at blocks 2 and 7 of the file, counting from 1
+	if ndx := files["001.NDX"]; !bytes.Equal(ndx, []byte{0, 0, 0, 0x82, 1, 0, 0, 0x60, 0x83, 1}) {
+		t.Errorf("Unexpected 001.NDX % x", ndx)
+	}
+	if _, ok := files["002.NDX"]; !ok {
+		t.Error("Expected an index for conference 2")
+	}
+	if _, ok := files["003.NDX"]; ok {
+		t.Error("Expected no index for a conference without messages")
+	}
+	if !strings.Contains(string(files["DOOR.ID"]), "MIXEDCASE = YES") {
+		t.Errorf("Unexpected DOOR.ID %q", files["DOOR.ID"])
+	}
+
+	if err := (&Packet{BBSID: "GOBBS"}).Write(io.Discard); err == nil {
+		t.Error("Expected a packet without conferences to fail")
+	}
+}
+
+// replyPacket makes a BBSID.MSG file the way readers do: the conference is
+// where a packet has the message number.
+func replyPacket(bbsID string, replies ...*Message) []byte {
+	data := pad([]byte(bbsID), blockSize)
+	for _, reply := range replies {
+		msg := *reply
+		msg.Number = reply.Conference
+		data = append(data, msg.encode()...)
+	}
+	return data
+}
+
+func TestReadReplies(t *testing.T) {
+	date := time.Date(2026, 3, 14, 15, 9, 0, 0, time.Local)
+	killed := &Message{Conference: 1, Subject: "Oops", Body: "Never mind"}
+	data := replyPacket("GOBBS",
+		&Message{Conference: 2, Date: date, To: "All", From: "ALICE", Subject: "New thread", Body: "Line one\nLine twö\n\n"},
+		&Message{Conference: 1, Date: date, To: "BOB", From: "ALICE", Subject: "Re: Hello", Reference: 7, Body: "Agreed"},
+		killed,
+		&Message{Conference: 1, Subject: "Short", Body: "Subject: A much longer subject than fits in the header\nTo: bob\n\nText"},
+	)
+	// kill the third message
+	data[blockSize*5+122] = 0xE2
+	// make the fourth private
+	data[blockSize*7] = '*'
+
+	replies, err := ReadReplies(data, "gobbs")
+	if err != nil {
+		t.Fatalf("ReadReplies failed: %v", err)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("Expected 3 replies, got %d", len(replies))
+	}
+
+	first := replies[0]
+	if first.Conference != 2 || first.Subject != "New thread" || first.From != "ALICE" || !first.Date.Equal(date) ||
+		first.Body != "Line one\nLine twö" || first.Reference != 0 || first.Private {
+		t.Errorf("Unexpected first reply %+v", first)
+	}
+	if second := replies[1]; second.Conference != 1 || second.Reference != 7 || second.To != "BOB" || second.Body != "Agreed" {
+		t.Errorf("Unexpected second reply %+v", second)
+	}
+	if last := replies[2]; last.Subject != "A much longer subject than fits in the header" || last.To != "bob" ||
+		last.Body != "Text" || !last.Private {
+		t.Errorf("Unexpected last reply %+v", last)
+	}
+
+	// the same in a ZIP archive, as readers send it
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, _ := zw.Create("gobbs.msg")
+	f.Write(data)
+	zw.Close()
+	if zipped, err := ReadReplies(archive.Bytes(), "GOBBS"); err != nil || len(zipped) != 3 {
+		t.Errorf("Expected 3 replies from the archive, got %d: %v", len(zipped), err)
+	}
+
+	if _, err := ReadReplies(data, "OTHER"); err == nil || !strings.Contains(err.Error(), "not OTHER") {
+		t.Errorf("Expected a packet for another BBS to fail, got %v", err)
+	}
+	if _, err := ReadReplies(data[:blockSize*4], "GOBBS"); err == nil {
+		t.Error("Expected a cut off packet to fail")
+	}
+	if _, err := ReadReplies(nil, "GOBBS"); err == nil {
+		t.Error("Expected an empty packet to fail")
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	if got := string(encodeText("π\r")); got != "?" {
+		t.Errorf("Expected π to be left out, got %q", got)
+	}
+}
+
+func TestMSBIN(t *testing.T) {
+	for _, test := range []struct {
+		n    int
+		want uint32
+	}{
+		{0, 0},
+		{1, 0x81000000},
+		{2, 0x82000000},
+		{7, 0x83600000},
+		{100, 0x87480000},
+	} {
+		if got := msbin(test.n); got != test.want {
+			t.Errorf("msbin(%d) = %08x, want %08x", test.n, got, test.want)
+		}
+	}
+}
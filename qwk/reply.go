@@ -0,0 +1,134 @@
+package qwk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/ansi"
+)
+
+// MaxReplySize bounds a REP packet, and the messages file unpacked from it.
+const MaxReplySize = 1 << 20
+
+// Reply is a message from a REP packet. Conference and Reference are as the
+// reader sent them.
+type Reply struct {
+	Message
+	// Private is set for messages only the addressee should read.
+	Private bool
+}
+
+// ReadReplies returns the messages in a REP packet for the BBS bbsID. data
+// may be the ZIP archive or the BBSID.MSG file on its own.
+func ReadReplies(data []byte, bbsID string) ([]*Reply, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		if data, err = unzipMessages(data, bbsID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) < blockSize {
+		return nil, fmt.Errorf("the reply packet is empty")
+	}
+	if id := strings.TrimSpace(string(data[:8])); !strings.EqualFold(id, bbsID) {
+		return nil, fmt.Errorf("the reply packet is for %q, not %s", id, bbsID)
+	}
+
+	var replies []*Reply
+	for offset := blockSize; offset+blockSize <= len(data); {
+		header := data[offset : offset+blockSize]
+		blocks, err := strconv.Atoi(strings.TrimSpace(string(header[116:122])))
+		if err != nil || blocks < 1 || offset+blocks*blockSize > len(data) {
+			return nil, fmt.Errorf("message %d in the reply packet is damaged", len(replies)+1)
+		}
+		body := data[offset+blockSize : offset+blocks*blockSize]
+		offset += blocks * blockSize
+
+		if header[122] != activeFlag {
+			// killed in the reader
+			continue
+		}
+		replies = append(replies, parseReply(header, body))
+	}
+	return replies, nil
+}
+
+func unzipMessages(data []byte, bbsID string) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid reply packet: %v", err)
+	}
+	for _, file := range archive.File {
+		if !strings.EqualFold(file.Name, bbsID+".MSG") {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply packet: %v", err)
+		}
+		defer f.Close()
+		messages, err := io.ReadAll(io.LimitReader(f, MaxReplySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply packet: %v", err)
+		}
+		if len(messages) > MaxReplySize {
+			return nil, fmt.Errorf("the reply packet is too large")
+		}
+		return messages, nil
+	}
+	return nil, fmt.Errorf("the reply packet has no %s.MSG", bbsID)
+}
+
+func parseReply(header, body []byte) *Reply {
+	text := func(from, to int) string {
+		return strings.TrimSpace(ansi.DecodeCP437(bytes.TrimRight(header[from:to], "\x00")))
+	}
+
+	reply := &Reply{Private: header[0] != ' ' && header[0] != '-'}
+	// a REP packet has the conference where a packet has the message number
+	if conference, err := strconv.Atoi(text(1, 8)); err == nil {
+		reply.Conference = conference
+	} else {
+		reply.Conference = int(binary.LittleEndian.Uint16(header[123:125]))
+	}
+	reply.Date, _ = time.ParseInLocation("01-02-0615:04", text(8, 16)+text(16, 21), time.Local)
+	reply.To = text(21, 46)
+	reply.From = text(46, 71)
+	reply.Subject = text(71, 96)
+	reply.Reference, _ = strconv.Atoi(text(108, 116))
+
+	body = bytes.TrimRight(body, " \x00")
+	lines := strings.Split(strings.ReplaceAll(ansi.DecodeCP437(bytes.ReplaceAll(body, []byte{lineEnd}, []byte("\n"))), "\r\n", "\n"), "\n")
+
+	// QWKE readers put long header values in kludge lines at the top
+	for len(lines) > 0 {
+		name, value, ok := strings.Cut(lines[0], ":")
+		if !ok {
+			break
+		}
+		switch strings.ToLower(name) {
+		case "subject":
+			reply.Subject = strings.TrimSpace(value)
+		case "to":
+			reply.To = strings.TrimSpace(value)
+		case "from":
+			reply.From = strings.TrimSpace(value)
+		default:
+			ok = false
+		}
+		if !ok {
+			break
+		}
+		lines = lines[1:]
+	}
+
+	reply.Body = strings.TrimLeft(strings.TrimRight(strings.Join(lines, "\n"), " \n"), "\n")
+	return reply
+}
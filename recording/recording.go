@@ -0,0 +1,265 @@
+// Package recording saves sessions in asciicast v2 format, the format
+// played by asciinema, for debugging the UI and as evidence for moderation.
+// Recordings live in a directory with the start time, node and username in
+// their names, and old ones are pruned by Run.
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// Recorder records one session. A nil *Recorder records nothing, so callers
+// need not check whether recording is on.
+type Recorder struct {
+	store      *Store
+	node       int
+	remoteAddr string
+
+	mu        sync.Mutex
+	userID    int
+	username  string
+	width     int
+	height    int
+	termType  string
+	file      *os.File
+	path      string
+	start     time.Time
+	hideInput bool
+	// output and input hold the start of a UTF-8 sequence split across
+	// writes, since asciicast events are strings.
+	output []byte
+	input  []byte
+}
+
+// Wrap returns rw with everything written to it recorded as output, and
+// everything read from it recorded as input if the store records input.
+func (r *Recorder) Wrap(rw io.ReadWriter) io.ReadWriter {
+	if r == nil {
+		return rw
+	}
+	return &recordingReadWriter{ReadWriter: rw, recorder: r}
+}
+
+type recordingReadWriter struct {
+	io.ReadWriter
+	recorder *Recorder
+}
+
+func (w *recordingReadWriter) Read(p []byte) (int, error) {
+	n, err := w.ReadWriter.Read(p)
+	if n > 0 && w.recorder.store.input {
+		w.recorder.event("i", p[:n])
+	}
+	return n, err
+}
+
+func (w *recordingReadWriter) Write(p []byte) (int, error) {
+	n, err := w.ReadWriter.Write(p)
+	if n > 0 {
+		w.recorder.event("o", p[:n])
+	}
+	return n, err
+}
+
+type header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Start creates the recording file once the terminal size is known. Nothing
+// is recorded before Start.
+func (r *Recorder) Start(width, height int, termType string) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.width, r.height, r.termType = width, height, termType
+	return r.create()
+}
+
+// create starts a recording file for r.username from now on.
+func (r *Recorder) create() error {
+	if err := os.MkdirAll(r.store.dir, 0700); err != nil {
+		return err
+	}
+
+	r.start = time.Now()
+	r.path = filepath.Join(r.store.dir, fileName(r.start, r.node, r.username))
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	data, _ := json.Marshal(header{
+		Version:   2,
+		Width:     r.width,
+		Height:    r.height,
+		Timestamp: r.start.Unix(),
+		Title:     fmt.Sprintf("%s on node %d from %s", r.username, r.node, r.remoteAddr),
+		Env:       map[string]string{"TERM": r.termType},
+	})
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		os.Remove(r.path)
+		return err
+	}
+
+	r.file = file
+	r.output, r.input = nil, nil
+	return nil
+}
+
+func (r *Recorder) event(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// while input is hidden, so is the output, which echoes what is typed
+	if r.file == nil || r.hideInput {
+		return
+	}
+
+	pending := &r.output
+	if kind == "i" {
+		pending = &r.input
+	}
+	data := append(*pending, p...)
+	data, *pending = splitUTF8(data)
+	if len(data) == 0 {
+		return
+	}
+
+	line, _ := json.Marshal([]interface{}{time.Since(r.start).Seconds(), kind, string(data)})
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write recording %s, stopping it: %v", r.path, err)
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+// splitUTF8 splits off an incomplete UTF-8 sequence at the end of p.
+func splitUTF8(p []byte) ([]byte, []byte) {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return p[:i], append([]byte(nil), p[i:]...)
+			}
+			break
+		}
+	}
+	return p, nil
+}
+
+// Login notes that user logged in partway through the session. A recording
+// made before anyone logged in is renamed after them, or deleted if they
+// opted out of being recorded. Each recording is of one user, so when
+// someone else logs in the recording is closed and the rest of the session
+// goes in a new one.
+func (r *Recorder) Login(user *domain.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.start.IsZero() || user.ID == r.userID {
+		return
+	}
+	previous := r.userID
+	r.userID = user.ID
+	r.username = user.Username
+
+	if previous == 0 && r.file != nil {
+		if user.RecordingOptOut {
+			r.file.Close()
+			r.file = nil
+			if err := os.Remove(r.path); err != nil {
+				log.Printf("Failed to delete recording %s: %v", r.path, err)
+			}
+			return
+		}
+
+		path := filepath.Join(r.store.dir, fileName(r.start, r.node, r.username))
+		if err := os.Rename(r.path, path); err != nil {
+			log.Printf("Failed to rename recording %s: %v", r.path, err)
+			return
+		}
+		r.path = path
+		return
+	}
+
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	if user.RecordingOptOut {
+		return
+	}
+	if err := r.create(); err != nil {
+		log.Printf("Failed to start recording node %d for %s: %v", r.node, r.username, err)
+	}
+}
+
+// HideInput stops recording while hide is true, output as well as input,
+// since the terminal echoes what is typed.
+func (r *Recorder) HideInput(hide bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hideInput = hide
+}
+
+// Close ends the recording.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+const (
+	// fileTimeFormat has milliseconds so that a caller who hangs up and
+	// gets the same node back within a second does not lose the recording.
+	// Names made before it had them are still read with parseTimeFormat,
+	// which takes the milliseconds too.
+	fileTimeFormat  = "20060102-150405.000"
+	parseTimeFormat = "20060102-150405"
+	fileExt         = ".cast"
+)
+
+func fileName(start time.Time, node int, username string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return -1
+	}, username)
+	if safe == "" {
+		safe = "unknown"
+	}
+	return fmt.Sprintf("%s-n%d-%s%s", start.Format(fileTimeFormat), node, safe, fileExt)
+}
@@ -0,0 +1,242 @@
+package recording
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+)
+
+type fakeConn struct {
+	in  *strings.Reader
+	out bytes.Buffer
+}
+
+func (c *fakeConn) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *fakeConn) Write(p []byte) (int, error) { return c.out.Write(p) }
+
+func newStore(t *testing.T, input bool) *Store {
+	cfg := config.Default()
+	cfg.RecordingDir = t.TempDir()
+	cfg.RecordInput = input
+	return NewStore(cfg)
+}
+
+func newSession(user *domain.User) *domain.Session {
+	session := &domain.Session{Node: 2, RemoteAddr: "192.0.2.1"}
+	session.SetUser(user)
+	return session
+}
+
+func TestRecorder(t *testing.T) {
+	store := newStore(t, true)
+	recorder := store.NewRecorder(newSession(&domain.User{ID: 1, Username: "dave"}))
+
+	conn := &fakeConn{in: strings.NewReader("abc")}
+	rw := recorder.Wrap(conn)
+	rw.Write([]byte("before start"))
+
+	if err := recorder.Start(100, 30, "xterm"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// "é" split across two writes
+	rw.Write([]byte("h\xc3"))
+	rw.Write([]byte("\xa9llo"))
+	rw.Read(make([]byte, 3))
+
+	recorder.HideInput(true)
+	conn.in = strings.NewReader("123456")
+	rw.Read(make([]byte, 6))
+	rw.Write([]byte("123456")) // the echo
+	recorder.HideInput(false)
+	rw.Write([]byte("!"))
+	recorder.Close()
+
+	if conn.out.String() != "before starthéllo123456!" {
+		t.Errorf("Expected output to pass through, got %q", conn.out.String())
+	}
+
+	infos, _ := store.List()
+	if len(infos) != 1 || infos[0].Username != "dave" || infos[0].Node != 2 {
+		t.Fatalf("Expected one recording of dave on node 2, got %d", len(infos))
+	}
+
+	cast, err := store.Load(infos[0].Name)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cast.Width != 100 || cast.Height != 30 || !strings.Contains(cast.Title, "dave") {
+		t.Errorf("Expected a 100x30 recording titled for dave, got %dx%d %q", cast.Width, cast.Height, cast.Title)
+	}
+
+	var got []string
+	for _, event := range cast.Events {
+		got = append(got, event.Kind+":"+event.Data)
+	}
+	if strings.Join(got, ",") != "o:h,o:éllo,i:abc,o:!" {
+		t.Errorf("Expected output and input without the code or its echo, got %v", got)
+	}
+}
+
+func TestRecorder_OptOut(t *testing.T) {
+	store := newStore(t, false)
+
+	if store.NewRecorder(newSession(&domain.User{ID: 1, Username: "dave", RecordingOptOut: true})) != nil {
+		t.Error("Expected no recorder for a user who opted out")
+	}
+
+	if NewStore(config.Default()).NewRecorder(newSession(nil)) != nil {
+		t.Error("Expected no recorder without a recording directory")
+	}
+
+	// a user who opts out and logs in from the login menu
+	recorder := store.NewRecorder(newSession(nil))
+	recorder.Start(80, 24, "xterm")
+	recorder.Login(&domain.User{ID: 1, Username: "dave", RecordingOptOut: true})
+	recorder.Wrap(&fakeConn{}).Write([]byte("hello"))
+
+	if infos, _ := store.List(); len(infos) != 0 {
+		t.Errorf("Expected the recording to be deleted, got %d", len(infos))
+	}
+}
+
+func TestRecorder_Login(t *testing.T) {
+	store := newStore(t, false)
+	recorder := store.NewRecorder(newSession(nil))
+	rw := recorder.Wrap(&fakeConn{})
+	recorder.Start(80, 24, "xterm")
+	recorder.Login(&domain.User{ID: 1, Username: "erin"})
+	rw.Write([]byte("erin's menu"))
+
+	infos, _ := store.List()
+	if len(infos) != 1 || infos[0].Username != "erin" {
+		t.Fatalf("Expected the recording to be renamed for erin, got %v", infos)
+	}
+
+	// erin logs out, and frank logs in on the same connection
+	recorder.Login(&domain.User{ID: 1, Username: "erin"})
+	recorder.Login(&domain.User{ID: 2, Username: "frank"})
+	rw.Write([]byte("frank's menu"))
+	// and then someone who opted out
+	recorder.Login(&domain.User{ID: 3, Username: "gus", RecordingOptOut: true})
+	rw.Write([]byte("gus's menu"))
+	recorder.Close()
+
+	infos, _ = store.List()
+	recorded := make(map[string]string)
+	for _, info := range infos {
+		cast, err := store.Load(info.Name)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		var output string
+		for _, event := range cast.Events {
+			output += event.Data
+		}
+		recorded[info.Username] = output
+	}
+	if len(recorded) != 2 || recorded["erin"] != "erin's menu" || recorded["frank"] != "frank's menu" {
+		t.Errorf("Expected a recording each for erin and frank and none for gus, got %v", recorded)
+	}
+}
+
+func TestRecorder_SameSecond(t *testing.T) {
+	store := newStore(t, false)
+	for i := 0; i < 2; i++ {
+		recorder := store.NewRecorder(newSession(&domain.User{ID: 1, Username: "dave"}))
+		if err := recorder.Start(80, 24, "xterm"); err != nil {
+			t.Fatalf("Expected a reconnect on the same node to be recorded too: %v", err)
+		}
+		recorder.Close()
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	infos, _ := store.List()
+	if len(infos) != 2 {
+		t.Errorf("Expected 2 recordings, got %d", len(infos))
+	}
+	if info, ok := parseName("20260101-000000-n1-old.cast"); !ok || info.Username != "old" {
+		t.Error("Expected names without milliseconds to still be read")
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	store := newStore(t, false)
+	store.maxAge = 24 * time.Hour
+	store.maxBytes = 150
+
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(store.dir, name)
+		os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0600)
+		os.Chtimes(path, now.Add(-age), now.Add(-age))
+	}
+	write("20260101-000000-n1-old.cast", 10, 48*time.Hour)
+	write("20260102-000000-n1-big.cast", 100, 2*time.Hour)
+	write("20260103-000000-n1-new.cast", 100, time.Hour)
+	write("notes.txt", 10, 48*time.Hour)
+
+	if err := store.Prune(now); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	infos, _ := store.List()
+	if len(infos) != 1 || infos[0].Username != "new" {
+		t.Errorf("Expected only the newest recording to be kept, got %d", len(infos))
+	}
+
+	if _, err := os.Stat(filepath.Join(store.dir, "notes.txt")); err != nil {
+		t.Error("Prune should leave other files alone")
+	}
+}
+
+func TestStore_InvalidName(t *testing.T) {
+	store := newStore(t, false)
+
+	for _, name := range []string{"../bbs.db", "notes.txt", "../20260101-000000-n1-x.cast"} {
+		if _, err := store.Load(name); err == nil {
+			t.Errorf("Expected Load(%q) to be refused", name)
+		}
+		if err := store.Delete(name); err == nil {
+			t.Errorf("Expected Delete(%q) to be refused", name)
+		}
+	}
+}
+
+func TestCast_Play(t *testing.T) {
+	cast, err := Read(strings.NewReader(`{"version": 2, "width": 80, "height": 24}
+[0.5, "o", "hello "]
+[0.6, "i", "x"]
+[600, "o", "world"]
+`))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	start := time.Now()
+	if !cast.Play(&out, 10*time.Millisecond, nil) {
+		t.Error("Expected Play to finish")
+	}
+
+	if out.String() != "hello world" {
+		t.Errorf("Expected only output to be played, got %q", out.String())
+	}
+
+	if time.Since(start) > time.Second {
+		t.Error("Expected long pauses to be shortened")
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if cast.Play(&out, time.Second, stop) {
+		t.Error("Expected Play to stop early")
+	}
+}
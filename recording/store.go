@@ -0,0 +1,321 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+)
+
+// pruneInterval is how often Run deletes recordings past retention.
+const pruneInterval = time.Hour
+
+// Store is the directory recordings are kept in.
+type Store struct {
+	dir      string
+	input    bool
+	maxAge   time.Duration
+	maxBytes int64
+}
+
+// NewStore returns the store configured in cfg. Recording is off unless
+// recording_dir is set.
+func NewStore(cfg *config.Config) *Store {
+	return &Store{
+		dir:      cfg.RecordingDir,
+		input:    cfg.RecordInput,
+		maxAge:   time.Duration(cfg.RecordingRetentionDays) * 24 * time.Hour,
+		maxBytes: int64(cfg.RecordingMaxMB) << 20,
+	}
+}
+
+func (s *Store) Enabled() bool {
+	return s.dir != ""
+}
+
+// NewRecorder returns a recorder for session, or nil if recording is off or
+// the session's user opted out.
+func (s *Store) NewRecorder(session *domain.Session) *Recorder {
+	if !s.Enabled() {
+		return nil
+	}
+
+	recorder := &Recorder{
+		store:      s,
+		node:       session.Node,
+		username:   "anonymous",
+		remoteAddr: session.RemoteAddr,
+	}
+	if user := session.CurrentUser(); user != nil {
+		if user.RecordingOptOut {
+			return nil
+		}
+		recorder.userID = user.ID
+		recorder.username = user.Username
+	}
+	return recorder
+}
+
+// Info describes a recording in the store.
+type Info struct {
+	Name      string
+	Username  string
+	Node      int
+	StartedAt time.Time
+	Duration  time.Duration // until the last write
+	Size      int64
+	modTime   time.Time
+}
+
+// List returns the recordings in the store, newest first.
+func (s *Store) List() ([]*Info, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var infos []*Info
+	for _, entry := range entries {
+		info, ok := parseName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		info.Size = stat.Size()
+		info.modTime = stat.ModTime()
+		info.Duration = info.modTime.Sub(info.StartedAt).Truncate(time.Second)
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.After(infos[j].StartedAt)
+	})
+	return infos, nil
+}
+
+// parseName reads the start time, node and username back out of a name
+// made by fileName.
+func parseName(name string) (*Info, bool) {
+	base, ok := strings.CutSuffix(name, fileExt)
+	if !ok {
+		return nil, false
+	}
+
+	parts := strings.SplitN(base, "-", 4)
+	if len(parts) != 4 || !strings.HasPrefix(parts[2], "n") {
+		return nil, false
+	}
+
+	start, err := time.ParseInLocation(parseTimeFormat, parts[0]+"-"+parts[1], time.Local)
+	if err != nil {
+		return nil, false
+	}
+	node, err := strconv.Atoi(parts[2][1:])
+	if err != nil {
+		return nil, false
+	}
+
+	return &Info{Name: name, Username: parts[3], Node: node, StartedAt: start}, true
+}
+
+// path returns where the recording called name is, refusing names that
+// are not recordings in the store.
+func (s *Store) path(name string) (string, error) {
+	if _, ok := parseName(name); !ok || filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid recording name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *Store) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Cast is a recording read back from the store.
+type Cast struct {
+	Width  int
+	Height int
+	Title  string
+	Events []Event
+}
+
+type Event struct {
+	Time float64 // seconds since the start
+	Kind string  // "o" for output, "i" for input
+	Data string
+}
+
+// maxLine bounds one event in a recording.
+const maxLine = 1 << 20
+
+// Load reads the recording called name.
+func (s *Store) Load(name string) (*Cast, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read parses an asciicast v2 recording. Events it does not understand are
+// skipped.
+func Read(r io.Reader) (*Cast, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("empty recording")
+	}
+
+	var h header
+	if err := json.Unmarshal(scanner.Bytes(), &h); err != nil {
+		return nil, fmt.Errorf("bad recording header: %v", err)
+	}
+	if h.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d", h.Version)
+	}
+
+	cast := &Cast{Width: h.Width, Height: h.Height, Title: h.Title}
+	for scanner.Scan() {
+		var fields []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil || len(fields) != 3 {
+			continue
+		}
+		t, ok1 := fields[0].(float64)
+		kind, ok2 := fields[1].(string)
+		data, ok3 := fields[2].(string)
+		if ok1 && ok2 && ok3 {
+			cast.Events = append(cast.Events, Event{Time: t, Kind: kind, Data: data})
+		}
+	}
+
+	// a recording cut short by a crash is still worth playing
+	if err := scanner.Err(); err != nil && len(cast.Events) == 0 {
+		return nil, err
+	}
+	return cast, nil
+}
+
+// Play writes the recording's output to w in real time, but never pauses
+// longer than maxIdle. It stops early when stop is closed, and reports
+// whether it played to the end.
+func (c *Cast) Play(w io.Writer, maxIdle time.Duration, stop <-chan struct{}) bool {
+	last := 0.0
+	for _, event := range c.Events {
+		if event.Kind != "o" {
+			continue
+		}
+
+		delay := time.Duration((event.Time - last) * float64(time.Second))
+		last = event.Time
+		if delay > maxIdle {
+			delay = maxIdle
+		}
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-stop:
+				timer.Stop()
+				return false
+			case <-timer.C:
+			}
+		} else {
+			select {
+			case <-stop:
+				return false
+			default:
+			}
+		}
+
+		if _, err := io.WriteString(w, event.Data); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Prune deletes recordings older than the retention period, then the
+// oldest ones until the store is under its size limit.
+func (s *Store) Prune(now time.Time) error {
+	infos, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].modTime.Before(infos[j].modTime)
+	})
+
+	var total int64
+	for _, info := range infos {
+		total += info.Size
+	}
+
+	for _, info := range infos {
+		expired := s.maxAge > 0 && now.Sub(info.modTime) > s.maxAge
+		full := s.maxBytes > 0 && total > s.maxBytes
+		if !expired && !full {
+			continue
+		}
+
+		if err := s.Delete(info.Name); err != nil {
+			return err
+		}
+		total -= info.Size
+	}
+	return nil
+}
+
+// Run prunes the store every pruneInterval until stop is closed.
+func (s *Store) Run(stop <-chan struct{}) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Prune(time.Now()); err != nil {
+			log.Printf("Failed to prune recordings: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestAPITokenRepository(t *testing.T) {
+	repo := mocks.NewAPITokenRepository()
+	apiToken, token, _ := domain.NewAPIToken(1, "script")
+
+	if err := repo.Create(apiToken); err != nil {
+		t.Errorf("Create should not return error: %v", err)
+	}
+
+	found, err := repo.GetByToken(token)
+	if err != nil || found.ID != apiToken.ID {
+		t.Errorf("GetByToken should find the token: %v", err)
+	}
+
+	if _, err := repo.GetByToken("bbs_wrong"); err == nil {
+		t.Error("GetByToken should fail for an unknown token")
+	}
+
+	usedAt := time.Now()
+	repo.MarkUsed(apiToken.ID, usedAt)
+	tokens, _ := repo.GetByUser(1)
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Errorf("Expected the token to be listed as used, got %d tokens", len(tokens))
+	}
+
+	if err := repo.Delete(2, apiToken.ID); err == nil {
+		t.Error("Delete should not remove another user's token")
+	}
+
+	if err := repo.Delete(1, apiToken.ID); err != nil {
+		t.Errorf("Delete should not return error: %v", err)
+	}
+
+	if _, err := repo.GetByToken(token); err == nil {
+		t.Error("GetByToken should fail after the token is deleted")
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestAuditRepository_GetRecent(t *testing.T) {
+	repo := mocks.NewAuditRepository()
+	sysop := &domain.User{ID: 1, Username: "carol"}
+
+	for _, action := range []string{domain.AuditSessionSnoop, domain.AuditSessionMessage, domain.AuditSessionLogout} {
+		if err := repo.Create(domain.NewAuditEntry(sysop, action, "node 2 (dave)", "192.0.2.1", nil)); err != nil {
+			t.Errorf("Create should not return error: %v", err)
+		}
+	}
+
+	entries, _ := repo.GetRecent(2)
+	if len(entries) != 2 || entries[0].Action != domain.AuditSessionLogout {
+		t.Errorf("Expected the 2 newest entries, got %d", len(entries))
+	}
+}
+
+func TestAuditRepository_Find(t *testing.T) {
+	repo := mocks.NewAuditRepository()
+	sysop := &domain.User{ID: 1, Username: "carol"}
+
+	repo.Create(domain.NewAuditEntry(nil, domain.AuditLoginFailed, "dave", "192.0.2.2", nil))
+	repo.Create(domain.NewAuditEntry(sysop, domain.AuditLogin, "carol", "192.0.2.1", nil))
+	repo.Create(domain.NewAuditEntry(sysop, domain.AuditUserBan, "dave", "192.0.2.1", nil))
+
+	entries, _ := repo.Find(domain.AuditFilter{Action: "auth"}, 10, 0)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 auth entries, got %d", len(entries))
+	}
+
+	entries, _ = repo.Find(domain.AuditFilter{Target: "dave"}, 1, 1)
+	if len(entries) != 1 || entries[0].Action != domain.AuditLoginFailed {
+		t.Errorf("Expected the second page to hold the failed login, got %d", len(entries))
+	}
+
+	var exported []string
+	repo.Export(domain.AuditFilter{Actor: "CAROL"}, func(entry *domain.AuditEntry) error {
+		exported = append(exported, entry.Action)
+		return nil
+	})
+	if len(exported) != 2 || exported[0] != domain.AuditLogin {
+		t.Errorf("Expected carol's 2 entries oldest first, got %v", exported)
+	}
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestEchomailRepository(t *testing.T) {
+	repo := mocks.NewEchomailRepository()
+
+	msg := &domain.EchomailMessage{MSGID: "21:1/1 0000abcd", PostID: 7, Area: "GENERAL", Imported: true, CreatedAt: time.Now()}
+	if err := repo.Add(msg); err != nil {
+		t.Fatalf("Add should not return error: %v", err)
+	}
+	if err := repo.Add(msg); err == nil {
+		t.Error("Expected a MSGID to be added only once")
+	}
+
+	if found, err := repo.GetByMSGID(msg.MSGID); err != nil || found.PostID != 7 || !found.Imported {
+		t.Errorf("Expected the message by MSGID, got %+v: %v", found, err)
+	}
+	if found, err := repo.GetByPost(7); err != nil || found.MSGID != msg.MSGID {
+		t.Errorf("Expected the message by post, got %+v: %v", found, err)
+	}
+	if _, err := repo.GetByPost(8); err == nil {
+		t.Error("Expected no message for another post")
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestEmailVerificationRepository_CreateAndGet(t *testing.T) {
+	repo := mocks.NewEmailVerificationRepository()
+
+	first, _, _ := domain.NewEmailVerification(1, "user@example.com")
+	repo.Create(first)
+	second, _, _ := domain.NewEmailVerification(1, "user@example.com")
+	if err := repo.Create(second); err != nil {
+		t.Errorf("Create should not return error: %v", err)
+	}
+
+	found, err := repo.GetByUser(1)
+	if err != nil {
+		t.Fatalf("GetByUser should not return error: %v", err)
+	}
+
+	if found.ID != second.ID {
+		t.Error("Create should replace the user's earlier verification")
+	}
+
+	if err := repo.IncrementAttempts(second.ID); err != nil {
+		t.Errorf("IncrementAttempts should not return error: %v", err)
+	}
+
+	found, _ = repo.GetByUser(1)
+	if found.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", found.Attempts)
+	}
+
+	repo.DeleteForUser(1)
+	if _, err := repo.GetByUser(1); err == nil {
+		t.Error("GetByUser should return error after DeleteForUser")
+	}
+}
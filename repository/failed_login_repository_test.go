@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestFailedLoginRepository_Filters(t *testing.T) {
+	repo := mocks.NewFailedLoginRepository()
+	repo.Create(domain.NewFailedLogin("alice", "192.0.2.1", domain.FailedLoginPassword))
+	repo.Create(domain.NewFailedLogin("bob", "192.0.2.2", domain.FailedLoginPassword))
+	repo.Create(domain.NewFailedLogin("Alice", "192.0.2.2", domain.FailedLoginCode))
+
+	attempts, err := repo.GetRecent(2)
+	if err != nil {
+		t.Errorf("GetRecent should not return error: %v", err)
+	}
+
+	if len(attempts) != 2 {
+		t.Errorf("Expected 2 recent attempts, got %d", len(attempts))
+	}
+
+	if attempts[0].Reason != domain.FailedLoginCode {
+		t.Error("GetRecent should return newest attempts first")
+	}
+
+	attempts, _ = repo.GetByUsername("ALICE", 10)
+	if len(attempts) != 2 {
+		t.Errorf("Expected 2 attempts for alice, got %d", len(attempts))
+	}
+
+	attempts, _ = repo.GetByRemoteAddr("192.0.2.2", 10)
+	if len(attempts) != 2 {
+		t.Errorf("Expected 2 attempts from 192.0.2.2, got %d", len(attempts))
+	}
+}
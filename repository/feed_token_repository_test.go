@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestFeedTokenRepository(t *testing.T) {
+	repo := mocks.NewFeedTokenRepository()
+	feedToken, token, _ := domain.NewFeedToken(1)
+
+	if err := repo.Set(feedToken); err != nil {
+		t.Errorf("Set should not return error: %v", err)
+	}
+
+	found, err := repo.GetByToken(token)
+	if err != nil || found.UserID != 1 {
+		t.Errorf("GetByToken should find the token: %v", err)
+	}
+
+	replacement, newToken, _ := domain.NewFeedToken(1)
+	repo.Set(replacement)
+	if _, err := repo.GetByToken(token); err == nil {
+		t.Error("GetByToken should fail for a replaced token")
+	}
+	if found, err := repo.GetByUser(1); err != nil || found.TokenHash != domain.HashToken(newToken) {
+		t.Errorf("GetByUser should return the new token: %v", err)
+	}
+
+	if err := repo.Delete(1); err != nil {
+		t.Errorf("Delete should not return error: %v", err)
+	}
+	if _, err := repo.GetByUser(1); err == nil {
+		t.Error("GetByUser should fail after the token is deleted")
+	}
+	if err := repo.Delete(1); err == nil {
+		t.Error("Delete should fail without a token")
+	}
+}
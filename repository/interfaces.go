@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/leinonen/bbs/domain"
 )
 
@@ -8,10 +10,20 @@ type UserRepository interface {
 	Create(user *domain.User) error
 	GetByID(id int) (*domain.User, error)
 	GetByUsername(username string) (*domain.User, error)
+	// GetByEmail finds a user by email address, ignoring case, preferring a
+	// verified address.
+	GetByEmail(email string) (*domain.User, error)
 	Update(user *domain.User) error
 	Delete(id int) error
 	Authenticate(username, password string) (*domain.User, error)
 	UpdateLastLogin(userID int) error
+	UpdatePassword(userID int, password string) error
+	SetTOTP(userID int, secret string, enabled bool) error
+	UseTOTPStep(userID int, step int64) error
+	SetStatus(userID int, status string) error
+	GetByStatus(status string) ([]*domain.User, error)
+	SetEmailVerified(userID int, verified bool) error
+	SetRecordingOptOut(userID int, optOut bool) error
 }
 
 type BoardRepository interface {
@@ -31,4 +43,181 @@ type PostRepository interface {
 	Update(post *domain.Post) error
 	Delete(id int) error
 	CountByBoard(boardID int) (int, error)
+	GetByUser(userID int, limit int) ([]*domain.Post, error)
+	CountByUser(userID int) (int, error)
+	GetThreadsSince(boardID int, since time.Time) ([]*domain.Post, error)
+	// GetThreadsBefore returns up to limit threads on a board with an ID
+	// below beforeID, newest first. A beforeID of 0 starts at the newest.
+	GetThreadsBefore(boardID, beforeID, limit int) ([]*domain.Post, error)
+	// GetRepliesAfter returns up to limit replies to a thread with an ID
+	// above afterID, oldest first.
+	GetRepliesAfter(postID, afterID, limit int) ([]*domain.Post, error)
+	// GetByBoardAfter returns up to limit posts and replies on a board with
+	// an ID above afterID, oldest first.
+	GetByBoardAfter(boardID, afterID, limit int) ([]*domain.Post, error)
+	// GetIDRange returns the lowest and highest IDs of the posts and replies
+	// on a board, or zeros if it has none.
+	GetIDRange(boardID int) (low, high int, err error)
+}
+
+type MotdRepository interface {
+	Get() (*domain.Motd, error)
+	Set(motd *domain.Motd) error
+	GetLastSeen(userID int) (time.Time, error)
+	MarkSeen(userID int, seenAt time.Time) error
+}
+
+type OneLinerRepository interface {
+	Create(oneLiner *domain.OneLiner) error
+	GetRecent(limit int) ([]*domain.OneLiner, error)
+	Delete(id int) error
+}
+
+type LoginRepository interface {
+	Create(login *domain.Login) error
+	End(id int, logoutAt time.Time, reason string) error
+	GetRecent(limit int) ([]*domain.Login, error)
+	GetByUser(userID int, limit int) ([]*domain.Login, error)
+	GetByRemoteAddr(remoteAddr string, limit int) ([]*domain.Login, error)
+	CountByUser(userID int) (int, error)
+}
+
+type PasswordResetRepository interface {
+	Create(reset *domain.PasswordReset) error
+	GetByToken(token string) (*domain.PasswordReset, error)
+	// MarkUsed marks an unused reset used, and fails if it already was, so
+	// that a token lets only one session in.
+	MarkUsed(id int, usedAt time.Time) error
+	InvalidateForUser(userID int) error
+}
+
+type RecoveryCodeRepository interface {
+	Replace(userID int, codes []string) error
+	Use(userID int, code string) error
+	CountUnused(userID int) (int, error)
+	DeleteForUser(userID int) error
+}
+
+type SettingsRepository interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+}
+
+type FailedLoginRepository interface {
+	Create(attempt *domain.FailedLogin) error
+	GetRecent(limit int) ([]*domain.FailedLogin, error)
+	GetByUsername(username string, limit int) ([]*domain.FailedLogin, error)
+	GetByRemoteAddr(remoteAddr string, limit int) ([]*domain.FailedLogin, error)
+}
+
+type EmailVerificationRepository interface {
+	// Create replaces any earlier verification for the same user.
+	Create(verification *domain.EmailVerification) error
+	GetByUser(userID int) (*domain.EmailVerification, error)
+	IncrementAttempts(id int) error
+	DeleteForUser(userID int) error
+}
+
+type InviteRepository interface {
+	Create(invite *domain.Invite) error
+	GetByCode(code string) (*domain.Invite, error)
+	GetByCreator(userID int) ([]*domain.Invite, error)
+	GetAll(limit int) ([]*domain.Invite, error)
+	// Use counts one use of the invite, failing if it has none left.
+	Use(code string) error
+	Delete(id int) error
+}
+
+type PrivateMessageRepository interface {
+	Create(message *domain.PrivateMessage) error
+	GetByID(id int) (*domain.PrivateMessage, error)
+	GetInbox(userID int, limit int) ([]*domain.PrivateMessage, error)
+	GetSent(userID int, limit int) ([]*domain.PrivateMessage, error)
+	CountUnread(userID int) (int, error)
+	MarkRead(id int, readAt time.Time) error
+	Delete(id int) error
+}
+
+type NotificationSettingsRepository interface {
+	// Get returns the user's settings, or everything off if they have none.
+	Get(userID int) (*domain.NotificationSettings, error)
+	Save(settings *domain.NotificationSettings) error
+	GetDigestDue(now time.Time) ([]*domain.NotificationSettings, error)
+}
+
+type SubscriptionRepository interface {
+	Subscribe(userID, boardID int) error
+	Unsubscribe(userID, boardID int) error
+	IsSubscribed(userID, boardID int) (bool, error)
+	GetBoardIDs(userID int) ([]int, error)
+	GetSubscribers(boardID int) ([]int, error)
+	WatchThread(userID, postID int) error
+	UnwatchThread(userID, postID int) error
+	IsWatching(userID, postID int) (bool, error)
+	GetWatchers(postID int) ([]int, error)
+}
+
+type EmailQueueRepository interface {
+	Enqueue(email *domain.QueuedEmail) error
+	GetDue(now time.Time, limit int) ([]*domain.QueuedEmail, error)
+	Update(email *domain.QueuedEmail) error
+	GetRecent(limit int) ([]*domain.QueuedEmail, error)
+}
+
+type NotificationRepository interface {
+	Create(notification *domain.Notification) error
+	GetByUser(userID int, limit int) ([]*domain.Notification, error)
+	CountUnread(userID int) (int, error)
+	MarkRead(id int, readAt time.Time) error
+	MarkAllRead(userID int, readAt time.Time) error
+}
+
+type AuditRepository interface {
+	Create(entry *domain.AuditEntry) error
+	GetRecent(limit int) ([]*domain.AuditEntry, error)
+	// Find returns entries matching filter, newest first.
+	Find(filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, error)
+	// Export calls fn with every entry matching filter, oldest first.
+	Export(filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error
+}
+
+type APITokenRepository interface {
+	Create(token *domain.APIToken) error
+	GetByToken(token string) (*domain.APIToken, error)
+	GetByUser(userID int) ([]*domain.APIToken, error)
+	MarkUsed(id int, usedAt time.Time) error
+	// Delete removes one of the user's tokens.
+	Delete(userID, id int) error
+}
+
+// FeedTokenRepository holds each user's single feed token.
+type FeedTokenRepository interface {
+	// Set gives the user token, replacing any token they had.
+	Set(token *domain.FeedToken) error
+	GetByToken(token string) (*domain.FeedToken, error)
+	GetByUser(userID int) (*domain.FeedToken, error)
+	Delete(userID int) error
+}
+
+// ReadPointerRepository remembers the last post each user has fetched from
+// each board in an offline mail packet.
+type ReadPointerRepository interface {
+	// GetAll returns the user's pointers by board ID.
+	GetAll(userID int) (map[int]int, error)
+	Set(userID, boardID, postID int) error
+}
+
+// EchomailRepository records which posts came in or went out as echomail.
+type EchomailRepository interface {
+	// Add records a message; a MSGID can only be added once.
+	Add(msg *domain.EchomailMessage) error
+	GetByMSGID(msgid string) (*domain.EchomailMessage, error)
+	GetByPost(postID int) (*domain.EchomailMessage, error)
+}
+
+type WebhookDeliveryRepository interface {
+	Enqueue(delivery *domain.WebhookDelivery) error
+	GetDue(now time.Time, limit int) ([]*domain.WebhookDelivery, error)
+	Update(delivery *domain.WebhookDelivery) error
+	GetRecent(limit int) ([]*domain.WebhookDelivery, error)
 }
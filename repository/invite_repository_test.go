@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestInviteRepository_Use(t *testing.T) {
+	repo := mocks.NewInviteRepository()
+	invite, _ := domain.NewInvite(1, 2)
+
+	if err := repo.Create(invite); err != nil {
+		t.Errorf("Create should not return error: %v", err)
+	}
+
+	if invite.ID == 0 {
+		t.Error("Create should set invite ID")
+	}
+
+	found, err := repo.GetByCode(" " + invite.Code + " ")
+	if err != nil || found.ID != invite.ID {
+		t.Errorf("GetByCode should find the invite: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.Use(invite.Code); err != nil {
+			t.Errorf("Use %d should not return error: %v", i+1, err)
+		}
+	}
+
+	if err := repo.Use(invite.Code); err == nil {
+		t.Error("Use should fail once the invite is used up")
+	}
+
+	if err := repo.Use("nonexistent"); err == nil {
+		t.Error("Use should fail for an unknown code")
+	}
+}
+
+func TestInviteRepository_Filters(t *testing.T) {
+	repo := mocks.NewInviteRepository()
+	for _, creator := range []int{1, 2, 1} {
+		invite, _ := domain.NewInvite(creator, 1)
+		repo.Create(invite)
+	}
+
+	invites, _ := repo.GetByCreator(1)
+	if len(invites) != 2 {
+		t.Errorf("Expected 2 invites by user 1, got %d", len(invites))
+	}
+
+	invites, _ = repo.GetAll(2)
+	if len(invites) != 2 || invites[0].ID != 3 {
+		t.Errorf("Expected the 2 newest invites, got %d", len(invites))
+	}
+
+	if err := repo.Delete(invites[0].ID); err != nil {
+		t.Errorf("Delete should not return error: %v", err)
+	}
+
+	if err := repo.Delete(999); err == nil {
+		t.Error("Delete should return error for non-existent invite")
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestLoginRepository_CreateAndEnd(t *testing.T) {
+	repo := mocks.NewLoginRepository()
+	login := domain.NewLogin(1, "testuser", "192.0.2.1", "SSH-2.0-Test")
+
+	err := repo.Create(login)
+	if err != nil {
+		t.Errorf("Create should not return error: %v", err)
+	}
+
+	if login.ID == 0 {
+		t.Error("Create should set login ID")
+	}
+
+	// Test ending a non-existent login
+	err = repo.End(999, time.Now(), domain.LogoutReasonExit)
+	if err == nil {
+		t.Error("End should return error for non-existent login")
+	}
+
+	err = repo.End(login.ID, time.Now(), domain.LogoutReasonExit)
+	if err != nil {
+		t.Errorf("End should not return error: %v", err)
+	}
+
+	logins, _ := repo.GetRecent(10)
+	if logins[0].LogoutAt == nil || logins[0].LogoutReason != domain.LogoutReasonExit {
+		t.Error("End should record logout time and reason")
+	}
+}
+
+func TestLoginRepository_Filters(t *testing.T) {
+	repo := mocks.NewLoginRepository()
+	repo.Create(domain.NewLogin(1, "alice", "192.0.2.1", "client"))
+	repo.Create(domain.NewLogin(2, "bob", "192.0.2.2", "client"))
+	repo.Create(domain.NewLogin(1, "alice", "192.0.2.2", "client"))
+
+	logins, err := repo.GetRecent(2)
+	if err != nil {
+		t.Errorf("GetRecent should not return error: %v", err)
+	}
+
+	if len(logins) != 2 {
+		t.Errorf("Expected 2 recent logins, got %d", len(logins))
+	}
+
+	logins, _ = repo.GetByUser(1, 10)
+	if len(logins) != 2 {
+		t.Errorf("Expected 2 logins for user 1, got %d", len(logins))
+	}
+
+	logins, _ = repo.GetByRemoteAddr("192.0.2.2", 10)
+	if len(logins) != 2 {
+		t.Errorf("Expected 2 logins from 192.0.2.2, got %d", len(logins))
+	}
+
+	count, err := repo.CountByUser(1)
+	if err != nil {
+		t.Errorf("CountByUser should not return error: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected call count 2, got %d", count)
+	}
+}
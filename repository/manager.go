@@ -2,23 +2,160 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"log"
+	"time"
 
+	"github.com/leinonen/bbs/domain"
 	"github.com/leinonen/bbs/repository/sqlite"
+	"github.com/leinonen/bbs/totp"
 )
 
+const twoFactorRolesSetting = "two_factor_roles"
+
 type Manager struct {
-	User  UserRepository
-	Board BoardRepository
-	Post  PostRepository
-	db    *sql.DB
+	User          UserRepository
+	Board         BoardRepository
+	Post          PostRepository
+	Motd          MotdRepository
+	OneLiner      OneLinerRepository
+	Login         LoginRepository
+	PasswordReset PasswordResetRepository
+	RecoveryCode  RecoveryCodeRepository
+	Settings      SettingsRepository
+	FailedLogin   FailedLoginRepository
+	Verification  EmailVerificationRepository
+	Invite        InviteRepository
+	Message       PrivateMessageRepository
+	Subscription  SubscriptionRepository
+	EmailQueue    EmailQueueRepository
+	Notification  NotificationRepository
+	Audit         AuditRepository
+	APIToken      APITokenRepository
+	FeedToken     FeedTokenRepository
+	Webhook       WebhookDeliveryRepository
+	ReadPointer   ReadPointerRepository
+	Echomail      EchomailRepository
+
+	NotificationSettings NotificationSettingsRepository
+
+	db *sql.DB
 }
 
 func NewManager(db *sql.DB) *Manager {
 	return &Manager{
-		User:  sqlite.NewUserRepository(db),
-		Board: sqlite.NewBoardRepository(db),
-		Post:  sqlite.NewPostRepository(db),
-		db:    db,
+		User:          sqlite.NewUserRepository(db),
+		Board:         sqlite.NewBoardRepository(db),
+		Post:          sqlite.NewPostRepository(db),
+		Motd:          sqlite.NewMotdRepository(db),
+		OneLiner:      sqlite.NewOneLinerRepository(db),
+		Login:         sqlite.NewLoginRepository(db),
+		PasswordReset: sqlite.NewPasswordResetRepository(db),
+		RecoveryCode:  sqlite.NewRecoveryCodeRepository(db),
+		Settings:      sqlite.NewSettingsRepository(db),
+		FailedLogin:   sqlite.NewFailedLoginRepository(db),
+		Verification:  sqlite.NewEmailVerificationRepository(db),
+		Invite:        sqlite.NewInviteRepository(db),
+		Message:       sqlite.NewPrivateMessageRepository(db),
+		Subscription:  sqlite.NewSubscriptionRepository(db),
+		EmailQueue:    sqlite.NewEmailQueueRepository(db),
+		Notification:  sqlite.NewNotificationRepository(db),
+		Audit:         sqlite.NewAuditRepository(db),
+		APIToken:      sqlite.NewAPITokenRepository(db),
+		FeedToken:     sqlite.NewFeedTokenRepository(db),
+		Webhook:       sqlite.NewWebhookDeliveryRepository(db),
+		ReadPointer:   sqlite.NewReadPointerRepository(db),
+		Echomail:      sqlite.NewEchomailRepository(db),
+
+		NotificationSettings: sqlite.NewNotificationSettingsRepository(db),
+
+		db: db,
+	}
+}
+
+// CheckPasswordReset returns the user and reset if token is an unused,
+// unexpired reset token for username. It does not use the reset up; the
+// session it lets in marks it used.
+func (m *Manager) CheckPasswordReset(username, token string) (*domain.User, *domain.PasswordReset, error) {
+	user, err := m.User.GetByUsername(username)
+	if err != nil {
+		return nil, nil, errors.New("invalid reset token")
+	}
+
+	reset, err := m.PasswordReset.GetByToken(token)
+	if err != nil || reset.UserID != user.ID || !reset.IsValid(time.Now()) {
+		return nil, nil, errors.New("invalid reset token")
+	}
+
+	return user, reset, nil
+}
+
+// VerifySecondFactor accepts a current authenticator code or, failing that,
+// one of the user's unused recovery codes. Either can only be used once.
+func (m *Manager) VerifySecondFactor(user *domain.User, code string) bool {
+	if step, ok := totp.ValidateStep(user.TOTPSecret, code, time.Now()); ok {
+		return m.User.UseTOTPStep(user.ID, step) == nil
+	}
+	return m.RecoveryCode.Use(user.ID, code) == nil
+}
+
+func (m *Manager) TwoFactorPolicy() (domain.TwoFactorPolicy, error) {
+	value, err := m.Settings.Get(twoFactorRolesSetting)
+	if err != nil {
+		return domain.TwoFactorPolicy{}, err
+	}
+	return domain.ParseTwoFactorPolicy(value), nil
+}
+
+func (m *Manager) SetTwoFactorPolicy(policy domain.TwoFactorPolicy) error {
+	return m.Settings.Set(twoFactorRolesSetting, policy.String())
+}
+
+// StartEmailVerification replaces any earlier verification for user and
+// returns the new code to send to their email address.
+func (m *Manager) StartEmailVerification(user *domain.User) (string, error) {
+	verification, code, err := domain.NewEmailVerification(user.ID, user.Email)
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.Verification.Create(verification); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// VerifyEmail checks a verification code and, when it matches, marks the
+// email verified and activates the user. Wrong codes count towards the verification's attempt limit.
+func (m *Manager) VerifyEmail(user *domain.User, code string) error {
+	verification, err := m.Verification.GetByUser(user.ID)
+	if err != nil {
+		return errors.New("no verification code has been sent, request a new one")
+	}
+
+	if err := verification.Check(code, time.Now()); err != nil {
+		m.Verification.IncrementAttempts(verification.ID)
+		return err
+	}
+
+	if err := m.User.SetEmailVerified(user.ID, true); err != nil {
+		return err
+	}
+	if err := m.User.SetStatus(user.ID, domain.UserStatusActive); err != nil {
+		return err
+	}
+	m.Verification.DeleteForUser(user.ID)
+
+	user.EmailVerified = true
+	user.Status = domain.UserStatusActive
+	return nil
+}
+
+// RecordAudit adds entry to the audit log. A failure is logged rather than
+// returned, so it never stops the action being audited.
+func (m *Manager) RecordAudit(entry *domain.AuditEntry) {
+	if err := m.Audit.Create(entry); err != nil {
+		log.Printf("Failed to record %s on %s in the audit log: %v", entry.Action, entry.Target, err)
 	}
 }
 
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestMotdRepository_GetAndSet(t *testing.T) {
+	repo := mocks.NewMotdRepository()
+
+	// Test getting before anything is set
+	_, err := repo.Get()
+	if err == nil {
+		t.Error("Get should return error when no MOTD is set")
+	}
+
+	motd := domain.NewMotd("Welcome to the board", 1)
+	err = repo.Set(motd)
+	if err != nil {
+		t.Errorf("Set should not return error: %v", err)
+	}
+
+	retrieved, err := repo.Get()
+	if err != nil {
+		t.Errorf("Get should not return error: %v", err)
+	}
+
+	if retrieved.Content != motd.Content {
+		t.Errorf("Expected content %s, got %s", motd.Content, retrieved.Content)
+	}
+}
+
+func TestMotdRepository_SeenTracking(t *testing.T) {
+	repo := mocks.NewMotdRepository()
+
+	lastSeen, err := repo.GetLastSeen(1)
+	if err != nil {
+		t.Errorf("GetLastSeen should not return error: %v", err)
+	}
+
+	if !lastSeen.IsZero() {
+		t.Error("GetLastSeen should return zero time for a user who has not seen the MOTD")
+	}
+
+	seenAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := repo.MarkSeen(1, seenAt); err != nil {
+		t.Errorf("MarkSeen should not return error: %v", err)
+	}
+
+	lastSeen, _ = repo.GetLastSeen(1)
+	if !lastSeen.Equal(seenAt) {
+		t.Errorf("Expected last seen %v, got %v", seenAt, lastSeen)
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestNotificationRepository_Unread(t *testing.T) {
+	repo := mocks.NewNotificationRepository()
+	thread := &domain.Post{ID: 1, UserID: 1, Username: "alice", Title: "Hello"}
+	reply := &domain.Post{ID: 2, UserID: 2, Username: "bob"}
+
+	first := domain.NewNotification(1, domain.NotificationReply, reply, thread)
+	second := domain.NewNotification(1, domain.NotificationMention, reply, thread)
+	other := domain.NewNotification(3, domain.NotificationReply, reply, thread)
+	for _, n := range []*domain.Notification{first, second, other} {
+		if err := repo.Create(n); err != nil {
+			t.Errorf("Create should not return error: %v", err)
+		}
+	}
+
+	notifications, _ := repo.GetByUser(1, 10)
+	if len(notifications) != 2 || notifications[0].ID != second.ID {
+		t.Errorf("Expected user 1's 2 notifications newest first, got %d", len(notifications))
+	}
+
+	if unread, _ := repo.CountUnread(1); unread != 2 {
+		t.Errorf("Expected 2 unread notifications, got %d", unread)
+	}
+
+	repo.MarkRead(first.ID, time.Now())
+	if unread, _ := repo.CountUnread(1); unread != 1 {
+		t.Errorf("Expected 1 unread notification, got %d", unread)
+	}
+
+	repo.MarkAllRead(1, time.Now())
+	if unread, _ := repo.CountUnread(1); unread != 0 {
+		t.Errorf("Expected no unread notifications, got %d", unread)
+	}
+
+	if unread, _ := repo.CountUnread(3); unread != 1 {
+		t.Errorf("Marking user 1's notifications should not touch user 3's, got %d unread", unread)
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestOneLinerRepository_Create(t *testing.T) {
+	repo := mocks.NewOneLinerRepository()
+	oneLiner := domain.NewOneLiner(1, "testuser", "Hello wall")
+
+	err := repo.Create(oneLiner)
+	if err != nil {
+		t.Errorf("Create should not return error: %v", err)
+	}
+
+	if oneLiner.ID == 0 {
+		t.Error("Create should set one-liner ID")
+	}
+}
+
+func TestOneLinerRepository_GetRecent(t *testing.T) {
+	repo := mocks.NewOneLinerRepository()
+
+	for i := 0; i < 12; i++ {
+		repo.Create(domain.NewOneLiner(1, "testuser", "line"))
+	}
+
+	oneLiners, err := repo.GetRecent(10)
+	if err != nil {
+		t.Errorf("GetRecent should not return error: %v", err)
+	}
+
+	if len(oneLiners) != 10 {
+		t.Errorf("Expected 10 one-liners, got %d", len(oneLiners))
+	}
+
+	if oneLiners[0].ID != 12 {
+		t.Errorf("Expected newest one-liner first, got ID %d", oneLiners[0].ID)
+	}
+}
+
+func TestOneLinerRepository_Delete(t *testing.T) {
+	repo := mocks.NewOneLinerRepository()
+
+	err := repo.Delete(999)
+	if err == nil {
+		t.Error("Delete should return error for non-existent one-liner")
+	}
+
+	oneLiner := domain.NewOneLiner(1, "testuser", "Hello wall")
+	repo.Create(oneLiner)
+
+	err = repo.Delete(oneLiner.ID)
+	if err != nil {
+		t.Errorf("Delete should not return error: %v", err)
+	}
+
+	oneLiners, _ := repo.GetRecent(10)
+	if len(oneLiners) != 0 {
+		t.Errorf("Expected 0 one-liners after deletion, got %d", len(oneLiners))
+	}
+}
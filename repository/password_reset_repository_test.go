@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestPasswordResetRepository_CreateAndGet(t *testing.T) {
+	repo := mocks.NewPasswordResetRepository()
+
+	reset, token, err := domain.NewPasswordReset(1, 2)
+	if err != nil {
+		t.Fatalf("NewPasswordReset failed: %v", err)
+	}
+
+	err = repo.Create(reset)
+	if err != nil {
+		t.Errorf("Create should not return error: %v", err)
+	}
+
+	if reset.ID == 0 {
+		t.Error("Create should set reset ID")
+	}
+
+	// Test unknown token
+	_, err = repo.GetByToken("not-a-token")
+	if err == nil {
+		t.Error("GetByToken should return error for unknown token")
+	}
+
+	retrieved, err := repo.GetByToken(token)
+	if err != nil {
+		t.Errorf("GetByToken should not return error: %v", err)
+	}
+
+	if retrieved.UserID != 1 {
+		t.Errorf("Expected UserID 1, got %d", retrieved.UserID)
+	}
+}
+
+func TestPasswordResetRepository_MarkUsed(t *testing.T) {
+	repo := mocks.NewPasswordResetRepository()
+
+	reset, token, _ := domain.NewPasswordReset(1, 2)
+	repo.Create(reset)
+
+	err := repo.MarkUsed(reset.ID, time.Now())
+	if err != nil {
+		t.Errorf("MarkUsed should not return error: %v", err)
+	}
+
+	retrieved, _ := repo.GetByToken(token)
+	if retrieved.IsValid(time.Now()) {
+		t.Error("Used reset should no longer be valid")
+	}
+
+	if err := repo.MarkUsed(reset.ID, time.Now()); err == nil {
+		t.Error("MarkUsed should fail for a reset that was already used")
+	}
+}
+
+func TestPasswordResetRepository_InvalidateForUser(t *testing.T) {
+	repo := mocks.NewPasswordResetRepository()
+
+	first, firstToken, _ := domain.NewPasswordReset(1, 2)
+	other, otherToken, _ := domain.NewPasswordReset(3, 2)
+	repo.Create(first)
+	repo.Create(other)
+
+	err := repo.InvalidateForUser(1)
+	if err != nil {
+		t.Errorf("InvalidateForUser should not return error: %v", err)
+	}
+
+	retrieved, _ := repo.GetByToken(firstToken)
+	if retrieved.IsValid(time.Now()) {
+		t.Error("Reset for user 1 should be invalidated")
+	}
+
+	retrieved, _ = repo.GetByToken(otherToken)
+	if !retrieved.IsValid(time.Now()) {
+		t.Error("Reset for other users should stay valid")
+	}
+}
@@ -352,3 +352,102 @@ func TestPostRepository_CountByBoard(t *testing.T) {
 		t.Errorf("Expected 1 post for board 2, got %d", count)
 	}
 }
+
+func TestPostRepository_GetByUser(t *testing.T) {
+	repo := mocks.NewPostRepository()
+
+	post1 := domain.NewPost(1, 42, "user1", "Post 1", "Content 1")
+	post2 := domain.NewPost(2, 42, "user1", "Post 2", "Content 2")
+	post2.CreatedAt = post1.CreatedAt.Add(time.Minute)
+	post3 := domain.NewPost(1, 43, "user2", "Post 3", "Content 3")
+
+	repo.Create(post1)
+	repo.Create(post2)
+	repo.Create(post3)
+
+	posts, err := repo.GetByUser(42, 10)
+	if err != nil {
+		t.Errorf("GetByUser should not return error: %v", err)
+	}
+
+	if len(posts) != 2 {
+		t.Errorf("Expected 2 posts for user 42, got %d", len(posts))
+	}
+
+	if posts[0].ID != post2.ID {
+		t.Error("GetByUser should return newest post first")
+	}
+
+	count, err := repo.CountByUser(42)
+	if err != nil {
+		t.Errorf("CountByUser should not return error: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 posts for user 42, got %d", count)
+	}
+}
+
+func TestPostRepository_Cursors(t *testing.T) {
+	repo := mocks.NewPostRepository()
+	var threads []*domain.Post
+	for i := 0; i < 3; i++ {
+		thread := domain.NewPost(1, 42, "testuser", "Thread", "Content")
+		repo.Create(thread)
+		threads = append(threads, thread)
+	}
+	for i := 0; i < 3; i++ {
+		repo.Create(domain.NewReply(1, 43, "otheruser", "Reply", threads[0].ID))
+	}
+
+	page, _ := repo.GetThreadsBefore(1, 0, 2)
+	if len(page) != 2 || page[0].ID != threads[2].ID || page[1].ID != threads[1].ID {
+		t.Fatalf("Expected the two newest threads, got %d", len(page))
+	}
+
+	page, _ = repo.GetThreadsBefore(1, page[1].ID, 2)
+	if len(page) != 1 || page[0].ID != threads[0].ID {
+		t.Errorf("Expected the oldest thread on the second page, got %d", len(page))
+	}
+
+	replies, _ := repo.GetRepliesAfter(threads[0].ID, 0, 2)
+	if len(replies) != 2 {
+		t.Fatalf("Expected 2 replies, got %d", len(replies))
+	}
+
+	replies, _ = repo.GetRepliesAfter(threads[0].ID, replies[1].ID, 2)
+	if len(replies) != 1 {
+		t.Errorf("Expected the last reply on the second page, got %d", len(replies))
+	}
+}
+
+func TestPostRepository_GetByBoardAfter(t *testing.T) {
+	repo := mocks.NewPostRepository()
+
+	low, high, err := repo.GetIDRange(1)
+	if err != nil || low != 0 || high != 0 {
+		t.Errorf("Expected zeros for an empty board, got %d-%d: %v", low, high, err)
+	}
+
+	thread := domain.NewPost(1, 42, "testuser", "Thread", "Content")
+	repo.Create(thread)
+	repo.Create(domain.NewPost(2, 42, "testuser", "Elsewhere", "Content"))
+	for i := 0; i < 3; i++ {
+		repo.Create(domain.NewReply(1, 43, "otheruser", "Reply", thread.ID))
+	}
+
+	low, high, _ = repo.GetIDRange(1)
+	if low != 1 || high != 5 {
+		t.Errorf("Expected IDs 1-5, got %d-%d", low, high)
+	}
+
+	posts, _ := repo.GetByBoardAfter(1, 0, 3)
+	if len(posts) != 3 || posts[0].ID != 1 || posts[1].ID != 3 || posts[2].ID != 4 {
+		t.Fatalf("Expected posts 1, 3 and 4, got %d", len(posts))
+	}
+
+	posts, _ = repo.GetByBoardAfter(1, 4, 3)
+	if len(posts) != 1 || posts[0].ID != 5 {
+		t.Errorf("Expected only post 5 after 4, got %d", len(posts))
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestPrivateMessageRepository_Inbox(t *testing.T) {
+	repo := mocks.NewPrivateMessageRepository()
+	alice := &domain.User{ID: 1, Username: "alice"}
+	bob := &domain.User{ID: 2, Username: "bob"}
+
+	first := domain.NewPrivateMessage(alice, bob, "Hello", "Hi bob")
+	second := domain.NewPrivateMessage(alice, bob, "Again", "Hi again")
+	reply := domain.NewPrivateMessage(bob, alice, domain.ReplySubject("Hello"), "Hi alice")
+	for _, message := range []*domain.PrivateMessage{first, second, reply} {
+		if err := repo.Create(message); err != nil {
+			t.Errorf("Create should not return error: %v", err)
+		}
+	}
+
+	inbox, _ := repo.GetInbox(bob.ID, 10)
+	if len(inbox) != 2 || inbox[0].ID != second.ID {
+		t.Errorf("Expected bob's 2 messages newest first, got %d", len(inbox))
+	}
+
+	sent, _ := repo.GetSent(bob.ID, 10)
+	if len(sent) != 1 || sent[0].Subject != "Re: Hello" {
+		t.Errorf("Expected bob's reply in sent, got %d", len(sent))
+	}
+
+	if unread, _ := repo.CountUnread(bob.ID); unread != 2 {
+		t.Errorf("Expected 2 unread messages, got %d", unread)
+	}
+
+	if err := repo.MarkRead(first.ID, time.Now()); err != nil {
+		t.Errorf("MarkRead should not return error: %v", err)
+	}
+
+	if unread, _ := repo.CountUnread(bob.ID); unread != 1 {
+		t.Errorf("Expected 1 unread message, got %d", unread)
+	}
+
+	found, _ := repo.GetByID(first.ID)
+	if !found.IsRead() {
+		t.Error("Expected the message to be read")
+	}
+
+	if err := repo.Delete(first.ID); err != nil {
+		t.Errorf("Delete should not return error: %v", err)
+	}
+
+	if _, err := repo.GetByID(first.ID); err == nil {
+		t.Error("GetByID should fail for a deleted message")
+	}
+}
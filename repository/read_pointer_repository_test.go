@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestReadPointerRepository(t *testing.T) {
+	repo := mocks.NewReadPointerRepository()
+
+	pointers, err := repo.GetAll(1)
+	if err != nil || len(pointers) != 0 {
+		t.Errorf("Expected no pointers for a new user: %v", err)
+	}
+
+	repo.Set(1, 1, 10)
+	repo.Set(1, 2, 20)
+	repo.Set(2, 1, 5)
+	repo.Set(1, 1, 15)
+
+	pointers, _ = repo.GetAll(1)
+	if len(pointers) != 2 || pointers[1] != 15 || pointers[2] != 20 {
+		t.Errorf("Expected pointers 15 and 20, got %v", pointers)
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestRecoveryCodeRepository_Use(t *testing.T) {
+	repo := mocks.NewRecoveryCodeRepository()
+
+	err := repo.Replace(1, []string{"aaaaa-11111", "bbbbb-22222"})
+	if err != nil {
+		t.Errorf("Replace should not return error: %v", err)
+	}
+
+	count, _ := repo.CountUnused(1)
+	if count != 2 {
+		t.Errorf("Expected 2 unused codes, got %d", count)
+	}
+
+	// Codes are accepted without the separator and in any case
+	err = repo.Use(1, "AAAAA11111")
+	if err != nil {
+		t.Errorf("Use should not return error: %v", err)
+	}
+
+	err = repo.Use(1, "aaaaa-11111")
+	if err == nil {
+		t.Error("Use should return error for an already used code")
+	}
+
+	err = repo.Use(2, "bbbbb-22222")
+	if err == nil {
+		t.Error("Use should return error for another user's code")
+	}
+
+	count, _ = repo.CountUnused(1)
+	if count != 1 {
+		t.Errorf("Expected 1 unused code, got %d", count)
+	}
+}
+
+func TestRecoveryCodeRepository_Replace(t *testing.T) {
+	repo := mocks.NewRecoveryCodeRepository()
+
+	repo.Replace(1, []string{"aaaaa-11111"})
+	repo.Replace(1, []string{"ccccc-33333"})
+
+	if err := repo.Use(1, "aaaaa-11111"); err == nil {
+		t.Error("Replaced codes should no longer work")
+	}
+
+	repo.DeleteForUser(1)
+	count, _ := repo.CountUnused(1)
+	if count != 0 {
+		t.Errorf("Expected 0 codes after delete, got %d", count)
+	}
+}
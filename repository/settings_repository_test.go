@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestSettingsRepository_GetSet(t *testing.T) {
+	repo := mocks.NewSettingsRepository()
+
+	value, err := repo.Get("missing")
+	if err != nil {
+		t.Errorf("Get should not return error for missing key: %v", err)
+	}
+
+	if value != "" {
+		t.Errorf("Expected empty value, got %q", value)
+	}
+
+	repo.Set("key", "one")
+	repo.Set("key", "two")
+
+	value, _ = repo.Get("key")
+	if value != "two" {
+		t.Errorf("Expected two, got %q", value)
+	}
+}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type APITokenRepository struct {
+	db *sql.DB
+}
+
+func NewAPITokenRepository(db *sql.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+const apiTokenColumns = "id, user_id, name, token_hash, created_at, last_used_at"
+
+func scanAPIToken(row rowScanner) (*domain.APIToken, error) {
+	token := &domain.APIToken{}
+	var lastUsedAt sql.NullTime
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&token.CreatedAt,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return token, nil
+}
+
+func (r *APITokenRepository) Create(token *domain.APIToken) error {
+	token.Sanitize()
+
+	query := `
+		INSERT INTO api_tokens (user_id, name, token_hash, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	token.ID = int(id)
+	return nil
+}
+
+func (r *APITokenRepository) GetByToken(token string) (*domain.APIToken, error) {
+	query := "SELECT " + apiTokenColumns + " FROM api_tokens WHERE token_hash = ?"
+
+	apiToken, err := scanAPIToken(r.db.QueryRow(query, domain.HashToken(token)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("API token not found")
+		}
+		return nil, err
+	}
+
+	return apiToken, nil
+}
+
+func (r *APITokenRepository) GetByUser(userID int) ([]*domain.APIToken, error) {
+	query := "SELECT " + apiTokenColumns + " FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*domain.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *APITokenRepository) MarkUsed(id int, usedAt time.Time) error {
+	_, err := r.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", usedAt, id)
+	return err
+}
+
+func (r *APITokenRepository) Delete(userID, id int) error {
+	result, err := r.db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("API token not found")
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditColumns = "id, actor_id, actor, action, target, remote_addr, details, created_at"
+
+func scanAuditEntry(row rowScanner) (*domain.AuditEntry, error) {
+	entry := &domain.AuditEntry{}
+	err := row.Scan(
+		&entry.ID,
+		&entry.ActorID,
+		&entry.Actor,
+		&entry.Action,
+		&entry.Target,
+		&entry.RemoteAddr,
+		&entry.Details,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *AuditRepository) Create(entry *domain.AuditEntry) error {
+	entry.Sanitize()
+
+	query := `
+		INSERT INTO audit_log (actor_id, actor, action, target, remote_addr, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		entry.ActorID,
+		entry.Actor,
+		entry.Action,
+		entry.Target,
+		entry.RemoteAddr,
+		entry.Details,
+		entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = int(id)
+	return nil
+}
+
+func (r *AuditRepository) GetRecent(limit int) ([]*domain.AuditEntry, error) {
+	return r.Find(domain.AuditFilter{}, limit, 0)
+}
+
+// likeEscaper escapes a string for LIKE ... ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func auditWhere(filter domain.AuditFilter) (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ? COLLATE NOCASE")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, `(action = ? OR action LIKE ? ESCAPE '\')`)
+		args = append(args, filter.Action, likeEscaper.Replace(filter.Action)+".%")
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, `target LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(filter.Target)+"%")
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func (r *AuditRepository) Find(filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, error) {
+	where, args := auditWhere(filter)
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE " + where +
+		" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	return r.query(query, append(args, limit, offset)...)
+}
+
+// exportBatch is how many entries Export reads at a time, so the database
+// is not held open while a slow client reads the export.
+const exportBatch = 500
+
+func (r *AuditRepository) Export(filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error {
+	where, args := auditWhere(filter)
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE " + where + " AND id > ? ORDER BY id ASC LIMIT ?"
+
+	lastID := 0
+	for {
+		entries, err := r.query(query, append(args, lastID, exportBatch)...)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+			lastID = entry.ID
+		}
+
+		if len(entries) < exportBatch {
+			return nil
+		}
+	}
+}
+
+func (r *AuditRepository) query(query string, args ...interface{}) ([]*domain.AuditEntry, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*domain.AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -16,6 +16,8 @@ func NewBoardRepository(db *sql.DB) *BoardRepository {
 }
 
 func (r *BoardRepository) Create(board *domain.Board) error {
+	board.Sanitize()
+
 	query := `
 		INSERT INTO boards (name, description, created_at)
 		VALUES (?, ?, ?)
@@ -97,6 +99,8 @@ func (r *BoardRepository) GetAll() ([]*domain.Board, error) {
 }
 
 func (r *BoardRepository) Update(board *domain.Board) error {
+	board.Sanitize()
+
 	query := `
 		UPDATE boards
 		SET name = ?, description = ?
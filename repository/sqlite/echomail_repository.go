@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type EchomailRepository struct {
+	db *sql.DB
+}
+
+func NewEchomailRepository(db *sql.DB) *EchomailRepository {
+	return &EchomailRepository{db: db}
+}
+
+const echomailColumns = "msgid, post_id, area, imported, created_at"
+
+func scanEchomail(row rowScanner) (*domain.EchomailMessage, error) {
+	msg := &domain.EchomailMessage{}
+	err := row.Scan(&msg.MSGID, &msg.PostID, &msg.Area, &msg.Imported, &msg.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("echomail message not found")
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (r *EchomailRepository) Add(msg *domain.EchomailMessage) error {
+	query := `
+		INSERT INTO echomail (msgid, post_id, area, imported, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := r.db.Exec(query, msg.MSGID, msg.PostID, msg.Area, msg.Imported, msg.CreatedAt)
+	return err
+}
+
+func (r *EchomailRepository) GetByMSGID(msgid string) (*domain.EchomailMessage, error) {
+	query := "SELECT " + echomailColumns + " FROM echomail WHERE msgid = ?"
+	return scanEchomail(r.db.QueryRow(query, msgid))
+}
+
+func (r *EchomailRepository) GetByPost(postID int) (*domain.EchomailMessage, error) {
+	query := "SELECT " + echomailColumns + " FROM echomail WHERE post_id = ?"
+	return scanEchomail(r.db.QueryRow(query, postID))
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type EmailQueueRepository struct {
+	db *sql.DB
+}
+
+func NewEmailQueueRepository(db *sql.DB) *EmailQueueRepository {
+	return &EmailQueueRepository{db: db}
+}
+
+const queuedEmailColumns = `
+	id, to_addr, subject, body, status, attempts, last_error,
+	next_attempt_at, created_at, sent_at
+`
+
+func scanQueuedEmail(row rowScanner) (*domain.QueuedEmail, error) {
+	email := &domain.QueuedEmail{}
+	var sentAt sql.NullTime
+
+	err := row.Scan(
+		&email.ID,
+		&email.To,
+		&email.Subject,
+		&email.Body,
+		&email.Status,
+		&email.Attempts,
+		&email.LastError,
+		&email.NextAttemptAt,
+		&email.CreatedAt,
+		&sentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if sentAt.Valid {
+		email.SentAt = &sentAt.Time
+	}
+	return email, nil
+}
+
+func (r *EmailQueueRepository) Enqueue(email *domain.QueuedEmail) error {
+	query := `
+		INSERT INTO email_queue (to_addr, subject, body, status, attempts, last_error,
+		                         next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		email.To,
+		email.Subject,
+		email.Body,
+		email.Status,
+		email.Attempts,
+		email.LastError,
+		email.NextAttemptAt,
+		email.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	email.ID = int(id)
+	return nil
+}
+
+// GetDue returns pending emails whose next attempt is due, oldest first.
+func (r *EmailQueueRepository) GetDue(now time.Time, limit int) ([]*domain.QueuedEmail, error) {
+	query := "SELECT " + queuedEmailColumns + `
+		FROM email_queue
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at ASC, id ASC
+		LIMIT ?`
+	return r.query(query, domain.EmailPending, now, limit)
+}
+
+func (r *EmailQueueRepository) GetRecent(limit int) ([]*domain.QueuedEmail, error) {
+	query := "SELECT " + queuedEmailColumns + " FROM email_queue ORDER BY created_at DESC, id DESC LIMIT ?"
+	return r.query(query, limit)
+}
+
+func (r *EmailQueueRepository) query(query string, args ...interface{}) ([]*domain.QueuedEmail, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*domain.QueuedEmail
+	for rows.Next() {
+		email, err := scanQueuedEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// Update saves the outcome of a delivery attempt.
+func (r *EmailQueueRepository) Update(email *domain.QueuedEmail) error {
+	query := `
+		UPDATE email_queue
+		SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, sent_at = ?
+		WHERE id = ?
+	`
+
+	var sentAt sql.NullTime
+	if email.SentAt != nil {
+		sentAt = sql.NullTime{Time: *email.SentAt, Valid: true}
+	}
+
+	result, err := r.db.Exec(query,
+		email.Status,
+		email.Attempts,
+		email.LastError,
+		email.NextAttemptAt,
+		sentAt,
+		email.ID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("queued email not found")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type EmailVerificationRepository struct {
+	db *sql.DB
+}
+
+func NewEmailVerificationRepository(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+func (r *EmailVerificationRepository) Create(verification *domain.EmailVerification) error {
+	query := `
+		INSERT OR REPLACE INTO email_verifications (user_id, email, code_hash, attempts, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		verification.UserID,
+		verification.Email,
+		verification.CodeHash,
+		verification.Attempts,
+		verification.ExpiresAt,
+		verification.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	verification.ID = int(id)
+	return nil
+}
+
+func (r *EmailVerificationRepository) GetByUser(userID int) (*domain.EmailVerification, error) {
+	verification := &domain.EmailVerification{}
+
+	query := `
+		SELECT id, user_id, email, code_hash, attempts, expires_at, created_at
+		FROM email_verifications WHERE user_id = ?
+	`
+
+	err := r.db.QueryRow(query, userID).Scan(
+		&verification.ID,
+		&verification.UserID,
+		&verification.Email,
+		&verification.CodeHash,
+		&verification.Attempts,
+		&verification.ExpiresAt,
+		&verification.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("email verification not found")
+		}
+		return nil, err
+	}
+
+	return verification, nil
+}
+
+func (r *EmailVerificationRepository) IncrementAttempts(id int) error {
+	result, err := r.db.Exec("UPDATE email_verifications SET attempts = attempts + 1 WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("email verification not found")
+	}
+	return nil
+}
+
+func (r *EmailVerificationRepository) DeleteForUser(userID int) error {
+	_, err := r.db.Exec("DELETE FROM email_verifications WHERE user_id = ?", userID)
+	return err
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type FailedLoginRepository struct {
+	db *sql.DB
+}
+
+func NewFailedLoginRepository(db *sql.DB) *FailedLoginRepository {
+	return &FailedLoginRepository{db: db}
+}
+
+func (r *FailedLoginRepository) Create(attempt *domain.FailedLogin) error {
+	attempt.Sanitize()
+
+	query := `
+		INSERT INTO failed_logins (username, remote_addr, reason, attempted_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, attempt.Username, attempt.RemoteAddr, attempt.Reason, attempt.AttemptedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	attempt.ID = int(id)
+	return nil
+}
+
+func (r *FailedLoginRepository) GetRecent(limit int) ([]*domain.FailedLogin, error) {
+	return r.query("", limit)
+}
+
+func (r *FailedLoginRepository) GetByUsername(username string, limit int) ([]*domain.FailedLogin, error) {
+	return r.query("WHERE username = ? COLLATE NOCASE", limit, username)
+}
+
+func (r *FailedLoginRepository) GetByRemoteAddr(remoteAddr string, limit int) ([]*domain.FailedLogin, error) {
+	return r.query("WHERE remote_addr = ?", limit, remoteAddr)
+}
+
+func (r *FailedLoginRepository) query(where string, limit int, args ...interface{}) ([]*domain.FailedLogin, error) {
+	query := `
+		SELECT id, username, remote_addr, reason, attempted_at
+		FROM failed_logins
+		` + where + `
+		ORDER BY attempted_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*domain.FailedLogin
+	for rows.Next() {
+		attempt := &domain.FailedLogin{}
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.Username,
+			&attempt.RemoteAddr,
+			&attempt.Reason,
+			&attempt.AttemptedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
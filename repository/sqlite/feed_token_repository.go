@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type FeedTokenRepository struct {
+	db *sql.DB
+}
+
+func NewFeedTokenRepository(db *sql.DB) *FeedTokenRepository {
+	return &FeedTokenRepository{db: db}
+}
+
+const feedTokenColumns = "user_id, token_hash, created_at"
+
+func scanFeedToken(row rowScanner) (*domain.FeedToken, error) {
+	token := &domain.FeedToken{}
+	err := row.Scan(&token.UserID, &token.TokenHash, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("feed token not found")
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *FeedTokenRepository) Set(token *domain.FeedToken) error {
+	query := `
+		INSERT INTO feed_tokens (user_id, token_hash, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			token_hash = excluded.token_hash,
+			created_at = excluded.created_at
+	`
+	_, err := r.db.Exec(query, token.UserID, token.TokenHash, token.CreatedAt)
+	return err
+}
+
+func (r *FeedTokenRepository) GetByToken(token string) (*domain.FeedToken, error) {
+	query := "SELECT " + feedTokenColumns + " FROM feed_tokens WHERE token_hash = ?"
+	return scanFeedToken(r.db.QueryRow(query, domain.HashToken(token)))
+}
+
+func (r *FeedTokenRepository) GetByUser(userID int) (*domain.FeedToken, error) {
+	query := "SELECT " + feedTokenColumns + " FROM feed_tokens WHERE user_id = ?"
+	return scanFeedToken(r.db.QueryRow(query, userID))
+}
+
+func (r *FeedTokenRepository) Delete(userID int) error {
+	result, err := r.db.Exec("DELETE FROM feed_tokens WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("feed token not found")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type InviteRepository struct {
+	db *sql.DB
+}
+
+func NewInviteRepository(db *sql.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+const inviteColumns = "id, code, created_by, max_uses, uses, created_at"
+
+func scanInvite(row rowScanner) (*domain.Invite, error) {
+	invite := &domain.Invite{}
+	err := row.Scan(
+		&invite.ID,
+		&invite.Code,
+		&invite.CreatedBy,
+		&invite.MaxUses,
+		&invite.Uses,
+		&invite.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (r *InviteRepository) Create(invite *domain.Invite) error {
+	query := `
+		INSERT INTO invites (code, created_by, max_uses, uses, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		invite.Code,
+		invite.CreatedBy,
+		invite.MaxUses,
+		invite.Uses,
+		invite.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	invite.ID = int(id)
+	return nil
+}
+
+func (r *InviteRepository) GetByCode(code string) (*domain.Invite, error) {
+	query := "SELECT " + inviteColumns + " FROM invites WHERE code = ?"
+
+	invite, err := scanInvite(r.db.QueryRow(query, domain.NormalizeInviteCode(code)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invite not found")
+		}
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (r *InviteRepository) GetByCreator(userID int) ([]*domain.Invite, error) {
+	query := "SELECT " + inviteColumns + " FROM invites WHERE created_by = ? ORDER BY created_at DESC, id DESC"
+	return r.query(query, userID)
+}
+
+func (r *InviteRepository) GetAll(limit int) ([]*domain.Invite, error) {
+	query := "SELECT " + inviteColumns + " FROM invites ORDER BY created_at DESC, id DESC LIMIT ?"
+	return r.query(query, limit)
+}
+
+func (r *InviteRepository) query(query string, args ...interface{}) ([]*domain.Invite, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []*domain.Invite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, rows.Err()
+}
+
+func (r *InviteRepository) Use(code string) error {
+	query := "UPDATE invites SET uses = uses + 1 WHERE code = ? AND uses < max_uses"
+	result, err := r.db.Exec(query, domain.NormalizeInviteCode(code))
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("invite code is not valid")
+	}
+	return nil
+}
+
+func (r *InviteRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM invites WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("invite not found")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type LoginRepository struct {
+	db *sql.DB
+}
+
+func NewLoginRepository(db *sql.DB) *LoginRepository {
+	return &LoginRepository{db: db}
+}
+
+func (r *LoginRepository) Create(login *domain.Login) error {
+	login.Sanitize()
+
+	query := `
+		INSERT INTO logins (user_id, remote_addr, client_version, login_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, login.UserID, login.RemoteAddr, login.ClientVersion, login.LoginAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	login.ID = int(id)
+	return nil
+}
+
+func (r *LoginRepository) End(id int, logoutAt time.Time, reason string) error {
+	query := `
+		UPDATE logins
+		SET logout_at = ?, logout_reason = ?
+		WHERE id = ? AND logout_at IS NULL
+	`
+
+	_, err := r.db.Exec(query, logoutAt, reason, id)
+	return err
+}
+
+func (r *LoginRepository) GetRecent(limit int) ([]*domain.Login, error) {
+	return r.query("", limit)
+}
+
+func (r *LoginRepository) GetByUser(userID int, limit int) ([]*domain.Login, error) {
+	return r.query("WHERE l.user_id = ?", limit, userID)
+}
+
+func (r *LoginRepository) GetByRemoteAddr(remoteAddr string, limit int) ([]*domain.Login, error) {
+	return r.query("WHERE l.remote_addr = ?", limit, remoteAddr)
+}
+
+func (r *LoginRepository) CountByUser(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM logins WHERE user_id = ?", userID).Scan(&count)
+	return count, err
+}
+
+func (r *LoginRepository) query(where string, limit int, args ...interface{}) ([]*domain.Login, error) {
+	query := `
+		SELECT l.id, l.user_id, u.username, l.remote_addr, l.client_version,
+		       l.login_at, l.logout_at, l.logout_reason
+		FROM logins l
+		JOIN users u ON l.user_id = u.id
+		` + where + `
+		ORDER BY l.login_at DESC, l.id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logins []*domain.Login
+	for rows.Next() {
+		login := &domain.Login{}
+		var clientVersion, logoutReason sql.NullString
+		var logoutAt sql.NullTime
+
+		err := rows.Scan(
+			&login.ID,
+			&login.UserID,
+			&login.Username,
+			&login.RemoteAddr,
+			&clientVersion,
+			&login.LoginAt,
+			&logoutAt,
+			&logoutReason,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		login.ClientVersion = clientVersion.String
+		login.LogoutReason = logoutReason.String
+		if logoutAt.Valid {
+			login.LogoutAt = &logoutAt.Time
+		}
+
+		logins = append(logins, login)
+	}
+
+	return logins, rows.Err()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type MotdRepository struct {
+	db *sql.DB
+}
+
+func NewMotdRepository(db *sql.DB) *MotdRepository {
+	return &MotdRepository{db: db}
+}
+
+func (r *MotdRepository) Get() (*domain.Motd, error) {
+	motd := &domain.Motd{}
+	var updatedBy sql.NullInt64
+
+	query := "SELECT content, updated_by, updated_at FROM motd WHERE id = 1"
+
+	err := r.db.QueryRow(query).Scan(&motd.Content, &updatedBy, &motd.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("motd not found")
+		}
+		return nil, err
+	}
+
+	motd.UpdatedBy = int(updatedBy.Int64)
+	return motd, nil
+}
+
+func (r *MotdRepository) Set(motd *domain.Motd) error {
+	motd.Sanitize()
+
+	query := `
+		INSERT INTO motd (id, content, updated_by, updated_at)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			content = excluded.content,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query, motd.Content, motd.UpdatedBy, motd.UpdatedAt)
+	return err
+}
+
+func (r *MotdRepository) GetLastSeen(userID int) (time.Time, error) {
+	var seenAt time.Time
+
+	err := r.db.QueryRow("SELECT seen_at FROM motd_seen WHERE user_id = ?", userID).Scan(&seenAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return seenAt, err
+}
+
+func (r *MotdRepository) MarkSeen(userID int, seenAt time.Time) error {
+	query := `
+		INSERT INTO motd_seen (user_id, seen_at)
+		VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET seen_at = excluded.seen_at
+	`
+
+	_, err := r.db.Exec(query, userID, seenAt)
+	return err
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+const notificationColumns = `
+	id, user_id, kind, post_id, thread_id, actor_id, actor_username,
+	title, created_at, read_at
+`
+
+func scanNotification(row rowScanner) (*domain.Notification, error) {
+	notification := &domain.Notification{}
+	var readAt sql.NullTime
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.Kind,
+		&notification.PostID,
+		&notification.ThreadID,
+		&notification.ActorID,
+		&notification.ActorUsername,
+		&notification.Title,
+		&notification.CreatedAt,
+		&readAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if readAt.Valid {
+		notification.ReadAt = &readAt.Time
+	}
+	return notification, nil
+}
+
+func (r *NotificationRepository) Create(notification *domain.Notification) error {
+	notification.Sanitize()
+
+	query := `
+		INSERT INTO notifications (user_id, kind, post_id, thread_id, actor_id, actor_username,
+		                           title, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		notification.UserID,
+		notification.Kind,
+		notification.PostID,
+		notification.ThreadID,
+		notification.ActorID,
+		notification.ActorUsername,
+		notification.Title,
+		notification.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	notification.ID = int(id)
+	return nil
+}
+
+func (r *NotificationRepository) GetByUser(userID int, limit int) ([]*domain.Notification, error) {
+	query := "SELECT " + notificationColumns + `
+		FROM notifications WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*domain.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL"
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *NotificationRepository) MarkRead(id int, readAt time.Time) error {
+	query := "UPDATE notifications SET read_at = ? WHERE id = ? AND read_at IS NULL"
+	_, err := r.db.Exec(query, readAt, id)
+	return err
+}
+
+func (r *NotificationRepository) MarkAllRead(userID int, readAt time.Time) error {
+	query := "UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL"
+	_, err := r.db.Exec(query, readAt, userID)
+	return err
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type NotificationSettingsRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationSettingsRepository(db *sql.DB) *NotificationSettingsRepository {
+	return &NotificationSettingsRepository{db: db}
+}
+
+const notificationSettingsColumns = "user_id, email_replies, email_messages, email_digest, digest_sent_at"
+
+func scanNotificationSettings(row rowScanner) (*domain.NotificationSettings, error) {
+	settings := &domain.NotificationSettings{}
+	err := row.Scan(
+		&settings.UserID,
+		&settings.EmailReplies,
+		&settings.EmailMessages,
+		&settings.EmailDigest,
+		&settings.DigestSentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *NotificationSettingsRepository) Get(userID int) (*domain.NotificationSettings, error) {
+	query := "SELECT " + notificationSettingsColumns + " FROM notification_settings WHERE user_id = ?"
+
+	settings, err := scanNotificationSettings(r.db.QueryRow(query, userID))
+	if err == sql.ErrNoRows {
+		return &domain.NotificationSettings{UserID: userID}, nil
+	}
+	return settings, err
+}
+
+func (r *NotificationSettingsRepository) Save(settings *domain.NotificationSettings) error {
+	query := `
+		INSERT INTO notification_settings (user_id, email_replies, email_messages, email_digest, digest_sent_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			email_replies = excluded.email_replies,
+			email_messages = excluded.email_messages,
+			email_digest = excluded.email_digest,
+			digest_sent_at = excluded.digest_sent_at
+	`
+
+	_, err := r.db.Exec(query,
+		settings.UserID,
+		settings.EmailReplies,
+		settings.EmailMessages,
+		settings.EmailDigest,
+		settings.DigestSentAt)
+	return err
+}
+
+// GetDigestDue returns the settings of users whose daily digest is due.
+func (r *NotificationSettingsRepository) GetDigestDue(now time.Time) ([]*domain.NotificationSettings, error) {
+	query := "SELECT " + notificationSettingsColumns + `
+		FROM notification_settings
+		WHERE email_digest = 1 AND digest_sent_at <= ?
+		ORDER BY user_id`
+
+	rows, err := r.db.Query(query, now.Add(-domain.DigestInterval))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []*domain.NotificationSettings
+	for rows.Next() {
+		settings, err := scanNotificationSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, settings)
+	}
+
+	return due, rows.Err()
+}
@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type OneLinerRepository struct {
+	db *sql.DB
+}
+
+func NewOneLinerRepository(db *sql.DB) *OneLinerRepository {
+	return &OneLinerRepository{db: db}
+}
+
+func (r *OneLinerRepository) Create(oneLiner *domain.OneLiner) error {
+	oneLiner.Sanitize()
+
+	query := `
+		INSERT INTO oneliners (user_id, text, created_at)
+		VALUES (?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, oneLiner.UserID, oneLiner.Text, oneLiner.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	oneLiner.ID = int(id)
+	return nil
+}
+
+func (r *OneLinerRepository) GetRecent(limit int) ([]*domain.OneLiner, error) {
+	query := `
+		SELECT o.id, o.user_id, u.username, o.text, o.created_at
+		FROM oneliners o
+		JOIN users u ON o.user_id = u.id
+		ORDER BY o.created_at DESC, o.id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var oneLiners []*domain.OneLiner
+	for rows.Next() {
+		oneLiner := &domain.OneLiner{}
+		err := rows.Scan(
+			&oneLiner.ID,
+			&oneLiner.UserID,
+			&oneLiner.Username,
+			&oneLiner.Text,
+			&oneLiner.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		oneLiners = append(oneLiners, oneLiner)
+	}
+
+	return oneLiners, rows.Err()
+}
+
+func (r *OneLinerRepository) Delete(id int) error {
+	_, err := r.db.Exec("DELETE FROM oneliners WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) Create(reset *domain.PasswordReset) error {
+	query := `
+		INSERT INTO password_resets (user_id, token_hash, created_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		reset.UserID,
+		reset.TokenHash,
+		reset.CreatedBy,
+		reset.CreatedAt,
+		reset.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	reset.ID = int(id)
+	return nil
+}
+
+func (r *PasswordResetRepository) GetByToken(token string) (*domain.PasswordReset, error) {
+	reset := &domain.PasswordReset{}
+	var createdBy sql.NullInt64
+	var usedAt sql.NullTime
+
+	query := `
+		SELECT id, user_id, token_hash, created_by, created_at, expires_at, used_at
+		FROM password_resets WHERE token_hash = ?
+	`
+
+	err := r.db.QueryRow(query, domain.HashToken(token)).Scan(
+		&reset.ID,
+		&reset.UserID,
+		&reset.TokenHash,
+		&createdBy,
+		&reset.CreatedAt,
+		&reset.ExpiresAt,
+		&usedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("password reset not found")
+		}
+		return nil, err
+	}
+
+	reset.CreatedBy = int(createdBy.Int64)
+	if usedAt.Valid {
+		reset.UsedAt = &usedAt.Time
+	}
+
+	return reset, nil
+}
+
+func (r *PasswordResetRepository) MarkUsed(id int, usedAt time.Time) error {
+	result, err := r.db.Exec("UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL", usedAt, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("password reset not found or already used")
+	}
+	return nil
+}
+
+func (r *PasswordResetRepository) InvalidateForUser(userID int) error {
+	query := "UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL"
+	_, err := r.db.Exec(query, time.Now(), userID)
+	return err
+}
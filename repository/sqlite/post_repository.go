@@ -3,6 +3,7 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/leinonen/bbs/domain"
 )
@@ -16,9 +17,11 @@ func NewPostRepository(db *sql.DB) *PostRepository {
 }
 
 func (r *PostRepository) Create(post *domain.Post) error {
+	post.Sanitize()
+
 	query := `
-		INSERT INTO posts (board_id, user_id, title, content, created_at, updated_at, reply_to)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO posts (board_id, user_id, author, title, content, created_at, updated_at, reply_to)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	var replyTo sql.NullInt64
@@ -29,6 +32,7 @@ func (r *PostRepository) Create(post *domain.Post) error {
 	result, err := r.db.Exec(query,
 		post.BoardID,
 		post.UserID,
+		post.Author,
 		post.Title,
 		post.Content,
 		post.CreatedAt,
@@ -52,7 +56,7 @@ func (r *PostRepository) GetByID(id int) (*domain.Post, error) {
 	var replyTo sql.NullInt64
 
 	query := `
-		SELECT p.id, p.board_id, p.user_id, u.username, p.title, p.content,
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
 		       p.created_at, p.updated_at, p.reply_to,
 		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
 		FROM posts p
@@ -65,6 +69,7 @@ func (r *PostRepository) GetByID(id int) (*domain.Post, error) {
 		&post.BoardID,
 		&post.UserID,
 		&post.Username,
+		&post.Author,
 		&post.Title,
 		&post.Content,
 		&post.CreatedAt,
@@ -89,7 +94,7 @@ func (r *PostRepository) GetByID(id int) (*domain.Post, error) {
 
 func (r *PostRepository) GetByBoard(boardID int, limit, offset int) ([]*domain.Post, error) {
 	query := `
-		SELECT p.id, p.board_id, p.user_id, u.username, p.title, p.content,
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
 		       p.created_at, p.updated_at, p.reply_to,
 		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
 		FROM posts p
@@ -115,6 +120,7 @@ func (r *PostRepository) GetByBoard(boardID int, limit, offset int) ([]*domain.P
 			&post.BoardID,
 			&post.UserID,
 			&post.Username,
+			&post.Author,
 			&post.Title,
 			&post.Content,
 			&post.CreatedAt,
@@ -139,7 +145,7 @@ func (r *PostRepository) GetByBoard(boardID int, limit, offset int) ([]*domain.P
 
 func (r *PostRepository) GetReplies(postID int) ([]*domain.Post, error) {
 	query := `
-		SELECT p.id, p.board_id, p.user_id, u.username, p.title, p.content,
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
 		       p.created_at, p.updated_at, p.reply_to, 0 as reply_count
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
@@ -163,6 +169,7 @@ func (r *PostRepository) GetReplies(postID int) ([]*domain.Post, error) {
 			&post.BoardID,
 			&post.UserID,
 			&post.Username,
+			&post.Author,
 			&post.Title,
 			&post.Content,
 			&post.CreatedAt,
@@ -187,7 +194,7 @@ func (r *PostRepository) GetReplies(postID int) ([]*domain.Post, error) {
 
 func (r *PostRepository) GetRecent(limit int) ([]*domain.Post, error) {
 	query := `
-		SELECT p.id, p.board_id, p.user_id, u.username, p.title, p.content,
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
 		       p.created_at, p.updated_at, p.reply_to,
 		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
 		FROM posts p
@@ -212,6 +219,7 @@ func (r *PostRepository) GetRecent(limit int) ([]*domain.Post, error) {
 			&post.BoardID,
 			&post.UserID,
 			&post.Username,
+			&post.Author,
 			&post.Title,
 			&post.Content,
 			&post.CreatedAt,
@@ -235,6 +243,8 @@ func (r *PostRepository) GetRecent(limit int) ([]*domain.Post, error) {
 }
 
 func (r *PostRepository) Update(post *domain.Post) error {
+	post.Sanitize()
+
 	query := `
 		UPDATE posts
 		SET title = ?, content = ?, updated_at = ?
@@ -256,3 +266,201 @@ func (r *PostRepository) CountByBoard(boardID int) (int, error) {
 	err := r.db.QueryRow(query, boardID).Scan(&count)
 	return count, err
 }
+
+func (r *PostRepository) GetByUser(userID int, limit int) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
+		       p.created_at, p.updated_at, p.reply_to,
+		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ?
+		ORDER BY p.created_at DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*domain.Post
+	for rows.Next() {
+		post := &domain.Post{}
+		var replyTo sql.NullInt64
+
+		err := rows.Scan(
+			&post.ID,
+			&post.BoardID,
+			&post.UserID,
+			&post.Username,
+			&post.Author,
+			&post.Title,
+			&post.Content,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&replyTo,
+			&post.Replies,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if replyTo.Valid {
+			replyToInt := int(replyTo.Int64)
+			post.ReplyTo = &replyToInt
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+func (r *PostRepository) CountByUser(userID int) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM posts WHERE user_id = ?"
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+// GetThreadsSince returns threads started on a board after since, oldest
+// first.
+func (r *PostRepository) GetThreadsSince(boardID int, since time.Time) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
+		       p.created_at, p.updated_at, p.reply_to,
+		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.board_id = ? AND p.reply_to IS NULL AND p.created_at > ?
+		ORDER BY p.created_at ASC
+	`
+
+	rows, err := r.db.Query(query, boardID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*domain.Post
+	for rows.Next() {
+		post := &domain.Post{}
+		var replyTo sql.NullInt64
+
+		err := rows.Scan(
+			&post.ID,
+			&post.BoardID,
+			&post.UserID,
+			&post.Username,
+			&post.Author,
+			&post.Title,
+			&post.Content,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&replyTo,
+			&post.Replies,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if replyTo.Valid {
+			replyToInt := int(replyTo.Int64)
+			post.ReplyTo = &replyToInt
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+func (r *PostRepository) GetThreadsBefore(boardID, beforeID, limit int) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
+		       p.created_at, p.updated_at, p.reply_to,
+		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.board_id = ? AND p.reply_to IS NULL AND (? = 0 OR p.id < ?)
+		ORDER BY p.id DESC
+		LIMIT ?
+	`
+	return r.query(query, boardID, beforeID, beforeID, limit)
+}
+
+func (r *PostRepository) GetRepliesAfter(postID, afterID, limit int) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
+		       p.created_at, p.updated_at, p.reply_to, 0 as reply_count
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.reply_to = ? AND p.id > ?
+		ORDER BY p.id ASC
+		LIMIT ?
+	`
+	return r.query(query, postID, afterID, limit)
+}
+
+func (r *PostRepository) GetByBoardAfter(boardID, afterID, limit int) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, p.board_id, p.user_id, COALESCE(NULLIF(p.author, ''), u.username), p.author, p.title, p.content,
+		       p.created_at, p.updated_at, p.reply_to,
+		       (SELECT COUNT(*) FROM posts WHERE reply_to = p.id) as reply_count
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.board_id = ? AND p.id > ?
+		ORDER BY p.id ASC
+		LIMIT ?
+	`
+	return r.query(query, boardID, afterID, limit)
+}
+
+func (r *PostRepository) GetIDRange(boardID int) (int, int, error) {
+	var low, high int
+	query := "SELECT COALESCE(MIN(id), 0), COALESCE(MAX(id), 0) FROM posts WHERE board_id = ?"
+	err := r.db.QueryRow(query, boardID).Scan(&low, &high)
+	return low, high, err
+}
+
+func (r *PostRepository) query(query string, args ...interface{}) ([]*domain.Post, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*domain.Post
+	for rows.Next() {
+		post := &domain.Post{}
+		var replyTo sql.NullInt64
+
+		err := rows.Scan(
+			&post.ID,
+			&post.BoardID,
+			&post.UserID,
+			&post.Username,
+			&post.Author,
+			&post.Title,
+			&post.Content,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&replyTo,
+			&post.Replies,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if replyTo.Valid {
+			replyToInt := int(replyTo.Int64)
+			post.ReplyTo = &replyToInt
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
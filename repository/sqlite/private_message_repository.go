@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type PrivateMessageRepository struct {
+	db *sql.DB
+}
+
+func NewPrivateMessageRepository(db *sql.DB) *PrivateMessageRepository {
+	return &PrivateMessageRepository{db: db}
+}
+
+const privateMessageColumns = `
+	id, from_user_id, from_username, to_user_id, to_username,
+	subject, body, created_at, read_at
+`
+
+func scanPrivateMessage(row rowScanner) (*domain.PrivateMessage, error) {
+	message := &domain.PrivateMessage{}
+	var readAt sql.NullTime
+
+	err := row.Scan(
+		&message.ID,
+		&message.FromUserID,
+		&message.FromUsername,
+		&message.ToUserID,
+		&message.ToUsername,
+		&message.Subject,
+		&message.Body,
+		&message.CreatedAt,
+		&readAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if readAt.Valid {
+		message.ReadAt = &readAt.Time
+	}
+	return message, nil
+}
+
+func (r *PrivateMessageRepository) Create(message *domain.PrivateMessage) error {
+	message.Sanitize()
+
+	query := `
+		INSERT INTO private_messages (from_user_id, from_username, to_user_id, to_username,
+		                              subject, body, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		message.FromUserID,
+		message.FromUsername,
+		message.ToUserID,
+		message.ToUsername,
+		message.Subject,
+		message.Body,
+		message.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	message.ID = int(id)
+	return nil
+}
+
+func (r *PrivateMessageRepository) GetByID(id int) (*domain.PrivateMessage, error) {
+	query := "SELECT " + privateMessageColumns + " FROM private_messages WHERE id = ?"
+
+	message, err := scanPrivateMessage(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("message not found")
+		}
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func (r *PrivateMessageRepository) GetInbox(userID int, limit int) ([]*domain.PrivateMessage, error) {
+	query := "SELECT " + privateMessageColumns + `
+		FROM private_messages WHERE to_user_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT ?`
+	return r.query(query, userID, limit)
+}
+
+func (r *PrivateMessageRepository) GetSent(userID int, limit int) ([]*domain.PrivateMessage, error) {
+	query := "SELECT " + privateMessageColumns + `
+		FROM private_messages WHERE from_user_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT ?`
+	return r.query(query, userID, limit)
+}
+
+func (r *PrivateMessageRepository) query(query string, args ...interface{}) ([]*domain.PrivateMessage, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*domain.PrivateMessage
+	for rows.Next() {
+		message, err := scanPrivateMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *PrivateMessageRepository) CountUnread(userID int) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM private_messages WHERE to_user_id = ? AND read_at IS NULL"
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *PrivateMessageRepository) MarkRead(id int, readAt time.Time) error {
+	query := "UPDATE private_messages SET read_at = ? WHERE id = ? AND read_at IS NULL"
+	_, err := r.db.Exec(query, readAt, id)
+	return err
+}
+
+func (r *PrivateMessageRepository) Delete(id int) error {
+	result, err := r.db.Exec("DELETE FROM private_messages WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("message not found")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+)
+
+type ReadPointerRepository struct {
+	db *sql.DB
+}
+
+func NewReadPointerRepository(db *sql.DB) *ReadPointerRepository {
+	return &ReadPointerRepository{db: db}
+}
+
+func (r *ReadPointerRepository) GetAll(userID int) (map[int]int, error) {
+	rows, err := r.db.Query("SELECT board_id, post_id FROM read_pointers WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pointers := make(map[int]int)
+	for rows.Next() {
+		var boardID, postID int
+		if err := rows.Scan(&boardID, &postID); err != nil {
+			return nil, err
+		}
+		pointers[boardID] = postID
+	}
+
+	return pointers, rows.Err()
+}
+
+func (r *ReadPointerRepository) Set(userID, boardID, postID int) error {
+	query := `
+		INSERT INTO read_pointers (user_id, board_id, post_id, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, board_id) DO UPDATE SET
+			post_id = excluded.post_id,
+			updated_at = excluded.updated_at
+	`
+	_, err := r.db.Exec(query, userID, boardID, postID, time.Now())
+	return err
+}
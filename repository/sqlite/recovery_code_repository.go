@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type RecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewRecoveryCodeRepository(db *sql.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// Replace discards the user's existing codes and stores hashes of the new
+// ones.
+func (r *RecoveryCodeRepository) Replace(userID int, codes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		hash := domain.HashToken(domain.NormalizeRecoveryCode(code))
+		if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *RecoveryCodeRepository) Use(userID int, code string) error {
+	hash := domain.HashToken(domain.NormalizeRecoveryCode(code))
+
+	query := `
+		UPDATE recovery_codes SET used_at = ?
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+			LIMIT 1
+		)
+	`
+
+	result, err := r.db.Exec(query, time.Now(), userID, hash)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("recovery code not found")
+	}
+	return nil
+}
+
+func (r *RecoveryCodeRepository) CountUnused(userID int) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL"
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *RecoveryCodeRepository) DeleteForUser(userID int) error {
+	_, err := r.db.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	return err
+}
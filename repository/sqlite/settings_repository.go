@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"database/sql"
+)
+
+type SettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Get returns the value stored for key, or an empty string if it was never
+// set.
+func (r *SettingsRepository) Get(key string) (string, error) {
+	var value string
+	err := r.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (r *SettingsRepository) Set(key, value string) error {
+	query := `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`
+	_, err := r.db.Exec(query, key, value)
+	return err
+}
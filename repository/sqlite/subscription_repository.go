@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+func (r *SubscriptionRepository) Subscribe(userID, boardID int) error {
+	query := "INSERT OR IGNORE INTO board_subscriptions (user_id, board_id, created_at) VALUES (?, ?, ?)"
+	_, err := r.db.Exec(query, userID, boardID, time.Now())
+	return err
+}
+
+func (r *SubscriptionRepository) Unsubscribe(userID, boardID int) error {
+	_, err := r.db.Exec("DELETE FROM board_subscriptions WHERE user_id = ? AND board_id = ?", userID, boardID)
+	return err
+}
+
+func (r *SubscriptionRepository) IsSubscribed(userID, boardID int) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM board_subscriptions WHERE user_id = ? AND board_id = ?"
+	err := r.db.QueryRow(query, userID, boardID).Scan(&count)
+	return count > 0, err
+}
+
+func (r *SubscriptionRepository) GetBoardIDs(userID int) ([]int, error) {
+	return r.ids("SELECT board_id FROM board_subscriptions WHERE user_id = ? ORDER BY board_id", userID)
+}
+
+func (r *SubscriptionRepository) GetSubscribers(boardID int) ([]int, error) {
+	return r.ids("SELECT user_id FROM board_subscriptions WHERE board_id = ? ORDER BY user_id", boardID)
+}
+
+func (r *SubscriptionRepository) WatchThread(userID, postID int) error {
+	query := "INSERT OR IGNORE INTO thread_watches (user_id, post_id, created_at) VALUES (?, ?, ?)"
+	_, err := r.db.Exec(query, userID, postID, time.Now())
+	return err
+}
+
+func (r *SubscriptionRepository) UnwatchThread(userID, postID int) error {
+	_, err := r.db.Exec("DELETE FROM thread_watches WHERE user_id = ? AND post_id = ?", userID, postID)
+	return err
+}
+
+func (r *SubscriptionRepository) IsWatching(userID, postID int) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM thread_watches WHERE user_id = ? AND post_id = ?"
+	err := r.db.QueryRow(query, userID, postID).Scan(&count)
+	return count > 0, err
+}
+
+func (r *SubscriptionRepository) GetWatchers(postID int) ([]int, error) {
+	return r.ids("SELECT user_id FROM thread_watches WHERE post_id = ? ORDER BY user_id", postID)
+}
+
+func (r *SubscriptionRepository) ids(query string, args ...interface{}) ([]int, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -3,29 +3,63 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+	"log"
+	"sync"
 	"time"
 
 	"github.com/leinonen/bbs/domain"
+	"github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordCost is the bcrypt cost for new hashes. Hashes made with a lower
+// cost are upgraded the next time the user logs in. Tests lower it before
+// creating any repositories.
+var PasswordCost = 12
+
 type UserRepository struct {
-	db *sql.DB
+	db   *sql.DB
+	cost int
+
+	dummyOnce sync.Once
+	dummy     []byte
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{db: db}
+	return &UserRepository{db: db, cost: PasswordCost}
+}
+
+func (r *UserRepository) SetPasswordCost(cost int) {
+	r.cost = cost
+	r.dummyOnce = sync.Once{}
+}
+
+// dummyHash is compared against when a username does not exist, so unknown
+// users take as long to reject as a wrong password and can't be told apart.
+func (r *UserRepository) dummyHash() []byte {
+	r.dummyOnce.Do(func() {
+		r.dummy, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), r.cost)
+	})
+	return r.dummy
 }
 
 func (r *UserRepository) Create(user *domain.User) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	user.Sanitize()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), r.cost)
 	if err != nil {
 		return err
 	}
 
+	if user.Status == "" {
+		user.Status = domain.UserStatusActive
+	}
+
 	query := `
-		INSERT INTO users (username, password, email, created_at, last_login, is_admin)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO users (username, password, email, created_at, last_login, is_admin,
+		                   location, bio, homepage, signature, timezone, date_format,
+		                   status, invited_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.Exec(query,
@@ -34,8 +68,20 @@ func (r *UserRepository) Create(user *domain.User) error {
 		user.Email,
 		user.CreatedAt,
 		user.LastLogin,
-		user.IsAdmin)
+		user.IsAdmin,
+		user.Location,
+		user.Bio,
+		user.Homepage,
+		user.Signature,
+		user.TimeZone,
+		user.DateFormat,
+		user.Status,
+		user.InvitedBy)
 	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return domain.ErrUserExists
+		}
 		return err
 	}
 
@@ -48,21 +94,51 @@ func (r *UserRepository) Create(user *domain.User) error {
 	return nil
 }
 
-func (r *UserRepository) GetByID(id int) (*domain.User, error) {
-	user := &domain.User{}
-	query := `
-		SELECT id, username, email, created_at, last_login, is_admin
-		FROM users WHERE id = ?
-	`
+const userColumns = `
+	id, username, email, created_at, last_login, is_admin,
+	location, bio, homepage, signature, timezone, date_format,
+	totp_secret, totp_enabled, totp_last_step, status, invited_by,
+	email_verified, recording_opt_out
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	err := r.db.QueryRow(query, id).Scan(
+func scanUser(row rowScanner, extra ...interface{}) (*domain.User, error) {
+	user := &domain.User{}
+	dest := append([]interface{}{
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.CreatedAt,
 		&user.LastLogin,
 		&user.IsAdmin,
-	)
+		&user.Location,
+		&user.Bio,
+		&user.Homepage,
+		&user.Signature,
+		&user.TimeZone,
+		&user.DateFormat,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.TOTPLastStep,
+		&user.Status,
+		&user.InvitedBy,
+		&user.EmailVerified,
+		&user.RecordingOptOut,
+	}, extra...)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepository) GetByID(id int) (*domain.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+
+	user, err := scanUser(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found")
@@ -74,20 +150,23 @@ func (r *UserRepository) GetByID(id int) (*domain.User, error) {
 }
 
 func (r *UserRepository) GetByUsername(username string) (*domain.User, error) {
-	user := &domain.User{}
-	query := `
-		SELECT id, username, email, created_at, last_login, is_admin
-		FROM users WHERE username = ?
-	`
+	query := "SELECT " + userColumns + " FROM users WHERE username = ?"
 
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.CreatedAt,
-		&user.LastLogin,
-		&user.IsAdmin,
-	)
+	user, err := scanUser(r.db.QueryRow(query, username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE LOWER(email) = LOWER(?) ORDER BY email_verified DESC, id LIMIT 1"
+
+	user, err := scanUser(r.db.QueryRow(query, email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found")
@@ -99,13 +178,27 @@ func (r *UserRepository) GetByUsername(username string) (*domain.User, error) {
 }
 
 func (r *UserRepository) Update(user *domain.User) error {
+	user.Sanitize()
+
 	query := `
 		UPDATE users
-		SET username = ?, email = ?, is_admin = ?
+		SET username = ?, email = ?, is_admin = ?,
+		    location = ?, bio = ?, homepage = ?, signature = ?,
+		    timezone = ?, date_format = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.Exec(query, user.Username, user.Email, user.IsAdmin, user.ID)
+	_, err := r.db.Exec(query,
+		user.Username,
+		user.Email,
+		user.IsAdmin,
+		user.Location,
+		user.Bio,
+		user.Homepage,
+		user.Signature,
+		user.TimeZone,
+		user.DateFormat,
+		user.ID)
 	return err
 }
 
@@ -115,25 +208,14 @@ func (r *UserRepository) Delete(id int) error {
 }
 
 func (r *UserRepository) Authenticate(username, password string) (*domain.User, error) {
-	user := &domain.User{}
 	var hashedPassword string
 
-	query := `
-		SELECT id, username, password, email, created_at, last_login, is_admin
-		FROM users WHERE username = ?
-	`
+	query := "SELECT " + userColumns + ", password FROM users WHERE username = ?"
 
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID,
-		&user.Username,
-		&hashedPassword,
-		&user.Email,
-		&user.CreatedAt,
-		&user.LastLogin,
-		&user.IsAdmin,
-	)
+	user, err := scanUser(r.db.QueryRow(query, username), &hashedPassword)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			bcrypt.CompareHashAndPassword(r.dummyHash(), []byte(password))
 			return nil, errors.New("invalid credentials")
 		}
 		return nil, err
@@ -143,9 +225,121 @@ func (r *UserRepository) Authenticate(username, password string) (*domain.User,
 		return nil, errors.New("invalid credentials")
 	}
 
+	if cost, err := bcrypt.Cost([]byte(hashedPassword)); err == nil && cost < r.cost {
+		if err := r.UpdatePassword(user.ID, password); err != nil {
+			log.Printf("Failed to upgrade password hash for %s: %v", user.Username, err)
+		}
+	}
+
 	return user, nil
 }
 
+// SetTOTP stores the user's authenticator secret. Enrolment stores the secret
+// disabled until the user has confirmed a code; disabling clears it.
+func (r *UserRepository) SetTOTP(userID int, secret string, enabled bool) error {
+	query := "UPDATE users SET totp_secret = ?, totp_enabled = ?, totp_last_step = 0 WHERE id = ?"
+	result, err := r.db.Exec(query, secret, enabled, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// UseTOTPStep records step as used. It fails if the step is not newer than
+// the last one accepted, so the same code cannot be used twice.
+func (r *UserRepository) UseTOTPStep(userID int, step int64) error {
+	query := "UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?"
+	result, err := r.db.Exec(query, step, userID, step)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("code already used")
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdatePassword(userID int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), r.cost)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec("UPDATE users SET password = ? WHERE id = ?", string(hashedPassword), userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *UserRepository) SetStatus(userID int, status string) error {
+	result, err := r.db.Exec("UPDATE users SET status = ? WHERE id = ?", status, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *UserRepository) SetEmailVerified(userID int, verified bool) error {
+	result, err := r.db.Exec("UPDATE users SET email_verified = ? WHERE id = ?", verified, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *UserRepository) SetRecordingOptOut(userID int, optOut bool) error {
+	result, err := r.db.Exec("UPDATE users SET recording_opt_out = ? WHERE id = ?", optOut, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// GetByStatus returns users with the given status, oldest first, so the
+// approval queue is worked through in order.
+func (r *UserRepository) GetByStatus(status string) ([]*domain.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE status = ? ORDER BY created_at ASC, id ASC"
+
+	rows, err := r.db.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
 func (r *UserRepository) UpdateLastLogin(userID int) error {
 	query := "UPDATE users SET last_login = ? WHERE id = ?"
 	_, err := r.db.Exec(query, time.Now(), userID)
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func TestSubscriptionRepository(t *testing.T) {
+	repo := mocks.NewSubscriptionRepository()
+
+	repo.Subscribe(1, 2)
+	repo.Subscribe(1, 3)
+	if err := repo.Subscribe(1, 2); err != nil {
+		t.Errorf("Subscribing twice should not return error: %v", err)
+	}
+
+	if ok, _ := repo.IsSubscribed(1, 2); !ok {
+		t.Error("Expected user 1 to be subscribed to board 2")
+	}
+
+	ids, _ := repo.GetBoardIDs(1)
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", len(ids))
+	}
+
+	repo.Unsubscribe(1, 2)
+	if ok, _ := repo.IsSubscribed(1, 2); ok {
+		t.Error("Expected user 1 to be unsubscribed from board 2")
+	}
+}
+
+func TestSubscriptionRepository_Watches(t *testing.T) {
+	repo := mocks.NewSubscriptionRepository()
+
+	repo.Subscribe(1, 2)
+	repo.Subscribe(3, 2)
+	if ids, _ := repo.GetSubscribers(2); len(ids) != 2 || ids[0] != 1 {
+		t.Errorf("Expected users 1 and 3 to subscribe to board 2, got %v", ids)
+	}
+
+	repo.WatchThread(1, 10)
+	repo.WatchThread(2, 10)
+	if watching, _ := repo.IsWatching(1, 10); !watching {
+		t.Error("Expected user 1 to watch thread 10")
+	}
+
+	repo.UnwatchThread(1, 10)
+	if ids, _ := repo.GetWatchers(10); len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("Expected only user 2 to watch thread 10, got %v", ids)
+	}
+}
@@ -83,6 +83,21 @@ func TestUserRepository_GetByUsername(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetByEmail(t *testing.T) {
+	repo := mocks.NewUserRepository()
+	user := domain.NewUser("testuser", "Test@Example.com")
+	user.Password = "password123"
+	repo.Create(user)
+
+	if _, err := repo.GetByEmail("other@example.com"); err == nil {
+		t.Error("GetByEmail should return error for an unknown address")
+	}
+	found, err := repo.GetByEmail("test@example.COM")
+	if err != nil || found.ID != user.ID {
+		t.Errorf("Expected the user by email in any case, got %v: %v", found, err)
+	}
+}
+
 func TestUserRepository_Update(t *testing.T) {
 	repo := mocks.NewUserRepository()
 	user := domain.NewUser("testuser", "test@example.com")
@@ -188,3 +203,29 @@ func TestUserRepository_UpdateLastLogin(t *testing.T) {
 		t.Errorf("UpdateLastLogin should not return error: %v", err)
 	}
 }
+
+func TestUserRepository_UpdatePassword(t *testing.T) {
+	repo := mocks.NewUserRepository()
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+
+	// Test updating password for non-existent user
+	err := repo.UpdatePassword(999, "newpassword")
+	if err == nil {
+		t.Error("UpdatePassword should return error for non-existent user")
+	}
+
+	repo.Create(user)
+	err = repo.UpdatePassword(user.ID, "newpassword")
+	if err != nil {
+		t.Errorf("UpdatePassword should not return error: %v", err)
+	}
+
+	if _, err := repo.Authenticate("testuser", "newpassword"); err != nil {
+		t.Error("Authenticate should accept the new password")
+	}
+
+	if _, err := repo.Authenticate("testuser", "password123"); err == nil {
+		t.Error("Authenticate should reject the old password")
+	}
+}
@@ -0,0 +1,546 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxAPIBody      = 64 << 10
+
+	// tokenUseInterval is how often a token's last use is written down, so
+	// that a busy script does not write on every request.
+	tokenUseInterval = time.Minute
+)
+
+// APIServer serves a JSON API for programs that read and post to the
+// boards. A program authenticates with an API token its user created from
+// their profile, and may do what that user could do from a terminal.
+type APIServer struct {
+	services *ui.Services
+	config   *config.Config
+	repos    *repository.Manager
+	mux      *http.ServeMux
+	server   *http.Server
+}
+
+func NewAPIServer(services *ui.Services) *APIServer {
+	s := &APIServer{
+		services: services,
+		config:   services.Config,
+		repos:    services.Repos,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /api/v1/boards", s.anyone(s.listBoards))
+	s.mux.HandleFunc("GET /api/v1/boards/{id}", s.anyone(s.getBoard))
+	s.mux.HandleFunc("GET /api/v1/boards/{id}/threads", s.anyone(s.listThreads))
+	s.mux.HandleFunc("POST /api/v1/boards/{id}/threads", s.member(s.createThread))
+	s.mux.HandleFunc("GET /api/v1/threads/{id}", s.anyone(s.getThread))
+	s.mux.HandleFunc("POST /api/v1/threads/{id}/replies", s.member(s.createReply))
+	s.mux.HandleFunc("GET /api/v1/users/{username}", s.anyone(s.getUser))
+	s.mux.HandleFunc("GET /api/v1/me", s.member(s.getMe))
+	s.mux.HandleFunc("/", s.handleUnknown)
+
+	s.server = &http.Server{
+		Addr:              s.config.APIAddr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+func (s *APIServer) Start() error {
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *APIServer) Stop() {
+	s.server.Close()
+}
+
+// apiError is an error a client gets to see, sent as
+// {"error": {"code": ..., "message": ...}}.
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func errBadRequest(format string, args ...interface{}) *apiError {
+	return &apiError{http.StatusBadRequest, "bad_request", fmt.Sprintf(format, args...)}
+}
+
+func errNotFound(what string) *apiError {
+	return &apiError{http.StatusNotFound, "not_found", what + " not found"}
+}
+
+var (
+	errUnauthorized  = &apiError{http.StatusUnauthorized, "unauthorized", "an API token is required"}
+	errInvalidToken  = &apiError{http.StatusUnauthorized, "invalid_token", "the API token is not valid"}
+	errInactive      = &apiError{http.StatusForbidden, "account_inactive", "the account is not active"}
+	errInternalError = &apiError{http.StatusInternalServerError, "internal_error", "something went wrong, please try again later"}
+)
+
+// apiHandler handles a request for user, who is nil for a guest, and
+// returns the status and body of the response.
+type apiHandler func(r *http.Request, user *domain.User) (int, interface{}, error)
+
+// anyone lets guests in too when the BBS allows anonymous callers.
+func (s *APIServer) anyone(handler apiHandler) http.HandlerFunc {
+	return s.handle(handler, !s.config.AllowAnonymous)
+}
+
+// member needs an API token, as posting needs a login in the terminal.
+func (s *APIServer) member(handler apiHandler) http.HandlerFunc {
+	return s.handle(handler, true)
+}
+
+func (s *APIServer) handle(handler apiHandler, needUser bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.authenticate(r)
+		if err == nil && user == nil && needUser {
+			err = errUnauthorized
+		}
+
+		status, body := http.StatusOK, interface{}(nil)
+		if err == nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxAPIBody)
+			status, body, err = handler(r, user)
+		}
+
+		if err != nil {
+			var clientErr *apiError
+			if !errors.As(err, &clientErr) {
+				log.Printf("API request %s %s failed: %v", r.Method, r.URL.Path, err)
+				clientErr = errInternalError
+			}
+			if clientErr.Status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			}
+			status, body = clientErr.Status, map[string]*apiError{"error": clientErr}
+		}
+
+		writeJSON(w, status, body)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write API response: %v", err)
+	}
+}
+
+// handleUnknown answers requests no route matched, telling a path that does
+// not exist apart from one that does not take the method.
+func (s *APIServer) handleUnknown(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := s.mux.Handler(probe); pattern != "/" {
+			allowed = append(allowed, method)
+		}
+	}
+
+	err := errNotFound("endpoint")
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		err = &apiError{http.StatusMethodNotAllowed, "method_not_allowed", r.Method + " is not allowed here"}
+	}
+	writeJSON(w, err.Status, map[string]*apiError{"error": err})
+}
+
+// authenticate returns the user whose token the request carries, or nil if
+// it carries none. Tokens stop working while their user is banned or not
+// yet allowed in.
+func (s *APIServer) authenticate(r *http.Request) (*domain.User, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, nil
+	}
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, errInvalidToken
+	}
+
+	apiToken, err := s.repos.APIToken.GetByToken(token)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	user, err := s.repos.User.GetByID(apiToken.UserID)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	if !user.IsActive() {
+		return nil, errInactive
+	}
+
+	now := time.Now()
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= tokenUseInterval {
+		if err := s.repos.APIToken.MarkUsed(apiToken.ID, now); err != nil {
+			log.Printf("Failed to mark API token %d used: %v", apiToken.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+type apiBoard struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	Posts       int       `json:"post_count"`
+}
+
+type apiPost struct {
+	ID        int       `json:"id"`
+	BoardID   int       `json:"board_id"`
+	ThreadID  *int      `json:"thread_id,omitempty"`
+	Author    string    `json:"author"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Replies   *int      `json:"reply_count,omitempty"`
+}
+
+type apiUser struct {
+	Username    string     `json:"username"`
+	Email       string     `json:"email,omitempty"`
+	MemberSince time.Time  `json:"member_since"`
+	LastSeen    *time.Time `json:"last_seen,omitempty"`
+	Calls       int        `json:"calls"`
+	Posts       int        `json:"post_count"`
+	Admin       bool       `json:"admin"`
+	Location    string     `json:"location,omitempty"`
+	Homepage    string     `json:"homepage,omitempty"`
+	Bio         string     `json:"bio,omitempty"`
+}
+
+func (s *APIServer) toBoard(board *domain.Board) (*apiBoard, error) {
+	posts, err := s.repos.Post.CountByBoard(board.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &apiBoard{
+		ID:          board.ID,
+		Name:        board.Name,
+		Description: board.Description,
+		CreatedAt:   board.CreatedAt,
+		Posts:       posts,
+	}, nil
+}
+
+func toPost(post *domain.Post) *apiPost {
+	out := &apiPost{
+		ID:        post.ID,
+		BoardID:   post.BoardID,
+		ThreadID:  post.ReplyTo,
+		Author:    post.Username,
+		Title:     post.Title,
+		Content:   post.Content,
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
+	}
+	if post.ReplyTo == nil {
+		replies := post.Replies
+		out.Replies = &replies
+	}
+	return out
+}
+
+func toPosts(posts []*domain.Post) []*apiPost {
+	out := make([]*apiPost, 0, len(posts))
+	for _, post := range posts {
+		out = append(out, toPost(post))
+	}
+	return out
+}
+
+// toUser shows the same details about a user as their profile in the
+// terminal does.
+func (s *APIServer) toUser(user *domain.User) (*apiUser, error) {
+	calls, err := s.repos.Login.CountByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.repos.Post.CountByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &apiUser{
+		Username:    user.Username,
+		MemberSince: user.CreatedAt,
+		Calls:       calls,
+		Posts:       posts,
+		Admin:       user.IsAdmin,
+		Location:    user.Location,
+		Homepage:    user.Homepage,
+		Bio:         user.Bio,
+	}
+	if !user.LastLogin.IsZero() {
+		lastSeen := user.LastLogin
+		out.LastSeen = &lastSeen
+	}
+	return out, nil
+}
+
+// page reads the limit and cursor query parameters. Cursors are opaque to
+// clients; inside they hold the ID of the last post on the previous page.
+func page(r *http.Request) (limit, after int, err error) {
+	limit = defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, errBadRequest("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+		if err == nil {
+			after, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil || after < 1 {
+			return 0, 0, errBadRequest("invalid cursor")
+		}
+	}
+
+	return limit, after, nil
+}
+
+// nextCursor trims posts, fetched with one extra, to limit and returns the
+// cursor for the following page, or nil on the last page.
+func nextCursor(posts []*domain.Post, limit int) ([]*domain.Post, *string) {
+	if len(posts) <= limit {
+		return posts, nil
+	}
+	posts = posts[:limit]
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(posts[limit-1].ID)))
+	return posts, &cursor
+}
+
+func pathID(r *http.Request, what string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, errNotFound(what)
+	}
+	return id, nil
+}
+
+func (s *APIServer) board(r *http.Request) (*domain.Board, error) {
+	id, err := pathID(r, "board")
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.repos.Board.GetByID(id)
+	if err != nil {
+		return nil, errNotFound("board")
+	}
+	return board, nil
+}
+
+// thread returns the thread in the path. Replies are only reached through
+// their thread.
+func (s *APIServer) thread(r *http.Request) (*domain.Post, error) {
+	id, err := pathID(r, "thread")
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.repos.Post.GetByID(id)
+	if err != nil || post.ReplyTo != nil {
+		return nil, errNotFound("thread")
+	}
+	return post, nil
+}
+
+func (s *APIServer) listBoards(r *http.Request, user *domain.User) (int, interface{}, error) {
+	boards, err := s.repos.Board.GetAll()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	out := make([]*apiBoard, 0, len(boards))
+	for _, board := range boards {
+		apiBoard, err := s.toBoard(board)
+		if err != nil {
+			return 0, nil, err
+		}
+		out = append(out, apiBoard)
+	}
+	return http.StatusOK, map[string]interface{}{"boards": out}, nil
+}
+
+func (s *APIServer) getBoard(r *http.Request, user *domain.User) (int, interface{}, error) {
+	board, err := s.board(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	out, err := s.toBoard(board)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, out, nil
+}
+
+// listThreads pages through a board's threads, newest first. Unlike the
+// terminal, which lists the most recently active first, the order does not
+// change as threads get replies, so no thread is skipped between pages.
+func (s *APIServer) listThreads(r *http.Request, user *domain.User) (int, interface{}, error) {
+	board, err := s.board(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	limit, after, err := page(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	threads, err := s.repos.Post.GetThreadsBefore(board.ID, after, limit+1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	threads, cursor := nextCursor(threads, limit)
+	return http.StatusOK, map[string]interface{}{
+		"threads":     toPosts(threads),
+		"next_cursor": cursor,
+	}, nil
+}
+
+// getThread returns a thread with a page of its replies, oldest first.
+func (s *APIServer) getThread(r *http.Request, user *domain.User) (int, interface{}, error) {
+	thread, err := s.thread(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	limit, after, err := page(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	replies, err := s.repos.Post.GetRepliesAfter(thread.ID, after, limit+1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	replies, cursor := nextCursor(replies, limit)
+	return http.StatusOK, map[string]interface{}{
+		"thread":      toPost(thread),
+		"replies":     toPosts(replies),
+		"next_cursor": cursor,
+	}, nil
+}
+
+type newPost struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func readNewPost(r *http.Request) (*newPost, error) {
+	var body newPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, errBadRequest("invalid JSON body: %v", err)
+	}
+
+	body.Title = strings.TrimSpace(body.Title)
+	if strings.TrimSpace(body.Content) == "" {
+		return nil, errBadRequest("content cannot be empty")
+	}
+	return &body, nil
+}
+
+func (s *APIServer) createThread(r *http.Request, user *domain.User) (int, interface{}, error) {
+	board, err := s.board(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := readNewPost(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body.Title == "" {
+		return 0, nil, errBadRequest("title cannot be empty")
+	}
+
+	post := domain.NewPost(board.ID, user.ID, user.Username, body.Title, body.Content)
+	return s.createPost(user, post)
+}
+
+func (s *APIServer) createReply(r *http.Request, user *domain.User) (int, interface{}, error) {
+	thread, err := s.thread(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := readNewPost(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body.Title != "" {
+		return 0, nil, errBadRequest("replies do not have a title")
+	}
+
+	post := domain.NewReply(thread.BoardID, user.ID, user.Username, body.Content, thread.ID)
+	return s.createPost(user, post)
+}
+
+func (s *APIServer) createPost(user *domain.User, post *domain.Post) (int, interface{}, error) {
+	if err := s.services.CreatePost(user, post, nil); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, toPost(post), nil
+}
+
+func (s *APIServer) getUser(r *http.Request, user *domain.User) (int, interface{}, error) {
+	found, err := s.repos.User.GetByUsername(r.PathValue("username"))
+	if err != nil {
+		return 0, nil, errNotFound("user")
+	}
+
+	out, err := s.toUser(found)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, out, nil
+}
+
+// getMe returns the token's own user, including their email address.
+func (s *APIServer) getMe(r *http.Request, user *domain.User) (int, interface{}, error) {
+	out, err := s.toUser(user)
+	if err != nil {
+		return 0, nil, err
+	}
+	out.Email = user.Email
+	return http.StatusOK, out, nil
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type apiFixture struct {
+	*testFixture
+	server *APIServer
+	token  string
+}
+
+func newAPIFixture(t *testing.T, allowAnonymous bool) *apiFixture {
+	f := &apiFixture{testFixture: newTestFixture(t, allowAnonymous)}
+	f.server = NewAPIServer(f.services)
+	f.user.Signature = "alice was here"
+	f.repos.User.Update(f.user)
+	f.token = f.newToken(t, f.user)
+	return f
+}
+
+// newToken gives user an API token, and returns the token.
+func (f *apiFixture) newToken(t *testing.T, user *domain.User) string {
+	apiToken, token, _ := domain.NewAPIToken(user.ID, "test")
+	if err := f.repos.APIToken.Create(apiToken); err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+	return token
+}
+
+// do sends a request with token, if any, and decodes the JSON response.
+func (f *apiFixture) do(t *testing.T, method, path, token, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	f.server.server.Handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: expected a JSON response, got %q", method, path, ct)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q: %v", method, path, rec.Body.String(), err)
+	}
+	return rec.Code, decoded
+}
+
+func errorCode(body map[string]interface{}) string {
+	apiErr, _ := body["error"].(map[string]interface{})
+	code, _ := apiErr["code"].(string)
+	return code
+}
+
+func TestAPIServer_Authentication(t *testing.T) {
+	f := newAPIFixture(t, false)
+
+	status, body := f.do(t, "GET", "/api/v1/boards", "", "")
+	if status != http.StatusUnauthorized || errorCode(body) != "unauthorized" {
+		t.Errorf("Expected guests to be refused without anonymous access, got %d %v", status, body)
+	}
+
+	status, body = f.do(t, "GET", "/api/v1/boards", "bbs_0000", "")
+	if status != http.StatusUnauthorized || errorCode(body) != "invalid_token" {
+		t.Errorf("Expected an unknown token to be refused, got %d %v", status, body)
+	}
+
+	status, body = f.do(t, "GET", "/api/v1/boards", f.token, "")
+	if status != http.StatusOK || len(body["boards"].([]interface{})) != 1 {
+		t.Errorf("Expected the boards with a valid token, got %d %v", status, body)
+	}
+
+	apiToken, _ := f.repos.APIToken.GetByToken(f.token)
+	if apiToken.LastUsedAt == nil {
+		t.Error("Expected the token to be marked as used")
+	}
+
+	user, _ := f.repos.User.GetByUsername("alice")
+	user.Status = domain.UserStatusBanned
+	status, body = f.do(t, "GET", "/api/v1/boards", f.token, "")
+	if status != http.StatusForbidden || errorCode(body) != "account_inactive" {
+		t.Errorf("Expected a banned user's token to stop working, got %d %v", status, body)
+	}
+}
+
+func TestAPIServer_Guests(t *testing.T) {
+	f := newAPIFixture(t, true)
+
+	if status, _ := f.do(t, "GET", "/api/v1/boards", "", ""); status != http.StatusOK {
+		t.Errorf("Expected guests to read with anonymous access, got %d", status)
+	}
+
+	path := fmt.Sprintf("/api/v1/boards/%d/threads", f.general.ID)
+	status, body := f.do(t, "POST", path, "", `{"title":"Hi","content":"Hello"}`)
+	if status != http.StatusUnauthorized || errorCode(body) != "unauthorized" {
+		t.Errorf("Expected guests not to post, got %d %v", status, body)
+	}
+
+	if status, _ := f.do(t, "GET", "/api/v1/me", "", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected /me to need a token, got %d", status)
+	}
+}
+
+func TestAPIServer_Threads(t *testing.T) {
+	f := newAPIFixture(t, false)
+	path := fmt.Sprintf("/api/v1/boards/%d/threads", f.general.ID)
+
+	var ids []int
+	for i := 1; i <= 3; i++ {
+		status, body := f.do(t, "POST", path, f.token, fmt.Sprintf(`{"title":"Thread %d","content":"Hello"}`, i))
+		if status != http.StatusCreated {
+			t.Fatalf("Expected the thread to be created, got %d %v", status, body)
+		}
+		if body["author"] != "alice" || body["content"] != "Hello\n-- \nalice was here" {
+			t.Errorf("Expected the post signed by alice, got %v", body)
+		}
+		ids = append(ids, int(body["id"].(float64)))
+	}
+
+	status, body := f.do(t, "GET", path+"?limit=2", f.token, "")
+	threads := body["threads"].([]interface{})
+	if status != http.StatusOK || len(threads) != 2 || body["next_cursor"] == nil {
+		t.Fatalf("Expected a first page of 2 threads and a cursor, got %d %v", status, body)
+	}
+	if first := threads[0].(map[string]interface{}); int(first["id"].(float64)) != ids[2] {
+		t.Errorf("Expected the newest thread first, got %v", first)
+	}
+
+	status, body = f.do(t, "GET", path+"?limit=2&cursor="+body["next_cursor"].(string), f.token, "")
+	threads = body["threads"].([]interface{})
+	if status != http.StatusOK || len(threads) != 1 || body["next_cursor"] != nil {
+		t.Errorf("Expected the last thread and no cursor, got %d %v", status, body)
+	}
+
+	threadPath := fmt.Sprintf("/api/v1/threads/%d", ids[0])
+	status, body = f.do(t, "POST", threadPath+"/replies", f.token, `{"content":"A reply"}`)
+	if status != http.StatusCreated || int(body["thread_id"].(float64)) != ids[0] {
+		t.Fatalf("Expected the reply to be created, got %d %v", status, body)
+	}
+	replyID := int(body["id"].(float64))
+
+	status, body = f.do(t, "GET", threadPath, "", "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected reading a thread to need a token, got %d", status)
+	}
+
+	status, body = f.do(t, "GET", threadPath, f.token, "")
+	if status != http.StatusOK || len(body["replies"].([]interface{})) != 1 {
+		t.Errorf("Expected the thread with its reply, got %d %v", status, body)
+	}
+
+	status, body = f.do(t, "GET", fmt.Sprintf("/api/v1/threads/%d", replyID), f.token, "")
+	if status != http.StatusNotFound || errorCode(body) != "not_found" {
+		t.Errorf("Expected a reply not to be read as a thread, got %d %v", status, body)
+	}
+}
+
+func TestAPIServer_BadRequests(t *testing.T) {
+	f := newAPIFixture(t, false)
+	path := fmt.Sprintf("/api/v1/boards/%d/threads", f.general.ID)
+
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+	}{
+		{"unknown board", "GET", "/api/v1/boards/99/threads", "", http.StatusNotFound, "not_found"},
+		{"bad limit", "GET", path + "?limit=1000", "", http.StatusBadRequest, "bad_request"},
+		{"bad cursor", "GET", path + "?cursor=!!", "", http.StatusBadRequest, "bad_request"},
+		{"bad JSON", "POST", path, "{", http.StatusBadRequest, "bad_request"},
+		{"unknown field", "POST", path, `{"title":"Hi","content":"Hi","sticky":true}`, http.StatusBadRequest, "bad_request"},
+		{"no title", "POST", path, `{"content":"Hello"}`, http.StatusBadRequest, "bad_request"},
+		{"no content", "POST", path, `{"title":"Hi","content":"  "}`, http.StatusBadRequest, "bad_request"},
+		{"unknown endpoint", "GET", "/api/v1/nothing", "", http.StatusNotFound, "not_found"},
+		{"wrong method", "DELETE", path, "", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := f.do(t, tt.method, tt.path, f.token, tt.body)
+			if status != tt.status || errorCode(body) != tt.code {
+				t.Errorf("Expected %d %s, got %d %v", tt.status, tt.code, status, body)
+			}
+		})
+	}
+}
+
+func TestAPIServer_Users(t *testing.T) {
+	f := newAPIFixture(t, false)
+	f.createUser(t, "bob")
+
+	status, body := f.do(t, "GET", "/api/v1/users/bob", f.token, "")
+	if status != http.StatusOK || body["username"] != "bob" {
+		t.Fatalf("Expected bob's profile, got %d %v", status, body)
+	}
+	if _, ok := body["email"]; ok {
+		t.Error("Expected another user's email address to stay private")
+	}
+
+	status, body = f.do(t, "GET", "/api/v1/me", f.token, "")
+	if status != http.StatusOK || body["email"] != "alice@example.com" {
+		t.Errorf("Expected alice's own profile with the email address, got %d %v", status, body)
+	}
+
+	if status, _ := f.do(t, "GET", "/api/v1/users/nobody", f.token, ""); status != http.StatusNotFound {
+		t.Errorf("Expected an unknown user to be not found, got %d", status)
+	}
+}
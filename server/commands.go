@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"golang.org/x/crypto/ssh"
+)
+
+type sshCommand struct {
+	name  string
+	sysop bool
+	run   func(s *SSHServer, channel ssh.Channel, session *domain.Session, args []string) int
+}
+
+var sshCommands = []sshCommand{
+	{"audit-export", true, (*SSHServer).exportAudit},
+	{"ftn-toss", true, (*SSHServer).ftnToss},
+	{"ftn-scan", true, (*SSHServer).ftnScan},
+	{"qwk-download", false, (*SSHServer).qwkDownload},
+	{"qwk-upload", false, (*SSHServer).qwkUpload},
+}
+
+// runCommand runs a command sent with ssh exec instead of starting the BBS,
+// e.g. "ssh -p 2222 sysop@bbs audit-export -since 2026-01-01", and returns
+// its exit status.
+func (s *SSHServer) runCommand(channel ssh.Channel, session *domain.Session, command string) int {
+	user := session.CurrentUser()
+	if user == nil || user.ID == 0 || !user.IsActive() || session.PasswordResetID != 0 {
+		fmt.Fprintln(channel.Stderr(), "Commands are only available to logged in users.")
+		return 1
+	}
+
+	args := strings.Fields(command)
+	var available []string
+	for _, cmd := range sshCommands {
+		if cmd.sysop && !user.IsAdmin {
+			continue
+		}
+		if len(args) > 0 && args[0] == cmd.name {
+			return cmd.run(s, channel, session, args[1:])
+		}
+		available = append(available, cmd.name)
+	}
+
+	fmt.Fprintf(channel.Stderr(), "Unknown command %q. Available commands: %s\n", command, strings.Join(available, ", "))
+	return 127
+}
+
+// exportAudit writes the audit log as JSON lines, oldest first.
+func (s *SSHServer) exportAudit(channel ssh.Channel, session *domain.Session, args []string) int {
+	flags := flag.NewFlagSet("audit-export", flag.ContinueOnError)
+	flags.SetOutput(channel.Stderr())
+	actor := flags.String("user", "", "only entries by this username")
+	action := flags.String("action", "", "only this action, or a category such as auth")
+	target := flags.String("target", "", "only entries whose target contains this text")
+	since := flags.String("since", "", "only entries from this date on (YYYY-MM-DD)")
+	until := flags.String("until", "", "only entries before this date (YYYY-MM-DD)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := domain.AuditFilter{Actor: *actor, Action: *action, Target: *target}
+	for _, date := range []struct {
+		value string
+		dest  *time.Time
+	}{{*since, &filter.Since}, {*until, &filter.Until}} {
+		if date.value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", date.value, time.Local)
+		if err != nil {
+			fmt.Fprintf(channel.Stderr(), "Invalid date %q, use YYYY-MM-DD\n", date.value)
+			return 2
+		}
+		*date.dest = t
+	}
+
+	s.repos.RecordAudit(domain.NewAuditEntry(session.CurrentUser(), domain.AuditExport, "audit log", session.RemoteAddr,
+		map[string]interface{}{"args": strings.Join(args, " ")}))
+
+	w := bufio.NewWriter(channel)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	err := s.repos.Audit.Export(filter, func(entry *domain.AuditEntry) error {
+		return encoder.Encode(entry)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Export failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,416 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+)
+
+// feedSize is how many posts a feed holds.
+const feedSize = 50
+
+// FeedServer serves Atom and RSS 2.0 feeds of recent posts, site-wide, per
+// board and per thread. Feed readers cannot log in, so when guests are not
+// allowed a reader reads as a user by adding that user's feed token to the
+// feed's address.
+type FeedServer struct {
+	services *ui.Services
+	config   *config.Config
+	repos    *repository.Manager
+	mux      *http.ServeMux
+	server   *http.Server
+}
+
+func NewFeedServer(services *ui.Services) *FeedServer {
+	s := &FeedServer{
+		services: services,
+		config:   services.Config,
+		repos:    services.Repos,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /feeds/{format}", s.handle(s.siteFeed))
+	s.mux.HandleFunc("GET /feeds/boards/{id}/{format}", s.handle(s.boardFeed))
+	s.mux.HandleFunc("GET /feeds/threads/{id}/{format}", s.handle(s.threadFeed))
+
+	s.server = &http.Server{
+		Addr:              s.config.FeedAddr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+func (s *FeedServer) Start() error {
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *FeedServer) Stop() {
+	s.server.Close()
+}
+
+var (
+	errFeedTokenRequired = &apiError{http.StatusForbidden, "forbidden", "a feed token is required"}
+	errInvalidFeedToken  = &apiError{http.StatusForbidden, "forbidden", "the feed token is not valid"}
+)
+
+// feed is a list of posts, before it is written out as Atom or RSS.
+type feed struct {
+	title    string
+	subtitle string
+	updated  time.Time
+	entries  []*feedEntry
+}
+
+type feedEntry struct {
+	post  *domain.Post
+	title string
+	board string
+}
+
+type feedBuilder func(r *http.Request) (*feed, error)
+
+type feedFormat struct {
+	contentType string
+	render      func(f *feed, self string) ([]byte, error)
+}
+
+var feedFormats = map[string]feedFormat{
+	"atom": {"application/atom+xml; charset=utf-8", renderAtom},
+	"rss":  {"application/rss+xml; charset=utf-8", renderRSS},
+}
+
+// handle builds a feed in the format named by the path. Responses carry an
+// ETag and Last-Modified, so readers polling an unchanged feed get a 304.
+func (s *FeedServer) handle(build feedBuilder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := feedFormats[r.PathValue("format")]
+
+		var err error
+		var user *domain.User
+		if !ok {
+			err = errNotFound("feed")
+		} else {
+			user, err = s.authenticate(r)
+		}
+		if err == nil && user == nil && !s.config.AllowAnonymous {
+			err = errFeedTokenRequired
+		}
+
+		var f *feed
+		if err == nil {
+			f, err = build(r)
+		}
+		var body []byte
+		if err == nil {
+			body, err = format.render(f, s.config.FeedBaseURL()+r.URL.Path)
+		}
+
+		if err != nil {
+			var clientErr *apiError
+			if !errors.As(err, &clientErr) {
+				log.Printf("Feed request %s failed: %v", r.URL.Path, err)
+				clientErr = errInternalError
+			}
+			http.Error(w, clientErr.Message, clientErr.Status)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		if user != nil {
+			w.Header().Set("Cache-Control", "private")
+		}
+		http.ServeContent(w, r, "", f.updated, bytes.NewReader(body))
+	}
+}
+
+// authenticate returns the user whose feed token is in the query, or nil if
+// there is none.
+func (s *FeedServer) authenticate(r *http.Request) (*domain.User, error) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return nil, nil
+	}
+
+	feedToken, err := s.repos.FeedToken.GetByToken(token)
+	if err != nil {
+		return nil, errInvalidFeedToken
+	}
+
+	user, err := s.repos.User.GetByID(feedToken.UserID)
+	if err != nil {
+		return nil, errInvalidFeedToken
+	}
+	if !user.IsActive() {
+		return nil, errInactive
+	}
+	return user, nil
+}
+
+func (s *FeedServer) siteFeed(r *http.Request) (*feed, error) {
+	posts, err := s.repos.Post.GetRecent(feedSize)
+	if err != nil {
+		return nil, err
+	}
+	return s.newFeed(s.config.ServerName, "Recent posts", time.Time{}, posts)
+}
+
+func (s *FeedServer) boardFeed(r *http.Request) (*feed, error) {
+	id, err := pathID(r, "board")
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.repos.Board.GetByID(id)
+	if err != nil {
+		return nil, errNotFound("board")
+	}
+
+	threads, err := s.repos.Post.GetByBoard(board.ID, feedSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.newFeed(s.config.ServerName+": "+board.Name, board.Description, board.CreatedAt, threads)
+}
+
+func (s *FeedServer) threadFeed(r *http.Request) (*feed, error) {
+	id, err := pathID(r, "thread")
+	if err != nil {
+		return nil, err
+	}
+	thread, err := s.repos.Post.GetByID(id)
+	if err != nil || thread.ReplyTo != nil {
+		return nil, errNotFound("thread")
+	}
+
+	replies, err := s.repos.Post.GetReplies(thread.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first, like the other feeds
+	posts := make([]*domain.Post, 0, len(replies)+1)
+	for i := len(replies) - 1; i >= 0; i-- {
+		posts = append(posts, replies[i])
+	}
+	posts = append(posts, thread)
+	if len(posts) > feedSize {
+		posts = posts[:feedSize]
+	}
+	return s.newFeed(s.config.ServerName+": "+thread.Title, "", thread.CreatedAt, posts)
+}
+
+// newFeed makes a feed of posts. It was last updated when its newest post
+// was written or edited, or at since if it has none.
+func (s *FeedServer) newFeed(title, subtitle string, since time.Time, posts []*domain.Post) (*feed, error) {
+	boards, err := s.repos.Board.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	boardNames := make(map[int]string)
+	for _, board := range boards {
+		boardNames[board.ID] = board.Name
+	}
+
+	f := &feed{title: title, subtitle: subtitle}
+	threadTitles := make(map[int]string)
+	for _, post := range posts {
+		entry := &feedEntry{post: post, title: post.Title, board: boardNames[post.BoardID]}
+		if post.ReplyTo != nil {
+			threadTitle, ok := threadTitles[*post.ReplyTo]
+			if !ok {
+				if thread, err := s.repos.Post.GetByID(*post.ReplyTo); err == nil {
+					threadTitle = thread.Title
+				}
+				threadTitles[*post.ReplyTo] = threadTitle
+			}
+			entry.title = "Re: " + threadTitle
+		}
+
+		if post.UpdatedAt.After(f.updated) {
+			f.updated = post.UpdatedAt
+		}
+		f.entries = append(f.entries, entry)
+	}
+	if len(posts) == 0 {
+		f.updated = since
+	}
+	return f, nil
+}
+
+// entryID is a tag URI for a post. It stays the same when the post is edited,
+// so readers do not show it as a new post.
+func entryID(self string, post *domain.Post) string {
+	host := "localhost"
+	if u, err := url.Parse(self); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("tag:%s,%s:post/%d", host, post.CreatedAt.UTC().Format("2006-01-02"), post.ID)
+}
+
+// postHTML turns a post's plain text into escaped HTML, keeping its
+// paragraphs and line breaks.
+func postHTML(content string) string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(content), "\n\n") {
+		if paragraph = strings.Trim(paragraph, "\n"); paragraph != "" {
+			paragraphs = append(paragraphs, "<p>"+strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n")+"</p>")
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+func marshalFeed(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+type atomFeed struct {
+	XMLName   xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Subtitle  string       `xml:"subtitle,omitempty"`
+	Updated   string       `xml:"updated"`
+	Link      atomLink     `xml:"link"`
+	Generator string       `xml:"generator"`
+	Entries   []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+	Author    atomAuthor    `xml:"author"`
+	Category  *atomCategory `xml:"category"`
+	Content   atomContent   `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func renderAtom(f *feed, self string) ([]byte, error) {
+	out := &atomFeed{
+		ID:        self,
+		Title:     f.title,
+		Subtitle:  f.subtitle,
+		Updated:   f.updated.UTC().Format(time.RFC3339),
+		Link:      atomLink{Rel: "self", Type: "application/atom+xml", Href: self},
+		Generator: "gobbs",
+	}
+	for _, entry := range f.entries {
+		atom := &atomEntry{
+			ID:        entryID(self, entry.post),
+			Title:     entry.title,
+			Published: entry.post.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   entry.post.UpdatedAt.UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: entry.post.Username},
+			Content:   atomContent{Type: "html", Body: postHTML(entry.post.Content)},
+		}
+		if entry.board != "" {
+			atom.Category = &atomCategory{Term: entry.board}
+		}
+		out.Entries = append(out.Entries, atom)
+	}
+	return marshalFeed(out)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	AtomLink      atomLink   `xml:"atom:link"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Generator     string     `xml:"generator"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Creator     string  `xml:"dc:creator"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(f *feed, self string) ([]byte, error) {
+	description := f.subtitle
+	if description == "" {
+		description = f.title
+	}
+	out := &rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.title,
+			Link:          self,
+			Description:   description,
+			AtomLink:      atomLink{Rel: "self", Type: "application/rss+xml", Href: self},
+			LastBuildDate: f.updated.UTC().Format(time.RFC1123Z),
+			Generator:     "gobbs",
+		},
+	}
+	for _, entry := range f.entries {
+		out.Channel.Items = append(out.Channel.Items, &rssItem{
+			Title:       entry.title,
+			GUID:        rssGUID{Value: entryID(self, entry.post)},
+			PubDate:     entry.post.CreatedAt.UTC().Format(time.RFC1123Z),
+			Creator:     entry.post.Username,
+			Category:    entry.board,
+			Description: postHTML(entry.post.Content),
+		})
+	}
+	return marshalFeed(out)
+}
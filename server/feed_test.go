@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type feedFixture struct {
+	*testFixture
+	server *FeedServer
+	thread *domain.Post
+	token  string
+}
+
+func newFeedFixture(t *testing.T, allowAnonymous bool) *feedFixture {
+	f := &feedFixture{testFixture: newTestFixture(t, allowAnonymous)}
+	f.services.Config.FeedURL = "https://bbs.example.com"
+	f.server = NewFeedServer(f.services)
+
+	feedToken, token, _ := domain.NewFeedToken(f.user.ID)
+	f.repos.FeedToken.Set(feedToken)
+	f.token = token
+
+	f.thread = domain.NewPost(f.general.ID, f.user.ID, "alice", "Tips & <tricks>", "First line\nsecond <b>line</b>\n\nNew paragraph")
+	f.thread.CreatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	f.thread.UpdatedAt = f.thread.CreatedAt
+	f.repos.Post.Create(f.thread)
+	return f
+}
+
+func (f *feedFixture) reply(content string, at time.Time) *domain.Post {
+	reply := domain.NewReply(f.general.ID, f.user.ID, "alice", content, f.thread.ID)
+	reply.CreatedAt, reply.UpdatedAt = at, at
+	f.repos.Post.Create(reply)
+	return reply
+}
+
+func (f *feedFixture) get(path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	f.server.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+type testAtomFeed struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+	Entries []struct {
+		ID       string `xml:"id"`
+		Title    string `xml:"title"`
+		Updated  string `xml:"updated"`
+		Author   string `xml:"author>name"`
+		Category struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+		Content string `xml:"content"`
+	} `xml:"entry"`
+}
+
+func TestFeedServer_Atom(t *testing.T) {
+	f := newFeedFixture(t, true)
+	f.reply("Thanks!", f.thread.CreatedAt.Add(time.Hour))
+
+	rec := f.get("/feeds/threads/1/atom")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("Expected an Atom feed, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var feed testAtomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Invalid feed %s: %v", rec.Body.String(), err)
+	}
+	if feed.ID != "https://bbs.example.com/feeds/threads/1/atom" || feed.Updated != "2026-03-01T13:00:00Z" {
+		t.Errorf("Unexpected feed ID or updated time: %q %q", feed.ID, feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("Expected the thread and its reply, got %d entries", len(feed.Entries))
+	}
+
+	reply, thread := feed.Entries[0], feed.Entries[1]
+	if reply.Title != "Re: Tips & <tricks>" || reply.ID != "tag:bbs.example.com,2026-03-01:post/2" {
+		t.Errorf("Unexpected reply entry: %+v", reply)
+	}
+	if thread.ID != "tag:bbs.example.com,2026-03-01:post/1" || thread.Author != "alice" || thread.Category.Term != "general" {
+		t.Errorf("Unexpected thread entry: %+v", thread)
+	}
+	want := "<p>First line<br>\nsecond &lt;b&gt;line&lt;/b&gt;</p>\n<p>New paragraph</p>"
+	if thread.Content != want {
+		t.Errorf("Expected the content as escaped HTML %q, got %q", want, thread.Content)
+	}
+	if strings.Contains(rec.Body.String(), "<b>") {
+		t.Error("Expected markup in posts to be escaped")
+	}
+}
+
+func TestFeedServer_RSS(t *testing.T) {
+	f := newFeedFixture(t, true)
+	f.reply("Thanks!", f.thread.CreatedAt.Add(time.Hour))
+
+	rec := f.get("/feeds/rss")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/rss+xml") {
+		t.Fatalf("Expected an RSS feed, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var rss struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title   string `xml:"title"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+				Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &rss); err != nil {
+		t.Fatalf("Invalid feed %s: %v", rec.Body.String(), err)
+	}
+	if rss.Version != "2.0" || rss.Channel.LastBuildDate != "Sun, 01 Mar 2026 13:00:00 +0000" {
+		t.Errorf("Unexpected channel: %+v", rss.Channel)
+	}
+	if len(rss.Channel.Items) != 2 || rss.Channel.Items[0].Title != "Re: Tips & <tricks>" ||
+		rss.Channel.Items[1].GUID != "tag:bbs.example.com,2026-03-01:post/1" || rss.Channel.Items[1].Creator != "alice" {
+		t.Errorf("Unexpected items: %+v", rss.Channel.Items)
+	}
+
+	if rec := f.get("/feeds/boards/1/rss"); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Re: ") {
+		t.Errorf("Expected the board feed to list threads only, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFeedServer_NotFound(t *testing.T) {
+	f := newFeedFixture(t, true)
+	reply := f.reply("Thanks!", time.Now())
+
+	for _, path := range []string{"/feeds/json", "/feeds/boards/9/atom", "/feeds/threads/x/rss", fmt.Sprintf("/feeds/threads/%d/atom", reply.ID)} {
+		if rec := f.get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestFeedServer_Tokens(t *testing.T) {
+	f := newFeedFixture(t, false)
+
+	if rec := f.get("/feeds/atom"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected guests to be refused without anonymous access, got %d", rec.Code)
+	}
+	if rec := f.get("/feeds/atom?token=feed_0000"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected an unknown token to be refused, got %d", rec.Code)
+	}
+
+	rec := f.get("/feeds/atom?token=" + f.token)
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "private" {
+		t.Errorf("Expected a private feed with a token, got %d %v", rec.Code, rec.Header())
+	}
+	if strings.Contains(rec.Body.String(), f.token) {
+		t.Error("Expected the token to be left out of the feed")
+	}
+
+	f.user.Status = domain.UserStatusBanned
+	f.repos.User.Update(f.user)
+	if rec := f.get("/feeds/atom?token=" + f.token); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a banned user's token to stop working, got %d", rec.Code)
+	}
+}
+
+func TestFeedServer_ConditionalGet(t *testing.T) {
+	f := newFeedFixture(t, true)
+
+	rec := f.get("/feeds/boards/1/atom")
+	etag, modified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+	if etag == "" || modified != "Sun, 01 Mar 2026 12:00:00 GMT" {
+		t.Fatalf("Expected validators, got ETag %q Last-Modified %q", etag, modified)
+	}
+
+	if rec := f.get("/feeds/boards/1/atom", "If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected 304 for a matching ETag, got %d", rec.Code)
+	}
+	if rec := f.get("/feeds/boards/1/atom", "If-Modified-Since", modified); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 when not modified since, got %d", rec.Code)
+	}
+
+	f.thread.Content = "Edited"
+	f.thread.UpdatedAt = f.thread.CreatedAt.Add(time.Minute)
+	f.repos.Post.Update(f.thread)
+
+	if rec := f.get("/feeds/boards/1/atom", "If-None-Match", etag); rec.Code != http.StatusOK {
+		t.Errorf("Expected the edited feed for an old ETag, got %d", rec.Code)
+	}
+	if rec := f.get("/feeds/boards/1/atom", "If-Modified-Since", modified); rec.Code != http.StatusOK {
+		t.Errorf("Expected the edited feed after an edit, got %d", rec.Code)
+	}
+}
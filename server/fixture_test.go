@@ -0,0 +1,79 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/auth"
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/notify"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/test/mocks"
+	"github.com/leinonen/bbs/ui"
+)
+
+// testFixture is the services the protocol servers run on, backed by mock
+// repositories, with a "general" board and a user, alice. Each protocol's
+// fixture embeds it and adds its own server.
+type testFixture struct {
+	services *ui.Services
+	repos    *repository.Manager
+	general  *domain.Board
+	user     *domain.User
+}
+
+func newTestFixture(t *testing.T, allowAnonymous bool) *testFixture {
+	repos := &repository.Manager{
+		User:                 mocks.NewUserRepository(),
+		Board:                mocks.NewBoardRepository(),
+		Post:                 mocks.NewPostRepository(),
+		Login:                mocks.NewLoginRepository(),
+		APIToken:             mocks.NewAPITokenRepository(),
+		FeedToken:            mocks.NewFeedTokenRepository(),
+		Audit:                mocks.NewAuditRepository(),
+		FailedLogin:          mocks.NewFailedLoginRepository(),
+		PasswordReset:        mocks.NewPasswordResetRepository(),
+		RecoveryCode:         mocks.NewRecoveryCodeRepository(),
+		Message:              mocks.NewPrivateMessageRepository(),
+		NotificationSettings: mocks.NewNotificationSettingsRepository(),
+		Subscription:         mocks.NewSubscriptionRepository(),
+		EmailQueue:           mocks.NewEmailQueueRepository(),
+		Notification:         mocks.NewNotificationRepository(),
+	}
+
+	cfg := config.Default()
+	cfg.AllowAnonymous = allowAnonymous
+	sessions := domain.NewSessionManager()
+	f := &testFixture{repos: repos}
+	f.services = &ui.Services{
+		Config:   cfg,
+		Repos:    repos,
+		Sessions: sessions,
+		Auth:     auth.NewAuthenticator(repos, domain.NewLoginThrottle(domain.DefaultThrottlePolicy(), domain.DefaultThrottlePolicy())),
+		Notifier: notify.NewDispatcher(repos, sessions, nil, cfg.ServerName),
+	}
+
+	f.general = domain.NewBoard("general", "General discussion")
+	repos.Board.Create(f.general)
+	f.user = f.createUser(t, "alice")
+	return f
+}
+
+func (f *testFixture) createUser(t *testing.T, username string) *domain.User {
+	user := domain.NewUser(username, username+"@example.com")
+	user.Password = "password123"
+	if err := f.repos.User.Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+// post adds a post by alice to board, as a reply if replyTo is not 0.
+func (f *testFixture) post(board *domain.Board, replyTo int, title, content string) *domain.Post {
+	post := domain.NewPost(board.ID, f.user.ID, f.user.Username, title, content)
+	if replyTo != 0 {
+		post = domain.NewReply(board.ID, f.user.ID, f.user.Username, content, replyTo)
+	}
+	f.repos.Post.Create(post)
+	return post
+}
@@ -0,0 +1,470 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/ftn"
+	"github.com/leinonen/bbs/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// ftnScanSetting keeps the last post scanned on each board, by board ID.
+	ftnScanSetting = "ftn_scan_"
+	// ftnSerialSetting keeps the last MSGID serial number given out.
+	ftnSerialSetting = "ftn_msgid_serial"
+	ftnScanBatch     = 500
+)
+
+var errFTNDisabled = errors.New("echomail is not set up; see ftn in the config")
+
+// ftnToss imports the echomail packets in the inbound directory, e.g. from
+// cron after the mailer has polled: "ssh -p 2222 sysop@bbs ftn-toss".
+func (s *SSHServer) ftnToss(channel ssh.Channel, session *domain.Session, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(channel.Stderr(), "Usage: ftn-toss")
+		return 2
+	}
+
+	result, err := tossInbound(s.services)
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Toss failed: %v\n", err)
+		return 1
+	}
+	for _, err := range result.errs {
+		fmt.Fprintln(channel.Stderr(), err)
+	}
+	fmt.Fprintf(channel, "Tossed %d packets: %d messages imported, %d duplicates.\n",
+		result.packets, result.imported, result.dupes)
+	if len(result.errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// ftnScan writes the new posts on echo area boards to a packet in the
+// outbound directory: "ssh -p 2222 sysop@bbs ftn-scan".
+func (s *SSHServer) ftnScan(channel ssh.Channel, session *domain.Session, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(channel.Stderr(), "Usage: ftn-scan")
+		return 2
+	}
+
+	sent, errs, err := scanOutbound(s.services)
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Scan failed: %v\n", err)
+		return 1
+	}
+	for _, err := range errs {
+		fmt.Fprintln(channel.Stderr(), err)
+	}
+	fmt.Fprintf(channel, "Scanned %d messages out.\n", sent)
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+type tossResult struct {
+	packets  int
+	imported int
+	dupes    int
+	errs     []error
+}
+
+// tossInbound imports the packets in the inbound directory and removes them.
+// A packet that cannot be read, or is not for this node, is renamed to .bad
+// and left for the sysop.
+func tossInbound(services *ui.Services) (*tossResult, error) {
+	cfg := services.Config.FTN
+	if cfg.Address == "" {
+		return nil, errFTNDisabled
+	}
+	local, _ := ftn.ParseAddress(cfg.Address)
+	owner, err := services.Repos.User.GetByUsername(cfg.User)
+	if err != nil {
+		return nil, fmt.Errorf("imported posts belong to user %q, who does not exist", cfg.User)
+	}
+	boards, err := areaBoards(services)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(cfg.Inbound)
+	if err != nil {
+		return nil, err
+	}
+	result := &tossResult{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".pkt") {
+			continue
+		}
+		path := filepath.Join(cfg.Inbound, entry.Name())
+		packet, err := readPacketFile(path, local, cfg.Password)
+		if err != nil {
+			result.errs = append(result.errs, fmt.Errorf("%s: %v", entry.Name(), err))
+			if err := os.Rename(path, path+".bad"); err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		result.packets++
+		for i, msg := range packet.Messages {
+			imported, err := tossMessage(services, owner, boards, msg)
+			switch {
+			case err != nil:
+				result.errs = append(result.errs, fmt.Errorf("%s message %d (%q): %v", entry.Name(), i+1, msg.Subject, err))
+			case imported:
+				result.imported++
+			default:
+				result.dupes++
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func readPacketFile(path string, local ftn.Address, password string) (*ftn.Packet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	packet, err := ftn.ReadPacket(file)
+	if err != nil {
+		return nil, err
+	}
+	to := packet.To
+	// old type 2 packets leave the zone out
+	if to.Zone == 0 {
+		to.Zone = local.Zone
+	}
+	if to != local {
+		return nil, fmt.Errorf("packet is for %s, not this node", packet.To)
+	}
+	if !strings.EqualFold(packet.Password, password) {
+		return nil, fmt.Errorf("wrong packet password from %s", packet.From)
+	}
+	return packet, nil
+}
+
+// areaBoards returns the boards carrying echo areas, by upper case AREA tag.
+func areaBoards(services *ui.Services) (map[string]*domain.Board, error) {
+	all, err := services.Repos.Board.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	boards := make(map[string]*domain.Board)
+	for _, board := range all {
+		if tag, ok := services.Config.FTN.Areas[board.Name]; ok {
+			boards[strings.ToUpper(tag)] = board
+		}
+	}
+	return boards, nil
+}
+
+// tossMessage posts an echomail message as owner, under the name it was
+// written by. A reply to a message known here joins its thread. It returns
+// false for a message already imported.
+func tossMessage(services *ui.Services, owner *domain.User, boards map[string]*domain.Board, msg *ftn.Message) (bool, error) {
+	repos := services.Repos
+	if msg.Area == "" {
+		return false, errors.New("netmail is not supported")
+	}
+	board, ok := boards[msg.Area]
+	if !ok {
+		return false, fmt.Errorf("area %s is not carried here", msg.Area)
+	}
+
+	msgid := msg.MSGID
+	if msgid == "" {
+		// without a MSGID, the same message tossed twice still gets the same ID
+		sum := crc32.ChecksumIEEE([]byte(msg.Area + "\x00" + msg.FromName + "\x00" + msg.Subject + "\x00" + msg.Body))
+		msgid = fmt.Sprintf("%s %08x", msg.From, sum)
+	}
+	if _, err := repos.Echomail.GetByMSGID(msgid); err == nil {
+		return false, nil
+	}
+
+	body := msg.Body
+	if !strings.Contains("\n"+body, "\n * Origin: ") {
+		body += "\n * Origin: (" + msg.From.String() + ")"
+	}
+	// anyone on the network can sign with any name, so the name carries the
+	// node it came from and cannot pass for a member here
+	author := msg.FromName
+	if author == "" {
+		author = "Unknown"
+	}
+	author += "@" + originAddress(msg).String()
+
+	var post *domain.Post
+	if threadID := replyThread(services, msg.Reply, board); threadID != 0 {
+		post = domain.NewReply(board.ID, owner.ID, author, body, threadID)
+	} else {
+		subject := msg.Subject
+		if subject == "" {
+			subject = "(no subject)"
+		}
+		post = domain.NewPost(board.ID, owner.ID, author, subject, body)
+	}
+	post.Author = author
+	if !msg.Date.IsZero() && msg.Date.Before(post.CreatedAt) {
+		post.CreatedAt = msg.Date.Local()
+		post.UpdatedAt = post.CreatedAt
+	}
+
+	// without its MSGID the post would be imported again when the message
+	// is resent
+	err := services.CreatePost(nil, post, func(post *domain.Post) error {
+		return repos.Echomail.Add(&domain.EchomailMessage{
+			MSGID:     msgid,
+			PostID:    post.ID,
+			Area:      msg.Area,
+			Imported:  true,
+			CreatedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// originAddress returns the node a message was written on: the address in
+// its MSGID, or else the node that sent it.
+func originAddress(msg *ftn.Message) ftn.Address {
+	if origin, _, ok := strings.Cut(msg.MSGID, " "); ok {
+		if address, err := ftn.ParseAddress(origin); err == nil {
+			return address
+		}
+	}
+	return msg.From
+}
+
+// replyThread returns the thread on board that the message with MSGID reply
+// belongs to, or 0 if it is not known here.
+func replyThread(services *ui.Services, reply string, board *domain.Board) int {
+	if reply == "" {
+		return 0
+	}
+	parent, err := services.Repos.Echomail.GetByMSGID(reply)
+	if err != nil {
+		return 0
+	}
+	post, err := services.Repos.Post.GetByID(parent.PostID)
+	if err != nil || post.BoardID != board.ID {
+		return 0
+	}
+	if post.ReplyTo != nil {
+		return *post.ReplyTo
+	}
+	return post.ID
+}
+
+// scanOutbound packs the posts written here on area boards since the last
+// scan for the uplink. The first scan of a board only marks where to start,
+// so its history is not sent. It returns the number of messages sent and
+// the boards that could not be scanned.
+func scanOutbound(services *ui.Services) (int, []error, error) {
+	cfg := services.Config.FTN
+	if cfg.Address == "" {
+		return 0, nil, errFTNDisabled
+	}
+	repos := services.Repos
+	local, _ := ftn.ParseAddress(cfg.Address)
+	uplink, _ := ftn.ParseAddress(cfg.Uplink)
+	origin := cfg.Origin
+	if origin == "" {
+		origin = services.Config.ServerName
+	}
+
+	serial, err := ftnSerial(services)
+	if err != nil {
+		return 0, nil, err
+	}
+	packet := &ftn.Packet{From: local, To: uplink, Password: cfg.Password, Created: time.Now()}
+	var sent []*domain.EchomailMessage
+	msgids := make(map[int]string)
+	pointers := make(map[int]int)
+	var errs []error
+
+	boards, err := areaBoards(services)
+	if err != nil {
+		return 0, nil, err
+	}
+	tags := make([]string, 0, len(boards))
+	for tag := range boards {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for name, tag := range cfg.Areas {
+		if boards[strings.ToUpper(tag)] == nil {
+			errs = append(errs, fmt.Errorf("area %s: there is no board %q", strings.ToUpper(tag), name))
+		}
+	}
+
+	for _, tag := range tags {
+		board := boards[tag]
+		value, err := repos.Settings.Get(ftnScanSetting + strconv.Itoa(board.ID))
+		if err != nil {
+			return 0, nil, err
+		}
+		if value == "" {
+			_, high, err := repos.Post.GetIDRange(board.ID)
+			if err != nil {
+				return 0, nil, err
+			}
+			pointers[board.ID] = high
+			continue
+		}
+		last, _ := strconv.Atoi(value)
+
+		for {
+			posts, err := repos.Post.GetByBoardAfter(board.ID, last, ftnScanBatch)
+			if err != nil {
+				return 0, nil, err
+			}
+			if len(posts) == 0 {
+				break
+			}
+			for _, post := range posts {
+				last = post.ID
+				if _, err := repos.Echomail.GetByPost(post.ID); err == nil {
+					// imported, so it came from the network
+					continue
+				}
+				serial++
+				msg := echomailMessage(services, post, msgids, local, uplink, origin)
+				msg.Area = tag
+				msg.MSGID = fmt.Sprintf("%s %08x", local, serial)
+				msgids[post.ID] = msg.MSGID
+				packet.Messages = append(packet.Messages, msg)
+				sent = append(sent, &domain.EchomailMessage{MSGID: msg.MSGID, PostID: post.ID, Area: tag, CreatedAt: time.Now()})
+			}
+		}
+		pointers[board.ID] = last
+	}
+
+	if len(packet.Messages) > 0 {
+		if err := writePacketFile(cfg.Outbound, packet); err != nil {
+			return 0, nil, err
+		}
+		if err := repos.Settings.Set(ftnSerialSetting, strconv.FormatInt(serial, 10)); err != nil {
+			return 0, nil, err
+		}
+	}
+	// once the packet is out, the posts are recorded so they are not sent again
+	for _, msg := range sent {
+		if err := repos.Echomail.Add(msg); err != nil {
+			return 0, nil, err
+		}
+	}
+	for boardID, postID := range pointers {
+		if err := repos.Settings.Set(ftnScanSetting+strconv.Itoa(boardID), strconv.Itoa(postID)); err != nil {
+			return 0, nil, err
+		}
+	}
+	return len(sent), errs, nil
+}
+
+// ftnSerial returns the last MSGID serial given out. Serials start from the
+// time, so they stay unique if the setting is ever lost.
+func ftnSerial(services *ui.Services) (int64, error) {
+	value, err := services.Repos.Settings.Get(ftnSerialSetting)
+	if err != nil {
+		return 0, err
+	}
+	last, _ := strconv.ParseInt(value, 10, 64)
+	if now := time.Now().Unix(); now > last {
+		last = now
+	}
+	return last, nil
+}
+
+// echomailMessage makes a message from a post. A reply names the MSGID of
+// its thread, from this scan or from the echomail table.
+func echomailMessage(services *ui.Services, post *domain.Post, msgids map[int]string, local, uplink ftn.Address, origin string) *ftn.Message {
+	msg := &ftn.Message{
+		From:     local,
+		To:       uplink,
+		Date:     post.CreatedAt,
+		FromName: post.Username,
+		ToName:   "All",
+		Subject:  post.Title,
+		Body:     strings.TrimRight(post.Content, "\n") + "\n\n" + ftn.OriginLines(origin, local),
+		SeenBy:   []string{seenBy(local, uplink)},
+		Path:     []string{local.Short()},
+	}
+	if post.ReplyTo != nil {
+		if thread, err := services.Repos.Post.GetByID(*post.ReplyTo); err == nil {
+			msg.ToName = thread.Username
+			if i := strings.LastIndex(thread.Author, "@"); i > 0 {
+				msg.ToName = thread.Author[:i]
+			}
+			msg.Subject = "Re: " + thread.Title
+		}
+		msg.Reply = msgids[*post.ReplyTo]
+		if parent, err := services.Repos.Echomail.GetByPost(*post.ReplyTo); err == nil {
+			msg.Reply = parent.MSGID
+		}
+	}
+	return msg
+}
+
+// seenBy lists the nodes that have seen a message, in net order.
+func seenBy(nodes ...ftn.Address) string {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Net != nodes[j].Net {
+			return nodes[i].Net < nodes[j].Net
+		}
+		return nodes[i].Node < nodes[j].Node
+	})
+	var short []string
+	for _, node := range nodes {
+		short = append(short, node.Short())
+	}
+	return strings.Join(short, " ")
+}
+
+// writePacketFile writes the packet to a new file in dir, under a temporary
+// name until it is complete so a mailer never picks up half a packet.
+func writePacketFile(dir string, packet *ftn.Packet) error {
+	tmp, err := os.CreateTemp(dir, "gobbs-*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := packet.Write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// packets are named by time in hex; a taken name moves on to the next
+	name := packet.Created.Unix()
+	for {
+		path := filepath.Join(dir, fmt.Sprintf("%08x.pkt", uint32(name)))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return os.Rename(tmp.Name(), path)
+		}
+		name++
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/ftn"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+var (
+	ftnLocal  = ftn.Address{Zone: 21, Net: 1, Node: 100}
+	ftnUplink = ftn.Address{Zone: 21, Net: 1, Node: 1}
+)
+
+func newFTNFixture(t *testing.T) (*nntpFixture, *domain.User) {
+	f := newNNTPFixture(t, true)
+	f.repos.Echomail = mocks.NewEchomailRepository()
+	f.repos.Settings = mocks.NewSettingsRepository()
+	f.server.services.Config.FTN = config.FTN{
+		Address:  ftnLocal.String(),
+		Uplink:   ftnUplink.String(),
+		Password: "secret",
+		Inbound:  t.TempDir(),
+		Outbound: t.TempDir(),
+		User:     "echomail",
+		Areas:    map[string]string{"general": "GOBBS_GENERAL", "Tech Talk": "fsx_tech"},
+	}
+	return f, f.createUser(t, "echomail")
+}
+
+// writeInbound puts a packet from the uplink in the inbound directory.
+func writeInbound(t *testing.T, f *nntpFixture, name, password string, messages ...*ftn.Message) string {
+	t.Helper()
+	path := filepath.Join(f.server.services.Config.FTN.Inbound, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	packet := &ftn.Packet{From: ftnUplink, To: ftnLocal, Password: password, Created: time.Now(), Messages: messages}
+	if err := packet.Write(file); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readOutbound reads and removes the packets in the outbound directory.
+func readOutbound(t *testing.T, f *nntpFixture) []*ftn.Packet {
+	t.Helper()
+	paths, _ := filepath.Glob(filepath.Join(f.server.services.Config.FTN.Outbound, "*"))
+	var packets []*ftn.Packet
+	for _, path := range paths {
+		if !strings.HasSuffix(path, ".pkt") {
+			t.Errorf("Unexpected file %s in outbound", path)
+			continue
+		}
+		file, _ := os.Open(path)
+		packet, err := ftn.ReadPacket(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		packets = append(packets, packet)
+		os.Remove(path)
+	}
+	return packets
+}
+
+func TestFTNScan(t *testing.T) {
+	f, _ := newFTNFixture(t)
+	f.post(f.general, 0, "Old", "Before the first scan")
+
+	if sent, errs, err := scanOutbound(f.server.services); err != nil || sent != 0 || len(errs) != 0 {
+		t.Fatalf("Expected the first scan to send nothing, got %d: %v %v", sent, errs, err)
+	}
+
+	thread := f.post(f.general, 0, "Hello", "First post\n")
+	reply := f.post(f.general, thread.ID, "", "A reply")
+	f.post(f.tech, 0, "Compilers", "Elsewhere")
+
+	sent, _, err := scanOutbound(f.server.services)
+	if err != nil || sent != 3 {
+		t.Fatalf("Expected 3 messages scanned, got %d: %v", sent, err)
+	}
+	packets := readOutbound(t, f)
+	if len(packets) != 1 || len(packets[0].Messages) != 3 {
+		t.Fatalf("Expected a packet of 3 messages, got %d packets", len(packets))
+	}
+	packet := packets[0]
+	if packet.From != ftnLocal || packet.To != ftnUplink || packet.Password != "SECRET" {
+		t.Errorf("Unexpected packet header %+v", packet)
+	}
+
+	first, second, third := packet.Messages[0], packet.Messages[1], packet.Messages[2]
+	if third.Area != "GOBBS_GENERAL" || first.Area != "FSX_TECH" {
+		t.Errorf("Expected the areas in tag order, got %s and %s", first.Area, third.Area)
+	}
+	if second.Subject != "Hello" || second.FromName != "alice" || second.ToName != "All" ||
+		!strings.HasPrefix(second.MSGID, "21:1/100 ") || second.Reply != "" {
+		t.Errorf("Unexpected thread message %+v", second)
+	}
+	if second.Body != "First post\n\n--- gobbs\n * Origin: Go BBS System (21:1/100)" {
+		t.Errorf("Unexpected body %q", second.Body)
+	}
+	if third.Reply != second.MSGID || third.Subject != "Re: Hello" || third.ToName != "alice" {
+		t.Errorf("Expected the reply to refer to the thread, got %+v", third)
+	}
+	if len(third.SeenBy) != 1 || third.SeenBy[0] != "1/1 1/100" || third.Path[0] != "1/100" {
+		t.Errorf("Unexpected SEEN-BY %q or PATH %q", third.SeenBy, third.Path)
+	}
+	if msg, err := f.repos.Echomail.GetByPost(reply.ID); err != nil || msg.MSGID != third.MSGID || msg.Imported {
+		t.Errorf("Expected the reply recorded as sent, got %+v: %v", msg, err)
+	}
+
+	if sent, _, _ := scanOutbound(f.server.services); sent != 0 || len(readOutbound(t, f)) != 0 {
+		t.Errorf("Expected nothing new to scan, got %d", sent)
+	}
+
+	// MSGIDs stay unique across scans
+	f.post(f.general, thread.ID, "", "Later")
+	scanOutbound(f.server.services)
+	if later := readOutbound(t, f)[0].Messages[0]; later.MSGID <= third.MSGID || later.Reply != second.MSGID {
+		t.Errorf("Expected a new MSGID after %s, got %+v", third.MSGID, later)
+	}
+}
+
+func TestFTNToss(t *testing.T) {
+	f, owner := newFTNFixture(t)
+	local := f.post(f.general, 0, "Local", "Written here")
+	f.repos.Echomail.Add(&domain.EchomailMessage{MSGID: "21:1/100 00000001", PostID: local.ID, Area: "GOBBS_GENERAL"})
+
+	date := time.Date(2026, 3, 14, 15, 9, 26, 0, time.UTC)
+	thread := &ftn.Message{From: ftnUplink, To: ftnLocal, Date: date, FromName: "Björn", ToName: "All", Subject: "From afar",
+		Area: "GOBBS_GENERAL", MSGID: "21:1/1 0000abcd", Body: "Hello\n\n--- mail\n * Origin: Far BBS (21:1/1)"}
+	messages := []*ftn.Message{
+		thread,
+		{From: ftnUplink, Area: "GOBBS_GENERAL", MSGID: "21:1/1 0000abce", Reply: thread.MSGID, FromName: "Eve",
+			Subject: "Re: From afar", Body: "Agreed"},
+		{From: ftnUplink, Area: "GOBBS_GENERAL", MSGID: "21:1/1 0000abcf", Reply: "21:1/100 00000001", FromName: "Eve",
+			Subject: "Re: Local", Body: "Nice"},
+		{From: ftnUplink, Area: "FSX_TECH", FromName: "Sam", Subject: "No MSGID", Body: "Old software"},
+		{From: ftnUplink, Area: "ELSEWHERE", MSGID: "21:1/1 0000abd0", Subject: "Not carried", Body: "Text"},
+		{From: ftnUplink, Subject: "Netmail", Body: "Text"},
+	}
+	writeInbound(t, f, "0001.pkt", "secret", messages...)
+	writeInbound(t, f, "0002.PKT", "SECRET", thread, messages[3])
+	bad := writeInbound(t, f, "0003.pkt", "wrong", thread)
+
+	result, err := tossInbound(f.server.services)
+	if err != nil {
+		t.Fatalf("tossInbound failed: %v", err)
+	}
+	if result.packets != 2 || result.imported != 4 || result.dupes != 2 || len(result.errs) != 3 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if !strings.Contains(result.errs[2].Error(), "wrong packet password") {
+		t.Errorf("Expected the bad packet refused, got %v", result.errs)
+	}
+	if _, err := os.Stat(bad + ".bad"); err != nil {
+		t.Errorf("Expected the bad packet kept aside: %v", err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(f.server.services.Config.FTN.Inbound, "*.pkt")); len(left) != 0 {
+		t.Errorf("Expected tossed packets removed, found %v", left)
+	}
+
+	imported, _ := f.repos.Echomail.GetByMSGID(thread.MSGID)
+	post, _ := f.repos.Post.GetByID(imported.PostID)
+	if post.UserID != owner.ID || post.Author != "Björn@21:1/1" || post.Username != "Björn@21:1/1" || post.Title != "From afar" ||
+		!post.CreatedAt.Equal(date) || !strings.HasSuffix(post.Content, " * Origin: Far BBS (21:1/1)") {
+		t.Errorf("Unexpected imported post %+v", post)
+	}
+	replies, _ := f.repos.Post.GetReplies(post.ID)
+	if len(replies) != 1 || replies[0].Content != "Agreed\n * Origin: (21:1/1)" {
+		t.Errorf("Expected the reply in the imported thread, got %d replies", len(replies))
+	}
+	if replies, _ := f.repos.Post.GetReplies(local.ID); len(replies) != 1 || replies[0].Author != "Eve@21:1/1" {
+		t.Errorf("Expected the reply to the local post in its thread, got %d replies", len(replies))
+	}
+
+	// imported posts are not scanned back out
+	f.repos.Settings.Set(ftnScanSetting+"1", "1")
+	if sent, _, _ := scanOutbound(f.server.services); sent != 0 {
+		t.Errorf("Expected imported posts not to be sent back, got %d", sent)
+	}
+	f.post(f.general, post.ID, "", "Welcome")
+	scanOutbound(f.server.services)
+	if reply := readOutbound(t, f)[0].Messages[0]; reply.ToName != "Björn" || reply.Reply != thread.MSGID {
+		t.Errorf("Expected the reply addressed to Björn, got %+v", reply)
+	}
+
+	f.server.services.Config.FTN.User = "nobody"
+	if _, err := tossInbound(f.server.services); err == nil {
+		t.Error("Expected tossing without the owner account to fail")
+	}
+	f.server.services.Config.FTN.Address = ""
+	if _, err := tossInbound(f.server.services); err != errFTNDisabled {
+		t.Errorf("Expected tossing to need ftn set up, got %v", err)
+	}
+}
+
+// failingEchomail cannot record messages, as with a full disk.
+type failingEchomail struct {
+	repository.EchomailRepository
+}
+
+func (failingEchomail) Add(*domain.EchomailMessage) error {
+	return errors.New("disk full")
+}
+
+func TestFTNToss_RecordFails(t *testing.T) {
+	f, _ := newFTNFixture(t)
+	f.repos.Echomail = failingEchomail{f.repos.Echomail}
+	msg := &ftn.Message{From: ftnUplink, Area: "GOBBS_GENERAL", MSGID: "21:1/1 0000abcd", FromName: "Eve", Subject: "Hi", Body: "Text"}
+	writeInbound(t, f, "0001.pkt", "secret", msg)
+
+	result, err := tossInbound(f.server.services)
+	if err != nil || result.imported != 0 || len(result.errs) != 1 || !strings.Contains(result.errs[0].Error(), "disk full") {
+		t.Fatalf("Expected the message to fail, got %+v: %v", result, err)
+	}
+	if posts, _ := f.repos.Post.GetByBoardAfter(f.general.ID, 0, 10); len(posts) != 0 {
+		t.Errorf("Expected the post removed, got %d posts", len(posts))
+	}
+}
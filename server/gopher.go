@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+)
+
+const (
+	// gopherTimeout bounds a whole request: reading the selector and
+	// sending the reply.
+	gopherTimeout = 30 * time.Second
+	// maxSelector bounds the selector line. RFC 1436 allows 255 characters.
+	maxSelector = 1024
+	// gopherPageSize is how many threads a board menu lists.
+	gopherPageSize = 50
+)
+
+// GopherServer is a read-only view of the boards for Gopher clients
+// (RFC 1436). Selectors are made from board and post IDs, so bookmarks keep
+// working across restarts:
+//
+//	""                           the board index
+//	/boards/{id}                 the newest threads on a board
+//	/boards/{id}/before/{post}   older threads
+//	/threads/{id}                a thread and its replies as text
+type GopherServer struct {
+	config   *config.Config
+	repos    *repository.Manager
+	host     string
+	port     string
+	listener net.Listener
+	// clients bounds the connections open at once to max_users.
+	clients chan struct{}
+}
+
+func NewGopherServer(services *ui.Services) *GopherServer {
+	host, port := services.Config.GopherMenuHost()
+	return &GopherServer{
+		config:  services.Config,
+		repos:   services.Repos,
+		host:    host,
+		port:    port,
+		clients: make(chan struct{}, services.Config.MaxUsers),
+	}
+}
+
+func (s *GopherServer) Start() error {
+	listener, err := net.Listen("tcp", s.config.GopherAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	s.listener = listener
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.listener == nil {
+				return nil
+			}
+			log.Printf("Failed to accept Gopher connection: %v", err)
+			continue
+		}
+
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *GopherServer) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+}
+
+func (s *GopherServer) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(gopherTimeout))
+
+	w := textproto.NewWriter(bufio.NewWriter(conn))
+	select {
+	case s.clients <- struct{}{}:
+		defer func() { <-s.clients }()
+	default:
+		s.sendError(w, "Too many connections, try again later")
+		return
+	}
+
+	r := textproto.NewReader(bufio.NewReader(io.LimitReader(conn, maxSelector)))
+	line, err := r.ReadLine()
+	if err != nil {
+		return
+	}
+	// search terms and Gopher+ flags follow a tab
+	selector, _, _ := strings.Cut(line, "\t")
+
+	if err := s.serve(w, selector); err != nil {
+		var notFound gopherNotFound
+		if errors.As(err, &notFound) {
+			s.sendError(w, err.Error())
+			return
+		}
+		log.Printf("Gopher request %q from %s failed: %v", selector, remoteHost(conn.RemoteAddr()), err)
+		s.sendError(w, "Internal error, please try again later")
+	}
+}
+
+// gopherNotFound is a selector naming nothing on the BBS.
+type gopherNotFound string
+
+func (e gopherNotFound) Error() string {
+	return string(e) + " not found"
+}
+
+// serve sends the menu or document a selector names.
+func (s *GopherServer) serve(w *textproto.Writer, selector string) error {
+	parts := strings.Split(strings.Trim(selector, "/"), "/")
+
+	switch {
+	case selector == "" || selector == "/":
+		return s.boardIndex(w)
+	case len(parts) == 2 && parts[0] == "boards":
+		return s.boardMenu(w, parts[1], 0)
+	case len(parts) == 4 && parts[0] == "boards" && parts[2] == "before":
+		before, err := strconv.Atoi(parts[3])
+		if err != nil || before <= 0 {
+			return gopherNotFound("page")
+		}
+		return s.boardMenu(w, parts[1], before)
+	case len(parts) == 2 && parts[0] == "threads":
+		return s.thread(w, parts[1])
+	}
+	return gopherNotFound("selector")
+}
+
+func (s *GopherServer) boardIndex(w *textproto.Writer) error {
+	boards, err := s.repos.Board.GetAll()
+	if err != nil {
+		return err
+	}
+
+	m := s.newMenu()
+	m.info(s.config.ServerName)
+	m.info("")
+	for _, board := range boards {
+		m.link('1', fmt.Sprintf("%-20s %s", board.Name, board.Description), fmt.Sprintf("/boards/%d", board.ID))
+	}
+	if len(boards) == 0 {
+		m.info("There are no boards yet.")
+	}
+	return m.send(w)
+}
+
+// boardMenu lists a board's threads, newest first, starting below the
+// thread ID before if it is not 0.
+func (s *GopherServer) boardMenu(w *textproto.Writer, id string, before int) error {
+	board, err := s.findBoard(id)
+	if err != nil {
+		return err
+	}
+	threads, err := s.repos.Post.GetThreadsBefore(board.ID, before, gopherPageSize+1)
+	if err != nil {
+		return err
+	}
+	more := len(threads) > gopherPageSize
+	if more {
+		threads = threads[:gopherPageSize]
+	}
+
+	m := s.newMenu()
+	m.info(board.Name)
+	m.info(board.Description)
+	m.info("")
+	for _, thread := range threads {
+		m.link('0', fmt.Sprintf("%s - %s, %s, %s", thread.Title, thread.Username,
+			thread.CreatedAt.Format("2006-01-02"), replyCount(thread.Replies)),
+			fmt.Sprintf("/threads/%d", thread.ID))
+	}
+	if len(threads) == 0 {
+		m.info("No threads here yet.")
+	}
+	m.info("")
+	if more {
+		m.link('1', "Older threads", fmt.Sprintf("/boards/%d/before/%d", board.ID, threads[len(threads)-1].ID))
+	}
+	m.link('1', "All boards", "/")
+	return m.send(w)
+}
+
+// thread sends a thread and its replies as one text document.
+func (s *GopherServer) thread(w *textproto.Writer, id string) error {
+	postID, err := strconv.Atoi(id)
+	if err != nil {
+		return gopherNotFound("thread")
+	}
+	thread, err := s.repos.Post.GetByID(postID)
+	if err != nil || thread.ReplyTo != nil {
+		return gopherNotFound("thread")
+	}
+	board, err := s.repos.Board.GetByID(thread.BoardID)
+	if err != nil {
+		return err
+	}
+	replies, err := s.repos.Post.GetReplies(thread.ID)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n\n", thread.Title, board.Name)
+	writeGopherPost(&b, thread)
+	for _, reply := range replies {
+		b.WriteString("\n" + strings.Repeat("-", 70) + "\n\n")
+		writeGopherPost(&b, reply)
+	}
+
+	dw := w.DotWriter()
+	io.WriteString(dw, b.String())
+	return dw.Close()
+}
+
+func writeGopherPost(b *strings.Builder, post *domain.Post) {
+	fmt.Fprintf(b, "From: %s\nDate: %s\n\n", post.Username, post.CreatedAt.Format("2006-01-02 15:04 MST"))
+	b.WriteString(strings.TrimRight(strings.ReplaceAll(post.Content, "\r\n", "\n"), "\n") + "\n")
+}
+
+func (s *GopherServer) findBoard(id string) (*domain.Board, error) {
+	boardID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, gopherNotFound("board")
+	}
+	board, err := s.repos.Board.GetByID(boardID)
+	if err != nil {
+		return nil, gopherNotFound("board")
+	}
+	return board, nil
+}
+
+func replyCount(n int) string {
+	if n == 1 {
+		return "1 reply"
+	}
+	return fmt.Sprintf("%d replies", n)
+}
+
+func (s *GopherServer) sendError(w *textproto.Writer, message string) {
+	m := s.newMenu()
+	m.item('3', message, "", "error.host", "1")
+	m.send(w)
+}
+
+// gopherMenu is a Gopher directory: one item per line.
+type gopherMenu struct {
+	host, port string
+	lines      []string
+}
+
+func (s *GopherServer) newMenu() *gopherMenu {
+	return &gopherMenu{host: s.host, port: s.port}
+}
+
+func (m *gopherMenu) item(kind byte, display, selector, host, port string) {
+	m.lines = append(m.lines, fmt.Sprintf("%c%s\t%s\t%s\t%s", kind, gopherField(display), gopherField(selector), host, port))
+}
+
+// link adds an item on this server.
+func (m *gopherMenu) link(kind byte, display, selector string) {
+	m.item(kind, display, selector, m.host, m.port)
+}
+
+// info adds a line of text that is not a link.
+func (m *gopherMenu) info(text string) {
+	m.item('i', text, "", "fake", "0")
+}
+
+func (m *gopherMenu) send(w *textproto.Writer) error {
+	dw := w.DotWriter()
+	for _, line := range m.lines {
+		io.WriteString(dw, line+"\n")
+	}
+	return dw.Close()
+}
+
+// gopherField keeps tabs and line breaks out of a menu field.
+func gopherField(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, text)
+}
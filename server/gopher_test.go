@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type gopherFixture struct {
+	*testFixture
+	server *GopherServer
+}
+
+func newGopherFixture(t *testing.T) *gopherFixture {
+	f := &gopherFixture{testFixture: newTestFixture(t, true)}
+	f.services.Config.GopherAddr = ":7070"
+	f.services.Config.GopherHost = "bbs.example.com"
+	f.server = NewGopherServer(f.services)
+	return f
+}
+
+// request sends a selector and returns the reply's lines, without the
+// terminating dot.
+func (f *gopherFixture) request(t *testing.T, selector string) []string {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	go f.server.handleConnection(server)
+
+	fmt.Fprintf(client, "%s\r\n", selector)
+	data, err := io.ReadAll(bufio.NewReader(client))
+	if err != nil {
+		t.Fatalf("Failed to read the reply to %q: %v", selector, err)
+	}
+	reply := string(data)
+	if !strings.HasSuffix(reply, "\r\n.\r\n") {
+		t.Fatalf("Expected the reply to %q to end with a dot, got %q", selector, reply)
+	}
+	return strings.Split(strings.TrimSuffix(reply, "\r\n.\r\n"), "\r\n")
+}
+
+func TestGopherServer_Menus(t *testing.T) {
+	f := newGopherFixture(t)
+	other := domain.NewBoard("Tech\tTalk", "Technology")
+	f.repos.Board.Create(other)
+
+	var threads []*domain.Post
+	for i := 0; i < gopherPageSize+2; i++ {
+		thread := domain.NewPost(f.general.ID, 1, "alice", fmt.Sprintf("Thread %d", i), "Content")
+		f.repos.Post.Create(thread)
+		threads = append(threads, thread)
+	}
+	f.repos.Post.Create(domain.NewReply(f.general.ID, 2, "bob", "Reply", threads[len(threads)-1].ID))
+	// the mock does not count replies
+	threads[len(threads)-1].Replies = 1
+
+	index := f.request(t, "")
+	if index[0] != "i"+f.server.config.ServerName+"\t\tfake\t0" {
+		t.Errorf("Expected the server name first, got %q", index[0])
+	}
+	if !containsLine(index, "1general              General discussion\t/boards/1\tbbs.example.com\t7070") ||
+		!containsLine(index, "1Tech Talk            Technology\t/boards/2\tbbs.example.com\t7070") {
+		t.Errorf("Unexpected board index: %q", index)
+	}
+
+	menu := f.request(t, "/boards/1")
+	newest := threads[len(threads)-1]
+	want := fmt.Sprintf("0Thread %d - alice, %s, 1 reply\t/threads/%d\tbbs.example.com\t7070",
+		len(threads)-1, newest.CreatedAt.Format("2006-01-02"), newest.ID)
+	if menu[3] != want {
+		t.Errorf("Expected the newest thread first:\n%q\ngot\n%q", want, menu[3])
+	}
+	older := fmt.Sprintf("1Older threads\t/boards/1/before/%d\tbbs.example.com\t7070", threads[2].ID)
+	if !containsLine(menu, older) {
+		t.Fatalf("Expected a link to older threads, got %q", menu)
+	}
+
+	menu = f.request(t, fmt.Sprintf("/boards/1/before/%d\t+", threads[2].ID))
+	if !strings.HasPrefix(menu[3], "0Thread 1 ") || !strings.HasPrefix(menu[4], "0Thread 0 ") || containsLine(menu, older) {
+		t.Errorf("Expected the two oldest threads on the last page, got %q", menu)
+	}
+
+	if menu := f.request(t, "/boards/2"); !containsLine(menu, "iNo threads here yet.\t\tfake\t0") {
+		t.Errorf("Expected an empty board, got %q", menu)
+	}
+}
+
+func TestGopherServer_Thread(t *testing.T) {
+	f := newGopherFixture(t)
+	thread := domain.NewPost(f.general.ID, 1, "alice", "Hello", "First line\n.dotted line")
+	f.repos.Post.Create(thread)
+	reply := domain.NewReply(f.general.ID, 2, "bob", "A reply", thread.ID)
+	f.repos.Post.Create(reply)
+
+	text := strings.Join(f.request(t, "/threads/1"), "\n")
+	for _, want := range []string{"Hello\ngeneral\n", "From: alice\n", "First line\n..dotted line\n", "From: bob\n", "\n\nA reply"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in the thread, got:\n%s", want, text)
+		}
+	}
+
+	for _, selector := range []string{"/threads/2", "/threads/99", "/threads/x", "/boards/9", "/boards/1/before/x", "/secret"} {
+		reply := f.request(t, selector)
+		if len(reply) != 1 || !strings.HasPrefix(reply[0], "3") || !strings.Contains(reply[0], "not found") {
+			t.Errorf("Expected an error for %q, got %q", selector, reply)
+		}
+	}
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
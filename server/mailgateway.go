@@ -0,0 +1,357 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+)
+
+const (
+	// mailIdleTimeout is how long a client may stay silent. RFC 5321 asks
+	// for at least five minutes.
+	mailIdleTimeout = 5 * time.Minute
+	// maxMailLine bounds a command line; RFC 5321 allows 512 octets.
+	maxMailLine = 4096
+	// maxMailSize bounds a message, headers included.
+	maxMailSize = 1 << 20
+	// maxMailRecipients bounds the boards one message is posted to.
+	maxMailRecipients = 10
+)
+
+// MailGatewayServer posts email sent to board-name@domain. It speaks SMTP
+// (RFC 5321) and, after LHLO, LMTP (RFC 2033), so a mail server can hand
+// messages on to it. Only the trusted relays may connect, and a message is
+// only posted when the envelope sender is its From address, and a relay's
+// Authentication-Results header says that address passed DMARC, DKIM or
+// SPF.
+type MailGatewayServer struct {
+	services *ui.Services
+	config   *config.Config
+	repos    *repository.Manager
+	listener net.Listener
+	// clients bounds the connections open at once to max_users.
+	clients chan struct{}
+}
+
+func NewMailGatewayServer(services *ui.Services) *MailGatewayServer {
+	return &MailGatewayServer{
+		services: services,
+		config:   services.Config,
+		repos:    services.Repos,
+		clients:  make(chan struct{}, services.Config.MaxUsers),
+	}
+}
+
+func (s *MailGatewayServer) Start() error {
+	listener, err := net.Listen("tcp", s.config.MailGatewayAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	s.listener = listener
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.listener == nil {
+				return nil
+			}
+			log.Printf("Failed to accept mail gateway connection: %v", err)
+			continue
+		}
+
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *MailGatewayServer) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+}
+
+// domain is the mail domain boards are addressed at: mail_gateway_domain,
+// or else the domain of mail_from.
+func (s *MailGatewayServer) domain() string {
+	if s.config.MailGatewayDomain != "" {
+		return s.config.MailGatewayDomain
+	}
+	return messageHost(s.config)
+}
+
+// trusted reports whether addr is one of mail_gateway_trusted_relays.
+func (s *MailGatewayServer) trusted(addr net.Addr) bool {
+	ip := net.ParseIP(remoteHost(addr))
+	if ip == nil {
+		return false
+	}
+	for _, relay := range s.config.MailGatewayTrustedRelays {
+		if _, network, err := net.ParseCIDR(relay); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(relay)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MailGatewayServer) handleConnection(netConn net.Conn) {
+	defer netConn.Close()
+
+	select {
+	case s.clients <- struct{}{}:
+		defer func() { <-s.clients }()
+	default:
+		fmt.Fprintf(netConn, "421 Too many connections, try again later\r\n")
+		return
+	}
+
+	if !s.trusted(netConn.RemoteAddr()) {
+		fmt.Fprintf(netConn, "554 %s only takes mail from its relays\r\n", s.domain())
+		return
+	}
+
+	c := newMailConn(s, netConn)
+	c.reply(220, "%s %s mail gateway ready", s.domain(), s.config.ServerName)
+
+	for {
+		netConn.SetReadDeadline(time.Now().Add(mailIdleTimeout))
+		c.limit.N = maxMailLine
+		line, err := c.r.ReadLine()
+		if err != nil {
+			return
+		}
+		if !c.command(line) {
+			return
+		}
+	}
+}
+
+// mailConn is one client's connection and the message it is sending.
+type mailConn struct {
+	server     *MailGatewayServer
+	limit      *io.LimitedReader
+	r          *textproto.Reader
+	w          *textproto.Writer
+	remoteAddr string
+
+	greeted bool
+	lmtp    bool // LHLO was sent, so DATA gets a reply per recipient
+
+	inMail bool   // MAIL was accepted
+	sender string // its reverse-path
+	boards []*domain.Board
+}
+
+func newMailConn(s *MailGatewayServer, conn net.Conn) *mailConn {
+	limit := &io.LimitedReader{R: conn, N: maxMailLine}
+	return &mailConn{
+		server:     s,
+		limit:      limit,
+		r:          textproto.NewReader(bufio.NewReader(limit)),
+		w:          textproto.NewWriter(bufio.NewWriter(conn)),
+		remoteAddr: remoteHost(conn.RemoteAddr()),
+	}
+}
+
+func (c *mailConn) reply(code int, format string, args ...interface{}) {
+	c.w.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+func (c *mailConn) reset() {
+	c.inMail = false
+	c.sender = ""
+	c.boards = nil
+}
+
+// command runs one command line, and reports whether to read another.
+func (c *mailConn) command(line string) bool {
+	name, args, _ := strings.Cut(line, " ")
+	name = strings.ToUpper(name)
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case "HELO", "EHLO", "LHLO":
+		c.hello(name, args)
+	case "MAIL":
+		c.mail(args)
+	case "RCPT":
+		c.rcpt(args)
+	case "DATA":
+		return c.data()
+	case "RSET":
+		c.reset()
+		c.reply(250, "OK")
+	case "NOOP":
+		c.reply(250, "OK")
+	case "VRFY":
+		c.reply(252, "Cannot VRFY, send the message and see")
+	case "HELP":
+		c.reply(214, "Send email to board-name@%s to post on a board", c.server.domain())
+	case "QUIT":
+		c.reply(221, "Bye")
+		return false
+	default:
+		c.reply(502, "Command not implemented")
+	}
+	return true
+}
+
+func (c *mailConn) hello(name, args string) {
+	if args == "" {
+		c.reply(501, "Syntax: %s hostname", name)
+		return
+	}
+	c.reset()
+	c.greeted = true
+	c.lmtp = name == "LHLO"
+
+	if name == "HELO" {
+		c.reply(250, "%s", c.server.domain())
+		return
+	}
+	c.w.PrintfLine("250-%s", c.server.domain())
+	c.w.PrintfLine("250-8BITMIME")
+	c.w.PrintfLine("250-PIPELINING")
+	c.reply(250, "SIZE %d", maxMailSize)
+}
+
+func (c *mailConn) mail(args string) {
+	if !c.greeted {
+		c.reply(503, "Send HELO first")
+		return
+	}
+	if c.inMail {
+		c.reply(503, "Nested MAIL command")
+		return
+	}
+	// the sender is checked against the From header once the message is in
+	sender, params, ok := mailPath(args, "FROM:")
+	if !ok {
+		c.reply(501, "Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if size, err := strconv.Atoi(value); strings.EqualFold(key, "SIZE") && err == nil && size > maxMailSize {
+			c.reply(552, "Message larger than %d bytes", maxMailSize)
+			return
+		}
+	}
+	c.inMail = true
+	c.sender = sender
+	c.reply(250, "OK")
+}
+
+func (c *mailConn) rcpt(args string) {
+	if !c.inMail {
+		c.reply(503, "Send MAIL first")
+		return
+	}
+	path, _, ok := mailPath(args, "TO:")
+	if !ok {
+		c.reply(501, "Syntax: RCPT TO:<address>")
+		return
+	}
+	if len(c.boards) == maxMailRecipients {
+		c.reply(452, "Too many recipients")
+		return
+	}
+
+	local, host, _ := strings.Cut(path, "@")
+	if !strings.EqualFold(host, c.server.domain()) {
+		c.reply(550, "Relaying denied, this gateway only takes mail for %s", c.server.domain())
+		return
+	}
+	board, err := findBoard(c.server.services, local)
+	if err != nil {
+		log.Printf("Mail gateway failed to find board %q: %v", local, err)
+		c.reply(451, "Local error, try again later")
+		return
+	}
+	if board == nil {
+		c.reply(550, "No such board %s", local)
+		return
+	}
+	for _, b := range c.boards {
+		if b.ID == board.ID {
+			c.reply(250, "OK")
+			return
+		}
+	}
+	c.boards = append(c.boards, board)
+	c.reply(250, "OK")
+}
+
+func (c *mailConn) data() bool {
+	if !c.inMail || len(c.boards) == 0 {
+		c.reply(503, "Send RCPT first")
+		return true
+	}
+	c.reply(354, "End data with <CR><LF>.<CR><LF>")
+	c.limit.N = maxMailSize
+	data, err := io.ReadAll(c.r.DotReader())
+	if err != nil {
+		if c.limit.N == 0 {
+			c.reply(552, "Message larger than %d bytes", maxMailSize)
+		}
+		return false
+	}
+
+	sender, boards := c.sender, c.boards
+	c.reset()
+	results := c.server.deliver(sender, data, boards)
+
+	if c.lmtp {
+		for _, err := range results {
+			c.replyResult(err)
+		}
+		return true
+	}
+	// SMTP has a single reply, so it only fails if every board refused the
+	// message; otherwise the sender would send it again to all of them
+	for _, err := range results {
+		if err == nil {
+			c.reply(250, "OK, posted")
+			return true
+		}
+	}
+	c.replyResult(results[0])
+	return true
+}
+
+func (c *mailConn) replyResult(err error) {
+	switch err.(type) {
+	case nil:
+		c.reply(250, "OK, posted")
+	case mailRejection:
+		c.reply(550, "%v", err)
+	default:
+		c.reply(451, "Local error, try again later")
+	}
+}
+
+// mailPath parses the "FROM:<address> params" argument of MAIL or RCPT.
+func mailPath(args, prefix string) (string, []string, bool) {
+	if len(args) < len(prefix) || !strings.EqualFold(args[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	fields := strings.Fields(strings.TrimSpace(args[len(prefix):]))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "<") || !strings.HasSuffix(fields[0], ">") {
+		return "", nil, false
+	}
+	return strings.Trim(fields[0], "<>"), fields[1:], true
+}
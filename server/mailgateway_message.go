@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// mailRejection is a reason a message will never be posted, as opposed to
+// a local failure the sender should retry after.
+type mailRejection string
+
+func (r mailRejection) Error() string { return string(r) }
+
+// inboundMail is an email read for posting.
+type inboundMail struct {
+	user    *domain.User
+	subject string
+	content string   // the text without quotes
+	replyTo []string // Message-IDs the message replies to, nearest first
+}
+
+// deliver posts a message from sender to each of boards, and returns what
+// became of it on each.
+func (s *MailGatewayServer) deliver(sender string, data []byte, boards []*domain.Board) []error {
+	results := make([]error, len(boards))
+	m, err := s.readMail(sender, data)
+	for i, board := range boards {
+		if err != nil {
+			results[i] = err
+			continue
+		}
+		post, err := s.mailPost(m, board)
+		if err == nil {
+			err = s.services.CreatePost(m.user, post, nil)
+			if err != nil {
+				log.Printf("Failed to save emailed post from %s: %v", m.user.Username, err)
+			}
+		}
+		results[i] = err
+	}
+	return results
+}
+
+// readMail parses a message from the user whose verified address is in
+// From, which must also be the envelope sender and have been authenticated
+// by a relay.
+func (s *MailGatewayServer) readMail(sender string, data []byte) (*inboundMail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, mailRejection(fmt.Sprintf("Invalid message: %v", err))
+	}
+	// vacation replies and bounces are not posts
+	if auto := msg.Header.Get("Auto-Submitted"); auto != "" && !strings.EqualFold(auto, "no") {
+		return nil, mailRejection("Automatic messages are not posted")
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) != 1 {
+		return nil, mailRejection("The message needs one From address")
+	}
+	if !strings.EqualFold(sender, from[0].Address) {
+		return nil, mailRejection(fmt.Sprintf("The envelope sender <%s> is not the From address %s", sender, from[0].Address))
+	}
+	if !authenticated(msg.Header, s.config.MailGatewayAuthservID, from[0].Address) {
+		return nil, mailRejection(fmt.Sprintf("%s was not authenticated by DMARC, DKIM or SPF", from[0].Address))
+	}
+	user, err := s.repos.User.GetByEmail(from[0].Address)
+	if err != nil || !user.EmailVerified || !user.IsActive() {
+		return nil, mailRejection(fmt.Sprintf("Unknown sender %s; send from the verified address of your account", from[0].Address))
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, mailRejection(fmt.Sprintf("Invalid Subject header: %v", err))
+	}
+	text, err := mailText(msg.Header, msg.Body, 0)
+	if err != nil {
+		return nil, mailRejection(err.Error())
+	}
+	content := stripQuotes(text)
+	if content == "" {
+		return nil, mailRejection("The message has no text of its own")
+	}
+
+	m := &inboundMail{user: user, subject: strings.TrimSpace(subject), content: content}
+	// the last reference is the message replied to
+	m.replyTo = strings.Fields(msg.Header.Get("In-Reply-To"))
+	references := strings.Fields(msg.Header.Get("References"))
+	for i := len(references) - 1; i >= 0; i-- {
+		m.replyTo = append(m.replyTo, references[i])
+	}
+	return m, nil
+}
+
+// mailPost makes a post on board from an email. An email replying to a post
+// goes to that post's thread; any other starts a thread.
+func (s *MailGatewayServer) mailPost(m *inboundMail, board *domain.Board) (*domain.Post, error) {
+	user := m.user
+	for _, id := range m.replyTo {
+		parent := findPost(s.services, id)
+		if parent == nil {
+			continue
+		}
+		if parent.BoardID != board.ID {
+			parentBoard, err := s.repos.Board.GetByID(parent.BoardID)
+			if err != nil {
+				return nil, err
+			}
+			return nil, mailRejection(fmt.Sprintf("Replies must be sent to %s@%s", groupName(parentBoard), s.domain()))
+		}
+		// replies are flat, so a reply to a reply goes to the same thread
+		threadID := parent.ID
+		if parent.ReplyTo != nil {
+			threadID = *parent.ReplyTo
+		}
+		return domain.NewReply(board.ID, user.ID, user.Username, m.content, threadID), nil
+	}
+
+	if m.subject == "" {
+		return nil, mailRejection("A new thread needs a Subject")
+	}
+	return domain.NewPost(board.ID, user.ID, user.Username, m.subject, m.content), nil
+}
+
+// mailText returns the plain text of a message: its body, or the first
+// plain text part of a multipart message.
+func mailText(header interface{ Get(string) string }, body io.Reader, depth int) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return plainText(header, body)
+	}
+	if depth == 3 {
+		return "", errors.New("the message is nested too deeply")
+	}
+
+	parts := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			return "", errors.New("the message has no plain text part")
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid multipart body: %v", err)
+		}
+		if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+			continue
+		}
+		if text, err := mailText(part.Header, part, depth+1); err == nil {
+			return text, nil
+		}
+	}
+}
+
+// originalMessage starts what Outlook and others put below a reply.
+var originalMessage = regexp.MustCompile(`^(-+ ?Original Message ?-+|_{10,})$`)
+
+// authProperties maps the methods that can vouch for a From address to the
+// Authentication-Results property naming the domain they checked.
+var authProperties = map[string]string{
+	"dmarc": "header.from",
+	"dkim":  "header.d",
+	"spf":   "smtp.mailfrom",
+}
+
+// authenticated reports whether the Authentication-Results header (RFC
+// 8601) added by the relay calling itself authservID says address passed
+// DMARC, or DKIM or SPF for its domain. Only the topmost such header counts:
+// those below it came with the message, and anyone can write them.
+func authenticated(header mail.Header, authservID, address string) bool {
+	_, addrDomain, _ := strings.Cut(address, "@")
+	for _, value := range header["Authentication-Results"] {
+		results := strings.Split(stripComments(value), ";")
+		if id := strings.Fields(results[0]); len(id) == 0 || !strings.EqualFold(id[0], authservID) {
+			continue
+		}
+
+		for _, result := range results[1:] {
+			fields := strings.Fields(result)
+			if len(fields) == 0 {
+				continue
+			}
+			method, outcome, _ := strings.Cut(fields[0], "=")
+			method, _, _ = strings.Cut(method, "/")
+			property, ok := authProperties[strings.ToLower(method)]
+			if !ok || !strings.EqualFold(outcome, "pass") {
+				continue
+			}
+			for _, field := range fields[1:] {
+				key, value, _ := strings.Cut(field, "=")
+				value = strings.Trim(value, `"`)
+				if i := strings.LastIndex(value, "@"); i >= 0 {
+					value = value[i+1:]
+				}
+				if strings.EqualFold(key, property) && strings.EqualFold(value, addrDomain) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// stripComments removes the parenthesised comments from a header value.
+func stripComments(value string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range value {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// stripQuotes removes what a mail client adds to a reply: quoted lines with
+// the "On ... wrote:" line above them, the original message below a
+// separator, and the signature below "-- ".
+func stripQuotes(text string) string {
+	var kept []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || trimmed == "--" || originalMessage.MatchString(trimmed) {
+			break
+		}
+		if !strings.HasPrefix(line, ">") {
+			kept = append(kept, strings.TrimRight(line, " \t"))
+			continue
+		}
+
+		// drop the attribution above the quote, which may be wrapped
+		end := len(kept)
+		for end > 0 && kept[end-1] == "" {
+			end--
+		}
+		if end > 0 && strings.HasSuffix(strings.ToLower(kept[end-1]), "wrote:") {
+			end--
+			if end > 0 && !strings.HasPrefix(kept[end], "On ") && strings.HasPrefix(kept[end-1], "On ") {
+				end--
+			}
+			kept = kept[:end]
+		}
+	}
+
+	// quotes leave gaps of blank lines behind
+	var lines []string
+	for _, line := range kept {
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// authResults is what the relay, mx.example.com, adds to mail from alice.
+const authResults = "Authentication-Results: mx.example.com; dkim=pass header.d=example.com\r\n"
+
+// relayConn is a connection from a mail server at addr.
+type relayConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c relayConn) RemoteAddr() net.Addr { return c.addr }
+
+// dialFrom connects to the gateway as the mail server at host would.
+func dialFrom(f *nntpFixture, host string) *textproto.Conn {
+	f.server.services.Config.MailGatewayAuthservID = "mx.example.com"
+	client, server := net.Pipe()
+	addr := &net.TCPAddr{IP: net.ParseIP(host), Port: 25}
+	go NewMailGatewayServer(f.server.services).handleConnection(relayConn{server, addr})
+	return textproto.NewConn(client)
+}
+
+func dialMailGateway(t *testing.T, f *nntpFixture) *textproto.Conn {
+	conn := dialFrom(f, "127.0.0.1")
+	t.Cleanup(func() { conn.Close() })
+	if _, _, err := conn.ReadCodeLine(220); err != nil {
+		t.Fatalf("Expected a greeting: %v", err)
+	}
+	return conn
+}
+
+// sendMail sends a message with DATA and returns the replies to it.
+func sendMail(t *testing.T, conn *textproto.Conn, replies int, message string) []string {
+	t.Helper()
+	send(t, conn, 354, "DATA")
+	dw := conn.DotWriter()
+	dw.Write([]byte(message))
+	dw.Close()
+
+	var lines []string
+	for i := 0; i < replies; i++ {
+		code, line, err := conn.ReadCodeLine(0)
+		if err != nil && code == 0 {
+			t.Fatalf("Failed to read the reply to DATA: %v", err)
+		}
+		lines = append(lines, line)
+		if code != 250 {
+			lines[i] = "refused: " + line
+		}
+	}
+	return lines
+}
+
+func verifiedUser(f *nntpFixture) {
+	f.user.EmailVerified = true
+	f.user.Signature = "alice was here"
+	f.repos.User.Update(f.user)
+}
+
+func TestMailGateway_SMTP(t *testing.T) {
+	f := newNNTPFixture(t, true)
+	verifiedUser(f)
+	thread := f.post(f.general, 0, "Hello", "First post")
+	reply := f.post(f.general, thread.ID, "", "A reply")
+
+	conn := dialMailGateway(t, f)
+	send(t, conn, 503, "MAIL FROM:<alice@example.com>")
+	conn.PrintfLine("EHLO client.example.org")
+	if _, message, err := conn.ReadResponse(250); err != nil || !strings.Contains(message, "SIZE") {
+		t.Fatalf("Unexpected EHLO reply %q: %v", message, err)
+	}
+
+	send(t, conn, 503, "RCPT TO:<general@example.com>")
+	send(t, conn, 250, "MAIL FROM:<alice@example.com> BODY=8BITMIME")
+	send(t, conn, 550, "RCPT TO:<general@elsewhere.example>")
+	send(t, conn, 550, "RCPT TO:<misc@example.com>")
+	send(t, conn, 250, "RCPT TO:<Tech-Talk@EXAMPLE.com>")
+	result := sendMail(t, conn, 1, authResults+"From: Alice <ALICE@example.com>\r\nSubject: =?utf-8?q?Caf=C3=A9?=\r\n\r\n"+
+		"New thread\r\n.with a dot\r\n\r\n-- \r\nAlice\r\nSent from my phone\r\n")
+	if result[0] != "OK, posted" {
+		t.Fatalf("Expected the thread posted, got %q", result)
+	}
+
+	created, _ := f.repos.Post.GetByID(3)
+	if created == nil || created.BoardID != f.tech.ID || created.Title != "Café" || created.ReplyTo != nil ||
+		created.UserID != f.user.ID || created.Content != "New thread\n.with a dot\n-- \nalice was here" {
+		t.Errorf("Unexpected thread: %+v", created)
+	}
+
+	send(t, conn, 250, "MAIL FROM:<alice@example.com>")
+	send(t, conn, 250, "RCPT TO:<general@example.com>")
+	sendMail(t, conn, 1, authResults+"From: alice@example.com\r\nSubject: Re: Hello\r\nIn-Reply-To: <post-2@example.com>\r\n"+
+		"References: <post-1@example.com> <post-2@example.com>\r\n\r\n"+
+		"Agreed.\r\n\r\nOn Mon, 19 Oct 2026 at 10:00, alice <bbs@example.com>\r\nwrote:\r\n> A reply\r\n>\r\n\r\nAnd more.\r\n")
+	created, _ = f.repos.Post.GetByID(4)
+	if created == nil || created.ReplyTo == nil || *created.ReplyTo != thread.ID ||
+		created.Content != "Agreed.\n\nAnd more.\n-- \nalice was here" {
+		t.Errorf("Expected a reply to reply %d to join thread %d, got %+v", reply.ID, thread.ID, created)
+	}
+
+	send(t, conn, 221, "QUIT")
+}
+
+func TestMailGateway_Refused(t *testing.T) {
+	f := newNNTPFixture(t, true)
+	verifiedUser(f)
+	f.createUser(t, "bob")
+	f.post(f.general, 0, "Hello", "First post")
+
+	conn := dialMailGateway(t, f)
+	send(t, conn, 250, "HELO client.example.org")
+	tests := []struct {
+		sender  string
+		message string
+		reason  string
+	}{
+		{"mallory@example.net", "Authentication-Results: mx.example.com; spf=pass smtp.mailfrom=mallory@example.net\r\n" +
+			"From: mallory@example.net\r\nSubject: Spam\r\n\r\nBuy now\r\n", "Unknown sender"},
+		{"bob@example.com", authResults + "From: bob@example.com\r\nSubject: Hi\r\n\r\nNot verified\r\n", "Unknown sender"},
+		// forged as alice
+		{"mallory@example.net", authResults + "From: alice@example.com\r\nSubject: Hi\r\n\r\nForged\r\n", "envelope sender"},
+		{"alice@example.com", "From: alice@example.com\r\nSubject: Hi\r\n\r\nForged\r\n", "not authenticated"},
+		{"alice@example.com", "Authentication-Results: mx.example.com; dkim=fail header.d=example.com; spf=softfail smtp.mailfrom=example.com\r\n" +
+			"From: alice@example.com\r\nSubject: Hi\r\n\r\nForged\r\n", "not authenticated"},
+		{"alice@example.com", "Authentication-Results: mx.example.com; dkim=pass header.d=example.net\r\n" +
+			"From: alice@example.com\r\nSubject: Hi\r\n\r\nForged\r\n", "not authenticated"},
+		// the relay's own header comes first; the sender wrote the one below it
+		{"alice@example.com", "Authentication-Results: mx.example.com; dkim=none\r\n" + authResults +
+			"From: alice@example.com\r\nSubject: Hi\r\n\r\nForged\r\n", "not authenticated"},
+		{"alice@example.com", "Authentication-Results: mx.example.net; dmarc=pass header.from=example.com\r\n" +
+			"From: alice@example.com\r\nSubject: Hi\r\n\r\nForged\r\n", "not authenticated"},
+		{"alice@example.com", authResults + "From: alice@example.com\r\n\r\nNo subject\r\n", "Subject"},
+		{"alice@example.com", authResults + "From: alice@example.com\r\nSubject: Re: Hello\r\nIn-Reply-To: <post-1@example.com>\r\n\r\n> x\r\n", "no text"},
+		{"alice@example.com", authResults + "From: alice@example.com\r\nSubject: Away\r\nAuto-Submitted: auto-replied\r\n\r\nOut of office\r\n", "Automatic"},
+		{"alice@example.com", authResults + "From: alice@example.com\r\nSubject: Web\r\nContent-Type: text/html\r\n\r\n<b>x</b>\r\n", "plain text"},
+	}
+	for _, tt := range tests {
+		send(t, conn, 250, "MAIL FROM:<%s>", tt.sender)
+		send(t, conn, 250, "RCPT TO:<general@example.com>")
+		if result := sendMail(t, conn, 1, tt.message); !strings.Contains(result[0], tt.reason) {
+			t.Errorf("Expected %q from %s to be refused for %q, got %q", tt.message, tt.sender, tt.reason, result[0])
+		}
+	}
+
+	send(t, conn, 250, "MAIL FROM:<alice@example.com>")
+	send(t, conn, 250, "RCPT TO:<tech-talk@example.com>")
+	result := sendMail(t, conn, 1, authResults+"From: alice@example.com\r\nSubject: Re: Hello\r\nIn-Reply-To: <post-1@example.com>\r\n\r\nText\r\n")
+	if !strings.Contains(result[0], "Replies must be sent to general@example.com") {
+		t.Errorf("Expected a reply to another board refused, got %q", result[0])
+	}
+
+	if posts, _ := f.repos.Post.GetByBoardAfter(f.general.ID, 0, 10); len(posts) != 1 {
+		t.Errorf("Expected nothing posted, got %d posts", len(posts))
+	}
+}
+
+func TestMailGateway_LMTP(t *testing.T) {
+	f := newNNTPFixture(t, true)
+	verifiedUser(f)
+	f.server.services.Config.MailGatewayDomain = "boards.example.net"
+	thread := f.post(f.general, 0, "Hello", "First post")
+
+	conn := dialMailGateway(t, f)
+	conn.PrintfLine("LHLO mx.example.net")
+	if _, _, err := conn.ReadResponse(250); err != nil {
+		t.Fatalf("LHLO: %v", err)
+	}
+	send(t, conn, 250, "MAIL FROM:<alice@example.com>")
+	send(t, conn, 250, "RCPT TO:<general@boards.example.net>")
+	send(t, conn, 250, "RCPT TO:<tech-talk@boards.example.net>")
+	send(t, conn, 250, "RCPT TO:<general@boards.example.net>")
+
+	// a reply to a thread on general is refused on tech-talk
+	result := sendMail(t, conn, 2, authResults+"From: alice@example.com\r\nSubject: Re: Hello\r\n"+
+		"References: <post-1@example.com>\r\nContent-Type: multipart/mixed; boundary=b\r\n\r\n"+
+		"--b\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nFrom my mail client\r\n"+
+		"--b\r\nContent-Type: text/plain\r\nContent-Disposition: attachment; filename=a.txt\r\n\r\nAttached\r\n--b--\r\n")
+	if result[0] != "OK, posted" || !strings.HasPrefix(result[1], "refused: Replies must be sent to general@boards.example.net") {
+		t.Fatalf("Expected a reply per board, got %q", result)
+	}
+
+	replies, _ := f.repos.Post.GetReplies(thread.ID)
+	if len(replies) != 1 || replies[0].Content != "From my mail client\n-- \nalice was here" {
+		t.Errorf("Expected the text part posted as a reply, got %d replies", len(replies))
+	}
+}
+
+func TestMailGateway_UntrustedPeer(t *testing.T) {
+	f := newNNTPFixture(t, true)
+	verifiedUser(f)
+
+	// a forged message straight from somewhere on the internet
+	conn := dialFrom(f, "192.0.2.1")
+	defer conn.Close()
+	if _, line, err := conn.ReadCodeLine(554); err != nil {
+		t.Fatalf("Expected an untrusted peer refused, got %q: %v", line, err)
+	}
+	conn.PrintfLine("HELO mallory.example.net")
+	conn.PrintfLine("MAIL FROM:<alice@example.com>")
+	conn.PrintfLine("RCPT TO:<general@example.com>")
+	conn.PrintfLine("DATA")
+	if _, _, err := conn.ReadCodeLine(0); err == nil {
+		t.Error("Expected the connection closed after the refusal")
+	}
+
+	// the relays can be a range
+	f.server.services.Config.MailGatewayTrustedRelays = []string{"192.0.2.0/24"}
+	conn = dialFrom(f, "192.0.2.1")
+	defer conn.Close()
+	if _, _, err := conn.ReadCodeLine(220); err != nil {
+		t.Fatalf("Expected a peer in a trusted range greeted: %v", err)
+	}
+
+	if posts, _ := f.repos.Post.GetByBoardAfter(f.general.ID, 0, 10); len(posts) != 0 {
+		t.Errorf("Expected nothing posted, got %d posts", len(posts))
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"mx.example.com; dmarc=pass (p=reject) header.from=example.com", true},
+		{"mx.example.com 1; spf=pass smtp.mailfrom=alice@EXAMPLE.com", true},
+		{"mx.example.com (relay); dkim=pass (2048-bit key) header.d=example.com header.s=mail; spf=none", true},
+		{"mx.example.com; dkim/1=pass header.d=\"example.com\"", true},
+		{"mx.example.com; dkim=pass header.i=@example.com", false},
+		{"mx.example.com; dmarc=fail header.from=example.com", false},
+		{"mx.example.com; spf=pass smtp.mailfrom=alice@mail.example.com", false},
+		{"mx.example.com; none", false},
+		{"other.example.com; dkim=pass header.d=example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		header := mail.Header{"Authentication-Results": {tt.header}}
+		if got := authenticated(header, "mx.example.com", "alice@example.com"); got != tt.want {
+			t.Errorf("authenticated(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestStripQuotes(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Just text\n\nin two paragraphs", "Just text\n\nin two paragraphs"},
+		{"Yes.\n\nOn Tue, Oct 20, 2026, Bob wrote:\n> Lunch?\n> \n\nSee you", "Yes.\n\nSee you"},
+		{"> Lunch?\nYes.\n> At noon?\nNoon.", "Yes.\nNoon."},
+		{"Inline\nbob@example.com wrote:\n\n> quote\n", "Inline"},
+		{"Thanks\n\n-- \nBob\nbob.example.org", "Thanks"},
+		{"Thanks\n\n-----Original Message-----\nFrom: Bob\n\nLunch?", "Thanks"},
+		{"Thanks\n________________________________\nFrom: Bob", "Thanks"},
+		{"> only a quote", ""},
+	}
+	for _, tt := range tests {
+		if got := stripQuotes(tt.text); got != tt.want {
+			t.Errorf("stripQuotes(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMailPath(t *testing.T) {
+	path, params, ok := mailPath("to:<general@example.com> NOTIFY=NEVER", "TO:")
+	if !ok || path != "general@example.com" || len(params) != 1 {
+		t.Errorf("Unexpected path %q %q %v", path, params, ok)
+	}
+	if path, _, ok := mailPath("FROM:<>", "FROM:"); !ok || path != "" {
+		t.Errorf("Expected the null sender accepted, got %q %v", path, ok)
+	}
+	for _, args := range []string{"", "TO:", "TO:general@example.com", "FROM:<a@example.com>"} {
+		if _, _, ok := mailPath(args, "TO:"); ok {
+			t.Errorf("Expected %q to be invalid", args)
+		}
+	}
+}
@@ -0,0 +1,674 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+)
+
+const (
+	// nntpIdleTimeout is how long a client may stay silent. RFC 3977 asks
+	// for at least three minutes.
+	nntpIdleTimeout = 10 * time.Minute
+	// maxNNTPLine bounds a command line. RFC 3977 allows 512 octets, but
+	// some clients send longer AUTHINFO and LIST lines.
+	maxNNTPLine = 4096
+	// maxArticleSize bounds a posted article, headers included.
+	maxArticleSize = 64 << 10
+	// overviewBatch is how many posts OVER loads at a time.
+	overviewBatch = 500
+)
+
+// NNTPServer lets Usenet clients read and post to the boards, each of which
+// is a newsgroup. It speaks the reader commands of RFC 3977 and AUTHINFO
+// USER/PASS from RFC 4643. Articles are numbered by post ID, so numbers
+// never change, with gaps where other boards' posts went.
+type NNTPServer struct {
+	services *ui.Services
+	config   *config.Config
+	repos    *repository.Manager
+	listener net.Listener
+	// clients bounds the connections open at once to max_users.
+	clients chan struct{}
+}
+
+func NewNNTPServer(services *ui.Services) *NNTPServer {
+	return &NNTPServer{
+		services: services,
+		config:   services.Config,
+		repos:    services.Repos,
+		clients:  make(chan struct{}, services.Config.MaxUsers),
+	}
+}
+
+func (s *NNTPServer) Start() error {
+	listener, err := net.Listen("tcp", s.config.NNTPAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	s.listener = listener
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.listener == nil {
+				return nil
+			}
+			log.Printf("Failed to accept NNTP connection: %v", err)
+			continue
+		}
+
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *NNTPServer) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+}
+
+func (s *NNTPServer) handleConnection(netConn net.Conn) {
+	defer netConn.Close()
+
+	select {
+	case s.clients <- struct{}{}:
+		defer func() { <-s.clients }()
+	default:
+		fmt.Fprintf(netConn, "400 Too many connections, try again later\r\n")
+		return
+	}
+
+	c := newNNTPConn(s, netConn)
+	c.reply(200, "%s news server ready, posting allowed", s.config.ServerName)
+
+	for {
+		netConn.SetReadDeadline(time.Now().Add(nntpIdleTimeout))
+		c.limit.N = maxNNTPLine
+		line, err := c.r.ReadLine()
+		if err != nil {
+			return
+		}
+		if !c.command(line) {
+			return
+		}
+	}
+}
+
+// nntpConn is one client's connection and the state its commands change.
+type nntpConn struct {
+	server     *NNTPServer
+	conn       net.Conn
+	limit      *io.LimitedReader
+	r          *textproto.Reader
+	w          *textproto.Writer
+	remoteAddr string
+
+	user     *domain.User
+	username string // from AUTHINFO USER, until AUTHINFO PASS
+
+	group   *domain.Board
+	article int // the current article number, or 0 if there is none
+}
+
+func newNNTPConn(s *NNTPServer, conn net.Conn) *nntpConn {
+	limit := &io.LimitedReader{R: conn, N: maxNNTPLine}
+	return &nntpConn{
+		server:     s,
+		conn:       conn,
+		limit:      limit,
+		r:          textproto.NewReader(bufio.NewReader(limit)),
+		w:          textproto.NewWriter(bufio.NewWriter(conn)),
+		remoteAddr: remoteHost(conn.RemoteAddr()),
+	}
+}
+
+func (c *nntpConn) reply(code int, format string, args ...interface{}) {
+	c.w.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+// replyLines sends a multi-line response: the status line, then lines, then
+// the terminating dot.
+func (c *nntpConn) replyLines(code int, status string, lines []string) {
+	c.reply(code, "%s", status)
+	dw := c.w.DotWriter()
+	for _, line := range lines {
+		io.WriteString(dw, line+"\n")
+	}
+	dw.Close()
+}
+
+func (c *nntpConn) internalError(err error) {
+	log.Printf("NNTP command from %s failed: %v", c.remoteAddr, err)
+	c.reply(403, "Internal fault, please try again later")
+}
+
+// nntpReaderCommands need a login unless the BBS lets guests in.
+var nntpReaderCommands = map[string]func(c *nntpConn, args []string){
+	"LIST":    (*nntpConn).list,
+	"GROUP":   (*nntpConn).groupCommand,
+	"ARTICLE": func(c *nntpConn, args []string) { c.sendArticle(args, 220, true, true) },
+	"HEAD":    func(c *nntpConn, args []string) { c.sendArticle(args, 221, true, false) },
+	"BODY":    func(c *nntpConn, args []string) { c.sendArticle(args, 222, false, true) },
+	"STAT":    func(c *nntpConn, args []string) { c.sendArticle(args, 223, false, false) },
+	"OVER":    (*nntpConn).over,
+	"XOVER":   (*nntpConn).over,
+}
+
+// command runs one command line, and reports whether to read another.
+func (c *nntpConn) command(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		c.reply(500, "Unknown command")
+		return true
+	}
+	name, args := strings.ToUpper(fields[0]), fields[1:]
+
+	switch name {
+	case "QUIT":
+		c.reply(205, "Bye")
+		return false
+	case "CAPABILITIES":
+		c.capabilities()
+	case "HELP":
+		c.help()
+	case "MODE":
+		if len(args) != 1 || !strings.EqualFold(args[0], "READER") {
+			c.reply(501, "Only MODE READER is supported")
+			return true
+		}
+		c.reply(200, "Posting allowed")
+	case "AUTHINFO":
+		c.authinfo(line, args)
+	case "POST":
+		return c.post()
+	default:
+		handler, ok := nntpReaderCommands[name]
+		if !ok {
+			c.reply(500, "Unknown command")
+			return true
+		}
+		if c.user == nil && !c.server.config.AllowAnonymous {
+			c.reply(480, "Authentication required")
+			return true
+		}
+		handler(c, args)
+	}
+	return true
+}
+
+func (c *nntpConn) capabilities() {
+	lines := []string{
+		"VERSION 2",
+		"IMPLEMENTATION gobbs",
+		"READER",
+		"POST",
+		"LIST ACTIVE NEWSGROUPS OVERVIEW.FMT",
+		"OVER MSGID",
+	}
+	if c.user == nil {
+		lines = append(lines, "AUTHINFO USER")
+	}
+	c.replyLines(101, "Capability list:", lines)
+}
+
+func (c *nntpConn) help() {
+	c.replyLines(100, "Help text follows", []string{
+		"ARTICLE|HEAD|BODY|STAT [number|<message-id>]",
+		"AUTHINFO USER name",
+		"AUTHINFO PASS password",
+		"CAPABILITIES",
+		"GROUP newsgroup",
+		"LIST [ACTIVE|NEWSGROUPS [wildmat]|OVERVIEW.FMT]",
+		"MODE READER",
+		"OVER|XOVER [range|<message-id>]",
+		"POST",
+		"QUIT",
+	})
+}
+
+// authinfo logs in with AUTHINFO USER and then AUTHINFO PASS. The password
+// is the account's password, or one of the user's API tokens, which is the
+// only way in for accounts with two-factor authentication.
+func (c *nntpConn) authinfo(line string, args []string) {
+	if c.user != nil {
+		c.reply(502, "Already authenticated")
+		return
+	}
+	if len(args) < 2 {
+		c.reply(501, "Syntax: AUTHINFO USER name, AUTHINFO PASS password")
+		return
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "USER":
+		c.username = args[1]
+		c.reply(381, "Password required")
+	case "PASS":
+		if c.username == "" {
+			c.reply(482, "Send AUTHINFO USER first")
+			return
+		}
+		// the password is the rest of the line, spaces and all
+		password := strings.TrimLeft(line, " \t")
+		for i := 0; i < 2; i++ {
+			password = strings.TrimLeft(password[strings.IndexAny(password, " \t"):], " \t")
+		}
+
+		user, err := c.server.login(c.username, password, c.remoteAddr)
+		c.username = ""
+		if err != nil {
+			c.reply(481, "Authentication failed: %v", err)
+			return
+		}
+		c.user = user
+		c.reply(281, "Authentication accepted")
+	default:
+		c.reply(501, "Only AUTHINFO USER and PASS are supported")
+	}
+}
+
+var (
+	errNNTPSecondFactor  = errors.New("two-factor authentication is on, use an API token as the password")
+	errNNTPPasswordReset = errors.New("log in over SSH to choose a new password first")
+	errNNTPInactive      = errors.New("the account is not active")
+)
+
+func (s *NNTPServer) login(username, password, remoteAddr string) (*domain.User, error) {
+	var user *domain.User
+	if strings.HasPrefix(password, domain.APITokenPrefix) {
+		apiToken, err := s.repos.APIToken.GetByToken(password)
+		if err == nil {
+			user, err = s.repos.User.GetByID(apiToken.UserID)
+		}
+		if err != nil || !strings.EqualFold(user.Username, username) {
+			return nil, errors.New("invalid credentials")
+		}
+		if err := s.repos.APIToken.MarkUsed(apiToken.ID, time.Now()); err != nil {
+			log.Printf("Failed to mark API token %d used: %v", apiToken.ID, err)
+		}
+	} else {
+		result, err := s.services.Auth.Password(username, password, remoteAddr)
+		if err != nil {
+			return nil, err
+		}
+		if result.NeedsSecondFactor() {
+			return nil, errNNTPSecondFactor
+		}
+		if result.PasswordResetID != 0 {
+			return nil, errNNTPPasswordReset
+		}
+		user = result.User
+	}
+
+	if !user.IsActive() {
+		return nil, errNNTPInactive
+	}
+	s.repos.RecordAudit(domain.NewAuditEntry(user, domain.AuditLogin, user.Username, remoteAddr,
+		map[string]interface{}{"client": "nntp"}))
+	return user, nil
+}
+
+func (c *nntpConn) list(args []string) {
+	keyword := "ACTIVE"
+	if len(args) > 0 {
+		keyword = strings.ToUpper(args[0])
+	}
+	if len(args) > 2 || (keyword == "OVERVIEW.FMT" && len(args) > 1) {
+		c.reply(501, "Syntax: LIST [ACTIVE|NEWSGROUPS [wildmat]|OVERVIEW.FMT]")
+		return
+	}
+
+	if keyword == "OVERVIEW.FMT" {
+		c.replyLines(215, "Order of fields in overview database", []string{
+			"Subject:", "From:", "Date:", "Message-ID:", "References:", ":bytes", ":lines",
+		})
+		return
+	}
+	if keyword != "ACTIVE" && keyword != "NEWSGROUPS" {
+		c.reply(501, "Unknown LIST keyword")
+		return
+	}
+
+	boards, err := c.server.repos.Board.GetAll()
+	if err != nil {
+		c.internalError(err)
+		return
+	}
+
+	var lines []string
+	for _, board := range boards {
+		name := groupName(board)
+		if len(args) == 2 && !wildmat(args[1], name) {
+			continue
+		}
+		if keyword == "NEWSGROUPS" {
+			lines = append(lines, name+"\t"+board.Description)
+			continue
+		}
+		_, low, high, err := c.server.groupRange(board)
+		if err != nil {
+			c.internalError(err)
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s %d %d y", name, high, low))
+	}
+
+	if keyword == "NEWSGROUPS" {
+		c.replyLines(215, "List of newsgroup descriptions follows", lines)
+	} else {
+		c.replyLines(215, "List of newsgroups follows", lines)
+	}
+}
+
+// groupName is the newsgroup name of a board: its name in lower case, with
+// characters newsgroup names cannot have turned into dashes.
+func groupName(board *domain.Board) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || strings.ContainsRune(",*?[]!\\", r) {
+			return '-'
+		}
+		return r
+	}, strings.ToLower(board.Name))
+}
+
+// findBoard returns the board whose newsgroup name is name, or nil. Boards
+// are addressed by the same names in email.
+func findBoard(services *ui.Services, name string) (*domain.Board, error) {
+	boards, err := services.Repos.Board.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, board := range boards {
+		if groupName(board) == strings.ToLower(name) {
+			return board, nil
+		}
+	}
+	return nil, nil
+}
+
+// groupRange returns the number of articles in a group and their lowest and
+// highest numbers. An empty group has a low number above its high one.
+func (s *NNTPServer) groupRange(board *domain.Board) (count, low, high int, err error) {
+	low, high, err = s.repos.Post.GetIDRange(board.ID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if count, err = s.repos.Post.CountByBoard(board.ID); err != nil {
+		return 0, 0, 0, err
+	}
+	if count == 0 {
+		low = high + 1
+	}
+	return count, low, high, nil
+}
+
+// wildmat matches name against an RFC 3977 wildmat: patterns separated by
+// commas, the last matching one deciding, and "!" negating a pattern.
+func wildmat(pattern, name string) bool {
+	matched := false
+	for _, p := range strings.Split(pattern, ",") {
+		negate := strings.HasPrefix(p, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(p, "!"), name); ok {
+			matched = !negate
+		}
+	}
+	return matched
+}
+
+func (c *nntpConn) groupCommand(args []string) {
+	if len(args) != 1 {
+		c.reply(501, "Syntax: GROUP newsgroup")
+		return
+	}
+
+	board, err := findBoard(c.server.services, args[0])
+	if err != nil {
+		c.internalError(err)
+		return
+	}
+	if board == nil {
+		c.reply(411, "No such newsgroup")
+		return
+	}
+
+	count, low, high, err := c.server.groupRange(board)
+	if err != nil {
+		c.internalError(err)
+		return
+	}
+
+	c.group = board
+	c.article = 0
+	if count > 0 {
+		c.article = low
+	}
+	c.reply(211, "%d %d %d %s", count, low, high, groupName(board))
+}
+
+// selectArticle finds the article a command names: a message-id, a number
+// in the current group, or else the current article. It returns the number
+// of the article in the current group, or 0 if it is not in it. If there is
+// no such article it replies with the error and returns nil.
+func (c *nntpConn) selectArticle(args []string) (*domain.Post, int) {
+	if len(args) > 1 {
+		c.reply(501, "Too many arguments")
+		return nil, 0
+	}
+
+	if len(args) == 1 && strings.HasPrefix(args[0], "<") {
+		post := findPost(c.server.services, args[0])
+		if post == nil {
+			c.reply(430, "No article with that message-id")
+			return nil, 0
+		}
+		if c.group != nil && post.BoardID == c.group.ID {
+			return post, post.ID
+		}
+		return post, 0
+	}
+
+	if c.group == nil {
+		c.reply(412, "No newsgroup selected")
+		return nil, 0
+	}
+
+	number := c.article
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			c.reply(501, "Invalid article number")
+			return nil, 0
+		}
+		number = n
+	} else if number == 0 {
+		c.reply(420, "Current article number is invalid")
+		return nil, 0
+	}
+
+	post, err := c.server.repos.Post.GetByID(number)
+	if err != nil || post.BoardID != c.group.ID {
+		if len(args) == 0 {
+			c.reply(420, "Current article number is invalid")
+		} else {
+			c.reply(423, "No article with that number")
+		}
+		return nil, 0
+	}
+
+	c.article = number
+	return post, number
+}
+
+// sendArticle answers ARTICLE, HEAD, BODY and STAT.
+func (c *nntpConn) sendArticle(args []string, code int, head, body bool) {
+	post, number := c.selectArticle(args)
+	if post == nil {
+		return
+	}
+
+	article, err := newArticleSource(c.server).article(post)
+	if err != nil {
+		c.internalError(err)
+		return
+	}
+
+	c.reply(code, "%d %s", number, article.messageID)
+	if !head && !body {
+		return
+	}
+
+	dw := c.w.DotWriter()
+	if head {
+		io.WriteString(dw, article.header())
+	}
+	if head && body {
+		io.WriteString(dw, "\n")
+	}
+	if body {
+		io.WriteString(dw, article.body)
+	}
+	dw.Close()
+}
+
+// over answers OVER and XOVER with a line of overview for each article.
+func (c *nntpConn) over(args []string) {
+	if len(args) > 1 {
+		c.reply(501, "Syntax: OVER [range|<message-id>]")
+		return
+	}
+	source := newArticleSource(c.server)
+
+	if len(args) == 1 && strings.HasPrefix(args[0], "<") {
+		post, number := c.selectArticle(args)
+		if post == nil {
+			return
+		}
+		article, err := source.article(post)
+		if err != nil {
+			c.internalError(err)
+			return
+		}
+		c.replyLines(224, "Overview information follows", []string{article.overview(number)})
+		return
+	}
+
+	if c.group == nil {
+		c.reply(412, "No newsgroup selected")
+		return
+	}
+
+	low, high := c.article, c.article
+	if len(args) == 1 {
+		var ok bool
+		if low, high, ok = parseRange(args[0]); !ok {
+			c.reply(501, "Invalid range")
+			return
+		}
+	} else if c.article == 0 {
+		c.reply(420, "Current article number is invalid")
+		return
+	}
+
+	posts, err := c.server.repos.Post.GetByBoardAfter(c.group.ID, low-1, overviewBatch)
+	if err != nil {
+		c.internalError(err)
+		return
+	}
+	if len(posts) == 0 || posts[0].ID > high {
+		c.reply(423, "No articles in that range")
+		return
+	}
+
+	c.reply(224, "Overview information follows")
+	dw := c.w.DotWriter()
+	defer dw.Close()
+	for len(posts) > 0 {
+		for _, post := range posts {
+			if post.ID > high {
+				return
+			}
+			article, err := source.article(post)
+			if err != nil {
+				log.Printf("Failed to load article %d: %v", post.ID, err)
+				continue
+			}
+			io.WriteString(dw, article.overview(post.ID)+"\n")
+		}
+		if len(posts) < overviewBatch {
+			return
+		}
+		if posts, err = c.server.repos.Post.GetByBoardAfter(c.group.ID, posts[len(posts)-1].ID, overviewBatch); err != nil {
+			log.Printf("Failed to load overview for %s: %v", c.remoteAddr, err)
+			return
+		}
+	}
+}
+
+// parseRange parses an article range: "n", "n-" or "n-m".
+func parseRange(s string) (low, high int, ok bool) {
+	from, to, isRange := strings.Cut(s, "-")
+	low, err := strconv.Atoi(from)
+	if err != nil || low < 0 {
+		return 0, 0, false
+	}
+	if !isRange {
+		return low, low, true
+	}
+	if to == "" {
+		return low, math.MaxInt, true
+	}
+	high, err = strconv.Atoi(to)
+	if err != nil {
+		return 0, 0, false
+	}
+	return low, high, true
+}
+
+// post reads an article and adds it to the boards. It reports whether the
+// connection can carry on: an article that is too large cannot be skipped.
+func (c *nntpConn) post() bool {
+	if c.user == nil {
+		c.reply(480, "Authentication required")
+		return true
+	}
+
+	c.reply(340, "Send article to be posted. End with <CR-LF>.<CR-LF>")
+	c.limit.N = maxArticleSize
+	data, err := io.ReadAll(c.r.DotReader())
+	if err != nil {
+		if c.limit.N == 0 {
+			c.reply(441, "Posting failed: the article is larger than %d bytes", maxArticleSize)
+		}
+		return false
+	}
+
+	post, err := c.server.parseArticle(c.user, data)
+	if err != nil {
+		c.reply(441, "Posting failed: %v", err)
+		return true
+	}
+	if err := c.server.services.CreatePost(c.user, post, nil); err != nil {
+		log.Printf("Failed to save NNTP post from %s: %v", c.user.Username, err)
+		c.reply(441, "Posting failed, please try again later")
+		return true
+	}
+
+	c.reply(240, "Article received OK")
+	return true
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/ui"
+)
+
+// nntpArticle is a post as a news article. The body uses "\n" line endings;
+// the DotWriter sends CRLF.
+type nntpArticle struct {
+	post       *domain.Post
+	group      string
+	subject    string
+	from       string
+	date       string
+	messageID  string
+	references string
+	host       string
+	body       string
+}
+
+func (a *nntpArticle) header() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Path: %s!not-for-mail\n", a.host)
+	fmt.Fprintf(&b, "From: %s\n", a.from)
+	fmt.Fprintf(&b, "Newsgroups: %s\n", a.group)
+	fmt.Fprintf(&b, "Subject: %s\n", a.subject)
+	fmt.Fprintf(&b, "Date: %s\n", a.date)
+	fmt.Fprintf(&b, "Message-ID: %s\n", a.messageID)
+	if a.references != "" {
+		fmt.Fprintf(&b, "References: %s\n", a.references)
+	}
+	fmt.Fprintf(&b, "Xref: %s %s:%d\n", a.host, a.group, a.post.ID)
+	b.WriteString("MIME-Version: 1.0\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\n")
+	return b.String()
+}
+
+// overview is the article's line in an OVER response.
+func (a *nntpArticle) overview(number int) string {
+	header := a.header()
+	lines := strings.Count(a.body, "\n")
+	// the size as sent, with CRLF line endings
+	size := len(header) + strings.Count(header, "\n") + 2 + len(a.body) + lines
+
+	fields := []string{
+		strconv.Itoa(number), a.subject, a.from, a.date, a.messageID, a.references,
+		strconv.Itoa(size), strconv.Itoa(lines),
+	}
+	for i, field := range fields {
+		fields[i] = strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\r' || r == '\n' {
+				return ' '
+			}
+			return r
+		}, field)
+	}
+	return strings.Join(fields, "\t")
+}
+
+// articleSource turns posts into articles, remembering the boards and
+// thread titles it has looked up for the rest of a command.
+type articleSource struct {
+	server *NNTPServer
+	host   string
+	boards map[int]*domain.Board
+	titles map[int]string
+}
+
+func newArticleSource(s *NNTPServer) *articleSource {
+	return &articleSource{
+		server: s,
+		host:   messageHost(s.config),
+		boards: make(map[int]*domain.Board),
+		titles: make(map[int]string),
+	}
+}
+
+func (src *articleSource) article(post *domain.Post) (*nntpArticle, error) {
+	board, ok := src.boards[post.BoardID]
+	if !ok {
+		var err error
+		if board, err = src.server.repos.Board.GetByID(post.BoardID); err != nil {
+			return nil, err
+		}
+		src.boards[post.BoardID] = board
+	}
+
+	subject := post.Title
+	references := ""
+	if post.ReplyTo != nil {
+		title, ok := src.titles[*post.ReplyTo]
+		if !ok {
+			if thread, err := src.server.repos.Post.GetByID(*post.ReplyTo); err == nil {
+				title = thread.Title
+			}
+			src.titles[*post.ReplyTo] = title
+		}
+		subject = "Re: " + title
+		references = messageID(*post.ReplyTo, src.host)
+	}
+
+	body := strings.ReplaceAll(post.Content, "\r\n", "\n")
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+
+	return &nntpArticle{
+		post:       post,
+		group:      groupName(board),
+		subject:    mime.QEncoding.Encode("utf-8", headerValue(subject)),
+		from:       fmt.Sprintf("%s <%s@%s>", mime.QEncoding.Encode("utf-8", post.Username), post.Username, src.host),
+		date:       post.CreatedAt.Format("Mon, 02 Jan 2006 15:04:05 -0700"),
+		messageID:  messageID(post.ID, src.host),
+		references: references,
+		host:       src.host,
+		body:       body,
+	}, nil
+}
+
+// headerValue keeps a header on one line.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+}
+
+// messageHost is the domain of Message-IDs, the same as in email from the
+// BBS.
+func messageHost(cfg *config.Config) string {
+	_, host, _ := strings.Cut(cfg.MailFrom, "@")
+	host = strings.Trim(host, "<> ")
+	if host == "" {
+		host = "localhost"
+	}
+	return host
+}
+
+func messageID(postID int, host string) string {
+	return fmt.Sprintf("<post-%d@%s>", postID, host)
+}
+
+// findPost returns the post with a Message-ID, or nil.
+func findPost(services *ui.Services, id string) *domain.Post {
+	id = strings.TrimSuffix(strings.TrimPrefix(id, "<"), ">")
+	local, host, ok := strings.Cut(id, "@")
+	if !ok || !strings.EqualFold(host, messageHost(services.Config)) || !strings.HasPrefix(local, "post-") {
+		return nil
+	}
+	postID, err := strconv.Atoi(strings.TrimPrefix(local, "post-"))
+	if err != nil {
+		return nil
+	}
+	post, err := services.Repos.Post.GetByID(postID)
+	if err != nil {
+		return nil
+	}
+	return post
+}
+
+// parseArticle makes a post from an article sent with POST. An article
+// whose References name a post is a reply to that post's thread; any other
+// starts a thread in the one newsgroup it is posted to.
+func (s *NNTPServer) parseArticle(user *domain.User, data []byte) (*domain.Post, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid article: %v", err)
+	}
+
+	var groups []string
+	for _, group := range strings.Split(msg.Header.Get("Newsgroups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("no Newsgroups header")
+	}
+	if len(groups) > 1 {
+		return nil, errors.New("cross-posting is not supported")
+	}
+	board, err := findBoard(s.services, groups[0])
+	if err != nil {
+		return nil, err
+	}
+	if board == nil {
+		return nil, fmt.Errorf("no such newsgroup %s", groups[0])
+	}
+
+	content, err := articleText(msg)
+	if err != nil {
+		return nil, err
+	}
+	content = strings.TrimRight(content, " \t\n")
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.New("the article has no text")
+	}
+
+	// the last reference is the article replied to; replies are flat, so
+	// a reply to a reply goes to the same thread
+	references := strings.Fields(msg.Header.Get("References"))
+	for i := len(references) - 1; i >= 0; i-- {
+		parent := findPost(s.services, references[i])
+		if parent == nil {
+			continue
+		}
+		if parent.BoardID != board.ID {
+			parentBoard, err := s.repos.Board.GetByID(parent.BoardID)
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("replies must be posted to %s", groupName(parentBoard))
+		}
+		threadID := parent.ID
+		if parent.ReplyTo != nil {
+			threadID = *parent.ReplyTo
+		}
+		return domain.NewReply(board.ID, user.ID, user.Username, content, threadID), nil
+	}
+	if len(references) > 0 {
+		return nil, errors.New("the article replied to was not found")
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Subject header: %v", err)
+	}
+	if subject = strings.TrimSpace(subject); subject == "" {
+		return nil, errors.New("a new thread needs a Subject")
+	}
+	return domain.NewPost(board.ID, user.ID, user.Username, subject, content), nil
+}
+
+// articleText decodes the body of a plain text article to UTF-8.
+func articleText(msg *mail.Message) (string, error) {
+	return plainText(msg.Header, msg.Body)
+}
+
+// plainText decodes a text/plain body, described by its headers, to UTF-8.
+func plainText(header interface{ Get(string) string }, body io.Reader) (string, error) {
+	mediaType, params := "text/plain", map[string]string{}
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(contentType); err != nil {
+			return "", fmt.Errorf("invalid Content-Type header: %v", err)
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", errors.New("only plain text is accepted")
+	}
+
+	switch encoding := strings.ToLower(header.Get("Content-Transfer-Encoding")); encoding {
+	case "", "7bit", "8bit", "binary":
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	default:
+		return "", fmt.Errorf("unsupported Content-Transfer-Encoding %s", encoding)
+	}
+	text, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid body: %v", err)
+	}
+
+	switch charset := strings.ToLower(params["charset"]); charset {
+	case "", "us-ascii", "utf-8":
+		if !utf8.Valid(text) {
+			return "", errors.New("the text is not valid UTF-8")
+		}
+		return string(text), nil
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	default:
+		return "", fmt.Errorf("unsupported charset %s, please post in UTF-8", charset)
+	}
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type nntpFixture struct {
+	*testFixture
+	server *NNTPServer
+	tech   *domain.Board
+}
+
+func newNNTPFixture(t *testing.T, allowAnonymous bool) *nntpFixture {
+	f := &nntpFixture{testFixture: newTestFixture(t, allowAnonymous)}
+	f.services.Config.MailFrom = "bbs@example.com"
+	f.server = NewNNTPServer(f.services)
+	f.tech = domain.NewBoard("Tech Talk", "Technology and programming")
+	f.repos.Board.Create(f.tech)
+	return f
+}
+
+func (f *nntpFixture) dial(t *testing.T) *textproto.Conn {
+	client, server := net.Pipe()
+	go f.server.handleConnection(server)
+
+	conn := textproto.NewConn(client)
+	t.Cleanup(func() { conn.Close() })
+	if _, _, err := conn.ReadCodeLine(200); err != nil {
+		t.Fatalf("Expected a greeting: %v", err)
+	}
+	return conn
+}
+
+// send sends a command and checks the status code of the reply.
+func send(t *testing.T, conn *textproto.Conn, code int, format string, args ...interface{}) string {
+	t.Helper()
+	conn.PrintfLine(format, args...)
+	_, message, err := conn.ReadCodeLine(code)
+	if err != nil {
+		t.Fatalf("%s: %v", fmt.Sprintf(format, args...), err)
+	}
+	return message
+}
+
+func readLines(t *testing.T, conn *textproto.Conn) []string {
+	t.Helper()
+	lines, err := conn.ReadDotLines()
+	if err != nil {
+		t.Fatalf("Failed to read lines: %v", err)
+	}
+	return lines
+}
+
+func TestNNTPServer_Reading(t *testing.T) {
+	f := newNNTPFixture(t, true)
+	thread := f.post(f.general, 0, "Hello", "First post\n.starts with a dot")
+	f.post(f.general, thread.ID, "", "A reply")
+	f.post(f.tech, 0, "Compilers", "Elsewhere")
+	f.post(f.general, thread.ID, "", "Another reply")
+
+	conn := f.dial(t)
+
+	send(t, conn, 101, "CAPABILITIES")
+	if lines := strings.Join(readLines(t, conn), "\n"); !strings.Contains(lines, "READER") || !strings.Contains(lines, "AUTHINFO USER") {
+		t.Errorf("Unexpected capabilities: %s", lines)
+	}
+
+	send(t, conn, 215, "LIST")
+	if lines := readLines(t, conn); len(lines) != 2 || lines[0] != "tech-talk 3 3 y" || lines[1] != "general 4 1 y" {
+		t.Errorf("Unexpected active list: %q", lines)
+	}
+	send(t, conn, 215, "LIST NEWSGROUPS tech*")
+	if lines := readLines(t, conn); len(lines) != 1 || lines[0] != "tech-talk\tTechnology and programming" {
+		t.Errorf("Unexpected newsgroups list: %q", lines)
+	}
+
+	send(t, conn, 412, "ARTICLE 1")
+	send(t, conn, 411, "GROUP misc")
+	if status := send(t, conn, 211, "GROUP general"); status != "3 1 4 general" {
+		t.Errorf("Unexpected GROUP response: %q", status)
+	}
+
+	if status := send(t, conn, 220, "ARTICLE"); status != "1 <post-1@example.com>" {
+		t.Errorf("Expected the first article to be current, got %q", status)
+	}
+	article := strings.Join(readLines(t, conn), "\n")
+	if !strings.Contains(article, "Subject: Hello\n") || !strings.Contains(article, "Newsgroups: general\n") ||
+		!strings.Contains(article, "\n\nFirst post\n.starts with a dot") {
+		t.Errorf("Unexpected article:\n%s", article)
+	}
+
+	send(t, conn, 221, "HEAD 2")
+	head := strings.Join(readLines(t, conn), "\n")
+	if !strings.Contains(head, "Subject: Re: Hello\n") || !strings.Contains(head, "References: <post-1@example.com>\n") ||
+		!strings.Contains(head, "From: alice <alice@example.com>") || strings.Contains(head, "A reply") {
+		t.Errorf("Unexpected head:\n%s", head)
+	}
+
+	send(t, conn, 423, "ARTICLE 3")
+	if status := send(t, conn, 222, "BODY <post-3@example.com>"); status != "0 <post-3@example.com>" {
+		t.Errorf("Expected an article from another group to have number 0, got %q", status)
+	}
+	if body := readLines(t, conn); len(body) != 1 || body[0] != "Elsewhere" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+	send(t, conn, 430, "STAT <post-3@elsewhere.example>")
+	send(t, conn, 223, "STAT 4")
+
+	send(t, conn, 224, "OVER 1-")
+	over := readLines(t, conn)
+	if len(over) != 3 {
+		t.Fatalf("Expected overview of 3 articles, got %q", over)
+	}
+	fields := strings.Split(over[1], "\t")
+	if len(fields) != 8 || fields[0] != "2" || fields[1] != "Re: Hello" || fields[4] != "<post-2@example.com>" ||
+		fields[5] != "<post-1@example.com>" || fields[7] != "1" {
+		t.Errorf("Unexpected overview line: %q", over[1])
+	}
+	send(t, conn, 423, "XOVER 5-9")
+
+	send(t, conn, 205, "QUIT")
+}
+
+func TestNNTPServer_Authentication(t *testing.T) {
+	f := newNNTPFixture(t, false)
+	conn := f.dial(t)
+
+	send(t, conn, 480, "GROUP general")
+	send(t, conn, 480, "POST")
+	send(t, conn, 482, "AUTHINFO PASS password123")
+	send(t, conn, 381, "AUTHINFO USER alice")
+	send(t, conn, 481, "AUTHINFO PASS wrong")
+	send(t, conn, 381, "AUTHINFO USER alice")
+	send(t, conn, 281, "AUTHINFO PASS password123")
+	send(t, conn, 502, "AUTHINFO USER alice")
+	send(t, conn, 211, "GROUP general")
+
+	send(t, conn, 101, "CAPABILITIES")
+	if lines := strings.Join(readLines(t, conn), "\n"); strings.Contains(lines, "AUTHINFO") {
+		t.Errorf("Expected AUTHINFO to be gone after logging in: %s", lines)
+	}
+
+	entries, _ := f.repos.Audit.GetRecent(10)
+	if len(entries) == 0 || entries[0].Action != domain.AuditLogin || entries[0].Target != "alice" {
+		t.Errorf("Expected the login in the audit log, got %+v", entries)
+	}
+}
+
+func TestNNTPServer_TwoFactorNeedsToken(t *testing.T) {
+	f := newNNTPFixture(t, false)
+	carol := f.createUser(t, "carol")
+	carol.TOTPEnabled = true
+	f.repos.User.Update(carol)
+	apiToken, token, _ := domain.NewAPIToken(carol.ID, "newsreader")
+	f.repos.APIToken.Create(apiToken)
+
+	conn := f.dial(t)
+	send(t, conn, 381, "AUTHINFO USER carol")
+	if status := send(t, conn, 481, "AUTHINFO PASS password123"); !strings.Contains(status, "API token") {
+		t.Errorf("Expected a hint to use an API token, got %q", status)
+	}
+	send(t, conn, 381, "AUTHINFO USER alice")
+	send(t, conn, 481, "AUTHINFO PASS %s", token)
+	send(t, conn, 381, "AUTHINFO USER carol")
+	send(t, conn, 281, "AUTHINFO PASS %s", token)
+
+	carol.Status = domain.UserStatusBanned
+	f.repos.User.Update(carol)
+	conn = f.dial(t)
+	send(t, conn, 381, "AUTHINFO USER carol")
+	send(t, conn, 481, "AUTHINFO PASS %s", token)
+}
+
+func TestNNTPServer_Post(t *testing.T) {
+	f := newNNTPFixture(t, true)
+	f.user.Signature = "alice was here"
+	f.repos.User.Update(f.user)
+	thread := f.post(f.general, 0, "Hello", "First post")
+	reply := f.post(f.general, thread.ID, "", "A reply")
+
+	conn := f.dial(t)
+	send(t, conn, 480, "POST")
+	send(t, conn, 381, "AUTHINFO USER alice")
+	send(t, conn, 281, "AUTHINFO PASS password123")
+
+	post := func(code int, article string) string {
+		t.Helper()
+		send(t, conn, 340, "POST")
+		dw := conn.DotWriter()
+		dw.Write([]byte(article))
+		dw.Close()
+		_, message, err := conn.ReadCodeLine(code)
+		if err != nil {
+			t.Fatalf("Posting %q: %v", article, err)
+		}
+		return message
+	}
+
+	post(240, "From: alice <alice@example.com>\nNewsgroups: tech-talk\nSubject: =?utf-8?q?Caf=C3=A9?=\n\nNew thread\n.with a dot\n")
+	post(240, "Newsgroups: general\nSubject: Re: Hello\nReferences: <post-1@example.com> <post-2@example.com>\n"+
+		"Content-Type: text/plain; charset=iso-8859-1\nContent-Transfer-Encoding: quoted-printable\n\nS=E5 fint\n")
+
+	created, _ := f.repos.Post.GetByID(3)
+	if created == nil || created.BoardID != f.tech.ID || created.Title != "Café" || created.ReplyTo != nil ||
+		created.Content != "New thread\n.with a dot\n-- \nalice was here" {
+		t.Errorf("Unexpected thread: %+v", created)
+	}
+	created, _ = f.repos.Post.GetByID(4)
+	if created == nil || created.ReplyTo == nil || *created.ReplyTo != thread.ID || !strings.HasPrefix(created.Content, "Så fint") {
+		t.Errorf("Expected a reply to reply %d to join thread %d, got %+v", reply.ID, thread.ID, created)
+	}
+
+	for article, reason := range map[string]string{
+		"Newsgroups: general,tech-talk\nSubject: Both\n\nText\n":                   "cross-posting",
+		"Newsgroups: misc\nSubject: Nowhere\n\nText\n":                             "no such newsgroup",
+		"Newsgroups: tech-talk\nReferences: <post-1@example.com>\n\nText\n":        "replies must be posted to general",
+		"Newsgroups: general\nReferences: <abc@elsewhere.example>\n\nText\n":       "not found",
+		"Newsgroups: general\nSubject: Web\nContent-Type: text/html\n\n<b>x</b>\n": "plain text",
+		"Newsgroups: general\n\nNo subject\n":                                      "Subject",
+		"Newsgroups: general\nSubject: Empty\n\n\n":                                "no text",
+	} {
+		if status := post(441, article); !strings.Contains(status, reason) {
+			t.Errorf("Expected %q to be refused for %q, got %q", article, reason, status)
+		}
+	}
+
+	send(t, conn, 211, "GROUP general")
+}
+
+func TestWildmat(t *testing.T) {
+	for _, test := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "general", true},
+		{"gen*", "general", true},
+		{"tech", "general", false},
+		{"*,!gen*", "general", false},
+		{"*,!gen*", "tech", true},
+		{"!gen*,general", "general", true},
+	} {
+		if got := wildmat(test.pattern, test.name); got != test.want {
+			t.Errorf("wildmat(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
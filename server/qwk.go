@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/qwk"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+	"golang.org/x/crypto/ssh"
+)
+
+// qwkPacketLimit bounds the messages in one packet; the rest come in the
+// next.
+const qwkPacketLimit = 2000
+
+var errNoSubscriptions = errors.New("subscribe to boards from the board view to get them in packets")
+
+// qwkDownload writes a QWK packet of the new posts on the user's subscribed
+// boards, e.g. "ssh -p 2222 user@bbs qwk-download > GOBBS.QWK". The user's
+// read pointers move past the posts once the packet is sent.
+func (s *SSHServer) qwkDownload(channel ssh.Channel, session *domain.Session, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(channel.Stderr(), "Usage: qwk-download > PACKET.QWK")
+		return 2
+	}
+
+	packet, pointers, err := qwkPacket(s.services, session.CurrentUser())
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Packet failed: %v\n", err)
+		return 1
+	}
+
+	w := bufio.NewWriter(channel)
+	if err := packet.Write(w); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Packet failed: %v\n", err)
+		return 1
+	}
+
+	for boardID, postID := range pointers {
+		if err := s.repos.ReadPointer.Set(session.CurrentUser().ID, boardID, postID); err != nil {
+			fmt.Fprintf(channel.Stderr(), "Failed to update read pointers: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(channel.Stderr(), "%d messages in %d conferences.\n", len(packet.Messages), len(packet.Conferences))
+	return 0
+}
+
+// qwkUpload posts the messages in a REP packet read from stdin, e.g.
+// "ssh -p 2222 user@bbs qwk-upload < GOBBS.REP".
+func (s *SSHServer) qwkUpload(channel ssh.Channel, session *domain.Session, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(channel.Stderr(), "Usage: qwk-upload < PACKET.REP")
+		return 2
+	}
+
+	data, err := io.ReadAll(io.LimitReader(channel, qwk.MaxReplySize+1))
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Upload failed: %v\n", err)
+		return 1
+	}
+	if len(data) > qwk.MaxReplySize {
+		fmt.Fprintf(channel.Stderr(), "Upload failed: the packet is larger than %d bytes\n", qwk.MaxReplySize)
+		return 1
+	}
+
+	replies, err := qwk.ReadReplies(data, strings.ToUpper(s.config.QWKID))
+	if err != nil {
+		fmt.Fprintf(channel.Stderr(), "Upload failed: %v\n", err)
+		return 1
+	}
+
+	posts, errs := importReplies(s.services, session.CurrentUser(), replies)
+	for _, err := range errs {
+		fmt.Fprintln(channel.Stderr(), err)
+	}
+	fmt.Fprintf(channel.Stderr(), "Posted %d of %d messages.\n", len(posts), len(replies))
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// qwkPacket builds a packet of the posts on the user's subscribed boards
+// after their read pointers, leaving out the user's own. It returns the
+// pointers to store once the packet is delivered.
+func qwkPacket(services *ui.Services, user *domain.User) (*qwk.Packet, map[int]int, error) {
+	repos := services.Repos
+	boardIDs, err := repos.Subscription.GetBoardIDs(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pointers, err := repos.ReadPointer.GetAll(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	packet := &qwk.Packet{
+		BBSID:   strings.ToUpper(services.Config.QWKID),
+		BBSName: services.Config.ServerName,
+		Sysop:   "Sysop",
+		User:    user.Username,
+		Created: time.Now(),
+	}
+	threads := make(map[int]*domain.Post)
+	moved := make(map[int]int)
+
+	for _, boardID := range boardIDs {
+		board, err := repos.Board.GetByID(boardID)
+		if err != nil {
+			// deleted since the user subscribed
+			continue
+		}
+		packet.Conferences = append(packet.Conferences, qwk.Conference{Number: board.ID, Name: board.Name})
+
+		last := pointers[board.ID]
+		for len(packet.Messages) < qwkPacketLimit {
+			posts, err := repos.Post.GetByBoardAfter(board.ID, last, qwkPacketLimit-len(packet.Messages))
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(posts) == 0 {
+				break
+			}
+			for _, post := range posts {
+				last = post.ID
+				if post.UserID != user.ID {
+					packet.Messages = append(packet.Messages, qwkMessage(repos.Post, threads, post))
+				}
+			}
+		}
+		if last != pointers[board.ID] {
+			moved[board.ID] = last
+		}
+	}
+
+	if len(packet.Conferences) == 0 {
+		return nil, nil, errNoSubscriptions
+	}
+	return packet, moved, nil
+}
+
+// qwkMessage makes a packet message from a post. Messages are numbered by
+// post ID, and replies refer to their thread.
+func qwkMessage(posts repository.PostRepository, threads map[int]*domain.Post, post *domain.Post) *qwk.Message {
+	msg := &qwk.Message{
+		Number:     post.ID,
+		Conference: post.BoardID,
+		Date:       post.CreatedAt.Local(),
+		To:         "All",
+		From:       post.Username,
+		Subject:    post.Title,
+		Body:       post.Content,
+	}
+	if post.ReplyTo != nil {
+		thread, ok := threads[*post.ReplyTo]
+		if !ok {
+			thread, _ = posts.GetByID(*post.ReplyTo)
+			threads[*post.ReplyTo] = thread
+		}
+		msg.Reference = *post.ReplyTo
+		if thread != nil {
+			msg.To = thread.Username
+			msg.Subject = "Re: " + thread.Title
+		}
+	}
+	return msg
+}
+
+// importReplies posts the messages from a REP packet as the user. A message
+// referring to a post replies to that post's thread; any other starts a
+// thread in its conference. It returns the posts made and why the other
+// messages were refused.
+func importReplies(services *ui.Services, user *domain.User, replies []*qwk.Reply) ([]*domain.Post, []error) {
+	var posts []*domain.Post
+	var errs []error
+	for i, reply := range replies {
+		post, err := replyPost(services, user, reply)
+		if err == nil {
+			err = services.CreatePost(user, post, nil)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %d (%q): %v", i+1, reply.Subject, err))
+			continue
+		}
+		posts = append(posts, post)
+	}
+	return posts, errs
+}
+
+func replyPost(services *ui.Services, user *domain.User, reply *qwk.Reply) (*domain.Post, error) {
+	if reply.Private {
+		return nil, errors.New("private messages are not supported")
+	}
+	board, err := services.Repos.Board.GetByID(reply.Conference)
+	if err != nil {
+		return nil, fmt.Errorf("no conference %d", reply.Conference)
+	}
+	if strings.TrimSpace(reply.Body) == "" {
+		return nil, errors.New("the message has no text")
+	}
+
+	if reply.Reference != 0 {
+		parent, err := services.Repos.Post.GetByID(reply.Reference)
+		if err != nil {
+			return nil, fmt.Errorf("message %d replied to was not found", reply.Reference)
+		}
+		if parent.BoardID != board.ID {
+			return nil, fmt.Errorf("message %d replied to is in another conference", reply.Reference)
+		}
+		// replies are flat, so a reply to a reply goes to the same thread
+		threadID := parent.ID
+		if parent.ReplyTo != nil {
+			threadID = *parent.ReplyTo
+		}
+		return domain.NewReply(board.ID, user.ID, user.Username, reply.Body, threadID), nil
+	}
+
+	subject := strings.TrimSpace(reply.Subject)
+	if subject == "" {
+		return nil, errors.New("a new thread needs a subject")
+	}
+	return domain.NewPost(board.ID, user.ID, user.Username, subject, reply.Body), nil
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/qwk"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+func newQWKFixture(t *testing.T) (*nntpFixture, *domain.User) {
+	f := newNNTPFixture(t, true)
+	f.repos.ReadPointer = mocks.NewReadPointerRepository()
+	reader := f.createUser(t, "reader")
+	return f, reader
+}
+
+func TestQWKPacket(t *testing.T) {
+	f, reader := newQWKFixture(t)
+
+	if _, _, err := qwkPacket(f.server.services, reader); err != errNoSubscriptions {
+		t.Fatalf("Expected a packet without subscriptions to fail, got %v", err)
+	}
+	f.repos.Subscription.Subscribe(reader.ID, f.general.ID)
+	f.repos.Subscription.Subscribe(reader.ID, f.tech.ID)
+
+	thread := f.post(f.general, 0, "Hello", "First post")
+	elsewhere := f.post(f.tech, 0, "Compilers", "Elsewhere")
+	reply := f.post(f.general, thread.ID, "", "A reply")
+	own := domain.NewPost(f.general.ID, reader.ID, reader.Username, "Mine", "Not sent back")
+	f.repos.Post.Create(own)
+
+	packet, pointers, err := qwkPacket(f.server.services, reader)
+	if err != nil {
+		t.Fatalf("qwkPacket failed: %v", err)
+	}
+	if packet.BBSID != "GOBBS" || packet.User != "reader" || len(packet.Conferences) != 2 ||
+		packet.Conferences[1].Name != "Tech Talk" {
+		t.Errorf("Unexpected packet %+v", packet)
+	}
+	if len(packet.Messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(packet.Messages))
+	}
+	msg := packet.Messages[1]
+	if msg.Number != reply.ID || msg.Reference != thread.ID || msg.Subject != "Re: Hello" || msg.To != "alice" ||
+		msg.Conference != f.general.ID {
+		t.Errorf("Unexpected reply message %+v", msg)
+	}
+	if pointers[f.general.ID] != own.ID || pointers[f.tech.ID] != elsewhere.ID {
+		t.Errorf("Expected the pointers to move past the user's own post, got %v", pointers)
+	}
+
+	for boardID, postID := range pointers {
+		f.repos.ReadPointer.Set(reader.ID, boardID, postID)
+	}
+	f.post(f.general, thread.ID, "", "Another reply")
+	packet, pointers, _ = qwkPacket(f.server.services, reader)
+	if len(packet.Messages) != 1 || packet.Messages[0].Body != "Another reply" || len(pointers) != 1 {
+		t.Errorf("Expected only the new reply, got %d messages and pointers %v", len(packet.Messages), pointers)
+	}
+}
+
+func TestQWKImportReplies(t *testing.T) {
+	f, reader := newQWKFixture(t)
+	reader.Signature = "reader sig"
+	thread := f.post(f.general, 0, "Hello", "First post")
+	reply := f.post(f.general, thread.ID, "", "A reply")
+
+	replies := []*qwk.Reply{
+		{Message: qwk.Message{Conference: f.tech.ID, Subject: "New thread", Body: "Starting out"}},
+		{Message: qwk.Message{Conference: f.general.ID, Subject: "Re: Hello", Reference: reply.ID, Body: "Reply to a reply"}},
+		{Message: qwk.Message{Conference: f.general.ID, Subject: "Re: Hello", Reference: thread.ID, Body: "Reply to the thread"}},
+		{Message: qwk.Message{Conference: f.tech.ID, Subject: "Re: Hello", Reference: thread.ID, Body: "Wrong board"}},
+		{Message: qwk.Message{Conference: f.general.ID, Subject: "Re: Gone", Reference: 99, Body: "Missing"}},
+		{Message: qwk.Message{Conference: 42, Subject: "Nowhere", Body: "Text"}},
+		{Message: qwk.Message{Conference: f.general.ID, Body: "No subject"}},
+		{Message: qwk.Message{Conference: f.general.ID, Subject: "Empty", Body: " "}},
+		{Message: qwk.Message{Conference: f.general.ID, Subject: "Secret", Body: "Psst"}, Private: true},
+	}
+
+	posts, errs := importReplies(f.server.services, reader, replies)
+	if len(posts) != 3 || len(errs) != 6 {
+		t.Fatalf("Expected 3 posts and 6 errors, got %d and %v", len(posts), errs)
+	}
+	if posts[0].BoardID != f.tech.ID || posts[0].Title != "New thread" || posts[0].ReplyTo != nil ||
+		posts[0].Content != "Starting out\n-- \nreader sig" {
+		t.Errorf("Unexpected new thread %+v", posts[0])
+	}
+	for _, post := range posts[1:] {
+		if post.ReplyTo == nil || *post.ReplyTo != thread.ID || post.Title != "" {
+			t.Errorf("Expected a reply to thread %d, got %+v", thread.ID, post)
+		}
+	}
+	for i, want := range []string{"another conference", "not found", "no conference 42", "needs a subject", "no text", "private"} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("Expected error %d to mention %q, got %v", i, want, errs[i])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/leinonen/bbs/auth"
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/mail"
+	"github.com/leinonen/bbs/notify"
+	"github.com/leinonen/bbs/recording"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/ui"
+	"github.com/leinonen/bbs/webhook"
+	"golang.org/x/term"
+)
+
+// NewServices sets up what the SSH and telnet servers share, so that node
+// numbers, connection limits, login throttling and notices work the same
+// whichever way a caller connects.
+func NewServices(cfg *config.Config, repos *repository.Manager) *ui.Services {
+	mailer := mail.New(cfg)
+	sessions := domain.NewSessionManager()
+	return &ui.Services{
+		Config:     cfg,
+		Repos:      repos,
+		Sessions:   sessions,
+		Auth:       auth.NewAuthenticator(repos, loginThrottle(cfg)),
+		Mailer:     mailer,
+		Notifier:   notify.NewDispatcher(repos, sessions, mailer, cfg.ServerName),
+		Webhooks:   webhook.NewDispatcher(cfg, repos),
+		Recordings: recording.NewStore(cfg),
+	}
+}
+
+func loginThrottle(cfg *config.Config) *domain.LoginThrottle {
+	lockout := time.Duration(cfg.LoginLockoutMinutes) * time.Minute
+
+	account := domain.DefaultThrottlePolicy()
+	account.LockoutAttempts = cfg.LoginLockoutAttempts
+	account.LockoutDuration = lockout
+
+	// one address may legitimately serve many users, so it gets more room
+	ip := domain.DefaultThrottlePolicy()
+	ip.FreeAttempts = 10
+	ip.LockoutAttempts = cfg.LoginIPLockoutAttempts
+	ip.LockoutDuration = lockout
+
+	return domain.NewLoginThrottle(account, ip)
+}
+
+// RunJobs delivers email and webhooks and prunes recordings until stop is
+// closed.
+func RunJobs(services *ui.Services, stop <-chan struct{}) {
+	go services.Notifier.Run(stop)
+	go services.Webhooks.Run(stop)
+	services.Recordings.Run(stop)
+}
+
+// openSession takes a node for user, or tells the caller on w that every
+// node is busy.
+func openSession(services *ui.Services, user *domain.User, w io.Writer) (*domain.Session, bool) {
+	session, err := services.Sessions.OpenSession(user, nil, services.Config.MaxUsers)
+	if err != nil {
+		fmt.Fprintf(w, "All %d nodes of %s are busy. Please call again later.\r\n",
+			services.Config.MaxUsers, services.Config.ServerName)
+		return nil, false
+	}
+	return session, true
+}
+
+// runUI runs the BBS for session on t until the caller leaves, recording
+// it at width x height if recorder is not nil.
+func runUI(services *ui.Services, session *domain.Session, t *term.Terminal, recorder *recording.Recorder, width, height int) {
+	if recorder != nil {
+		if err := recorder.Start(width, height, session.TermType); err != nil {
+			log.Printf("Failed to start recording node %d: %v", session.Node, err)
+		} else {
+			session.Recording = recorder
+		}
+	}
+
+	ui.NewUI(t, services, session).Run()
+}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 
+	"github.com/leinonen/bbs/auth"
 	"github.com/leinonen/bbs/config"
 	"github.com/leinonen/bbs/domain"
 	"github.com/leinonen/bbs/repository"
@@ -19,17 +20,17 @@ import (
 )
 
 type SSHServer struct {
+	services *ui.Services
 	config   *config.Config
 	repos    *repository.Manager
 	listener net.Listener
-	sessions *domain.SessionManager
 }
 
-func NewSSHServer(cfg *config.Config, repos *repository.Manager) *SSHServer {
+func NewSSHServer(services *ui.Services) *SSHServer {
 	return &SSHServer{
-		config:   cfg,
-		repos:    repos,
-		sessions: domain.NewSessionManager(),
+		services: services,
+		config:   services.Config,
+		repos:    services.Repos,
 	}
 }
 
@@ -37,20 +38,26 @@ func (s *SSHServer) Start() error {
 	sshConfig := &ssh.ServerConfig{
 		NoClientAuth: s.config.AllowAnonymous,
 		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
-			user, err := s.repos.User.Authenticate(conn.User(), string(password))
+			extensions := map[string]string{}
+
+			result, err := s.services.Auth.Password(conn.User(), string(password), remoteHost(conn.RemoteAddr()))
 			if err != nil {
-				return nil, fmt.Errorf("invalid credentials")
+				return nil, err
+			}
+			user := result.User
+			if result.PasswordResetID != 0 {
+				extensions["password-reset"] = fmt.Sprintf("%d", result.PasswordResetID)
 			}
 
-			if err := s.repos.User.UpdateLastLogin(user.ID); err != nil {
-				log.Printf("Failed to update last login: %v", err)
+			if result.NeedsSecondFactor() {
+				return nil, &ssh.PartialSuccessError{
+					Next: ssh.ServerAuthCallbacks{
+						KeyboardInteractiveCallback: s.secondFactorCallback(user, extensions),
+					},
+				}
 			}
 
-			return &ssh.Permissions{
-				Extensions: map[string]string{
-					"user-id": fmt.Sprintf("%d", user.ID),
-				},
-			}, nil
+			return s.permissions(user, extensions), nil
 		},
 	}
 
@@ -80,6 +87,35 @@ func (s *SSHServer) Start() error {
 	}
 }
 
+// secondFactorCallback asks for an authenticator or recovery code once the
+// password has been accepted.
+func (s *SSHServer) secondFactorCallback(user *domain.User, extensions map[string]string) func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+	return func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+		answers, err := challenge("", "Two-factor authentication", []string{"Authentication code: "}, []bool{true})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(answers) != 1 {
+			return nil, auth.ErrInvalidCode
+		}
+		if err := s.services.Auth.SecondFactor(user, answers[0], remoteHost(conn.RemoteAddr())); err != nil {
+			return nil, err
+		}
+
+		return s.permissions(user, extensions), nil
+	}
+}
+
+func (s *SSHServer) permissions(user *domain.User, extensions map[string]string) *ssh.Permissions {
+	if err := s.repos.User.UpdateLastLogin(user.ID); err != nil {
+		log.Printf("Failed to update last login: %v", err)
+	}
+
+	extensions["user-id"] = fmt.Sprintf("%d", user.ID)
+	return &ssh.Permissions{Extensions: extensions}
+}
+
 func (s *SSHServer) Stop() {
 	if s.listener != nil {
 		s.listener.Close()
@@ -120,8 +156,6 @@ func (s *SSHServer) handleConnection(netConn net.Conn, config *ssh.ServerConfig)
 func (s *SSHServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request, sshConn *ssh.ServerConn) {
 	defer channel.Close()
 
-	term := term.NewTerminal(channel, "")
-
 	var user *domain.User
 	if s.config.AllowAnonymous && sshConn.Permissions == nil {
 		user = &domain.User{
@@ -135,20 +169,66 @@ func (s *SSHServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Requ
 		user, _ = s.repos.User.GetByID(userID)
 	}
 
-	session := s.sessions.CreateSession(user, term)
-	defer s.sessions.RemoveSession(session.ID)
+	session, ok := openSession(s.services, user, channel)
+	if !ok {
+		return
+	}
+	defer s.services.Sessions.RemoveSession(session.ID)
 
+	if sshConn.Permissions != nil {
+		fmt.Sscanf(sshConn.Permissions.Extensions["password-reset"], "%d", &session.PasswordResetID)
+	}
+	session.RemoteAddr = remoteHost(sshConn.RemoteAddr())
+	session.ClientVersion = string(sshConn.ClientVersion())
+	session.Transport = "SSH"
+
+	recorder := s.services.Recordings.NewRecorder(session)
+	defer recorder.Close()
+
+	term := term.NewTerminal(recorder.Wrap(session.Writer(channel)), "")
+	session.Terminal = term
+	session.Conn = channel
+
+	// the size the client asked for, which the recording starts at
+	ptyWidth, ptyHeight := 80, 24
+	// command is set instead of starting the BBS when the client uses exec
+	command := ""
+	shellStarted := make(chan struct{})
 	go func() {
+		defer closeOnce(shellStarted)
 		for req := range requests {
 			switch req.Type {
 			case "pty-req":
-				termLen := req.Payload[3]
+				pty, err := parsePtyRequest(req.Payload)
+				if err != nil {
+					req.Reply(false, nil)
+					continue
+				}
+				session.TermType = pty.Term
 				term.SetPrompt("")
-				width, height := parseDims(req.Payload[termLen+4:])
+				width, height := int(pty.Columns), int(pty.Rows)
 				term.SetSize(width, height)
+				ptyWidth, ptyHeight = width, height
 				req.Reply(true, nil)
 			case "shell":
 				req.Reply(true, nil)
+				closeOnce(shellStarted)
+			case "exec":
+				var payload struct{ Command string }
+				select {
+				case <-shellStarted:
+					// too late, the BBS is already running
+					req.Reply(false, nil)
+					continue
+				default:
+				}
+				if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+					req.Reply(false, nil)
+					continue
+				}
+				command = payload.Command
+				req.Reply(true, nil)
+				closeOnce(shellStarted)
 			case "window-change":
 				width, height := parseDims(req.Payload)
 				term.SetSize(width, height)
@@ -158,8 +238,25 @@ func (s *SSHServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Requ
 		}
 	}()
 
-	ui := ui.NewUI(term, s.repos, session)
-	ui.Run()
+	// Wait for the client to finish its pty-req so the terminal type is
+	// known before the first screen is drawn.
+	<-shellStarted
+
+	if command != "" {
+		status := s.runCommand(channel, session, command)
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+		return
+	}
+
+	runUI(s.services, session, term, recorder, ptyWidth, ptyHeight)
+}
+
+func closeOnce(ch chan struct{}) {
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
 }
 
 func (s *SSHServer) loadOrGenerateHostKey() (ssh.Signer, error) {
@@ -201,6 +298,31 @@ func (s *SSHServer) generateHostKey(path string) (ssh.Signer, error) {
 	return ssh.NewSignerFromKey(key)
 }
 
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// ptyRequest is the payload of a pty-req (RFC 4254, section 6.2).
+type ptyRequest struct {
+	Term                         string
+	Columns, Rows, Width, Height uint32
+	Modes                        string
+}
+
+// parsePtyRequest decodes a pty-req payload, which comes from the client
+// before it has logged in and so cannot be trusted to be well formed.
+func parsePtyRequest(payload []byte) (*ptyRequest, error) {
+	var pty ptyRequest
+	if err := ssh.Unmarshal(payload, &pty); err != nil {
+		return nil, err
+	}
+	return &pty, nil
+}
+
 func parseDims(b []byte) (int, int) {
 	if len(b) < 8 {
 		return 80, 24
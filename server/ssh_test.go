@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParsePtyRequest(t *testing.T) {
+	payload := ssh.Marshal(ptyRequest{Term: "xterm-256color", Columns: 132, Rows: 43, Modes: "\x00"})
+	pty, err := parsePtyRequest(payload)
+	if err != nil || pty.Term != "xterm-256color" || pty.Columns != 132 || pty.Rows != 43 {
+		t.Fatalf("Unexpected pty request %+v: %v", pty, err)
+	}
+
+	// a terminal name of 252 bytes or more once overflowed the length
+	long := ssh.Marshal(ptyRequest{Term: string(make([]byte, 300)), Columns: 80, Rows: 24})
+	if pty, err := parsePtyRequest(long); err != nil || len(pty.Term) != 300 {
+		t.Errorf("Expected a long terminal name to be read, got %v", err)
+	}
+
+	for name, payload := range map[string][]byte{
+		"empty":          nil,
+		"short":          {0, 0},
+		"oversized term": {0, 0, 0, 0xff, 'x', 't', 'e', 'r', 'm'},
+		"truncated dims": append(ssh.Marshal(struct{ Term string }{"vt100"}), 0, 0, 0, 80),
+		"huge term":      {0xff, 0xff, 0xff, 0xff, 'x'},
+	} {
+		if _, err := parsePtyRequest(payload); err == nil {
+			t.Errorf("Expected the %s payload to be refused", name)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/ui"
+	"golang.org/x/term"
+)
+
+// Telnet commands and options, from RFC 854, 857, 858, 1073 and 1091.
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWill = 251
+	telnetWont = 252
+	telnetDo   = 253
+	telnetDont = 254
+	telnetIAC  = 255
+
+	optEcho  = 1
+	optSGA   = 3
+	optTType = 24
+	optNAWS  = 31
+
+	ttypeIs   = 0
+	ttypeSend = 1
+)
+
+// negotiateTimeout is how long a new caller's client has to report its
+// terminal type and window size before the first screen is drawn anyway.
+const negotiateTimeout = 2 * time.Second
+
+// maxSubnegotiation caps how much of a subnegotiation is kept, so a broken
+// client cannot make the server buffer without end.
+const maxSubnegotiation = 64
+
+// TelnetServer lets callers with only a telnet client into the same BBS as
+// SSHServer. There is no transport login, so everyone starts at the login
+// menu.
+type TelnetServer struct {
+	services *ui.Services
+	config   *config.Config
+	listener net.Listener
+}
+
+func NewTelnetServer(services *ui.Services) *TelnetServer {
+	return &TelnetServer{
+		services: services,
+		config:   services.Config,
+	}
+}
+
+func (s *TelnetServer) Start() error {
+	listener, err := net.Listen("tcp", s.config.TelnetAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	s.listener = listener
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.listener == nil {
+				return nil
+			}
+			log.Printf("Failed to accept telnet connection: %v", err)
+			continue
+		}
+
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *TelnetServer) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+}
+
+func (s *TelnetServer) handleConnection(netConn net.Conn) {
+	defer netConn.Close()
+
+	log.Printf("New telnet connection from %s", netConn.RemoteAddr())
+
+	conn := newTelnetConn(netConn)
+	if err := conn.negotiate(negotiateTimeout); err != nil {
+		log.Printf("Telnet negotiation with %s failed: %v", netConn.RemoteAddr(), err)
+		return
+	}
+
+	var user *domain.User
+	if s.config.AllowAnonymous {
+		user = &domain.User{
+			Username: "anonymous",
+			ID:       0,
+		}
+	}
+
+	session, ok := openSession(s.services, user, conn)
+	if !ok {
+		return
+	}
+	defer s.services.Sessions.RemoveSession(session.ID)
+
+	session.RemoteAddr = remoteHost(netConn.RemoteAddr())
+	session.ClientVersion = "telnet"
+	session.Transport = "Telnet"
+	session.TermType = conn.termType
+	session.Conn = netConn
+
+	recorder := s.services.Recordings.NewRecorder(session)
+	defer recorder.Close()
+
+	term := term.NewTerminal(recorder.Wrap(session.Writer(conn)), "")
+	session.Terminal = term
+	term.SetSize(conn.width, conn.height)
+	conn.resize = func(width, height int) {
+		term.SetSize(width, height)
+	}
+
+	runUI(s.services, session, term, recorder, conn.width, conn.height)
+}
+
+// Parser states for the bytes coming from the client.
+const (
+	stateData = iota
+	stateIAC
+	stateOption
+	stateSub
+	stateSubIAC
+)
+
+// telnetConn speaks the telnet protocol on conn, so that reads return only
+// what the caller typed and writes reach the client unharmed.
+//
+// The BBS echoes and edits lines itself, so the server offers ECHO and SGA
+// to put the client in character mode, and asks for NAWS and TTYPE to learn
+// the window size and terminal type.
+type telnetConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	// Everything below belongs to whichever goroutine is reading.
+	buf     [512]byte
+	pending []byte
+	state   int
+	command byte
+	sub     []byte
+	lastCR  bool
+
+	// offered and requested are the WILLs and DOs the server sent that the
+	// client has not answered yet, so that its answers are not answered
+	// again. local and remote are the options in effect on each side.
+	offered, requested [256]bool
+	local, remote      [256]bool
+
+	width, height int
+	sizeReported  bool
+	termType      string
+	ttypeRefused  bool
+	nawsRefused   bool
+	resize        func(width, height int)
+}
+
+func newTelnetConn(conn net.Conn) *telnetConn {
+	return &telnetConn{conn: conn, width: 80, height: 24}
+}
+
+// negotiate offers the options the BBS needs and waits up to timeout for
+// the client's terminal type and window size. Anything typed meanwhile is
+// kept for the first Read.
+func (c *telnetConn) negotiate(timeout time.Duration) error {
+	c.offer(optEcho)
+	c.offer(optSGA)
+	c.request(optNAWS)
+	c.request(optTType)
+
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	for !c.negotiated() {
+		n, err := c.conn.Read(c.buf[:])
+		c.parse(c.buf[:n])
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *telnetConn) negotiated() bool {
+	return (c.termType != "" || c.ttypeRefused) && (c.sizeReported || c.nawsRefused)
+}
+
+func (c *telnetConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		n, err := c.conn.Read(c.buf[:])
+		c.parse(c.buf[:n])
+		if err != nil && len(c.pending) == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write sends p to the client, doubling any IAC bytes in it.
+func (c *telnetConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	data := p
+	if bytes.IndexByte(p, telnetIAC) >= 0 {
+		data = bytes.ReplaceAll(p, []byte{telnetIAC}, []byte{telnetIAC, telnetIAC})
+	}
+	if _, err := c.conn.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *telnetConn) send(data ...byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.Write(data)
+}
+
+func (c *telnetConn) offer(option byte) {
+	c.offered[option] = true
+	c.send(telnetIAC, telnetWill, option)
+}
+
+func (c *telnetConn) request(option byte) {
+	c.requested[option] = true
+	c.send(telnetIAC, telnetDo, option)
+}
+
+// parse strips telnet commands from b, acting on them, and appends the rest
+// to c.pending. The CR LF and CR NUL a client sends for Enter become a
+// single CR.
+func (c *telnetConn) parse(b []byte) {
+	for _, ch := range b {
+		switch c.state {
+		case stateData:
+			if ch == telnetIAC {
+				c.state = stateIAC
+				continue
+			}
+			if c.lastCR && (ch == '\n' || ch == 0) {
+				c.lastCR = false
+				continue
+			}
+			c.lastCR = ch == '\r'
+			c.pending = append(c.pending, ch)
+		case stateIAC:
+			c.state = stateData
+			switch ch {
+			case telnetIAC:
+				c.lastCR = false
+				c.pending = append(c.pending, ch)
+			case telnetWill, telnetWont, telnetDo, telnetDont:
+				c.command = ch
+				c.state = stateOption
+			case telnetSB:
+				c.sub = c.sub[:0]
+				c.state = stateSub
+			}
+		case stateOption:
+			c.option(c.command, ch)
+			c.state = stateData
+		case stateSub:
+			if ch == telnetIAC {
+				c.state = stateSubIAC
+			} else if len(c.sub) < maxSubnegotiation {
+				c.sub = append(c.sub, ch)
+			}
+		case stateSubIAC:
+			switch ch {
+			case telnetSE:
+				c.subnegotiation(c.sub)
+				c.state = stateData
+			case telnetIAC:
+				if len(c.sub) < maxSubnegotiation {
+					c.sub = append(c.sub, ch)
+				}
+				c.state = stateSub
+			default:
+				c.state = stateData
+			}
+		}
+	}
+}
+
+// option answers a WILL, WONT, DO or DONT from the client, following the
+// rule of RFC 854 that a request for the state an option is already in is
+// not acknowledged.
+func (c *telnetConn) option(command, option byte) {
+	switch command {
+	case telnetWill:
+		requested := c.requested[option]
+		c.requested[option] = false
+		if option != optNAWS && option != optTType {
+			c.send(telnetIAC, telnetDont, option)
+			return
+		}
+		if c.remote[option] {
+			return
+		}
+		c.remote[option] = true
+		if !requested {
+			c.send(telnetIAC, telnetDo, option)
+		}
+		if option == optTType {
+			c.send(telnetIAC, telnetSB, optTType, ttypeSend, telnetIAC, telnetSE)
+		}
+	case telnetWont:
+		c.requested[option] = false
+		if c.remote[option] {
+			c.remote[option] = false
+			c.send(telnetIAC, telnetDont, option)
+		}
+		switch option {
+		case optTType:
+			c.ttypeRefused = true
+		case optNAWS:
+			c.nawsRefused = true
+		}
+	case telnetDo:
+		offered := c.offered[option]
+		c.offered[option] = false
+		if option != optEcho && option != optSGA {
+			c.send(telnetIAC, telnetWont, option)
+			return
+		}
+		if c.local[option] {
+			return
+		}
+		c.local[option] = true
+		if !offered {
+			c.send(telnetIAC, telnetWill, option)
+		}
+	case telnetDont:
+		c.offered[option] = false
+		if c.local[option] {
+			c.local[option] = false
+			c.send(telnetIAC, telnetWont, option)
+		}
+	}
+}
+
+func (c *telnetConn) subnegotiation(sub []byte) {
+	if len(sub) == 0 {
+		return
+	}
+
+	switch sub[0] {
+	case optNAWS:
+		if len(sub) < 5 {
+			return
+		}
+		width := int(sub[1])<<8 | int(sub[2])
+		height := int(sub[3])<<8 | int(sub[4])
+		if width == 0 || height == 0 {
+			return
+		}
+		c.width, c.height = width, height
+		c.sizeReported = true
+		if c.resize != nil {
+			c.resize(width, height)
+		}
+	case optTType:
+		if len(sub) < 2 || sub[1] != ttypeIs || c.termType != "" {
+			return
+		}
+		termType := strings.ToLower(string(sub[2:]))
+		c.termType = domain.SanitizeLine(termType)
+		if c.termType == "" {
+			c.ttypeRefused = true
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// telnetClient is the far end of a telnetConn, which records everything the
+// server sends.
+type telnetClient struct {
+	conn net.Conn
+	mu   sync.Mutex
+	got  bytes.Buffer
+	done chan struct{}
+}
+
+func newTelnetPair(t *testing.T) (*telnetConn, *telnetClient) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	c := &telnetClient{conn: client, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		buf := make([]byte, 256)
+		for {
+			n, err := client.Read(buf)
+			c.mu.Lock()
+			c.got.Write(buf[:n])
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return newTelnetConn(server), c
+}
+
+func (c *telnetClient) received() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.got.Bytes()...)
+}
+
+func TestTelnetConn_Negotiate(t *testing.T) {
+	conn, client := newTelnetPair(t)
+
+	result := make(chan error, 1)
+	go func() { result <- conn.negotiate(time.Second) }()
+
+	client.conn.Write([]byte{
+		telnetIAC, telnetDo, optEcho,
+		telnetIAC, telnetDo, optSGA,
+		telnetIAC, telnetWill, optNAWS,
+		telnetIAC, telnetSB, optNAWS, 0, 100, 0, 30, telnetIAC, telnetSE,
+		telnetIAC, telnetWill, optTType,
+		telnetIAC, telnetSB, optTType, ttypeIs, 'X', 'T', 'E', 'R', 'M', telnetIAC, telnetSE,
+		'h', 'i', '\r', '\n',
+	})
+
+	if err := <-result; err != nil {
+		t.Fatalf("negotiate failed: %v", err)
+	}
+	if conn.width != 100 || conn.height != 30 {
+		t.Errorf("Expected a 100x30 window, got %dx%d", conn.width, conn.height)
+	}
+	if conn.termType != "xterm" {
+		t.Errorf("Expected terminal type xterm, got %q", conn.termType)
+	}
+
+	buf := make([]byte, 16)
+	n, _ := conn.Read(buf)
+	if string(buf[:n]) != "hi\r" {
+		t.Errorf("Expected typed text with CR LF as CR, got %q", buf[:n])
+	}
+
+	conn.conn.Close()
+	<-client.done
+	want := []byte{
+		telnetIAC, telnetWill, optEcho,
+		telnetIAC, telnetWill, optSGA,
+		telnetIAC, telnetDo, optNAWS,
+		telnetIAC, telnetDo, optTType,
+		telnetIAC, telnetSB, optTType, ttypeSend, telnetIAC, telnetSE,
+	}
+	if got := client.received(); !bytes.Equal(got, want) {
+		t.Errorf("Expected only the offers and a TTYPE SEND, got %v", got)
+	}
+}
+
+func TestTelnetConn_Timeout(t *testing.T) {
+	conn, _ := newTelnetPair(t)
+
+	start := time.Now()
+	if err := conn.negotiate(50 * time.Millisecond); err != nil {
+		t.Fatalf("negotiate should give up quietly, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("negotiate should stop waiting after the timeout")
+	}
+	if conn.width != 80 || conn.height != 24 {
+		t.Errorf("Expected the default 80x24 window, got %dx%d", conn.width, conn.height)
+	}
+}
+
+func TestTelnetConn_Data(t *testing.T) {
+	conn, client := newTelnetPair(t)
+
+	resized := ""
+	conn.resize = func(width, height int) {
+		if width == 132 && height == 50 {
+			resized = "132x50"
+		}
+	}
+
+	go func() {
+		client.conn.Write([]byte{
+			'a', telnetIAC, telnetIAC, 'b', '\r', 0,
+			telnetIAC, telnetWill, 99,
+			telnetIAC, telnetDo, 99,
+			telnetIAC, telnetSB, optNAWS, 0, 132, 0, 50, telnetIAC, telnetSE,
+			'c',
+		})
+	}()
+
+	var got []byte
+	buf := make([]byte, 16)
+	for len(got) < 5 {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if string(got) != "a\xffb\rc" {
+		t.Errorf("Expected telnet commands stripped from the input, got %q", got)
+	}
+	if resized != "132x50" {
+		t.Error("Expected a window size change to resize the terminal")
+	}
+
+	conn.Write([]byte("x\xffy"))
+	conn.conn.Close()
+	<-client.done
+	want := []byte{
+		telnetIAC, telnetDont, 99,
+		telnetIAC, telnetWont, 99,
+		'x', telnetIAC, telnetIAC, 'y',
+	}
+	if got := client.received(); !bytes.Equal(got, want) {
+		t.Errorf("Expected unknown options refused and IAC doubled, got %v", got)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/ui"
+	"github.com/leinonen/bbs/websocket"
+	"golang.org/x/term"
+)
+
+// webAssets are the terminal page, script and styles, built in so the
+// gateway works without anything from the internet.
+//
+//go:embed web
+var webAssets embed.FS
+
+var webPage = template.Must(template.ParseFS(webAssets, "web/index.html"))
+
+// maxWebSize caps the window size a page may ask for.
+const maxWebSize = 500
+
+// WebServer lets callers use the BBS from a browser. It serves a page with
+// a terminal emulator, and runs a session for each WebSocket the page opens.
+// Like telnet callers, everyone starts at the login menu.
+type WebServer struct {
+	services *ui.Services
+	config   *config.Config
+	server   *http.Server
+}
+
+func NewWebServer(services *ui.Services) *WebServer {
+	s := &WebServer{
+		services: services,
+		config:   services.Config,
+	}
+
+	assets, _ := fs.Sub(webAssets, "web")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(assets))))
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+
+	s.server = &http.Server{
+		Addr:              s.config.WebAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+func (s *WebServer) Start() error {
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Stop stops accepting callers. Sessions already running carry on until
+// they end, as they do for SSH.
+func (s *WebServer) Stop() {
+	s.server.Close()
+}
+
+func (s *WebServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webPage.Execute(w, s.config); err != nil {
+		log.Printf("Failed to render web terminal page: %v", err)
+	}
+}
+
+func (s *WebServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := websocket.Upgrade(w, r)
+	if err != nil {
+		log.Printf("Refused WebSocket from %s: %v", r.RemoteAddr, err)
+		return
+	}
+	defer ws.Close()
+
+	log.Printf("New web connection from %s (%s)", r.RemoteAddr, r.UserAgent())
+
+	// The page sends its size as soon as it connects, like an SSH pty-req.
+	conn := &webConn{ws: ws, width: 80, height: 24}
+	ws.SetReadDeadline(time.Now().Add(negotiateTimeout))
+	if err := conn.waitForSize(); err != nil {
+		log.Printf("Web terminal from %s did not start: %v", r.RemoteAddr, err)
+		return
+	}
+	ws.SetReadDeadline(time.Time{})
+
+	var user *domain.User
+	if s.config.AllowAnonymous {
+		user = &domain.User{
+			Username: "anonymous",
+			ID:       0,
+		}
+	}
+
+	session, ok := openSession(s.services, user, conn)
+	if !ok {
+		return
+	}
+	defer s.services.Sessions.RemoveSession(session.ID)
+
+	session.RemoteAddr = remoteHost(ws.RemoteAddr())
+	session.ClientVersion = "web"
+	session.Transport = "Web"
+	session.TermType = "xterm"
+	session.Conn = ws
+
+	recorder := s.services.Recordings.NewRecorder(session)
+	defer recorder.Close()
+
+	term := term.NewTerminal(recorder.Wrap(session.Writer(conn)), "")
+	session.Terminal = term
+	term.SetSize(conn.width, conn.height)
+	conn.resize = func(width, height int) {
+		term.SetSize(width, height)
+	}
+
+	runUI(s.services, session, term, recorder, conn.width, conn.height)
+}
+
+// webConn carries a terminal over a WebSocket. Binary messages hold the
+// terminal's bytes in each direction, and text messages from the page are
+// JSON control messages such as {"type":"resize","cols":80,"rows":24}.
+type webConn struct {
+	ws      *websocket.Conn
+	pending []byte
+
+	width, height int
+	resize        func(width, height int)
+}
+
+type webControl struct {
+	Type string `json:"type"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// waitForSize reads until the page reports its size. Anything typed first
+// is kept for the first Read.
+func (c *webConn) waitForSize() error {
+	for {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if messageType == websocket.BinaryMessage {
+			c.pending = append(c.pending, data...)
+			continue
+		}
+		if c.control(data) {
+			return nil
+		}
+	}
+}
+
+func (c *webConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		if messageType == websocket.BinaryMessage {
+			c.pending = data
+		} else {
+			c.control(data)
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *webConn) Write(p []byte) (int, error) {
+	if err := c.ws.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// control handles a control message from the page, and reports whether it
+// was a resize.
+func (c *webConn) control(data []byte) bool {
+	var msg webControl
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "resize" {
+		return false
+	}
+	if msg.Cols < 1 || msg.Rows < 1 || msg.Cols > maxWebSize || msg.Rows > maxWebSize {
+		return false
+	}
+
+	c.width, c.height = msg.Cols, msg.Rows
+	if c.resize != nil {
+		c.resize(msg.Cols, msg.Rows)
+	}
+	return true
+}
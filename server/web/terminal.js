@@ -0,0 +1,689 @@
+// A small terminal emulator for the BBS: it understands the ANSI escape
+// sequences the BBS and its ANSI art use, and bridges the keyboard and the
+// screen to a WebSocket. Binary messages carry terminal bytes both ways;
+// text messages to the server are JSON control messages.
+(function () {
+  "use strict";
+
+  var SCROLLBACK = 1000;
+
+  var PALETTE = [
+    "#000000", "#aa0000", "#00aa00", "#aa5500", "#0000aa", "#aa00aa", "#00aaaa", "#aaaaaa",
+    "#555555", "#ff5555", "#55ff55", "#ffff55", "#5555ff", "#ff55ff", "#55ffff", "#ffffff"
+  ];
+  for (var i = 16; i < 256; i++) {
+    if (i < 232) {
+      var n = i - 16;
+      PALETTE.push(rgb(level(Math.floor(n / 36)), level(Math.floor(n / 6) % 6), level(n % 6)));
+    } else {
+      var grey = 8 + (i - 232) * 10;
+      PALETTE.push(rgb(grey, grey, grey));
+    }
+  }
+
+  function level(v) {
+    return v === 0 ? 0 : 55 + v * 40;
+  }
+
+  function rgb(r, g, b) {
+    return "#" + [r, g, b].map(function (v) {
+      return ("0" + v.toString(16)).slice(-2);
+    }).join("");
+  }
+
+  var DEFAULT_ATTR = { fg: null, bg: null, bold: false, underline: false, blink: false, inverse: false };
+
+  function Terminal(element) {
+    this.element = element;
+    this.cols = 80;
+    this.rows = 24;
+    this.attr = DEFAULT_ATTR;
+    this.state = "ground";
+    this.params = "";
+    this.cursorVisible = true;
+    this.screen = element.appendChild(document.createElement("div"));
+    this.reset();
+  }
+
+  Terminal.prototype.reset = function () {
+    this.lines = [];
+    this.rowElements = [];
+    this.screen.textContent = "";
+    for (var y = 0; y < this.rows; y++) {
+      this.lines.push(this.blankLine());
+      this.rowElements.push(this.screen.appendChild(this.newRow()));
+    }
+    this.x = 0;
+    this.y = 0;
+    this.wrapPending = false;
+    this.top = 0;
+    this.bottom = this.rows - 1;
+    this.saved = { x: 0, y: 0, attr: DEFAULT_ATTR };
+    this.dirty = {};
+    this.markAll();
+  };
+
+  Terminal.prototype.newRow = function () {
+    var row = document.createElement("div");
+    row.className = "row";
+    return row;
+  };
+
+  Terminal.prototype.blankLine = function () {
+    var line = [];
+    for (var x = 0; x < this.cols; x++) {
+      line.push({ ch: " ", attr: this.attr });
+    }
+    return line;
+  };
+
+  Terminal.prototype.resize = function (cols, rows) {
+    if (cols === this.cols && rows === this.rows) {
+      return;
+    }
+    var x, y;
+    for (y = 0; y < this.lines.length; y++) {
+      var line = this.lines[y];
+      if (line.length > cols) {
+        line.length = cols;
+      }
+      for (x = line.length; x < cols; x++) {
+        line.push({ ch: " ", attr: DEFAULT_ATTR });
+      }
+    }
+    this.cols = cols;
+    // keep the bottom of the screen, where the cursor usually is
+    while (this.lines.length > rows) {
+      this.scrollOff(this.lines.shift(), this.rowElements.shift());
+      this.y = Math.max(0, this.y - 1);
+    }
+    while (this.lines.length < rows) {
+      this.lines.push(this.blankLine());
+      this.rowElements.push(this.screen.appendChild(this.newRow()));
+    }
+    this.rows = rows;
+    this.top = 0;
+    this.bottom = rows - 1;
+    this.x = Math.min(this.x, cols - 1);
+    this.y = Math.min(this.y, rows - 1);
+    this.wrapPending = false;
+    this.markAll();
+  };
+
+  // scrollOff keeps a line that left the top of the screen as scrollback.
+  Terminal.prototype.scrollOff = function (line, row) {
+    this.renderRow(row, line, -1);
+    this.screen.parentNode.insertBefore(row, this.screen);
+    var history = this.element.querySelectorAll(":scope > .row");
+    if (history.length > SCROLLBACK) {
+      history[0].remove();
+    }
+  };
+
+  Terminal.prototype.markAll = function () {
+    for (var y = 0; y < this.rows; y++) {
+      this.dirty[y] = true;
+    }
+  };
+
+  Terminal.prototype.write = function (text) {
+    for (var i = 0; i < text.length; i++) {
+      var ch = text[i];
+      var code = text.charCodeAt(i);
+      if (code >= 0xd800 && code <= 0xdbff && i + 1 < text.length) {
+        ch += text[++i];
+      }
+      this.feed(ch, code);
+    }
+    this.dirty[this.y] = true;
+  };
+
+  Terminal.prototype.feed = function (ch, code) {
+    switch (this.state) {
+      case "ground":
+        if (code === 0x1b) {
+          this.state = "escape";
+        } else if (code < 0x20 || code === 0x7f) {
+          this.control(code);
+        } else {
+          this.print(ch);
+        }
+        break;
+      case "escape":
+        this.state = "ground";
+        this.escape(ch);
+        break;
+      case "charset":
+        this.state = "ground";
+        break;
+      case "csi":
+        if (code >= 0x40 && code <= 0x7e) {
+          this.state = "ground";
+          this.csi(ch, this.params);
+        } else if (code >= 0x20 && code <= 0x3f) {
+          this.params += ch;
+        } else {
+          this.state = "ground";
+        }
+        break;
+      case "osc":
+        if (code === 0x07) {
+          this.state = "ground";
+        } else if (code === 0x1b) {
+          this.state = "oscEscape";
+        }
+        break;
+      case "oscEscape":
+        this.state = ch === "\\" ? "ground" : "osc";
+        break;
+    }
+  };
+
+  Terminal.prototype.control = function (code) {
+    switch (code) {
+      case 0x08:
+        this.moveTo(this.x - 1, this.y);
+        break;
+      case 0x09:
+        this.moveTo(Math.min(this.cols - 1, (Math.floor(this.x / 8) + 1) * 8), this.y);
+        break;
+      case 0x0a:
+      case 0x0b:
+      case 0x0c:
+        this.lineFeed();
+        break;
+      case 0x0d:
+        this.moveTo(0, this.y);
+        break;
+    }
+  };
+
+  Terminal.prototype.escape = function (ch) {
+    switch (ch) {
+      case "[":
+        this.state = "csi";
+        this.params = "";
+        break;
+      case "]":
+        this.state = "osc";
+        break;
+      case "(":
+      case ")":
+        this.state = "charset";
+        break;
+      case "7":
+        this.save();
+        break;
+      case "8":
+        this.restore();
+        break;
+      case "D":
+        this.lineFeed();
+        break;
+      case "E":
+        this.moveTo(0, this.y);
+        this.lineFeed();
+        break;
+      case "M":
+        if (this.y === this.top) {
+          this.scrollDown(1);
+        } else {
+          this.moveTo(this.x, this.y - 1);
+        }
+        break;
+      case "c":
+        this.attr = DEFAULT_ATTR;
+        this.reset();
+        break;
+    }
+  };
+
+  Terminal.prototype.csi = function (final, params) {
+    var isPrivate = params.charAt(0) === "?";
+    var args = (isPrivate ? params.slice(1) : params).split(";").map(function (p) {
+      return parseInt(p, 10);
+    });
+    var n = args[0] > 0 ? args[0] : 1;
+
+    switch (final) {
+      case "A": this.moveTo(this.x, Math.max(this.top, this.y - n)); break;
+      case "B": this.moveTo(this.x, Math.min(this.bottom, this.y + n)); break;
+      case "C": this.moveTo(this.x + n, this.y); break;
+      case "D": this.moveTo(this.x - n, this.y); break;
+      case "E": this.moveTo(0, this.y + n); break;
+      case "F": this.moveTo(0, this.y - n); break;
+      case "G": this.moveTo(n - 1, this.y); break;
+      case "d": this.moveTo(this.x, n - 1); break;
+      case "H":
+      case "f":
+        this.moveTo((args[1] > 0 ? args[1] : 1) - 1, n - 1);
+        break;
+      case "J": this.eraseDisplay(args[0] || 0); break;
+      case "K": this.eraseLine(args[0] || 0); break;
+      case "X": this.erase(this.y, this.x, this.x + n); break;
+      case "P": this.deleteChars(n); break;
+      case "@": this.insertChars(n); break;
+      case "L": this.insertLines(n); break;
+      case "M": this.deleteLines(n); break;
+      case "S": this.scrollUp(n); break;
+      case "T": this.scrollDown(n); break;
+      case "m": this.sgr(args); break;
+      case "s": this.save(); break;
+      case "u": this.restore(); break;
+      case "r":
+        var top = (args[0] > 0 ? args[0] : 1) - 1;
+        var bottom = (args[1] > 0 ? args[1] : this.rows) - 1;
+        if (top < bottom && bottom < this.rows) {
+          this.top = top;
+          this.bottom = bottom;
+          this.moveTo(0, 0);
+        }
+        break;
+      case "h":
+      case "l":
+        if (isPrivate && args[0] === 25) {
+          this.cursorVisible = final === "h";
+          this.dirty[this.y] = true;
+        }
+        break;
+    }
+  };
+
+  Terminal.prototype.sgr = function (args) {
+    var attr = Object.assign({}, this.attr);
+    for (var i = 0; i < args.length; i++) {
+      var a = isNaN(args[i]) ? 0 : args[i];
+      if (a === 0) {
+        attr = Object.assign({}, DEFAULT_ATTR);
+      } else if (a === 1) {
+        attr.bold = true;
+      } else if (a === 4) {
+        attr.underline = true;
+      } else if (a === 5 || a === 6) {
+        attr.blink = true;
+      } else if (a === 7) {
+        attr.inverse = true;
+      } else if (a === 22) {
+        attr.bold = false;
+      } else if (a === 24) {
+        attr.underline = false;
+      } else if (a === 25) {
+        attr.blink = false;
+      } else if (a === 27) {
+        attr.inverse = false;
+      } else if (a >= 30 && a <= 37) {
+        attr.fg = a - 30;
+      } else if (a === 39) {
+        attr.fg = null;
+      } else if (a >= 40 && a <= 47) {
+        attr.bg = a - 40;
+      } else if (a === 49) {
+        attr.bg = null;
+      } else if (a >= 90 && a <= 97) {
+        attr.fg = a - 90 + 8;
+      } else if (a >= 100 && a <= 107) {
+        attr.bg = a - 100 + 8;
+      } else if (a === 38 || a === 48) {
+        var color = null;
+        if (args[i + 1] === 5) {
+          color = args[i + 2];
+          i += 2;
+        } else if (args[i + 1] === 2) {
+          color = rgb(args[i + 2] & 255, args[i + 3] & 255, args[i + 4] & 255);
+          i += 4;
+        }
+        if (a === 38) {
+          attr.fg = color;
+        } else {
+          attr.bg = color;
+        }
+      }
+    }
+    this.attr = attr;
+  };
+
+  Terminal.prototype.print = function (ch) {
+    if (this.wrapPending) {
+      this.wrapPending = false;
+      this.x = 0;
+      this.lineFeed();
+    }
+    this.lines[this.y][this.x] = { ch: ch, attr: this.attr };
+    this.dirty[this.y] = true;
+    if (this.x === this.cols - 1) {
+      // like xterm, wrap only when the next character arrives
+      this.wrapPending = true;
+    } else {
+      this.x++;
+    }
+  };
+
+  Terminal.prototype.moveTo = function (x, y) {
+    this.dirty[this.y] = true;
+    this.x = Math.max(0, Math.min(this.cols - 1, x));
+    this.y = Math.max(0, Math.min(this.rows - 1, y));
+    this.wrapPending = false;
+    this.dirty[this.y] = true;
+  };
+
+  Terminal.prototype.lineFeed = function () {
+    this.wrapPending = false;
+    if (this.y === this.bottom) {
+      this.scrollUp(1);
+    } else if (this.y < this.rows - 1) {
+      this.dirty[this.y] = true;
+      this.y++;
+    }
+  };
+
+  Terminal.prototype.scrollUp = function (n) {
+    for (var i = 0; i < n; i++) {
+      var line = this.lines.splice(this.top, 1)[0];
+      this.lines.splice(this.bottom, 0, this.blankLine());
+      if (this.top === 0 && this.bottom === this.rows - 1) {
+        var row = this.rowElements.shift();
+        this.scrollOff(line, row);
+        this.rowElements.push(this.screen.appendChild(this.newRow()));
+      }
+    }
+    this.markRegion();
+  };
+
+  Terminal.prototype.scrollDown = function (n) {
+    for (var i = 0; i < n; i++) {
+      this.lines.splice(this.bottom, 1);
+      this.lines.splice(this.top, 0, this.blankLine());
+    }
+    this.markRegion();
+  };
+
+  Terminal.prototype.markRegion = function () {
+    for (var y = this.top; y <= this.bottom; y++) {
+      this.dirty[y] = true;
+    }
+  };
+
+  Terminal.prototype.insertLines = function (n) {
+    if (this.y < this.top || this.y > this.bottom) {
+      return;
+    }
+    var top = this.top;
+    this.top = this.y;
+    this.scrollDown(n);
+    this.top = top;
+  };
+
+  Terminal.prototype.deleteLines = function (n) {
+    if (this.y < this.top || this.y > this.bottom) {
+      return;
+    }
+    for (var i = 0; i < n; i++) {
+      this.lines.splice(this.y, 1);
+      this.lines.splice(this.bottom, 0, this.blankLine());
+    }
+    this.markRegion();
+  };
+
+  Terminal.prototype.erase = function (y, from, to) {
+    var line = this.lines[y];
+    for (var x = Math.max(0, from); x < Math.min(this.cols, to); x++) {
+      line[x] = { ch: " ", attr: this.attr };
+    }
+    this.dirty[y] = true;
+  };
+
+  Terminal.prototype.eraseLine = function (mode) {
+    if (mode === 0) {
+      this.erase(this.y, this.x, this.cols);
+    } else if (mode === 1) {
+      this.erase(this.y, 0, this.x + 1);
+    } else {
+      this.erase(this.y, 0, this.cols);
+    }
+  };
+
+  Terminal.prototype.eraseDisplay = function (mode) {
+    var y;
+    if (mode === 0) {
+      this.eraseLine(0);
+      for (y = this.y + 1; y < this.rows; y++) {
+        this.erase(y, 0, this.cols);
+      }
+    } else if (mode === 1) {
+      this.eraseLine(1);
+      for (y = 0; y < this.y; y++) {
+        this.erase(y, 0, this.cols);
+      }
+    } else {
+      for (y = 0; y < this.rows; y++) {
+        this.erase(y, 0, this.cols);
+      }
+    }
+  };
+
+  Terminal.prototype.deleteChars = function (n) {
+    var line = this.lines[this.y];
+    line.splice(this.x, n);
+    while (line.length < this.cols) {
+      line.push({ ch: " ", attr: this.attr });
+    }
+    this.dirty[this.y] = true;
+  };
+
+  Terminal.prototype.insertChars = function (n) {
+    var line = this.lines[this.y];
+    for (var i = 0; i < n; i++) {
+      line.splice(this.x, 0, { ch: " ", attr: this.attr });
+    }
+    line.length = this.cols;
+    this.dirty[this.y] = true;
+  };
+
+  Terminal.prototype.save = function () {
+    this.saved = { x: this.x, y: this.y, attr: this.attr };
+  };
+
+  Terminal.prototype.restore = function () {
+    this.attr = this.saved.attr;
+    this.moveTo(this.saved.x, this.saved.y);
+  };
+
+  // render redraws the rows that changed since the last frame.
+  Terminal.prototype.render = function () {
+    var atBottom = this.element.scrollTop + this.element.clientHeight >= this.element.scrollHeight - 4;
+    for (var y in this.dirty) {
+      y = +y;
+      if (y < this.rows) {
+        this.renderRow(this.rowElements[y], this.lines[y], y === this.y && this.cursorVisible ? this.x : -1);
+      }
+    }
+    this.dirty = {};
+    if (atBottom) {
+      this.element.scrollTop = this.element.scrollHeight;
+    }
+  };
+
+  Terminal.prototype.renderRow = function (row, line, cursorX) {
+    row.textContent = "";
+    var span = null;
+    var last = null;
+    for (var x = 0; x < line.length; x++) {
+      var cell = line[x];
+      var cursor = x === cursorX;
+      if (span === null || cell.attr !== last || cursor || x === cursorX + 1) {
+        span = row.appendChild(document.createElement("span"));
+        style(span, cell.attr);
+        if (cursor) {
+          span.className += " cursor";
+        }
+        last = cell.attr;
+      }
+      span.textContent += cell.ch;
+    }
+  };
+
+  function colorOf(color, bright) {
+    if (color === null) {
+      return null;
+    }
+    if (typeof color === "string") {
+      return color;
+    }
+    if (bright && color < 8) {
+      color += 8;
+    }
+    return PALETTE[color & 255];
+  }
+
+  function style(span, attr) {
+    var fg = colorOf(attr.fg, attr.bold) || (attr.bold ? PALETTE[15] : PALETTE[7]);
+    var bg = colorOf(attr.bg, false) || PALETTE[0];
+    if (attr.inverse) {
+      var swap = fg;
+      fg = bg;
+      bg = swap;
+    }
+    if (fg !== PALETTE[7]) {
+      span.style.color = fg;
+    }
+    if (bg !== PALETTE[0]) {
+      span.style.backgroundColor = bg;
+    }
+    span.className = (attr.underline ? "underline " : "") + (attr.blink ? "blink" : "");
+  }
+
+  // KEYS maps keys without a character of their own to what a VT100
+  // keyboard sends.
+  var KEYS = {
+    Enter: "\r",
+    Backspace: "\x7f",
+    Tab: "\t",
+    Escape: "\x1b",
+    ArrowUp: "\x1b[A",
+    ArrowDown: "\x1b[B",
+    ArrowRight: "\x1b[C",
+    ArrowLeft: "\x1b[D",
+    Home: "\x1b[H",
+    End: "\x1b[F",
+    Delete: "\x1b[3~",
+    PageUp: "\x1b[5~",
+    PageDown: "\x1b[6~"
+  };
+
+  function start() {
+    var element = document.getElementById("terminal");
+    var input = document.getElementById("input");
+    var status = document.getElementById("status");
+    var terminal = new Terminal(element);
+    var encoder = new TextEncoder();
+    var decoder = new TextDecoder();
+
+    var url = new URL("ws", window.location.href);
+    url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+    var socket = new WebSocket(url.href);
+    socket.binaryType = "arraybuffer";
+
+    var scheduled = false;
+    function scheduleRender() {
+      if (!scheduled) {
+        scheduled = true;
+        window.requestAnimationFrame(function () {
+          scheduled = false;
+          terminal.render();
+        });
+      }
+    }
+
+    function send(text) {
+      if (text && socket.readyState === WebSocket.OPEN) {
+        socket.send(encoder.encode(text));
+      }
+    }
+
+    function fit() {
+      var probe = document.createElement("span");
+      probe.textContent = "W".repeat(10);
+      element.appendChild(probe);
+      var cellWidth = probe.getBoundingClientRect().width / 10;
+      var cellHeight = terminal.rowElements[0].getBoundingClientRect().height;
+      probe.remove();
+
+      var cols = Math.max(20, Math.min(250, Math.floor((element.clientWidth - 8) / cellWidth)));
+      var rows = Math.max(5, Math.min(100, Math.floor((element.clientHeight - 8) / cellHeight)));
+      terminal.resize(cols, rows);
+      scheduleRender();
+      if (socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: "resize", cols: cols, rows: rows }));
+      }
+    }
+
+    socket.onopen = function () {
+      status.textContent = "Connected";
+      fit();
+      input.focus();
+    };
+
+    socket.onmessage = function (event) {
+      terminal.write(decoder.decode(new Uint8Array(event.data), { stream: true }));
+      scheduleRender();
+    };
+
+    socket.onclose = function () {
+      status.textContent = "Disconnected. Reload the page to call again.";
+      status.className = "closed";
+      terminal.cursorVisible = false;
+      terminal.dirty[terminal.y] = true;
+      scheduleRender();
+    };
+
+    var resizeTimer = null;
+    window.addEventListener("resize", function () {
+      clearTimeout(resizeTimer);
+      resizeTimer = setTimeout(fit, 200);
+    });
+
+    element.addEventListener("mouseup", function () {
+      // leave a selection alone so it can be copied
+      if (!window.getSelection().toString()) {
+        input.focus();
+      }
+    });
+    element.addEventListener("focus", function () {
+      input.focus();
+    });
+
+    input.addEventListener("keydown", function (event) {
+      if (event.isComposing) {
+        return;
+      }
+      var data = null;
+      if (event.ctrlKey && !event.altKey && event.key.length === 1) {
+        var code = event.key.toUpperCase().charCodeAt(0);
+        if (code >= 64 && code <= 95) {
+          data = String.fromCharCode(code - 64);
+        }
+      } else if (KEYS.hasOwnProperty(event.key)) {
+        data = KEYS[event.key];
+      }
+      if (data !== null) {
+        event.preventDefault();
+        send(data);
+      }
+    });
+
+    input.addEventListener("input", function (event) {
+      if (event.isComposing) {
+        return;
+      }
+      send(input.value.replace(/\r?\n/g, "\r"));
+      input.value = "";
+    });
+
+    input.addEventListener("compositionend", function () {
+      send(input.value);
+      input.value = "";
+    });
+  }
+
+  document.addEventListener("DOMContentLoaded", start);
+})();
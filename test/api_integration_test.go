@@ -0,0 +1,103 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteAPITokenRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	repo := sqlite.NewAPITokenRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	first, _, _ := domain.NewAPIToken(user.ID, "first")
+	second, token, _ := domain.NewAPIToken(user.ID, "second")
+	for _, apiToken := range []*domain.APIToken{first, second} {
+		if err := repo.Create(apiToken); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	found, err := repo.GetByToken(token)
+	if err != nil || found.ID != second.ID || found.Name != "second" {
+		t.Fatalf("GetByToken should find the token: %v", err)
+	}
+	if found.LastUsedAt != nil {
+		t.Error("A new token should not have been used")
+	}
+
+	if err := repo.MarkUsed(found.ID, time.Now()); err != nil {
+		t.Errorf("MarkUsed failed: %v", err)
+	}
+
+	tokens, err := repo.GetByUser(user.ID)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens, got %d: %v", len(tokens), err)
+	}
+	if tokens[0].ID != second.ID || tokens[0].LastUsedAt == nil {
+		t.Error("Expected the newest token first, marked as used")
+	}
+
+	if err := repo.Delete(user.ID+1, second.ID); err == nil {
+		t.Error("Delete should not remove another user's token")
+	}
+	if err := repo.Delete(user.ID, second.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if _, err := repo.GetByToken(token); err == nil {
+		t.Error("GetByToken should fail after the token is deleted")
+	}
+}
+
+func TestSQLitePostRepository_Cursors(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	boardRepo := sqlite.NewBoardRepository(db)
+	repo := sqlite.NewPostRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+	board := domain.NewBoard("Test", "Test board")
+	boardRepo.Create(board)
+
+	var threads []*domain.Post
+	for i := 0; i < 3; i++ {
+		thread := domain.NewPost(board.ID, user.ID, user.Username, "Thread", "Content")
+		repo.Create(thread)
+		threads = append(threads, thread)
+	}
+	for i := 0; i < 3; i++ {
+		repo.Create(domain.NewReply(board.ID, user.ID, user.Username, "Reply", threads[1].ID))
+	}
+
+	page, err := repo.GetThreadsBefore(board.ID, 0, 2)
+	if err != nil || len(page) != 2 || page[0].ID != threads[2].ID {
+		t.Fatalf("Expected the two newest threads: %v", err)
+	}
+	if page[1].Replies != 3 {
+		t.Errorf("Expected the reply count, got %d", page[1].Replies)
+	}
+
+	page, _ = repo.GetThreadsBefore(board.ID, page[1].ID, 2)
+	if len(page) != 1 || page[0].ID != threads[0].ID {
+		t.Errorf("Expected only the oldest thread on the second page, got %d", len(page))
+	}
+
+	replies, err := repo.GetRepliesAfter(threads[1].ID, 0, 2)
+	if err != nil || len(replies) != 2 || replies[0].ReplyTo == nil {
+		t.Fatalf("Expected 2 replies: %v", err)
+	}
+
+	replies, _ = repo.GetRepliesAfter(threads[1].ID, replies[1].ID, 2)
+	if len(replies) != 1 {
+		t.Errorf("Expected the last reply on the second page, got %d", len(replies))
+	}
+}
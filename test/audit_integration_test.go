@@ -0,0 +1,116 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteAuditRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewAuditRepository(db)
+
+	sysop := &domain.User{ID: 1, Username: "carol"}
+	repo.Create(domain.NewAuditEntry(sysop, domain.AuditSessionSnoop, "node 2 (dave)", "192.0.2.1", nil))
+
+	entry := domain.NewAuditEntry(sysop, domain.AuditSessionDisconnect, "node 3 (\x1b[2Jerin)", "192.0.2.1",
+		map[string]interface{}{"node": 3})
+	if err := repo.Create(entry); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if entry.ID == 0 {
+		t.Error("Create should set entry ID")
+	}
+
+	if strings.Contains(entry.Target, "\x1b") {
+		t.Errorf("Create should sanitize the target, got %q", entry.Target)
+	}
+
+	entries, err := repo.GetRecent(10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].ID != entry.ID {
+		t.Fatalf("Expected 2 entries, newest first, got %d", len(entries))
+	}
+
+	if entries[0].Actor != "carol" || entries[0].Details != `{"node":3}` {
+		t.Errorf("Expected actor carol with node details, got %s %s", entries[0].Actor, entries[0].Details)
+	}
+
+	if entries[1].Details != "{}" {
+		t.Errorf("Expected empty details, got %s", entries[1].Details)
+	}
+}
+
+func TestSQLiteAuditRepository_Find(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewAuditRepository(db)
+	sysop := &domain.User{ID: 1, Username: "carol"}
+
+	repo.Create(domain.NewAuditEntry(nil, domain.AuditLoginFailed, "dave", "192.0.2.2", nil))
+	repo.Create(domain.NewAuditEntry(sysop, domain.AuditLogin, "carol", "192.0.2.1", nil))
+	repo.Create(domain.NewAuditEntry(sysop, domain.AuditUserBan, "dave", "192.0.2.1", nil))
+	repo.Create(domain.NewAuditEntry(sysop, domain.AuditBoardCreate, "100%_off", "192.0.2.1", nil))
+
+	tests := []struct {
+		filter domain.AuditFilter
+		want   int
+	}{
+		{domain.AuditFilter{}, 4},
+		{domain.AuditFilter{Actor: "CAROL"}, 3},
+		{domain.AuditFilter{Action: "auth"}, 2},
+		{domain.AuditFilter{Action: domain.AuditLogin}, 1},
+		{domain.AuditFilter{Target: "DAV"}, 2},
+		{domain.AuditFilter{Target: "%"}, 1},
+		{domain.AuditFilter{Target: "0_o"}, 0},
+		{domain.AuditFilter{Since: time.Now().Add(-time.Hour)}, 4},
+		{domain.AuditFilter{Until: time.Now().Add(-time.Hour)}, 0},
+	}
+	for _, tt := range tests {
+		entries, err := repo.Find(tt.filter, 10, 0)
+		if err != nil {
+			t.Fatalf("Find failed: %v", err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("%+v: expected %d entries, got %d", tt.filter, tt.want, len(entries))
+		}
+	}
+
+	entries, _ := repo.Find(domain.AuditFilter{}, 2, 2)
+	if len(entries) != 2 || entries[1].Action != domain.AuditLoginFailed {
+		t.Errorf("Expected the oldest 2 entries on the second page, got %d", len(entries))
+	}
+}
+
+func TestSQLiteAuditRepository_Export(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewAuditRepository(db)
+
+	// more than one batch
+	for i := 0; i < 1200; i++ {
+		repo.Create(domain.NewAuditEntry(nil, domain.AuditLoginFailed, "dave", "192.0.2.2", nil))
+	}
+	repo.Create(domain.NewAuditEntry(nil, domain.AuditLogin, "dave", "192.0.2.2", nil))
+
+	count, lastID := 0, 0
+	err := repo.Export(domain.AuditFilter{Action: domain.AuditLoginFailed}, func(entry *domain.AuditEntry) error {
+		if entry.ID <= lastID {
+			t.Fatalf("Expected entries oldest first, got %d after %d", entry.ID, lastID)
+		}
+		lastID = entry.ID
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 1200 {
+		t.Errorf("Expected 1200 entries, got %d", count)
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteFailedLoginRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewFailedLoginRepository(db)
+
+	repo.Create(domain.NewFailedLogin("alice", "192.0.2.1", domain.FailedLoginPassword))
+	repo.Create(domain.NewFailedLogin("bob", "192.0.2.2", domain.FailedLoginPassword))
+
+	attempt := domain.NewFailedLogin("Alice\x1b[2J"+strings.Repeat("x", 100), "192.0.2.2", domain.FailedLoginCode)
+	if err := repo.Create(attempt); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if attempt.ID == 0 {
+		t.Error("Create should set attempt ID")
+	}
+
+	if strings.Contains(attempt.Username, "\x1b") || len(attempt.Username) > 64 {
+		t.Errorf("Create should sanitize the username, got %q", attempt.Username)
+	}
+
+	attempts, err := repo.GetRecent(10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+
+	if len(attempts) != 3 || attempts[0].ID != attempt.ID {
+		t.Errorf("Expected 3 attempts, newest first, got %d", len(attempts))
+	}
+
+	attempts, _ = repo.GetByUsername("ALICE", 10)
+	if len(attempts) != 1 {
+		t.Errorf("Expected 1 attempt for alice, got %d", len(attempts))
+	}
+
+	attempts, _ = repo.GetByRemoteAddr("192.0.2.2", 10)
+	if len(attempts) != 2 {
+		t.Errorf("Expected 2 attempts from 192.0.2.2, got %d", len(attempts))
+	}
+}
+
+func TestSQLiteUserRepository_UnknownUserTiming(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewUserRepository(db)
+	repo.SetPasswordCost(10)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	repo.Create(user)
+
+	// warm up the dummy hash so its one-off cost is not measured
+	repo.Authenticate("nobody", "password123")
+
+	start := time.Now()
+	repo.Authenticate("testuser", "wrong-password")
+	wrong := time.Since(start)
+
+	start = time.Now()
+	if _, err := repo.Authenticate("nobody", "password123"); err == nil {
+		t.Fatal("Authenticate should fail for an unknown user")
+	}
+	unknown := time.Since(start)
+
+	if unknown < wrong/4 {
+		t.Errorf("Unknown usernames should take about as long as wrong passwords, got %v vs %v", unknown, wrong)
+	}
+}
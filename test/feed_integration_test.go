@@ -0,0 +1,49 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteFeedTokenRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	repo := sqlite.NewFeedTokenRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	if _, err := repo.GetByUser(user.ID); err == nil {
+		t.Error("GetByUser should fail before a token is set")
+	}
+
+	first, oldToken, _ := domain.NewFeedToken(user.ID)
+	if err := repo.Set(first); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	second, token, _ := domain.NewFeedToken(user.ID)
+	if err := repo.Set(second); err != nil {
+		t.Fatalf("Set should replace the token: %v", err)
+	}
+
+	if _, err := repo.GetByToken(oldToken); err == nil {
+		t.Error("GetByToken should fail for the replaced token")
+	}
+	found, err := repo.GetByToken(token)
+	if err != nil || found.UserID != user.ID {
+		t.Fatalf("GetByToken should find the new token: %v", err)
+	}
+
+	if err := repo.Delete(user.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if _, err := repo.GetByToken(token); err == nil {
+		t.Error("GetByToken should fail after the token is deleted")
+	}
+	if err := repo.Delete(user.ID); err == nil {
+		t.Error("Delete should fail without a token")
+	}
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteEchomailRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	boardRepo := sqlite.NewBoardRepository(db)
+	postRepo := sqlite.NewPostRepository(db)
+	repo := sqlite.NewEchomailRepository(db)
+
+	user := domain.NewUser("echomail", "echomail@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+	board := domain.NewBoard("Echo", "An echo area")
+	boardRepo.Create(board)
+
+	post := domain.NewPost(board.ID, user.ID, user.Username, "From afar", "Hello")
+	post.Author = "Björn@21:1/1"
+	if err := postRepo.Create(post); err != nil {
+		t.Fatalf("Create should not return error: %v", err)
+	}
+	stored, _ := postRepo.GetByID(post.ID)
+	if stored.Author != "Björn@21:1/1" || stored.Username != "Björn@21:1/1" || stored.UserID != user.ID {
+		t.Errorf("Expected the post shown under its author, got %+v", stored)
+	}
+
+	msg := &domain.EchomailMessage{MSGID: "21:1/1 0000abcd", PostID: post.ID, Area: "ECHO", Imported: true, CreatedAt: time.Now()}
+	if err := repo.Add(msg); err != nil {
+		t.Fatalf("Add should not return error: %v", err)
+	}
+	if err := repo.Add(msg); err == nil {
+		t.Error("Expected a MSGID to be added only once")
+	}
+
+	found, err := repo.GetByMSGID(msg.MSGID)
+	if err != nil || found.PostID != post.ID || found.Area != "ECHO" || !found.Imported {
+		t.Errorf("Expected the message by MSGID, got %+v: %v", found, err)
+	}
+	if found, err := repo.GetByPost(post.ID); err != nil || found.MSGID != msg.MSGID {
+		t.Errorf("Expected the message by post, got %+v: %v", found, err)
+	}
+	if _, err := repo.GetByMSGID("21:1/1 00000000"); err == nil {
+		t.Error("Expected an unknown MSGID not to be found")
+	}
+}
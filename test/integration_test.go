@@ -2,6 +2,7 @@ package test
 
 import (
 	"database/sql"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -10,8 +11,15 @@ import (
 	"github.com/leinonen/bbs/domain"
 	"github.com/leinonen/bbs/repository/sqlite"
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func TestMain(m *testing.M) {
+	// the production cost makes each user take a quarter of a second to create
+	sqlite.PasswordCost = bcrypt.MinCost
+	os.Exit(m.Run())
+}
+
 func setupTestDB(t *testing.T) *sql.DB {
 	// Create temporary database file
 	tmpFile, err := ioutil.TempFile("", "test_bbs_*.db")
@@ -84,6 +92,23 @@ func TestSQLiteUserRepository_Integration(t *testing.T) {
 		t.Errorf("Expected ID %d, got %d", user.ID, userByName.ID)
 	}
 
+	// Test duplicates
+	for _, dup := range []*domain.User{domain.NewUser("testuser", "other@example.com"), domain.NewUser("other", "test@example.com")} {
+		dup.Password = "password123"
+		if err := repo.Create(dup); !errors.Is(err, domain.ErrUserExists) {
+			t.Errorf("Expected %s <%s> refused as a duplicate, got %v", dup.Username, dup.Email, err)
+		}
+	}
+
+	// Test GetByEmail
+	userByEmail, err := repo.GetByEmail("TEST@example.com")
+	if err != nil || userByEmail.ID != user.ID {
+		t.Errorf("GetByEmail failed: %v", err)
+	}
+	if _, err := repo.GetByEmail("nobody@example.com"); err == nil {
+		t.Error("GetByEmail should fail for an unknown address")
+	}
+
 	// Test Authenticate
 	authUser, err := repo.Authenticate("testuser", "password123")
 	if err != nil {
@@ -328,3 +353,93 @@ func TestSQLitePostRepository_Integration(t *testing.T) {
 		t.Errorf("Expected 0 replies after deletion, got %d", len(replies))
 	}
 }
+
+func TestSQLitePostRepository_GetByBoardAfter(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	boardRepo := sqlite.NewBoardRepository(db)
+	repo := sqlite.NewPostRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+	board := domain.NewBoard("Test", "Test board")
+	boardRepo.Create(board)
+	other := domain.NewBoard("Other", "Other board")
+	boardRepo.Create(other)
+
+	low, high, err := repo.GetIDRange(board.ID)
+	if err != nil || low != 0 || high != 0 {
+		t.Fatalf("Expected zeros for an empty board, got %d-%d: %v", low, high, err)
+	}
+
+	thread := domain.NewPost(board.ID, user.ID, user.Username, "Thread", "Content")
+	repo.Create(thread)
+	elsewhere := domain.NewPost(other.ID, user.ID, user.Username, "Elsewhere", "Content")
+	repo.Create(elsewhere)
+	reply := domain.NewReply(board.ID, user.ID, user.Username, "Reply", thread.ID)
+	repo.Create(reply)
+
+	low, high, err = repo.GetIDRange(board.ID)
+	if err != nil || low != thread.ID || high != reply.ID {
+		t.Errorf("Expected IDs %d-%d, got %d-%d: %v", thread.ID, reply.ID, low, high, err)
+	}
+
+	posts, err := repo.GetByBoardAfter(board.ID, 0, 10)
+	if err != nil || len(posts) != 2 || posts[0].ID != thread.ID || posts[1].ReplyTo == nil {
+		t.Fatalf("Expected the thread and its reply: %v", err)
+	}
+
+	posts, _ = repo.GetByBoardAfter(board.ID, thread.ID, 10)
+	if len(posts) != 1 || posts[0].ID != reply.ID {
+		t.Errorf("Expected only the reply after the thread, got %d", len(posts))
+	}
+}
+
+func TestSQLiteRepositories_SanitizeOnWrite(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	boardRepo := sqlite.NewBoardRepository(db)
+	postRepo := sqlite.NewPostRepository(db)
+
+	user := domain.NewUser("mallory\033[2J", "mallory@example.com")
+	user.Password = "password123"
+	if err := userRepo.Create(user); err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+
+	storedUser, _ := userRepo.GetByID(user.ID)
+	if storedUser.Username != "mallory" {
+		t.Errorf("Expected sanitized username, got %q", storedUser.Username)
+	}
+
+	board := domain.NewBoard("evil\033]0;title\007", "desc\033[H")
+	if err := boardRepo.Create(board); err != nil {
+		t.Fatalf("Create board failed: %v", err)
+	}
+
+	storedBoard, _ := boardRepo.GetByID(board.ID)
+	if storedBoard.Name != "evil" || storedBoard.Description != "desc" {
+		t.Errorf("Expected sanitized board, got %q / %q", storedBoard.Name, storedBoard.Description)
+	}
+
+	post := domain.NewPost(board.ID, user.ID, user.Username, "Hi\r", "line\n\033[31mred\033[0m")
+	if err := postRepo.Create(post); err != nil {
+		t.Fatalf("Create post failed: %v", err)
+	}
+
+	storedPost, _ := postRepo.GetByID(post.ID)
+	if storedPost.Title != "Hi" || storedPost.Content != "line\nred" {
+		t.Errorf("Expected sanitized post, got %q / %q", storedPost.Title, storedPost.Content)
+	}
+
+	post.Content = "edited\033c"
+	if err := postRepo.Update(post); err != nil {
+		t.Fatalf("Update post failed: %v", err)
+	}
+
+	storedPost, _ = postRepo.GetByID(post.ID)
+	if storedPost.Content != "edited" {
+		t.Errorf("Expected sanitized update, got %q", storedPost.Content)
+	}
+}
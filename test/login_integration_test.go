@@ -0,0 +1,103 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteLoginRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	repo := sqlite.NewLoginRepository(db)
+
+	alice := domain.NewUser("alice", "alice@example.com")
+	alice.Password = "password123"
+	userRepo.Create(alice)
+
+	bob := domain.NewUser("bob", "bob@example.com")
+	bob.Password = "password123"
+	userRepo.Create(bob)
+
+	// Test Create
+	first := domain.NewLogin(alice.ID, alice.Username, "192.0.2.1", "SSH-2.0-OpenSSH_9.6")
+	if err := repo.Create(first); err != nil {
+		t.Errorf("Create failed: %v", err)
+	}
+
+	if first.ID == 0 {
+		t.Error("Create should set login ID")
+	}
+
+	second := domain.NewLogin(bob.ID, bob.Username, "192.0.2.2", "SSH-2.0-PuTTY")
+	second.LoginAt = first.LoginAt.Add(time.Minute)
+	repo.Create(second)
+
+	third := domain.NewLogin(alice.ID, alice.Username, "192.0.2.2", "SSH-2.0-OpenSSH_9.6")
+	third.LoginAt = first.LoginAt.Add(2 * time.Minute)
+	repo.Create(third)
+
+	// Test End
+	logoutAt := first.LoginAt.Add(10 * time.Minute)
+	if err := repo.End(first.ID, logoutAt, domain.LogoutReasonLogout); err != nil {
+		t.Errorf("End failed: %v", err)
+	}
+
+	// Ending twice should keep the first logout
+	repo.End(first.ID, logoutAt.Add(time.Hour), domain.LogoutReasonDisconnect)
+
+	// Test GetRecent
+	logins, err := repo.GetRecent(10)
+	if err != nil {
+		t.Errorf("GetRecent failed: %v", err)
+	}
+
+	if len(logins) != 3 {
+		t.Fatalf("Expected 3 logins, got %d", len(logins))
+	}
+
+	if logins[0].ID != third.ID {
+		t.Error("GetRecent should return newest login first")
+	}
+
+	last := logins[2]
+	if last.Username != "alice" || last.ClientVersion != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("Unexpected login record %+v", last)
+	}
+
+	if last.LogoutAt == nil || last.LogoutReason != domain.LogoutReasonLogout {
+		t.Errorf("Expected logout to be recorded, got %+v", last)
+	}
+
+	if last.Duration(time.Now()) != 10*time.Minute {
+		t.Errorf("Expected session length 10m, got %v", last.Duration(time.Now()))
+	}
+
+	if logins[0].LogoutAt != nil {
+		t.Error("Open session should have no logout time")
+	}
+
+	// Test GetByUser
+	logins, _ = repo.GetByUser(alice.ID, 10)
+	if len(logins) != 2 {
+		t.Errorf("Expected 2 logins for alice, got %d", len(logins))
+	}
+
+	// Test GetByRemoteAddr
+	logins, _ = repo.GetByRemoteAddr("192.0.2.2", 10)
+	if len(logins) != 2 {
+		t.Errorf("Expected 2 logins from 192.0.2.2, got %d", len(logins))
+	}
+
+	// Test CountByUser
+	count, err := repo.CountByUser(alice.ID)
+	if err != nil {
+		t.Errorf("CountByUser failed: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 calls for alice, got %d", count)
+	}
+}
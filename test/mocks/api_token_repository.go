@@ -0,0 +1,93 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type APITokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[int]*domain.APIToken
+	nextID int
+}
+
+func NewAPITokenRepository() *APITokenRepository {
+	return &APITokenRepository{
+		tokens: make(map[int]*domain.APIToken),
+		nextID: 1,
+	}
+}
+
+func (r *APITokenRepository) Create(token *domain.APIToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return errors.New("API token already exists")
+		}
+	}
+
+	token.ID = r.nextID
+	r.nextID++
+	r.tokens[token.ID] = token
+	return nil
+}
+
+func (r *APITokenRepository) GetByToken(token string) (*domain.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hash := domain.HashToken(token)
+	for _, apiToken := range r.tokens {
+		if apiToken.TokenHash == hash {
+			return apiToken, nil
+		}
+	}
+	return nil, errors.New("API token not found")
+}
+
+func (r *APITokenRepository) GetByUser(userID int) ([]*domain.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tokens []*domain.APIToken
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID > tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *APITokenRepository) MarkUsed(id int, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.tokens[id]
+	if !exists {
+		return errors.New("API token not found")
+	}
+	token.LastUsedAt = &usedAt
+	return nil
+}
+
+func (r *APITokenRepository) Delete(userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token, exists := r.tokens[id]; !exists || token.UserID != userID {
+		return errors.New("API token not found")
+	}
+	delete(r.tokens, id)
+	return nil
+}
@@ -0,0 +1,75 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type AuditRepository struct {
+	mu      sync.RWMutex
+	entries map[int]*domain.AuditEntry
+	nextID  int
+}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{
+		entries: make(map[int]*domain.AuditEntry),
+		nextID:  1,
+	}
+}
+
+func (r *AuditRepository) Create(entry *domain.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = r.nextID
+	r.nextID++
+	r.entries[entry.ID] = entry
+	return nil
+}
+
+func (r *AuditRepository) GetRecent(limit int) ([]*domain.AuditEntry, error) {
+	return r.Find(domain.AuditFilter{}, limit, 0)
+}
+
+func (r *AuditRepository) matching(filter domain.AuditFilter) []*domain.AuditEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []*domain.AuditEntry
+	for _, entry := range r.entries {
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID > entries[j].ID
+	})
+	return entries
+}
+
+func (r *AuditRepository) Find(filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, error) {
+	entries := r.matching(filter)
+	if offset >= len(entries) {
+		return nil, nil
+	}
+	entries = entries[offset:]
+	if limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func (r *AuditRepository) Export(filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error {
+	entries := r.matching(filter)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := fn(entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
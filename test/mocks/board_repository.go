@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/leinonen/bbs/domain"
@@ -56,6 +57,9 @@ func (r *BoardRepository) GetAll() ([]*domain.Board, error) {
 	for _, board := range r.boards {
 		boards = append(boards, board)
 	}
+	sort.Slice(boards, func(i, j int) bool {
+		return boards[i].Name < boards[j].Name
+	})
 	return boards, nil
 }
 
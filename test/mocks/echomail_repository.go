@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type EchomailRepository struct {
+	mu       sync.RWMutex
+	messages map[string]*domain.EchomailMessage
+}
+
+func NewEchomailRepository() *EchomailRepository {
+	return &EchomailRepository{
+		messages: make(map[string]*domain.EchomailMessage),
+	}
+}
+
+func (r *EchomailRepository) Add(msg *domain.EchomailMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.messages[msg.MSGID]; ok {
+		return errors.New("UNIQUE constraint failed: echomail.msgid")
+	}
+	stored := *msg
+	r.messages[msg.MSGID] = &stored
+	return nil
+}
+
+func (r *EchomailRepository) GetByMSGID(msgid string) (*domain.EchomailMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if msg, ok := r.messages[msgid]; ok {
+		found := *msg
+		return &found, nil
+	}
+	return nil, errors.New("echomail message not found")
+}
+
+func (r *EchomailRepository) GetByPost(postID int) (*domain.EchomailMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, msg := range r.messages {
+		if msg.PostID == postID {
+			found := *msg
+			return &found, nil
+		}
+	}
+	return nil, errors.New("echomail message not found")
+}
@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type EmailQueueRepository struct {
+	mu     sync.RWMutex
+	emails map[int]*domain.QueuedEmail
+	nextID int
+}
+
+func NewEmailQueueRepository() *EmailQueueRepository {
+	return &EmailQueueRepository{
+		emails: make(map[int]*domain.QueuedEmail),
+		nextID: 1,
+	}
+}
+
+func (r *EmailQueueRepository) Enqueue(email *domain.QueuedEmail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email.ID = r.nextID
+	r.nextID++
+	stored := *email
+	r.emails[email.ID] = &stored
+	return nil
+}
+
+func (r *EmailQueueRepository) GetDue(now time.Time, limit int) ([]*domain.QueuedEmail, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var due []*domain.QueuedEmail
+	for _, email := range r.emails {
+		if email.Status == domain.EmailPending && !email.NextAttemptAt.After(now) {
+			e := *email
+			due = append(due, &e)
+		}
+	}
+
+	// Sort by ID (oldest first)
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ID < due[j].ID
+	})
+
+	if limit < len(due) {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *EmailQueueRepository) Update(email *domain.QueuedEmail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.emails[email.ID]; !exists {
+		return errors.New("queued email not found")
+	}
+	stored := *email
+	r.emails[email.ID] = &stored
+	return nil
+}
+
+func (r *EmailQueueRepository) GetRecent(limit int) ([]*domain.QueuedEmail, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var emails []*domain.QueuedEmail
+	for _, email := range r.emails {
+		e := *email
+		emails = append(emails, &e)
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].ID > emails[j].ID
+	})
+
+	if limit < len(emails) {
+		emails = emails[:limit]
+	}
+	return emails, nil
+}
@@ -0,0 +1,65 @@
+package mocks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type EmailVerificationRepository struct {
+	mu            sync.RWMutex
+	verifications map[int]*domain.EmailVerification // by user ID
+	nextID        int
+}
+
+func NewEmailVerificationRepository() *EmailVerificationRepository {
+	return &EmailVerificationRepository{
+		verifications: make(map[int]*domain.EmailVerification),
+		nextID:        1,
+	}
+}
+
+func (r *EmailVerificationRepository) Create(verification *domain.EmailVerification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification.ID = r.nextID
+	r.nextID++
+	r.verifications[verification.UserID] = verification
+	return nil
+}
+
+func (r *EmailVerificationRepository) GetByUser(userID int) (*domain.EmailVerification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	verification, exists := r.verifications[userID]
+	if !exists {
+		return nil, errors.New("email verification not found")
+	}
+	// return a copy so attempts only change through IncrementAttempts
+	v := *verification
+	return &v, nil
+}
+
+func (r *EmailVerificationRepository) IncrementAttempts(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, verification := range r.verifications {
+		if verification.ID == id {
+			verification.Attempts++
+			return nil
+		}
+	}
+	return errors.New("email verification not found")
+}
+
+func (r *EmailVerificationRepository) DeleteForUser(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.verifications, userID)
+	return nil
+}
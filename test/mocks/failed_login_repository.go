@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type FailedLoginRepository struct {
+	mu       sync.RWMutex
+	attempts map[int]*domain.FailedLogin
+	nextID   int
+}
+
+func NewFailedLoginRepository() *FailedLoginRepository {
+	return &FailedLoginRepository{
+		attempts: make(map[int]*domain.FailedLogin),
+		nextID:   1,
+	}
+}
+
+func (r *FailedLoginRepository) Create(attempt *domain.FailedLogin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt.ID = r.nextID
+	r.nextID++
+	r.attempts[attempt.ID] = attempt
+	return nil
+}
+
+func (r *FailedLoginRepository) GetRecent(limit int) ([]*domain.FailedLogin, error) {
+	return r.filter(limit, func(*domain.FailedLogin) bool { return true }), nil
+}
+
+func (r *FailedLoginRepository) GetByUsername(username string, limit int) ([]*domain.FailedLogin, error) {
+	return r.filter(limit, func(a *domain.FailedLogin) bool { return strings.EqualFold(a.Username, username) }), nil
+}
+
+func (r *FailedLoginRepository) GetByRemoteAddr(remoteAddr string, limit int) ([]*domain.FailedLogin, error) {
+	return r.filter(limit, func(a *domain.FailedLogin) bool { return a.RemoteAddr == remoteAddr }), nil
+}
+
+func (r *FailedLoginRepository) filter(limit int, match func(*domain.FailedLogin) bool) []*domain.FailedLogin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var attempts []*domain.FailedLogin
+	for _, attempt := range r.attempts {
+		if match(attempt) {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].ID > attempts[j].ID
+	})
+
+	if limit > 0 && limit < len(attempts) {
+		attempts = attempts[:limit]
+	}
+	return attempts
+}
@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type FeedTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[int]*domain.FeedToken
+}
+
+func NewFeedTokenRepository() *FeedTokenRepository {
+	return &FeedTokenRepository{
+		tokens: make(map[int]*domain.FeedToken),
+	}
+}
+
+func (r *FeedTokenRepository) Set(token *domain.FeedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for userID, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash && userID != token.UserID {
+			return errors.New("feed token already exists")
+		}
+	}
+
+	r.tokens[token.UserID] = token
+	return nil
+}
+
+func (r *FeedTokenRepository) GetByToken(token string) (*domain.FeedToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hash := domain.HashToken(token)
+	for _, feedToken := range r.tokens {
+		if feedToken.TokenHash == hash {
+			return feedToken, nil
+		}
+	}
+	return nil, errors.New("feed token not found")
+}
+
+func (r *FeedTokenRepository) GetByUser(userID int) (*domain.FeedToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, exists := r.tokens[userID]
+	if !exists {
+		return nil, errors.New("feed token not found")
+	}
+	return token, nil
+}
+
+func (r *FeedTokenRepository) Delete(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tokens[userID]; !exists {
+		return errors.New("feed token not found")
+	}
+	delete(r.tokens, userID)
+	return nil
+}
@@ -0,0 +1,108 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type InviteRepository struct {
+	mu      sync.RWMutex
+	invites map[int]*domain.Invite
+	nextID  int
+}
+
+func NewInviteRepository() *InviteRepository {
+	return &InviteRepository{
+		invites: make(map[int]*domain.Invite),
+		nextID:  1,
+	}
+}
+
+func (r *InviteRepository) Create(invite *domain.Invite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.invites {
+		if existing.Code == invite.Code {
+			return errors.New("invite code already exists")
+		}
+	}
+
+	invite.ID = r.nextID
+	r.nextID++
+	r.invites[invite.ID] = invite
+	return nil
+}
+
+func (r *InviteRepository) GetByCode(code string) (*domain.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	code = domain.NormalizeInviteCode(code)
+	for _, invite := range r.invites {
+		if invite.Code == code {
+			return invite, nil
+		}
+	}
+	return nil, errors.New("invite not found")
+}
+
+func (r *InviteRepository) GetByCreator(userID int) ([]*domain.Invite, error) {
+	return r.filter(0, func(invite *domain.Invite) bool {
+		return invite.CreatedBy == userID
+	}), nil
+}
+
+func (r *InviteRepository) GetAll(limit int) ([]*domain.Invite, error) {
+	return r.filter(limit, func(*domain.Invite) bool { return true }), nil
+}
+
+func (r *InviteRepository) filter(limit int, match func(*domain.Invite) bool) []*domain.Invite {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var invites []*domain.Invite
+	for _, invite := range r.invites {
+		if match(invite) {
+			invites = append(invites, invite)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].ID > invites[j].ID
+	})
+
+	if limit > 0 && len(invites) > limit {
+		invites = invites[:limit]
+	}
+	return invites
+}
+
+func (r *InviteRepository) Use(code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code = domain.NormalizeInviteCode(code)
+	for _, invite := range r.invites {
+		if invite.Code == code && invite.Uses < invite.MaxUses {
+			invite.Uses++
+			return nil
+		}
+	}
+	return errors.New("invite code is not valid")
+}
+
+func (r *InviteRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.invites[id]; !exists {
+		return errors.New("invite not found")
+	}
+	delete(r.invites, id)
+	return nil
+}
@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type LoginRepository struct {
+	mu     sync.RWMutex
+	logins map[int]*domain.Login
+	nextID int
+}
+
+func NewLoginRepository() *LoginRepository {
+	return &LoginRepository{
+		logins: make(map[int]*domain.Login),
+		nextID: 1,
+	}
+}
+
+func (r *LoginRepository) Create(login *domain.Login) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	login.ID = r.nextID
+	r.nextID++
+	r.logins[login.ID] = login
+	return nil
+}
+
+func (r *LoginRepository) End(id int, logoutAt time.Time, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	login, exists := r.logins[id]
+	if !exists {
+		return errors.New("login not found")
+	}
+	if login.LogoutAt == nil {
+		login.LogoutAt = &logoutAt
+		login.LogoutReason = reason
+	}
+	return nil
+}
+
+func (r *LoginRepository) GetRecent(limit int) ([]*domain.Login, error) {
+	return r.filter(limit, func(*domain.Login) bool { return true }), nil
+}
+
+func (r *LoginRepository) GetByUser(userID int, limit int) ([]*domain.Login, error) {
+	return r.filter(limit, func(l *domain.Login) bool { return l.UserID == userID }), nil
+}
+
+func (r *LoginRepository) GetByRemoteAddr(remoteAddr string, limit int) ([]*domain.Login, error) {
+	return r.filter(limit, func(l *domain.Login) bool { return l.RemoteAddr == remoteAddr }), nil
+}
+
+func (r *LoginRepository) CountByUser(userID int) (int, error) {
+	return len(r.filter(0, func(l *domain.Login) bool { return l.UserID == userID })), nil
+}
+
+func (r *LoginRepository) filter(limit int, match func(*domain.Login) bool) []*domain.Login {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var logins []*domain.Login
+	for _, login := range r.logins {
+		if match(login) {
+			logins = append(logins, login)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(logins, func(i, j int) bool {
+		return logins[i].ID > logins[j].ID
+	})
+
+	if limit > 0 && len(logins) > limit {
+		logins = logins[:limit]
+	}
+	return logins
+}
@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type MotdRepository struct {
+	mu       sync.RWMutex
+	motd     *domain.Motd
+	lastSeen map[int]time.Time
+}
+
+func NewMotdRepository() *MotdRepository {
+	return &MotdRepository{
+		lastSeen: make(map[int]time.Time),
+	}
+}
+
+func (r *MotdRepository) Get() (*domain.Motd, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.motd == nil {
+		return nil, errors.New("motd not found")
+	}
+	return r.motd, nil
+}
+
+func (r *MotdRepository) Set(motd *domain.Motd) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.motd = motd
+	return nil
+}
+
+func (r *MotdRepository) GetLastSeen(userID int) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastSeen[userID], nil
+}
+
+func (r *MotdRepository) MarkSeen(userID int, seenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastSeen[userID] = seenAt
+	return nil
+}
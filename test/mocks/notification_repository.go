@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type NotificationRepository struct {
+	mu            sync.RWMutex
+	notifications map[int]*domain.Notification
+	nextID        int
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		notifications: make(map[int]*domain.Notification),
+		nextID:        1,
+	}
+}
+
+func (r *NotificationRepository) Create(notification *domain.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification.ID = r.nextID
+	r.nextID++
+	r.notifications[notification.ID] = notification
+	return nil
+}
+
+func (r *NotificationRepository) GetByUser(userID int, limit int) ([]*domain.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var notifications []*domain.Notification
+	for _, notification := range r.notifications {
+		if notification.UserID == userID {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].ID > notifications[j].ID
+	})
+
+	if limit < len(notifications) {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, notification := range r.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *NotificationRepository) MarkRead(id int, readAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if notification, exists := r.notifications[id]; exists && notification.ReadAt == nil {
+		notification.ReadAt = &readAt
+	}
+	return nil
+}
+
+func (r *NotificationRepository) MarkAllRead(userID int, readAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, notification := range r.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			notification.ReadAt = &readAt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type NotificationSettingsRepository struct {
+	mu       sync.RWMutex
+	settings map[int]domain.NotificationSettings
+}
+
+func NewNotificationSettingsRepository() *NotificationSettingsRepository {
+	return &NotificationSettingsRepository{
+		settings: make(map[int]domain.NotificationSettings),
+	}
+}
+
+func (r *NotificationSettingsRepository) Get(userID int) (*domain.NotificationSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings, exists := r.settings[userID]
+	if !exists {
+		return &domain.NotificationSettings{UserID: userID}, nil
+	}
+	return &settings, nil
+}
+
+func (r *NotificationSettingsRepository) Save(settings *domain.NotificationSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings[settings.UserID] = *settings
+	return nil
+}
+
+func (r *NotificationSettingsRepository) GetDigestDue(now time.Time) ([]*domain.NotificationSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var due []*domain.NotificationSettings
+	for _, settings := range r.settings {
+		if settings.DigestDue(now) {
+			s := settings
+			due = append(due, &s)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].UserID < due[j].UserID
+	})
+
+	return due, nil
+}
@@ -0,0 +1,63 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type OneLinerRepository struct {
+	mu        sync.RWMutex
+	oneLiners map[int]*domain.OneLiner
+	nextID    int
+}
+
+func NewOneLinerRepository() *OneLinerRepository {
+	return &OneLinerRepository{
+		oneLiners: make(map[int]*domain.OneLiner),
+		nextID:    1,
+	}
+}
+
+func (r *OneLinerRepository) Create(oneLiner *domain.OneLiner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oneLiner.ID = r.nextID
+	r.nextID++
+	r.oneLiners[oneLiner.ID] = oneLiner
+	return nil
+}
+
+func (r *OneLinerRepository) GetRecent(limit int) ([]*domain.OneLiner, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var oneLiners []*domain.OneLiner
+	for _, oneLiner := range r.oneLiners {
+		oneLiners = append(oneLiners, oneLiner)
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(oneLiners, func(i, j int) bool {
+		return oneLiners[i].ID > oneLiners[j].ID
+	})
+
+	if limit > 0 && len(oneLiners) > limit {
+		oneLiners = oneLiners[:limit]
+	}
+	return oneLiners, nil
+}
+
+func (r *OneLinerRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.oneLiners[id]; !exists {
+		return errors.New("one-liner not found")
+	}
+	delete(r.oneLiners, id)
+	return nil
+}
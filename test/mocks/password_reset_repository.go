@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type PasswordResetRepository struct {
+	mu     sync.RWMutex
+	resets map[int]*domain.PasswordReset
+	nextID int
+}
+
+func NewPasswordResetRepository() *PasswordResetRepository {
+	return &PasswordResetRepository{
+		resets: make(map[int]*domain.PasswordReset),
+		nextID: 1,
+	}
+}
+
+func (r *PasswordResetRepository) Create(reset *domain.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset.ID = r.nextID
+	r.nextID++
+	r.resets[reset.ID] = reset
+	return nil
+}
+
+func (r *PasswordResetRepository) GetByToken(token string) (*domain.PasswordReset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hash := domain.HashToken(token)
+	for _, reset := range r.resets {
+		if reset.TokenHash == hash {
+			return reset, nil
+		}
+	}
+	return nil, errors.New("password reset not found")
+}
+
+func (r *PasswordResetRepository) MarkUsed(id int, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset, exists := r.resets[id]
+	if !exists || reset.UsedAt != nil {
+		return errors.New("password reset not found or already used")
+	}
+	reset.UsedAt = &usedAt
+	return nil
+}
+
+func (r *PasswordResetRepository) InvalidateForUser(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, reset := range r.resets {
+		if reset.UserID == userID && reset.UsedAt == nil {
+			reset.UsedAt = &now
+		}
+	}
+	return nil
+}
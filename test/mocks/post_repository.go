@@ -4,6 +4,7 @@ import (
 	"errors"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/leinonen/bbs/domain"
 )
@@ -27,6 +28,9 @@ func (r *PostRepository) Create(post *domain.Post) error {
 
 	post.ID = r.nextID
 	r.nextID++
+	if post.Author != "" {
+		post.Username = post.Author
+	}
 	r.posts[post.ID] = post
 	return nil
 }
@@ -145,3 +149,143 @@ func (r *PostRepository) CountByBoard(boardID int) (int, error) {
 	}
 	return count, nil
 }
+
+func (r *PostRepository) GetByUser(userID int, limit int) ([]*domain.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var userPosts []*domain.Post
+	for _, post := range r.posts {
+		if post.UserID == userID {
+			userPosts = append(userPosts, post)
+		}
+	}
+
+	// Sort by created time (newest first)
+	sort.Slice(userPosts, func(i, j int) bool {
+		return userPosts[i].CreatedAt.After(userPosts[j].CreatedAt)
+	})
+
+	if limit > len(userPosts) {
+		limit = len(userPosts)
+	}
+
+	return userPosts[:limit], nil
+}
+
+func (r *PostRepository) CountByUser(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, post := range r.posts {
+		if post.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *PostRepository) GetThreadsSince(boardID int, since time.Time) ([]*domain.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var threads []*domain.Post
+	for _, post := range r.posts {
+		if post.BoardID == boardID && post.ReplyTo == nil && post.CreatedAt.After(since) {
+			threads = append(threads, post)
+		}
+	}
+
+	// Sort by created time (oldest first)
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].CreatedAt.Before(threads[j].CreatedAt)
+	})
+
+	return threads, nil
+}
+
+func (r *PostRepository) GetThreadsBefore(boardID, beforeID, limit int) ([]*domain.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var threads []*domain.Post
+	for _, post := range r.posts {
+		if post.BoardID == boardID && post.ReplyTo == nil && (beforeID == 0 || post.ID < beforeID) {
+			threads = append(threads, post)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].ID > threads[j].ID
+	})
+
+	if limit < len(threads) {
+		threads = threads[:limit]
+	}
+	return threads, nil
+}
+
+func (r *PostRepository) GetRepliesAfter(postID, afterID, limit int) ([]*domain.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var replies []*domain.Post
+	for _, post := range r.posts {
+		if post.ReplyTo != nil && *post.ReplyTo == postID && post.ID > afterID {
+			replies = append(replies, post)
+		}
+	}
+
+	// Sort by ID (oldest first)
+	sort.Slice(replies, func(i, j int) bool {
+		return replies[i].ID < replies[j].ID
+	})
+
+	if limit < len(replies) {
+		replies = replies[:limit]
+	}
+	return replies, nil
+}
+
+func (r *PostRepository) GetByBoardAfter(boardID, afterID, limit int) ([]*domain.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var posts []*domain.Post
+	for _, post := range r.posts {
+		if post.BoardID == boardID && post.ID > afterID {
+			posts = append(posts, post)
+		}
+	}
+
+	// Sort by ID (oldest first)
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+
+	if limit < len(posts) {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+func (r *PostRepository) GetIDRange(boardID int) (int, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	low, high := 0, 0
+	for _, post := range r.posts {
+		if post.BoardID != boardID {
+			continue
+		}
+		if low == 0 || post.ID < low {
+			low = post.ID
+		}
+		if post.ID > high {
+			high = post.ID
+		}
+	}
+	return low, high, nil
+}
@@ -0,0 +1,108 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type PrivateMessageRepository struct {
+	mu       sync.RWMutex
+	messages map[int]*domain.PrivateMessage
+	nextID   int
+}
+
+func NewPrivateMessageRepository() *PrivateMessageRepository {
+	return &PrivateMessageRepository{
+		messages: make(map[int]*domain.PrivateMessage),
+		nextID:   1,
+	}
+}
+
+func (r *PrivateMessageRepository) Create(message *domain.PrivateMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message.ID = r.nextID
+	r.nextID++
+	r.messages[message.ID] = message
+	return nil
+}
+
+func (r *PrivateMessageRepository) GetByID(id int) (*domain.PrivateMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	message, exists := r.messages[id]
+	if !exists {
+		return nil, errors.New("message not found")
+	}
+	return message, nil
+}
+
+func (r *PrivateMessageRepository) GetInbox(userID int, limit int) ([]*domain.PrivateMessage, error) {
+	return r.filter(limit, func(m *domain.PrivateMessage) bool { return m.ToUserID == userID }), nil
+}
+
+func (r *PrivateMessageRepository) GetSent(userID int, limit int) ([]*domain.PrivateMessage, error) {
+	return r.filter(limit, func(m *domain.PrivateMessage) bool { return m.FromUserID == userID }), nil
+}
+
+func (r *PrivateMessageRepository) filter(limit int, match func(*domain.PrivateMessage) bool) []*domain.PrivateMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var messages []*domain.PrivateMessage
+	for _, message := range r.messages {
+		if match(message) {
+			messages = append(messages, message)
+		}
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID > messages[j].ID
+	})
+
+	if limit < len(messages) {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+func (r *PrivateMessageRepository) CountUnread(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, message := range r.messages {
+		if message.ToUserID == userID && message.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *PrivateMessageRepository) MarkRead(id int, readAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if message, exists := r.messages[id]; exists && message.ReadAt == nil {
+		message.ReadAt = &readAt
+	}
+	return nil
+}
+
+func (r *PrivateMessageRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.messages[id]; !exists {
+		return errors.New("message not found")
+	}
+	delete(r.messages, id)
+	return nil
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import "sync"
+
+type ReadPointerRepository struct {
+	mu       sync.RWMutex
+	pointers map[subscription]int
+}
+
+func NewReadPointerRepository() *ReadPointerRepository {
+	return &ReadPointerRepository{
+		pointers: make(map[subscription]int),
+	}
+}
+
+func (r *ReadPointerRepository) GetAll(userID int) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pointers := make(map[int]int)
+	for key, postID := range r.pointers {
+		if key.userID == userID {
+			pointers[key.boardID] = postID
+		}
+	}
+	return pointers, nil
+}
+
+func (r *ReadPointerRepository) Set(userID, boardID, postID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pointers[subscription{userID, boardID}] = postID
+	return nil
+}
@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type RecoveryCodeRepository struct {
+	mu    sync.RWMutex
+	codes map[int]map[string]bool // user ID -> code hash -> used
+}
+
+func NewRecoveryCodeRepository() *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{
+		codes: make(map[int]map[string]bool),
+	}
+}
+
+func (r *RecoveryCodeRepository) Replace(userID int, codes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codes[userID] = make(map[string]bool)
+	for _, code := range codes {
+		r.codes[userID][domain.HashToken(domain.NormalizeRecoveryCode(code))] = false
+	}
+	return nil
+}
+
+func (r *RecoveryCodeRepository) Use(userID int, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hash := domain.HashToken(domain.NormalizeRecoveryCode(code))
+	used, exists := r.codes[userID][hash]
+	if !exists || used {
+		return errors.New("recovery code not found")
+	}
+	r.codes[userID][hash] = true
+	return nil
+}
+
+func (r *RecoveryCodeRepository) CountUnused(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *RecoveryCodeRepository) DeleteForUser(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.codes, userID)
+	return nil
+}
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"sync"
+)
+
+type SettingsRepository struct {
+	mu       sync.RWMutex
+	settings map[string]string
+}
+
+func NewSettingsRepository() *SettingsRepository {
+	return &SettingsRepository{
+		settings: make(map[string]string),
+	}
+}
+
+func (r *SettingsRepository) Get(key string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.settings[key], nil
+}
+
+func (r *SettingsRepository) Set(key, value string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings[key] = value
+	return nil
+}
@@ -0,0 +1,120 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+)
+
+type subscription struct {
+	userID  int
+	boardID int
+}
+
+type watch struct {
+	userID int
+	postID int
+}
+
+type SubscriptionRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[subscription]bool
+	watches       map[watch]bool
+}
+
+func NewSubscriptionRepository() *SubscriptionRepository {
+	return &SubscriptionRepository{
+		subscriptions: make(map[subscription]bool),
+		watches:       make(map[watch]bool),
+	}
+}
+
+func (r *SubscriptionRepository) Subscribe(userID, boardID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscriptions[subscription{userID, boardID}] = true
+	return nil
+}
+
+func (r *SubscriptionRepository) Unsubscribe(userID, boardID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.subscriptions, subscription{userID, boardID})
+	return nil
+}
+
+func (r *SubscriptionRepository) IsSubscribed(userID, boardID int) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.subscriptions[subscription{userID, boardID}], nil
+}
+
+func (r *SubscriptionRepository) GetBoardIDs(userID int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var boardIDs []int
+	for s := range r.subscriptions {
+		if s.userID == userID {
+			boardIDs = append(boardIDs, s.boardID)
+		}
+	}
+
+	sort.Ints(boardIDs)
+	return boardIDs, nil
+}
+
+func (r *SubscriptionRepository) GetSubscribers(boardID int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var userIDs []int
+	for s := range r.subscriptions {
+		if s.boardID == boardID {
+			userIDs = append(userIDs, s.userID)
+		}
+	}
+
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
+
+func (r *SubscriptionRepository) WatchThread(userID, postID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.watches[watch{userID, postID}] = true
+	return nil
+}
+
+func (r *SubscriptionRepository) UnwatchThread(userID, postID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.watches, watch{userID, postID})
+	return nil
+}
+
+func (r *SubscriptionRepository) IsWatching(userID, postID int) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.watches[watch{userID, postID}], nil
+}
+
+func (r *SubscriptionRepository) GetWatchers(postID int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var userIDs []int
+	for w := range r.watches {
+		if w.postID == postID {
+			userIDs = append(userIDs, w.userID)
+		}
+	}
+
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/leinonen/bbs/domain"
@@ -24,10 +26,10 @@ func (r *UserRepository) Create(user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Check for duplicate username
+	// Check for duplicate username or email
 	for _, existingUser := range r.users {
-		if existingUser.Username == user.Username {
-			return errors.New("username already exists")
+		if existingUser.Username == user.Username || existingUser.Email == user.Email {
+			return domain.ErrUserExists
 		}
 	}
 
@@ -60,6 +62,22 @@ func (r *UserRepository) GetByUsername(username string) (*domain.User, error) {
 	return nil, errors.New("user not found")
 }
 
+func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *domain.User
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) && (found == nil || user.EmailVerified && !found.EmailVerified) {
+			found = user
+		}
+	}
+	if found == nil {
+		return nil, errors.New("user not found")
+	}
+	return found, nil
+}
+
 func (r *UserRepository) Update(user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -107,3 +125,99 @@ func (r *UserRepository) UpdateLastLogin(userID int) error {
 	_ = user
 	return nil
 }
+
+func (r *UserRepository) UpdatePassword(userID int, password string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.Password = password
+	return nil
+}
+
+func (r *UserRepository) SetTOTP(userID int, secret string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.TOTPSecret = secret
+	user.TOTPEnabled = enabled
+	user.TOTPLastStep = 0
+	return nil
+}
+
+func (r *UserRepository) UseTOTPStep(userID int, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	if step <= user.TOTPLastStep {
+		return errors.New("code already used")
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (r *UserRepository) SetStatus(userID int, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.Status = status
+	return nil
+}
+
+func (r *UserRepository) GetByStatus(status string) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*domain.User
+	for _, user := range r.users {
+		if user.Status == status {
+			users = append(users, user)
+		}
+	}
+
+	// Sort by ID (oldest first)
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return users, nil
+}
+
+func (r *UserRepository) SetEmailVerified(userID int, verified bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.EmailVerified = verified
+	return nil
+}
+
+func (r *UserRepository) SetRecordingOptOut(userID int, optOut bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.RecordingOptOut = optOut
+	return nil
+}
@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+type WebhookDeliveryRepository struct {
+	mu         sync.RWMutex
+	deliveries map[int]*domain.WebhookDelivery
+	nextID     int
+}
+
+func NewWebhookDeliveryRepository() *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		deliveries: make(map[int]*domain.WebhookDelivery),
+		nextID:     1,
+	}
+}
+
+func (r *WebhookDeliveryRepository) Enqueue(delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery.ID = r.nextID
+	r.nextID++
+	stored := *delivery
+	r.deliveries[delivery.ID] = &stored
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) GetDue(now time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var due []*domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == domain.WebhookPending && !delivery.NextAttemptAt.After(now) {
+			d := *delivery
+			due = append(due, &d)
+		}
+	}
+
+	// Sort by ID (oldest first)
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ID < due[j].ID
+	})
+
+	if limit < len(due) {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *WebhookDeliveryRepository) Update(delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[delivery.ID]; !exists {
+		return errors.New("webhook delivery not found")
+	}
+	stored := *delivery
+	r.deliveries[delivery.ID] = &stored
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) GetRecent(limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []*domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		d := *delivery
+		deliveries = append(deliveries, &d)
+	}
+
+	// Sort by ID (newest first)
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID > deliveries[j].ID
+	})
+
+	if limit < len(deliveries) {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteMotdRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	repo := sqlite.NewMotdRepository(db)
+
+	user := domain.NewUser("sysop", "sysop@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	// Test Get with no MOTD
+	if _, err := repo.Get(); err == nil {
+		t.Error("Get should fail when no MOTD is set")
+	}
+
+	// Test Set and Get
+	motd := domain.NewMotd("First message", user.ID)
+	if err := repo.Set(motd); err != nil {
+		t.Errorf("Set failed: %v", err)
+	}
+
+	motd = domain.NewMotd("Second message\033[2J", user.ID)
+	if err := repo.Set(motd); err != nil {
+		t.Errorf("Set (overwrite) failed: %v", err)
+	}
+
+	retrieved, err := repo.Get()
+	if err != nil {
+		t.Errorf("Get failed: %v", err)
+	}
+
+	if retrieved.Content != "Second message" {
+		t.Errorf("Expected overwritten and sanitized content, got %q", retrieved.Content)
+	}
+
+	if retrieved.UpdatedBy != user.ID {
+		t.Errorf("Expected UpdatedBy %d, got %d", user.ID, retrieved.UpdatedBy)
+	}
+
+	// Test seen tracking
+	lastSeen, err := repo.GetLastSeen(user.ID)
+	if err != nil {
+		t.Errorf("GetLastSeen failed: %v", err)
+	}
+
+	if !lastSeen.IsZero() {
+		t.Error("GetLastSeen should be zero before MarkSeen")
+	}
+
+	seenAt := time.Now().Truncate(time.Second)
+	if err := repo.MarkSeen(user.ID, seenAt); err != nil {
+		t.Errorf("MarkSeen failed: %v", err)
+	}
+
+	if err := repo.MarkSeen(user.ID, seenAt.Add(time.Hour)); err != nil {
+		t.Errorf("MarkSeen (again) failed: %v", err)
+	}
+
+	lastSeen, _ = repo.GetLastSeen(user.ID)
+	if !lastSeen.Equal(seenAt.Add(time.Hour)) {
+		t.Errorf("Expected last seen %v, got %v", seenAt.Add(time.Hour), lastSeen)
+	}
+}
+
+func TestSQLiteOneLinerRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	repo := sqlite.NewOneLinerRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	// Test Create
+	for i := 0; i < 12; i++ {
+		oneLiner := domain.NewOneLiner(user.ID, user.Username, "hello")
+		if err := repo.Create(oneLiner); err != nil {
+			t.Errorf("Create failed: %v", err)
+		}
+	}
+
+	// Test GetRecent
+	oneLiners, err := repo.GetRecent(10)
+	if err != nil {
+		t.Errorf("GetRecent failed: %v", err)
+	}
+
+	if len(oneLiners) != 10 {
+		t.Errorf("Expected 10 one-liners, got %d", len(oneLiners))
+	}
+
+	if oneLiners[0].Username != user.Username {
+		t.Errorf("Expected username %s, got %s", user.Username, oneLiners[0].Username)
+	}
+
+	// Test Delete
+	if err := repo.Delete(oneLiners[0].ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+
+	oneLiners, _ = repo.GetRecent(100)
+	if len(oneLiners) != 11 {
+		t.Errorf("Expected 11 one-liners after deletion, got %d", len(oneLiners))
+	}
+}
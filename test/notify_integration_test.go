@@ -0,0 +1,283 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLitePrivateMessageRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewPrivateMessageRepository(db)
+	alice := &domain.User{ID: 1, Username: "alice"}
+	bob := &domain.User{ID: 2, Username: "bob"}
+
+	message := domain.NewPrivateMessage(alice, bob, "Hello\x1b[2J", "Hi bob")
+	if err := repo.Create(message); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	repo.Create(domain.NewPrivateMessage(bob, alice, "Re: Hello", "Hi alice"))
+
+	retrieved, err := repo.GetByID(message.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if retrieved.Subject != "Hello" || retrieved.FromUsername != "alice" || retrieved.ToUsername != "bob" || retrieved.IsRead() {
+		t.Errorf("Unexpected message: %+v", retrieved)
+	}
+
+	inbox, _ := repo.GetInbox(bob.ID, 10)
+	if len(inbox) != 1 || inbox[0].ID != message.ID {
+		t.Errorf("Expected 1 message in bob's inbox, got %d", len(inbox))
+	}
+
+	sent, _ := repo.GetSent(bob.ID, 10)
+	if len(sent) != 1 || sent[0].ToUserID != alice.ID {
+		t.Errorf("Expected 1 message sent by bob, got %d", len(sent))
+	}
+
+	if unread, _ := repo.CountUnread(bob.ID); unread != 1 {
+		t.Errorf("Expected 1 unread message, got %d", unread)
+	}
+
+	if err := repo.MarkRead(message.ID, time.Now()); err != nil {
+		t.Errorf("MarkRead failed: %v", err)
+	}
+	if unread, _ := repo.CountUnread(bob.ID); unread != 0 {
+		t.Errorf("Expected no unread messages, got %d", unread)
+	}
+
+	if err := repo.Delete(message.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if err := repo.Delete(message.ID); err == nil {
+		t.Error("Delete should fail for a deleted message")
+	}
+}
+
+func TestSQLiteNotificationSettingsRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewNotificationSettingsRepository(db)
+
+	settings, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if settings.UserID != 1 || settings.Any() {
+		t.Errorf("Expected everything off by default, got %+v", settings)
+	}
+
+	now := time.Now()
+	settings.EmailReplies = true
+	settings.EmailDigest = true
+	settings.DigestSentAt = now.Add(-25 * time.Hour)
+	if err := repo.Save(settings); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	repo.Save(&domain.NotificationSettings{UserID: 2, EmailDigest: true, DigestSentAt: now})
+	repo.Save(&domain.NotificationSettings{UserID: 3, EmailMessages: true, DigestSentAt: now.Add(-48 * time.Hour)})
+
+	retrieved, _ := repo.Get(1)
+	if !retrieved.EmailReplies || retrieved.EmailMessages || !retrieved.EmailDigest {
+		t.Errorf("Unexpected settings: %+v", retrieved)
+	}
+
+	due, err := repo.GetDigestDue(now)
+	if err != nil {
+		t.Fatalf("GetDigestDue failed: %v", err)
+	}
+	if len(due) != 1 || due[0].UserID != 1 {
+		t.Errorf("Expected only user 1's digest to be due, got %d", len(due))
+	}
+
+	settings.DigestSentAt = now
+	repo.Save(settings)
+	if due, _ := repo.GetDigestDue(now); len(due) != 0 {
+		t.Errorf("Expected no digests due after saving, got %d", len(due))
+	}
+}
+
+func TestSQLiteSubscriptionRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewSubscriptionRepository(db)
+
+	if err := repo.Subscribe(1, 2); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := repo.Subscribe(1, 2); err != nil {
+		t.Errorf("Subscribing twice should not fail: %v", err)
+	}
+	repo.Subscribe(1, 3)
+	repo.Subscribe(2, 3)
+
+	if ok, _ := repo.IsSubscribed(1, 2); !ok {
+		t.Error("Expected user 1 to be subscribed to board 2")
+	}
+
+	ids, _ := repo.GetBoardIDs(1)
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", len(ids))
+	}
+
+	if err := repo.Unsubscribe(1, 2); err != nil {
+		t.Errorf("Unsubscribe failed: %v", err)
+	}
+	if ok, _ := repo.IsSubscribed(1, 2); ok {
+		t.Error("Expected user 1 to be unsubscribed from board 2")
+	}
+}
+
+func TestSQLiteEmailQueueRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewEmailQueueRepository(db)
+
+	email := domain.NewQueuedEmail("user@example.com", "Hello", "Hi there")
+	if err := repo.Enqueue(email); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	now := time.Now()
+	later := domain.NewQueuedEmail("user@example.com", "Later", "Not yet")
+	later.NextAttemptAt = now.Add(time.Hour)
+	repo.Enqueue(later)
+
+	due, err := repo.GetDue(now, 10)
+	if err != nil {
+		t.Fatalf("GetDue failed: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != email.ID || due[0].Body != "Hi there" {
+		t.Fatalf("Expected only the first email to be due, got %d", len(due))
+	}
+
+	due[0].Failed(errors.New("connection refused"), now)
+	if err := repo.Update(due[0]); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if due, _ := repo.GetDue(now, 10); len(due) != 0 {
+		t.Errorf("Expected no email due during the backoff, got %d", len(due))
+	}
+
+	due, _ = repo.GetDue(now.Add(2*time.Hour), 10)
+	if len(due) != 2 || due[0].Attempts != 1 || due[0].LastError != "connection refused" {
+		t.Fatalf("Expected both emails due later with the failure recorded, got %+v", due)
+	}
+
+	due[0].Sent(now)
+	repo.Update(due[0])
+
+	recent, _ := repo.GetRecent(10)
+	if len(recent) != 2 || recent[1].Status != domain.EmailSent || recent[1].SentAt == nil {
+		t.Errorf("Expected the first email to be sent, got %+v", recent)
+	}
+}
+
+func TestSQLitePostRepository_GetThreadsSince(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewPostRepository(db)
+	users := sqlite.NewUserRepository(db)
+	for _, username := range []string{"alice", "bob"} {
+		user := domain.NewUser(username, username+"@example.com")
+		user.Password = "password123"
+		users.Create(user)
+	}
+
+	old := domain.NewPost(1, 1, "alice", "Old thread", "Hello")
+	old.CreatedAt = time.Now().Add(-2 * time.Hour)
+	repo.Create(old)
+
+	since := time.Now().Add(-time.Hour)
+	thread := domain.NewPost(1, 1, "alice", "New thread", "Hello")
+	repo.Create(thread)
+	repo.Create(domain.NewReply(1, 2, "bob", "A reply", thread.ID))
+	repo.Create(domain.NewPost(2, 1, "alice", "Other board", "Hello"))
+
+	threads, err := repo.GetThreadsSince(1, since)
+	if err != nil {
+		t.Fatalf("GetThreadsSince failed: %v", err)
+	}
+	if len(threads) != 1 || threads[0].ID != thread.ID {
+		t.Errorf("Expected only the new thread, got %d posts", len(threads))
+	}
+}
+
+func TestSQLiteThreadWatches_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewSubscriptionRepository(db)
+
+	repo.Subscribe(1, 2)
+	repo.Subscribe(3, 2)
+	ids, err := repo.GetSubscribers(2)
+	if err != nil {
+		t.Fatalf("GetSubscribers failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("Expected users 1 and 3 to subscribe to board 2, got %v", ids)
+	}
+
+	if err := repo.WatchThread(1, 10); err != nil {
+		t.Fatalf("WatchThread failed: %v", err)
+	}
+	if err := repo.WatchThread(1, 10); err != nil {
+		t.Errorf("Watching twice should not fail: %v", err)
+	}
+	repo.WatchThread(2, 10)
+
+	if watching, _ := repo.IsWatching(1, 10); !watching {
+		t.Error("Expected user 1 to watch thread 10")
+	}
+
+	if err := repo.UnwatchThread(1, 10); err != nil {
+		t.Errorf("UnwatchThread failed: %v", err)
+	}
+	ids, _ = repo.GetWatchers(10)
+	if len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("Expected only user 2 to watch thread 10, got %v", ids)
+	}
+}
+
+func TestSQLiteNotificationRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewNotificationRepository(db)
+	thread := &domain.Post{ID: 1, UserID: 1, Username: "alice", Title: "Hello\x1b[31m"}
+	reply := &domain.Post{ID: 2, UserID: 2, Username: "bob"}
+
+	notification := domain.NewNotification(3, domain.NotificationMention, reply, thread)
+	if err := repo.Create(notification); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	repo.Create(domain.NewNotification(3, domain.NotificationReply, reply, thread))
+	repo.Create(domain.NewNotification(4, domain.NotificationReply, reply, thread))
+
+	notifications, err := repo.GetByUser(3, 10)
+	if err != nil {
+		t.Fatalf("GetByUser failed: %v", err)
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(notifications))
+	}
+
+	n := notifications[1]
+	if n.ID != notification.ID || n.Kind != domain.NotificationMention || n.PostID != 2 || n.ThreadID != 1 ||
+		n.ActorID != 2 || n.ActorUsername != "bob" || n.Title != "Hello" || n.IsRead() {
+		t.Errorf("Unexpected notification: %+v", n)
+	}
+
+	if err := repo.MarkRead(notification.ID, time.Now()); err != nil {
+		t.Errorf("MarkRead failed: %v", err)
+	}
+	if unread, _ := repo.CountUnread(3); unread != 1 {
+		t.Errorf("Expected 1 unread notification, got %d", unread)
+	}
+
+	if err := repo.MarkAllRead(3, time.Now()); err != nil {
+		t.Errorf("MarkAllRead failed: %v", err)
+	}
+	if unread, _ := repo.CountUnread(3); unread != 0 {
+		t.Errorf("Expected no unread notifications, got %d", unread)
+	}
+	if unread, _ := repo.CountUnread(4); unread != 1 {
+		t.Errorf("Expected user 4's notification to stay unread, got %d", unread)
+	}
+}
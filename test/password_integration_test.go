@@ -0,0 +1,169 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/repository/sqlite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSQLiteUserRepository_UpdatePassword(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewUserRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	repo.Create(user)
+
+	if err := repo.UpdatePassword(user.ID, "a new password"); err != nil {
+		t.Errorf("UpdatePassword failed: %v", err)
+	}
+
+	if _, err := repo.Authenticate("testuser", "password123"); err == nil {
+		t.Error("Authenticate should reject the old password")
+	}
+
+	if _, err := repo.Authenticate("testuser", "a new password"); err != nil {
+		t.Errorf("Authenticate should accept the new password: %v", err)
+	}
+
+	if err := repo.UpdatePassword(999, "whatever"); err == nil {
+		t.Error("UpdatePassword should fail for non-existent user")
+	}
+}
+
+func TestSQLiteUserRepository_RehashOnLogin(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewUserRepository(db)
+
+	// Create the user with a cheap hash, as older versions did
+	repo.SetPasswordCost(bcrypt.MinCost)
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	repo.Create(user)
+
+	repo.SetPasswordCost(bcrypt.MinCost + 1)
+	if _, err := repo.Authenticate("testuser", "password123"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	var hash string
+	db.QueryRow("SELECT password FROM users WHERE id = ?", user.ID).Scan(&hash)
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		t.Fatalf("Stored hash is not bcrypt: %v", err)
+	}
+
+	if cost != bcrypt.MinCost+1 {
+		t.Errorf("Expected hash to be upgraded to cost %d, got %d", bcrypt.MinCost+1, cost)
+	}
+
+	if _, err := repo.Authenticate("testuser", "password123"); err != nil {
+		t.Errorf("Authenticate should still work after rehash: %v", err)
+	}
+}
+
+func TestSQLitePasswordResetRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	repo := sqlite.NewPasswordResetRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	// Test Create
+	reset, token, _ := domain.NewPasswordReset(user.ID, user.ID)
+	if err := repo.Create(reset); err != nil {
+		t.Errorf("Create failed: %v", err)
+	}
+
+	// Test GetByToken
+	retrieved, err := repo.GetByToken(token)
+	if err != nil {
+		t.Fatalf("GetByToken failed: %v", err)
+	}
+
+	if retrieved.UserID != user.ID || !retrieved.IsValid(time.Now()) {
+		t.Errorf("Unexpected reset %+v", retrieved)
+	}
+
+	if _, err := repo.GetByToken("deadbeef"); err == nil {
+		t.Error("GetByToken should fail for unknown token")
+	}
+
+	// Test MarkUsed
+	if err := repo.MarkUsed(reset.ID, time.Now()); err != nil {
+		t.Errorf("MarkUsed failed: %v", err)
+	}
+
+	retrieved, _ = repo.GetByToken(token)
+	if retrieved.IsValid(time.Now()) {
+		t.Error("Used reset should not be valid")
+	}
+	if err := repo.MarkUsed(reset.ID, time.Now()); err == nil {
+		t.Error("MarkUsed should fail for a reset that was already used")
+	}
+
+	// Test InvalidateForUser
+	second, secondToken, _ := domain.NewPasswordReset(user.ID, user.ID)
+	repo.Create(second)
+
+	if err := repo.InvalidateForUser(user.ID); err != nil {
+		t.Errorf("InvalidateForUser failed: %v", err)
+	}
+
+	retrieved, _ = repo.GetByToken(secondToken)
+	if retrieved.IsValid(time.Now()) {
+		t.Error("InvalidateForUser should invalidate outstanding resets")
+	}
+}
+
+func TestManager_CheckPasswordReset(t *testing.T) {
+	db := setupTestDB(t)
+	repos := repository.NewManager(db)
+
+	alice := domain.NewUser("alice", "alice@example.com")
+	alice.Password = "password123"
+	repos.User.Create(alice)
+	bob := domain.NewUser("bob", "bob@example.com")
+	bob.Password = "password123"
+	repos.User.Create(bob)
+
+	reset, token, _ := domain.NewPasswordReset(alice.ID, bob.ID)
+	repos.PasswordReset.Create(reset)
+
+	user, found, err := repos.CheckPasswordReset("alice", token)
+	if err != nil {
+		t.Fatalf("CheckPasswordReset failed: %v", err)
+	}
+
+	if user.ID != alice.ID || found.ID != reset.ID {
+		t.Errorf("Expected reset %d for alice, got %d for %s", reset.ID, found.ID, user.Username)
+	}
+
+	// A token only works for the user it was issued to
+	if _, _, err := repos.CheckPasswordReset("bob", token); err == nil {
+		t.Error("CheckPasswordReset should reject a token issued to another user")
+	}
+
+	if _, _, err := repos.CheckPasswordReset("nobody", token); err == nil {
+		t.Error("CheckPasswordReset should reject unknown users")
+	}
+
+	repos.PasswordReset.MarkUsed(reset.ID, time.Now())
+	if _, _, err := repos.CheckPasswordReset("alice", token); err == nil {
+		t.Error("CheckPasswordReset should reject a used token")
+	}
+
+	expired, expiredToken, _ := domain.NewPasswordReset(alice.ID, bob.ID)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	repos.PasswordReset.Create(expired)
+	if _, _, err := repos.CheckPasswordReset("alice", expiredToken); err == nil {
+		t.Error("CheckPasswordReset should reject an expired token")
+	}
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/leinonen/bbs/database"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteUserRepository_Profile(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	boardRepo := sqlite.NewBoardRepository(db)
+	postRepo := sqlite.NewPostRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	// Test profile fields round trip through Update
+	user.Location = "Tampere"
+	user.Bio = "Line one\nLine two"
+	user.Homepage = "https://example.com"
+	user.Signature = "-- test"
+	user.TimeZone = "Europe/Helsinki"
+	user.DateFormat = "iso"
+
+	if err := userRepo.Update(user); err != nil {
+		t.Errorf("Update failed: %v", err)
+	}
+
+	stored, err := userRepo.GetByUsername("testuser")
+	if err != nil {
+		t.Fatalf("GetByUsername failed: %v", err)
+	}
+
+	if stored.Location != "Tampere" || stored.Bio != "Line one\nLine two" ||
+		stored.Homepage != "https://example.com" || stored.Signature != "-- test" ||
+		stored.TimeZone != "Europe/Helsinki" || stored.DateFormat != "iso" {
+		t.Errorf("Profile fields were not stored: %+v", stored)
+	}
+
+	authUser, err := userRepo.Authenticate("testuser", "password123")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	if authUser.Location != "Tampere" {
+		t.Error("Authenticate should load profile fields")
+	}
+
+	// Test post statistics
+	board := domain.NewBoard("General", "General discussion")
+	boardRepo.Create(board)
+
+	post := domain.NewPost(board.ID, user.ID, user.Username, "Hello", "World")
+	postRepo.Create(post)
+	postRepo.Create(domain.NewReply(board.ID, user.ID, user.Username, "Reply", post.ID))
+
+	count, err := postRepo.CountByUser(user.ID)
+	if err != nil {
+		t.Errorf("CountByUser failed: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 posts, got %d", count)
+	}
+
+	posts, err := postRepo.GetByUser(user.ID, 1)
+	if err != nil {
+		t.Errorf("GetByUser failed: %v", err)
+	}
+
+	if len(posts) != 1 {
+		t.Errorf("Expected limit to apply, got %d posts", len(posts))
+	}
+}
+
+func TestMigrate_UpgradesExistingDatabase(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "test_bbs_*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	db, err := sql.Open("sqlite3", tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// The users table as it looked before profile fields existed
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT UNIQUE NOT NULL,
+			password TEXT NOT NULL,
+			email TEXT UNIQUE NOT NULL,
+			created_at DATETIME NOT NULL,
+			last_login DATETIME NOT NULL,
+			is_admin BOOLEAN DEFAULT 0
+		);
+		INSERT INTO users (username, password, email, created_at, last_login)
+		VALUES ('olduser', 'x', 'old@example.com', datetime('now'), datetime('now'));
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Running it twice must be harmless
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Second Migrate failed: %v", err)
+	}
+
+	user, err := sqlite.NewUserRepository(db).GetByUsername("olduser")
+	if err != nil {
+		t.Fatalf("GetByUsername after upgrade failed: %v", err)
+	}
+
+	if user.Location != "" || user.Signature != "" {
+		t.Errorf("Expected empty defaults for new columns, got %+v", user)
+	}
+}
+
+func TestSQLiteUserRepository_RecordingOptOut(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+
+	if err := userRepo.SetRecordingOptOut(user.ID, true); err != nil {
+		t.Fatalf("SetRecordingOptOut failed: %v", err)
+	}
+
+	stored, _ := userRepo.GetByID(user.ID)
+	if !stored.RecordingOptOut {
+		t.Error("Expected the user to have opted out of recording")
+	}
+
+	if err := userRepo.SetRecordingOptOut(9999, true); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteReadPointerRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	userRepo := sqlite.NewUserRepository(db)
+	boardRepo := sqlite.NewBoardRepository(db)
+	repo := sqlite.NewReadPointerRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	userRepo.Create(user)
+	other := domain.NewUser("otheruser", "other@example.com")
+	other.Password = "password123"
+	userRepo.Create(other)
+	first := domain.NewBoard("First", "First board")
+	boardRepo.Create(first)
+	second := domain.NewBoard("Second", "Second board")
+	boardRepo.Create(second)
+
+	pointers, err := repo.GetAll(user.ID)
+	if err != nil || len(pointers) != 0 {
+		t.Fatalf("Expected no pointers for a new user: %v", err)
+	}
+
+	for _, set := range []struct{ user, board, post int }{
+		{user.ID, first.ID, 10},
+		{user.ID, second.ID, 20},
+		{other.ID, first.ID, 5},
+		{user.ID, first.ID, 15},
+	} {
+		if err := repo.Set(set.user, set.board, set.post); err != nil {
+			t.Fatalf("Set should not return error: %v", err)
+		}
+	}
+
+	pointers, err = repo.GetAll(user.ID)
+	if err != nil || len(pointers) != 2 || pointers[first.ID] != 15 || pointers[second.ID] != 20 {
+		t.Errorf("Expected pointers 15 and 20, got %v: %v", pointers, err)
+	}
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteUserRepository_Status(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewUserRepository(db)
+
+	active := domain.NewUser("active", "active@example.com")
+	active.Password = "password123"
+	repo.Create(active)
+
+	pending := domain.NewUser("pending", "pending@example.com")
+	pending.Password = "password123"
+	pending.Status = domain.UserStatusPending
+	pending.InvitedBy = active.ID
+	repo.Create(pending)
+
+	retrieved, _ := repo.GetByID(pending.ID)
+	if retrieved.Status != domain.UserStatusPending || retrieved.InvitedBy != active.ID {
+		t.Errorf("Expected a pending user invited by %d, got %q invited by %d", active.ID, retrieved.Status, retrieved.InvitedBy)
+	}
+
+	users, err := repo.GetByStatus(domain.UserStatusPending)
+	if err != nil {
+		t.Fatalf("GetByStatus failed: %v", err)
+	}
+
+	if len(users) != 1 || users[0].ID != pending.ID {
+		t.Errorf("Expected only the pending user, got %d users", len(users))
+	}
+
+	if err := repo.SetStatus(pending.ID, domain.UserStatusActive); err != nil {
+		t.Errorf("SetStatus failed: %v", err)
+	}
+
+	users, _ = repo.GetByStatus(domain.UserStatusPending)
+	if len(users) != 0 {
+		t.Errorf("Expected no pending users, got %d", len(users))
+	}
+
+	if err := repo.SetStatus(999, domain.UserStatusActive); err == nil {
+		t.Error("SetStatus should fail for non-existent user")
+	}
+}
+
+func TestSQLiteInviteRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewInviteRepository(db)
+
+	invite, _ := domain.NewInvite(1, 2)
+	if err := repo.Create(invite); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	other, _ := domain.NewInvite(2, 1)
+	repo.Create(other)
+
+	found, err := repo.GetByCode(" " + invite.Code + " ")
+	if err != nil {
+		t.Fatalf("GetByCode failed: %v", err)
+	}
+
+	if found.ID != invite.ID || found.MaxUses != 2 || found.Uses != 0 {
+		t.Errorf("Unexpected invite: %+v", found)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.Use(invite.Code); err != nil {
+			t.Errorf("Use %d failed: %v", i+1, err)
+		}
+	}
+
+	if err := repo.Use(invite.Code); err == nil {
+		t.Error("Use should fail once the invite is used up")
+	}
+
+	invites, _ := repo.GetByCreator(1)
+	if len(invites) != 1 || invites[0].Uses != 2 {
+		t.Errorf("Expected 1 used-up invite by user 1, got %+v", invites)
+	}
+
+	invites, _ = repo.GetAll(10)
+	if len(invites) != 2 {
+		t.Errorf("Expected 2 invites, got %d", len(invites))
+	}
+
+	if err := repo.Delete(other.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+
+	if _, err := repo.GetByCode(other.Code); err == nil {
+		t.Error("GetByCode should fail after Delete")
+	}
+}
+
+func TestManager_VerifyEmail(t *testing.T) {
+	db := setupTestDB(t)
+	repos := repository.NewManager(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	user.Status = domain.UserStatusUnverified
+	repos.User.Create(user)
+
+	if err := repos.VerifyEmail(user, "12345678"); err == nil {
+		t.Error("VerifyEmail should fail before a code has been sent")
+	}
+
+	code, err := repos.StartEmailVerification(user)
+	if err != nil {
+		t.Fatalf("StartEmailVerification failed: %v", err)
+	}
+
+	if err := repos.VerifyEmail(user, "x"+code); err == nil {
+		t.Error("VerifyEmail should reject a wrong code")
+	}
+
+	verification, _ := repos.Verification.GetByUser(user.ID)
+	if verification.Attempts != 1 {
+		t.Errorf("Expected 1 attempt recorded, got %d", verification.Attempts)
+	}
+
+	if err := repos.VerifyEmail(user, code); err != nil {
+		t.Fatalf("VerifyEmail failed: %v", err)
+	}
+
+	retrieved, _ := repos.User.GetByID(user.ID)
+	if retrieved.Status != domain.UserStatusActive || user.Status != domain.UserStatusActive {
+		t.Errorf("Expected the user to be active, got %q", retrieved.Status)
+	}
+
+	if _, err := repos.Verification.GetByUser(user.ID); err == nil {
+		t.Error("VerifyEmail should delete the used verification")
+	}
+
+	// a new code replaces the old one, and codes stop working after too many guesses
+	user.Status = domain.UserStatusUnverified
+	repos.StartEmailVerification(user)
+	code, _ = repos.StartEmailVerification(user)
+	for i := 0; i < domain.MaxVerificationAttempts; i++ {
+		repos.VerifyEmail(user, "wrong")
+	}
+	if err := repos.VerifyEmail(user, code); err == nil {
+		t.Error("VerifyEmail should refuse the code after too many wrong guesses")
+	}
+}
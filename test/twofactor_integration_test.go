@@ -0,0 +1,167 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/repository/sqlite"
+	"github.com/leinonen/bbs/totp"
+)
+
+func TestSQLiteUserRepository_TOTP(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewUserRepository(db)
+
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	repo.Create(user)
+
+	if err := repo.SetTOTP(user.ID, "JBSWY3DPEHPK3PXP", true); err != nil {
+		t.Fatalf("SetTOTP failed: %v", err)
+	}
+
+	retrieved, _ := repo.GetByID(user.ID)
+	if retrieved.TOTPSecret != "JBSWY3DPEHPK3PXP" || !retrieved.TOTPEnabled {
+		t.Errorf("Expected TOTP to be enabled, got %+v", retrieved)
+	}
+
+	// Authenticate returns the same columns
+	authed, _ := repo.Authenticate("testuser", "password123")
+	if !authed.TOTPEnabled {
+		t.Error("Authenticate should load TOTP settings")
+	}
+
+	if err := repo.UseTOTPStep(user.ID, 100); err != nil {
+		t.Errorf("UseTOTPStep failed: %v", err)
+	}
+
+	if err := repo.UseTOTPStep(user.ID, 100); err == nil {
+		t.Error("UseTOTPStep should reject a step that was already used")
+	}
+
+	if err := repo.UseTOTPStep(user.ID, 99); err == nil {
+		t.Error("UseTOTPStep should reject an older step")
+	}
+
+	if err := repo.SetTOTP(999, "", false); err == nil {
+		t.Error("SetTOTP should fail for non-existent user")
+	}
+}
+
+func TestSQLiteRecoveryCodeRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewRecoveryCodeRepository(db)
+
+	if err := repo.Replace(1, []string{"aaaaa-11111", "bbbbb-22222"}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	if err := repo.Use(1, "AAAAA 11111"); err != nil {
+		t.Errorf("Use failed: %v", err)
+	}
+
+	if err := repo.Use(1, "aaaaa-11111"); err == nil {
+		t.Error("Use should fail for a used code")
+	}
+
+	if err := repo.Use(2, "bbbbb-22222"); err == nil {
+		t.Error("Use should fail for another user's code")
+	}
+
+	count, err := repo.CountUnused(1)
+	if err != nil {
+		t.Errorf("CountUnused failed: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("Expected 1 unused code, got %d", count)
+	}
+
+	repo.Replace(1, []string{"ccccc-33333"})
+	if err := repo.Use(1, "bbbbb-22222"); err == nil {
+		t.Error("Replace should discard old codes")
+	}
+
+	repo.DeleteForUser(1)
+	if count, _ := repo.CountUnused(1); count != 0 {
+		t.Errorf("Expected no codes after DeleteForUser, got %d", count)
+	}
+}
+
+func TestSQLiteSettingsRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewSettingsRepository(db)
+
+	value, err := repo.Get("missing")
+	if err != nil || value != "" {
+		t.Errorf("Expected empty value for missing key, got %q, %v", value, err)
+	}
+
+	repo.Set("key", "one")
+	if err := repo.Set("key", "two"); err != nil {
+		t.Errorf("Set failed: %v", err)
+	}
+
+	value, _ = repo.Get("key")
+	if value != "two" {
+		t.Errorf("Expected two, got %q", value)
+	}
+}
+
+func TestManager_VerifySecondFactor(t *testing.T) {
+	db := setupTestDB(t)
+	repos := repository.NewManager(db)
+
+	secret, _ := totp.GenerateSecret()
+	user := domain.NewUser("testuser", "test@example.com")
+	user.Password = "password123"
+	repos.User.Create(user)
+	repos.User.SetTOTP(user.ID, secret, true)
+	repos.RecoveryCode.Replace(user.ID, []string{"aaaaa-11111"})
+	user, _ = repos.User.GetByID(user.ID)
+
+	code, _ := totp.Code(secret, time.Now())
+	if !repos.VerifySecondFactor(user, code) {
+		t.Error("Current code should be accepted")
+	}
+
+	if repos.VerifySecondFactor(user, code) {
+		t.Error("The same code should not be accepted twice")
+	}
+
+	stale, _ := totp.Code(secret, time.Now().Add(-10*time.Minute))
+	if repos.VerifySecondFactor(user, stale) {
+		t.Error("Codes from outside the window should be rejected")
+	}
+
+	if !repos.VerifySecondFactor(user, "aaaaa-11111") {
+		t.Error("Recovery code should be accepted")
+	}
+
+	if repos.VerifySecondFactor(user, "aaaaa-11111") {
+		t.Error("Recovery code should only work once")
+	}
+}
+
+func TestManager_TwoFactorPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	repos := repository.NewManager(db)
+
+	policy, err := repos.TwoFactorPolicy()
+	if err != nil {
+		t.Fatalf("TwoFactorPolicy failed: %v", err)
+	}
+
+	if len(policy.RequiredRoles) != 0 {
+		t.Errorf("Expected no required roles by default, got %v", policy.RequiredRoles)
+	}
+
+	repos.SetTwoFactorPolicy(policy.Toggle(domain.RoleAdmin))
+
+	policy, _ = repos.TwoFactorPolicy()
+	if !policy.RoleRequired(domain.RoleAdmin) {
+		t.Error("Expected admin role to require 2FA")
+	}
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository/sqlite"
+)
+
+func TestSQLiteWebhookDeliveryRepository_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	repo := sqlite.NewWebhookDeliveryRepository(db)
+
+	delivery := domain.NewWebhookDelivery("https://example.com/hook", "thread.created", `{"event":"thread.created"}`)
+	if err := repo.Enqueue(delivery); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	now := time.Now()
+	later := domain.NewWebhookDelivery("https://example.com/hook", "moderation", `{"event":"moderation"}`)
+	later.NextAttemptAt = now.Add(time.Hour)
+	repo.Enqueue(later)
+
+	due, err := repo.GetDue(now, 10)
+	if err != nil {
+		t.Fatalf("GetDue failed: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != delivery.ID || due[0].Payload != `{"event":"thread.created"}` {
+		t.Fatalf("Expected only the first delivery to be due, got %d", len(due))
+	}
+
+	due[0].Failed(503, errors.New("HTTP 503 Service Unavailable"), now)
+	if err := repo.Update(due[0]); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if due, _ := repo.GetDue(now, 10); len(due) != 0 {
+		t.Errorf("Expected no delivery due during the backoff, got %d", len(due))
+	}
+
+	due, _ = repo.GetDue(now.Add(2*time.Hour), 10)
+	if len(due) != 2 || due[0].Attempts != 1 || due[0].ResponseCode != 503 {
+		t.Fatalf("Expected both deliveries due later with the failure recorded, got %+v", due)
+	}
+
+	due[0].Delivered(200, now)
+	repo.Update(due[0])
+
+	recent, _ := repo.GetRecent(10)
+	if len(recent) != 2 || recent[1].Status != domain.WebhookDelivered || recent[1].DeliveredAt == nil {
+		t.Errorf("Expected the first delivery to be delivered, got %+v", recent)
+	}
+}
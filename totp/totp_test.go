@@ -0,0 +1,111 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors for SHA1, truncated to six digits.
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code failed: %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("At %d expected %s, got %s", tt.unix, tt.code, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+
+	now := time.Now()
+	code, _ := Code(secret, now)
+
+	if !Validate(secret, code, now) {
+		t.Error("Current code should be valid")
+	}
+
+	if !Validate(secret, code, now.Add(Period)) {
+		t.Error("Code from the previous step should be accepted")
+	}
+
+	if Validate(secret, code, now.Add(3*Period)) {
+		t.Error("Code from three steps ago should be rejected")
+	}
+
+	if !Validate(secret, code[:3]+" "+code[3:], now) {
+		t.Error("Spaces in the code should be ignored")
+	}
+
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if Validate(secret, bad, now) {
+			t.Errorf("Code %q should be rejected", bad)
+		}
+	}
+
+	if Validate("not base32!", code, now) {
+		t.Error("Invalid secret should never validate")
+	}
+}
+
+func TestValidateStep(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Unix(1700000000, 0)
+	code, _ := Code(secret, now.Add(-Period))
+
+	s, ok := ValidateStep(secret, code, now)
+	if !ok {
+		t.Fatal("Previous step code should be accepted")
+	}
+	if s != step(now)-1 {
+		t.Errorf("Expected step %d, got %d", step(now)-1, s)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, _ := GenerateSecret()
+	b, _ := GenerateSecret()
+
+	if a == b {
+		t.Error("Secrets should be random")
+	}
+
+	if len(a) != 32 {
+		t.Errorf("Expected 32 character secret, got %d", len(a))
+	}
+
+	// Lowercase secrets, as some apps display them, should still work
+	if _, err := Code(strings.ToLower(a), time.Now()); err != nil {
+		t.Errorf("Lowercase secret should decode: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Go BBS", "alice", "JBSWY3DPEHPK3PXP")
+	expected := "otpauth://totp/Go%20BBS:alice?issuer=Go+BBS&secret=JBSWY3DPEHPK3PXP"
+	if uri != expected {
+		t.Errorf("Expected %s, got %s", expected, uri)
+	}
+}
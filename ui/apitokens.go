@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// manageAPITokens lets a user create and revoke the tokens programs use to
+// reach the HTTP API as them.
+func (ui *UI) manageAPITokens() {
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		ui.printHeader("API Tokens")
+
+		tokens, err := ui.repos.APIToken.GetByUser(user.ID)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading API tokens: %v", err))
+			return
+		}
+
+		for i, token := range tokens {
+			lastUsed := "never used"
+			if token.LastUsedAt != nil {
+				lastUsed = "last used " + ui.formatTime(*token.LastUsedAt)
+			}
+			ui.println(fmt.Sprintf("%2d. %s  created %s, %s",
+				i+1, safe(token.Name), ui.formatTime(token.CreatedAt), lastUsed))
+		}
+		if len(tokens) == 0 {
+			ui.println("No API tokens yet.")
+		}
+
+		ui.println("")
+		ui.println("A token lets a program read and post as you. Revoke any you no longer use.")
+		ui.println("Commands: (N)ew token, (R)evoke <number>, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch {
+		case cmd == "n":
+			if len(tokens) >= domain.MaxAPITokens {
+				ui.printError(fmt.Sprintf("You can have at most %d API tokens", domain.MaxAPITokens))
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			ui.createAPIToken()
+		case strings.HasPrefix(cmd, "r"):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+			if err != nil || num < 1 || num > len(tokens) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			token := tokens[num-1]
+			if !ui.confirm(fmt.Sprintf("Revoke %s? Programs using it will stop working.", safe(token.Name))) {
+				continue
+			}
+			if err := ui.repos.APIToken.Delete(user.ID, token.ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to revoke token: %v", err))
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			ui.audit(domain.AuditAPITokenRevoke, user.Username, map[string]interface{}{"token_id": token.ID, "name": token.Name})
+		default:
+			return
+		}
+	}
+}
+
+func (ui *UI) createAPIToken() {
+	user := ui.session.CurrentUser()
+
+	name := ui.readLine("Name, e.g. what will use it: ")
+	apiToken, token, err := domain.NewAPIToken(user.ID, name)
+	if err == nil {
+		err = ui.repos.APIToken.Create(apiToken)
+	}
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to create token: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditAPITokenCreate, user.Username, map[string]interface{}{"token_id": apiToken.ID, "name": apiToken.Name})
+
+	ui.printSuccess(fmt.Sprintf("API token: %s", token))
+	ui.println("Copy it now, it will not be shown again.")
+	ui.readLine("Press Enter to continue...")
+}
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// auditPageSize is how many audit log entries fit on a page.
+const auditPageSize = 15
+
+func auditActor(entry *domain.AuditEntry) string {
+	if entry.Actor == "" {
+		return "-"
+	}
+	return entry.Actor
+}
+
+func describeAuditFilter(filter domain.AuditFilter) string {
+	var parts []string
+	if filter.Actor != "" {
+		parts = append(parts, "user "+filter.Actor)
+	}
+	if filter.Action != "" {
+		parts = append(parts, "action "+filter.Action)
+	}
+	if filter.Target != "" {
+		parts = append(parts, "target "+filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		parts = append(parts, "since "+filter.Since.Format("2006-01-02"))
+	}
+	if len(parts) == 0 {
+		return "all entries"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (ui *UI) showAuditLog() {
+	var filter domain.AuditFilter
+	page := 0
+	for {
+		ui.clear()
+		ui.printHeader("Audit Log")
+
+		entries, err := ui.repos.Audit.Find(filter, auditPageSize+1, page*auditPageSize)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading audit log: %v", err))
+		}
+		more := len(entries) > auditPageSize
+		if more {
+			entries = entries[:auditPageSize]
+		}
+
+		ui.println(fmt.Sprintf("Showing %s, page %d", safe(describeAuditFilter(filter)), page+1))
+		ui.println("")
+		if len(entries) == 0 {
+			ui.println("No entries.")
+		}
+		for i, entry := range entries {
+			ui.println(fmt.Sprintf("%2d. %s %-16s %-20s %s",
+				i+1,
+				entry.CreatedAt.Format("2006-01-02 15:04"),
+				safe(auditActor(entry)),
+				entry.Action,
+				safe(entry.Target)))
+		}
+
+		ui.println("")
+		ui.println("Filter: (U) <username>, (A) <action or category>, (T) <target>, (S) <since YYYY-MM-DD>, (C)lear")
+		ui.println("Commands: (V)iew #, (N)ext page, (P)revious page, (B)ack")
+
+		cmd := strings.TrimSpace(ui.readLine("> "))
+		lower := strings.ToLower(cmd)
+		arg := ""
+		if len(cmd) > 2 && cmd[1] == ' ' {
+			arg = strings.TrimSpace(cmd[2:])
+		}
+
+		switch {
+		case lower == "" || lower == "b":
+			return
+		case lower == "n":
+			if more {
+				page++
+			}
+		case lower == "p":
+			if page > 0 {
+				page--
+			}
+		case lower == "c":
+			filter = domain.AuditFilter{}
+			page = 0
+		case strings.HasPrefix(lower, "u ") && arg != "":
+			filter.Actor = arg
+			page = 0
+		case strings.HasPrefix(lower, "a ") && arg != "":
+			filter.Action = strings.ToLower(arg)
+			page = 0
+		case strings.HasPrefix(lower, "t ") && arg != "":
+			filter.Target = arg
+			page = 0
+		case strings.HasPrefix(lower, "s ") && arg != "":
+			since, err := time.ParseInLocation("2006-01-02", arg, time.Local)
+			if err != nil {
+				ui.printError("Use a date like 2006-01-02")
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			filter.Since = since
+			page = 0
+		case strings.HasPrefix(lower, "v"):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+			if err != nil || num < 1 || num > len(entries) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			ui.viewAuditEntry(entries[num-1])
+		default:
+			if num, err := strconv.Atoi(lower); err == nil && num > 0 && num <= len(entries) {
+				ui.viewAuditEntry(entries[num-1])
+			}
+		}
+	}
+}
+
+func (ui *UI) viewAuditEntry(entry *domain.AuditEntry) {
+	ui.clear()
+	ui.printHeader("Audit Entry")
+	ui.println(fmt.Sprintf("Time: %s", entry.CreatedAt.Format("2006-01-02 15:04:05")))
+	ui.println(fmt.Sprintf("Actor: %s", safe(auditActor(entry))))
+	ui.println(fmt.Sprintf("Address: %s", safe(entry.RemoteAddr)))
+	ui.println(fmt.Sprintf("Action: %s", entry.Action))
+	ui.println(fmt.Sprintf("Target: %s", safe(entry.Target)))
+
+	var details bytes.Buffer
+	if err := json.Indent(&details, []byte(entry.Details), "", "  "); err != nil {
+		details.Reset()
+		details.WriteString(entry.Details)
+	}
+	ui.println("Details:")
+	ui.println(safe(details.String()))
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+}
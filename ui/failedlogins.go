@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+func (ui *UI) showFailedLogins() {
+	filter := ""
+	for {
+		ui.clear()
+		ui.printHeader("Failed Logins")
+
+		throttled := ui.auth.Throttle().Throttled(time.Now())
+		if len(throttled) > 0 {
+			ui.println("Currently throttled:")
+			for i, status := range throttled {
+				state := "backoff"
+				if status.Locked {
+					state = "locked"
+				}
+				ui.println(fmt.Sprintf("%2d. %-7s %-24s %3d failures  %-7s %s left",
+					i+1, status.Kind, safe(status.Key), status.Failures, state, formatDuration(status.Wait)))
+			}
+			ui.printLine()
+		}
+
+		var attempts []*domain.FailedLogin
+		var err error
+		switch {
+		case strings.HasPrefix(filter, "u "):
+			attempts, err = ui.repos.FailedLogin.GetByUsername(strings.TrimSpace(filter[2:]), 50)
+		case strings.HasPrefix(filter, "i "):
+			attempts, err = ui.repos.FailedLogin.GetByRemoteAddr(strings.TrimSpace(filter[2:]), 50)
+		default:
+			attempts, err = ui.repos.FailedLogin.GetRecent(50)
+		}
+
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading failed logins: %v", err))
+		}
+		if len(attempts) == 0 && err == nil {
+			ui.println("No failed logins.")
+		}
+
+		for _, attempt := range attempts {
+			ui.println(fmt.Sprintf("%-16s %-15s %s %s",
+				safe(attempt.Username),
+				attempt.RemoteAddr,
+				attempt.AttemptedAt.Format("2006-01-02 15:04:05"),
+				attempt.Reason))
+		}
+
+		ui.println("")
+		ui.println("Filter: (U) <username>, (I) <ip address>, (A)ll")
+		if len(throttled) > 0 {
+			ui.println("(C) <number> to unlock, (B)ack")
+		} else {
+			ui.println("(B)ack")
+		}
+
+		cmd := strings.TrimSpace(ui.readLine("> "))
+		lower := strings.ToLower(cmd)
+		switch {
+		case lower == "b" || lower == "":
+			return
+		case lower == "a":
+			filter = ""
+		case strings.HasPrefix(lower, "u ") || strings.HasPrefix(lower, "i "):
+			filter = lower[:2] + cmd[2:]
+		case strings.HasPrefix(lower, "c "):
+			num, err := strconv.Atoi(strings.TrimSpace(lower[2:]))
+			if err != nil || num < 1 || num > len(throttled) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			status := throttled[num-1]
+			ui.auth.Throttle().Clear(status.Kind, status.Key)
+			ui.printSuccess(fmt.Sprintf("Unlocked %s %s", status.Kind, status.Key))
+			time.Sleep(1 * time.Second)
+		default:
+			ui.printError("Invalid filter")
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// manageFeeds shows the feed addresses and lets a user create or revoke the
+// token their feed reader uses to read as them.
+func (ui *UI) manageFeeds() {
+	for {
+		user := ui.session.CurrentUser()
+		base := ui.config.FeedBaseURL()
+
+		ui.clear()
+		ui.printHeader("Feeds")
+
+		ui.println("Recent posts (Atom): " + base + "/feeds/atom")
+		ui.println("Recent posts (RSS):  " + base + "/feeds/rss")
+		if boards, err := ui.repos.Board.GetAll(); err == nil {
+			for _, board := range boards {
+				ui.println(fmt.Sprintf("%-20s %s/feeds/boards/%d/atom", safe(board.Name)+":", base, board.ID))
+			}
+		}
+		ui.println("Each thread shows its own feed address. Use /rss instead of /atom for RSS.")
+		ui.println("")
+
+		feedToken, err := ui.repos.FeedToken.GetByUser(user.ID)
+		if err == nil {
+			ui.println(fmt.Sprintf("Your feed token was created %s.", ui.formatTime(feedToken.CreatedAt)))
+		} else {
+			ui.println("You have no feed token.")
+		}
+		if !ui.config.AllowAnonymous {
+			ui.println("Feed readers need your token: add ?token=<your token> to these addresses.")
+		}
+
+		ui.println("")
+		if feedToken != nil {
+			ui.println("Commands: (N)ew token, replacing the old one, (R)evoke token, (B)ack")
+		} else {
+			ui.println("Commands: (N)ew token, (B)ack")
+		}
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch {
+		case cmd == "n":
+			if feedToken != nil && !ui.confirm("Replace your feed token? Readers using the old one will stop working.") {
+				continue
+			}
+			ui.createFeedToken(base)
+		case cmd == "r" && feedToken != nil:
+			if !ui.confirm("Revoke your feed token? Readers using it will stop working.") {
+				continue
+			}
+			if err := ui.repos.FeedToken.Delete(user.ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to revoke token: %v", err))
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			ui.audit(domain.AuditFeedTokenRevoke, user.Username, nil)
+		default:
+			return
+		}
+	}
+}
+
+func (ui *UI) createFeedToken(base string) {
+	user := ui.session.CurrentUser()
+
+	feedToken, token, err := domain.NewFeedToken(user.ID)
+	if err == nil {
+		err = ui.repos.FeedToken.Set(feedToken)
+	}
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to create token: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditFeedTokenCreate, user.Username, nil)
+
+	ui.printSuccess(fmt.Sprintf("Feed token: %s", token))
+	ui.println("For example: " + base + "/feeds/atom?token=" + token)
+	ui.println("Copy it now, it will not be shown again. It can only read feeds.")
+	ui.readLine("Press Enter to continue...")
+}
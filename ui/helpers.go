@@ -2,8 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"log"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/leinonen/bbs/ansi"
+	"github.com/leinonen/bbs/domain"
 )
 
 func (ui *UI) clear() {
@@ -18,19 +23,118 @@ func (ui *UI) println(text string) {
 	ui.term.Write([]byte(text + "\n"))
 }
 
+// safe strips control characters and escape sequences from untrusted text
+// before it reaches the terminal. Anything that came from a user must go
+// through it; print and println deliberately do not, so our own ANSI codes
+// still work.
+func safe(text string) string {
+	return domain.SanitizeText(text)
+}
+
+// showScreen draws a screen from the art directory and reports whether one
+// was found, so callers can fall back to built-in text.
+func (ui *UI) showScreen(name string) bool {
+	text, err := ui.art.Load(name, ansi.ProfileFor(ui.session.TermType))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to load screen %s: %v", name, err)
+		}
+		return false
+	}
+
+	ui.print(text)
+	if !strings.HasSuffix(text, "\n") {
+		ui.println("")
+	}
+	return true
+}
+
+// disconnected is raised by the read helpers when the client goes away, and
+// recovered in Run. Every screen loops on input, so returning an empty line
+// instead would spin forever on a dead connection.
+type disconnected struct{}
+
+// loggedOut is raised by the read helpers when a sysop has logged the user
+// out, and recovered in untilLogout.
+type loggedOut struct{}
+
+// checkLogout raises loggedOut if a sysop has logged the user out.
+func (ui *UI) checkLogout() {
+	if ui.session.LogoutForced() && ui.session.CurrentUser() != nil {
+		panic(loggedOut{})
+	}
+}
+
+// readLine reads a line of input. The prompt is handed to the terminal rather
+// than printed, so notices arriving meanwhile can be written above it and
+// the prompt and the user's half-typed input redrawn below.
 func (ui *UI) readLine(prompt string) string {
-	if prompt != "" {
-		ui.print(prompt)
+	ui.checkLogout()
+	ui.startPrompt()
+	defer ui.endPrompt()
+
+	ui.term.SetPrompt(prompt)
+	defer ui.term.SetPrompt("")
+
+	line, err := ui.term.ReadLine()
+	if err != nil {
+		panic(disconnected{})
 	}
-	line, _ := ui.term.ReadLine()
+	ui.session.Touch()
+	ui.checkLogout()
 	return line
 }
 
+func (ui *UI) readPassword(prompt string) string {
+	ui.checkLogout()
+	ui.term.SetPrompt(prompt)
+	defer ui.term.SetPrompt("")
+
+	if ui.session.Recording != nil {
+		ui.session.Recording.HideInput(true)
+		defer ui.session.Recording.HideInput(false)
+	}
+
+	password, err := ui.term.ReadPassword(prompt)
+	if err != nil {
+		panic(disconnected{})
+	}
+	ui.checkLogout()
+	return password
+}
+
+// readCode reads a one-time code, such as an authentication or verification
+// code. It is shown as it is typed, but kept out of the recording like a
+// password.
+func (ui *UI) readCode(prompt string) string {
+	if ui.session.Recording != nil {
+		ui.session.Recording.HideInput(true)
+		defer ui.session.Recording.HideInput(false)
+	}
+	return ui.readLine(prompt)
+}
+
+// readText reads multi-line input terminated by a line containing only '.'.
+func (ui *UI) readText(label string) string {
+	ui.println(fmt.Sprintf("%s (type '.' on a new line to finish):", label))
+	lines := []string{}
+	for {
+		line := ui.readLine("")
+		if line == "." {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (ui *UI) printLine() {
 	ui.println(strings.Repeat("─", 60))
 }
 
 func (ui *UI) printHeader(text string) {
+	text = domain.SanitizeLine(text)
+	ui.session.SetActivity(text)
 	ui.println("")
 	ui.println(fmt.Sprintf("╔%s╗", strings.Repeat("═", len(text)+2)))
 	ui.println(fmt.Sprintf("║ %s ║", text))
@@ -38,11 +142,69 @@ func (ui *UI) printHeader(text string) {
 }
 
 func (ui *UI) printError(msg string) {
-	ui.println(fmt.Sprintf("\033[31m✗ %s\033[0m", msg))
+	ui.println(fmt.Sprintf("\033[31m✗ %s\033[0m", safe(msg)))
 }
 
 func (ui *UI) printSuccess(msg string) {
-	ui.println(fmt.Sprintf("\033[32m✓ %s\033[0m", msg))
+	ui.println(fmt.Sprintf("\033[32m✓ %s\033[0m", safe(msg)))
+}
+
+// readField prompts for a single-line value, keeping current on empty input
+// and clearing it on "-".
+func (ui *UI) readField(label, current string) string {
+	prompt := fmt.Sprintf("%s: ", label)
+	if current != "" {
+		prompt = fmt.Sprintf("%s [%s]: ", label, safe(current))
+	}
+
+	value := strings.TrimSpace(ui.readLine(prompt))
+	switch value {
+	case "":
+		return current
+	case "-":
+		return ""
+	}
+	return value
+}
+
+func (ui *UI) confirm(question string) bool {
+	answer := strings.ToLower(strings.TrimSpace(ui.readLine(question + " (y/N): ")))
+	return answer == "y" || answer == "yes"
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return safe(value)
+}
+
+// postSummary returns a post's title, or the first line of a reply.
+func postSummary(post *domain.Post) string {
+	if post.Title != "" {
+		return post.Title
+	}
+	line, _, _ := strings.Cut(post.Content, "\n")
+	return "Re: " + truncate(line, 50)
+}
+
+// truncate shortens text to at most n characters, marking the cut.
+func truncate(text string, n int) string {
+	if len([]rune(text)) > n {
+		return string([]rune(text)[:n]) + "..."
+	}
+	return text
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	sec := int(d.Seconds()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%dh%02dm", h, m)
+	}
+	return fmt.Sprintf("%dm%02ds", m, sec)
 }
 
 func (ui *UI) formatTime(t time.Time) string {
@@ -71,6 +233,19 @@ func (ui *UI) formatTime(t time.Time) string {
 		}
 		return fmt.Sprintf("%d days ago", days)
 	default:
+		if ui.session.CurrentUser() != nil {
+			return ui.session.CurrentUser().FormatDate(t)
+		}
 		return t.Format("Jan 02, 2006")
 	}
 }
+
+// audit records something the current user did in the audit log.
+func (ui *UI) audit(action, target string, details map[string]interface{}) *domain.AuditEntry {
+	entry := domain.NewAuditEntry(ui.session.CurrentUser(), action, target, ui.session.RemoteAddr, details)
+	ui.repos.RecordAudit(entry)
+	if domain.IsModerationAction(action) {
+		ui.webhooks.Moderation(entry)
+	}
+	return entry
+}
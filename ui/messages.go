@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+func (ui *UI) privateMessages() {
+	sent := false
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		var messages []*domain.PrivateMessage
+		var err error
+		if sent {
+			ui.printHeader("Private Messages - Sent")
+			messages, err = ui.repos.Message.GetSent(user.ID, 50)
+		} else {
+			ui.printHeader("Private Messages - Inbox")
+			messages, err = ui.repos.Message.GetInbox(user.ID, 50)
+		}
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading messages: %v", err))
+			return
+		}
+
+		if len(messages) == 0 {
+			ui.println("No messages.")
+		}
+		for i, message := range messages {
+			mark := " "
+			if !sent && !message.IsRead() {
+				mark = "*"
+			}
+			who := "from " + safe(message.FromUsername)
+			if sent {
+				who = "to " + safe(message.ToUsername)
+			}
+			ui.println(fmt.Sprintf("%2d.%s %s - %s", i+1, mark, safe(message.Subject), who))
+			ui.println(fmt.Sprintf("    %s", ui.formatTime(message.CreatedAt)))
+		}
+
+		ui.println("")
+		if sent {
+			ui.println("Commands: (R)ead #, (W)rite, (I)nbox, (B)ack")
+		} else {
+			ui.println("Commands: (R)ead #, (W)rite, (S)ent, (D)elete #, (B)ack")
+		}
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch {
+		case cmd == "w":
+			ui.writeMessage(nil, "")
+		case cmd == "s":
+			sent = true
+		case cmd == "i":
+			sent = false
+		case strings.HasPrefix(cmd, "r") || (strings.HasPrefix(cmd, "d") && !sent):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+			if err != nil || num < 1 || num > len(messages) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			message := messages[num-1]
+			if cmd[0] == 'r' {
+				ui.readMessage(message)
+				continue
+			}
+			if ui.confirm(fmt.Sprintf("Delete \"%s\"?", safe(message.Subject))) {
+				if err := ui.repos.Message.Delete(message.ID); err != nil {
+					ui.printError(fmt.Sprintf("Failed to delete message: %v", err))
+					time.Sleep(2 * time.Second)
+				}
+			}
+		default:
+			if num, err := strconv.Atoi(cmd); err == nil && num > 0 && num <= len(messages) {
+				ui.readMessage(messages[num-1])
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (ui *UI) readMessage(message *domain.PrivateMessage) {
+	user := ui.session.CurrentUser()
+	incoming := message.ToUserID == user.ID
+
+	if incoming && !message.IsRead() {
+		now := time.Now()
+		if err := ui.repos.Message.MarkRead(message.ID, now); err == nil {
+			message.ReadAt = &now
+		}
+	}
+
+	ui.clear()
+	ui.printHeader(message.Subject)
+	// the subject is private, so it must not show up in the node list
+	ui.session.SetActivity("Private Messages")
+	ui.println(fmt.Sprintf("From: %s", safe(message.FromUsername)))
+	ui.println(fmt.Sprintf("To: %s", safe(message.ToUsername)))
+	ui.println(fmt.Sprintf("Date: %s", ui.formatTime(message.CreatedAt)))
+	ui.printLine()
+	ui.println(safe(message.Body))
+	ui.printLine()
+	ui.println("")
+
+	if !incoming {
+		ui.readLine("Press Enter to continue...")
+		return
+	}
+
+	ui.println("Commands: (R)eply, (B)ack")
+	if strings.ToLower(strings.TrimSpace(ui.readLine("> "))) != "r" {
+		return
+	}
+
+	sender, err := ui.repos.User.GetByID(message.FromUserID)
+	if err != nil {
+		ui.printError("The sender no longer has an account")
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.writeMessage(sender, domain.ReplySubject(message.Subject))
+}
+
+// writeMessage composes a message to recipient, asking who to send it to
+// when recipient is nil.
+func (ui *UI) writeMessage(recipient *domain.User, subject string) {
+	ui.clear()
+	ui.printHeader("Write Message")
+
+	if recipient == nil {
+		username := strings.TrimSpace(ui.readLine("To: "))
+		if username == "" {
+			return
+		}
+		user, err := ui.repos.User.GetByUsername(username)
+		if err != nil || !user.IsActive() {
+			ui.printError("No such user")
+			time.Sleep(2 * time.Second)
+			return
+		}
+		recipient = user
+	} else {
+		ui.println(fmt.Sprintf("To: %s", safe(recipient.Username)))
+	}
+
+	if subject == "" {
+		subject = ui.readLine("Subject: ")
+	} else {
+		ui.println(fmt.Sprintf("Subject: %s", safe(subject)))
+	}
+	body := ui.readText("Message")
+
+	message := domain.NewPrivateMessage(ui.session.CurrentUser(), recipient, subject, body)
+	if err := message.Validate(); err != nil {
+		ui.printError(err.Error())
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	if err := ui.repos.Message.Create(message); err != nil {
+		ui.printError(fmt.Sprintf("Failed to send message: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	ui.notifier.MessageSent(message)
+	ui.printSuccess(fmt.Sprintf("Message sent to %s", recipient.Username))
+	time.Sleep(1 * time.Second)
+}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// nodeUser names the user on a node, who may not have logged in yet.
+func nodeUser(session *domain.Session) string {
+	user := session.CurrentUser()
+	switch {
+	case user == nil:
+		return "(logging in)"
+	case user.ID == 0:
+		return "anonymous"
+	}
+	return user.Username
+}
+
+func (ui *UI) showOnlineUsers() {
+	ui.clear()
+	ui.printHeader("Who's Online")
+
+	now := time.Now()
+	ui.println(fmt.Sprintf("%-4s %-16s %-30s %s", "Node", "User", "Activity", "Idle"))
+	ui.printLine()
+	for _, session := range ui.sessions.GetActiveSessions() {
+		activity, last := session.Status()
+		ui.println(fmt.Sprintf("%-4d %-16s %-30s %s",
+			session.Node,
+			safe(nodeUser(session)),
+			safe(activity),
+			formatDuration(now.Sub(last))))
+	}
+
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+}
+
+func (ui *UI) manageNodes() {
+	for {
+		ui.clear()
+		ui.printHeader("Nodes")
+
+		now := time.Now()
+		ui.println(fmt.Sprintf("%-4s %-16s %-15s %-24s %8s %s", "Node", "User", "Address", "Activity", "Online", "Idle"))
+		ui.printLine()
+		for _, session := range ui.sessions.GetActiveSessions() {
+			activity, last := session.Status()
+			ui.println(fmt.Sprintf("%-4d %-16s %-15s %-24.24s %8s %s",
+				session.Node,
+				safe(nodeUser(session)),
+				session.RemoteAddr,
+				safe(activity),
+				formatDuration(now.Sub(session.CreatedAt)),
+				formatDuration(now.Sub(last))))
+		}
+
+		ui.println("")
+		ui.println("Commands: (M)essage #, (S)noop #, (L)ogout #, (D)isconnect #, (R)efresh, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		if cmd == "r" {
+			continue
+		}
+		if cmd == "" || cmd == "b" || !strings.ContainsAny(cmd[:1], "msld") {
+			return
+		}
+
+		num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+		target, ok := ui.sessions.GetByNode(num)
+		if err != nil || !ok {
+			ui.printError("No such node")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		if target == ui.session {
+			ui.printError("That is your own node")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		ui.controlNode(cmd[0], target)
+	}
+}
+
+func (ui *UI) controlNode(cmd byte, target *domain.Session) {
+	name := fmt.Sprintf("node %d (%s)", target.Node, nodeUser(target))
+	details := map[string]interface{}{
+		"node":        target.Node,
+		"remote_addr": target.RemoteAddr,
+	}
+	if user := target.CurrentUser(); user != nil {
+		details["user_id"] = user.ID
+	}
+
+	switch cmd {
+	case 'm':
+		text := strings.TrimSpace(ui.readLine("Message: "))
+		if text == "" {
+			return
+		}
+		ui.audit(domain.AuditSessionMessage, name, details)
+		if !target.Notify(fmt.Sprintf("Message from sysop %s: %s", ui.session.CurrentUser().Username, text)) {
+			ui.printError("The node is not taking messages right now")
+			time.Sleep(2 * time.Second)
+			return
+		}
+		ui.printSuccess("Message sent")
+	case 's':
+		ui.audit(domain.AuditSessionSnoop, name, details)
+		ui.snoop(target, name)
+		return
+	case 'l':
+		if user := target.CurrentUser(); user == nil || user.ID == 0 {
+			ui.printError("Nobody is logged in on that node")
+			time.Sleep(2 * time.Second)
+			return
+		}
+		if !ui.confirm(fmt.Sprintf("Log out %s?", name)) {
+			return
+		}
+		ui.audit(domain.AuditSessionLogout, name, details)
+		// the user is taken back to the login menu at their next prompt
+		target.ForceLogout()
+		target.Notify("You have been logged out by the sysop. Press Enter to continue.")
+		ui.printSuccess(fmt.Sprintf("Logged out %s", name))
+	case 'd':
+		if !ui.confirm(fmt.Sprintf("Disconnect %s?", name)) {
+			return
+		}
+		ui.audit(domain.AuditSessionDisconnect, name, details)
+		target.Kick(domain.LogoutReasonKicked)
+		ui.printSuccess(fmt.Sprintf("Disconnected %s", name))
+	}
+	time.Sleep(1 * time.Second)
+}
+
+// snoop shows what another node sees until the sysop presses Enter or the
+// node hangs up. The snooped user cannot see or type into it.
+func (ui *UI) snoop(target *domain.Session, name string) {
+	output, stop := target.Snoop()
+	defer stop()
+
+	ui.clear()
+	ui.println(fmt.Sprintf("\033[1;33mSnooping on %s. Press Enter to stop.\033[0m", safe(name)))
+	ui.printLine()
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range output {
+			ui.term.Write(p)
+		}
+		select {
+		case <-quit:
+		default:
+			ui.term.Write([]byte("\r\n\033[1;33mThe node hung up. Press Enter to continue.\033[0m\r\n"))
+		}
+	}()
+
+	ui.readLine("")
+	close(quit)
+	stop()
+	<-done
+}
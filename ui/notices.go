@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPendingNotices bounds the notices held back while a screen is
+	// drawn; older ones are dropped first.
+	maxPendingNotices = 10
+	// pageInterval limits how often a user can page the sysop.
+	pageInterval = 2 * time.Minute
+)
+
+func (ui *UI) showNotices(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case text := <-ui.session.Notices:
+			ui.notice(text)
+		}
+	}
+}
+
+// notice shows text now if the user is at a prompt, or at the next prompt
+// otherwise, so it never lands in the middle of a screen.
+func (ui *UI) notice(text string) {
+	line := fmt.Sprintf("\033[1;33m>> %s\033[0m\n", safe(text))
+
+	ui.noticeMu.Lock()
+	defer ui.noticeMu.Unlock()
+
+	if ui.prompting {
+		ui.term.Write([]byte(line))
+		return
+	}
+
+	ui.pending = append(ui.pending, line)
+	if len(ui.pending) > maxPendingNotices {
+		ui.pending = ui.pending[1:]
+	}
+}
+
+// startPrompt shows the notices held back while the screen was drawn.
+func (ui *UI) startPrompt() {
+	ui.noticeMu.Lock()
+	defer ui.noticeMu.Unlock()
+
+	for _, line := range ui.pending {
+		ui.term.Write([]byte(line))
+	}
+	ui.pending = nil
+	ui.prompting = true
+}
+
+func (ui *UI) endPrompt() {
+	ui.noticeMu.Lock()
+	defer ui.noticeMu.Unlock()
+
+	ui.prompting = false
+}
+
+func (ui *UI) pageSysop() {
+	if wait := pageInterval - time.Since(ui.lastPage); wait > 0 {
+		ui.printError(fmt.Sprintf("You paged the sysop recently, please wait %s", formatDuration(wait)))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	reason := strings.TrimSpace(ui.readLine("What do you need the sysop for? "))
+	if reason == "" {
+		return
+	}
+
+	user := ui.session.CurrentUser()
+	if ui.sessions.NotifyAdmins(fmt.Sprintf("%s is paging the sysop: %s", user.Username, reason)) == 0 {
+		ui.printError("No sysop is online right now, try sending a private message instead")
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	ui.lastPage = time.Now()
+	ui.printSuccess("The sysop has been paged")
+	time.Sleep(1 * time.Second)
+}
+
+func (ui *UI) broadcast() {
+	ui.clear()
+	ui.printHeader("Broadcast Message")
+	ui.println("The message is shown to everyone online, on every node.")
+	ui.println("")
+
+	text := strings.TrimSpace(ui.readLine("Message: "))
+	if text == "" {
+		return
+	}
+
+	sent := ui.sessions.Broadcast(fmt.Sprintf("Sysop broadcast from %s: %s", ui.session.CurrentUser().Username, text))
+	ui.printSuccess(fmt.Sprintf("Sent to %d node(s)", sent))
+	time.Sleep(1 * time.Second)
+}
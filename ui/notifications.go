@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+func (ui *UI) notificationInbox() {
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		ui.printHeader("Notifications")
+
+		notifications, err := ui.repos.Notification.GetByUser(user.ID, 50)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading notifications: %v", err))
+			return
+		}
+
+		if len(notifications) == 0 {
+			ui.println("No notifications. Watch a thread or subscribe to a board to hear about new posts.")
+		}
+		for i, notification := range notifications {
+			mark := " "
+			if !notification.IsRead() {
+				mark = "*"
+			}
+			ui.println(fmt.Sprintf("%2d.%s %s", i+1, mark, safe(notification.Summary())))
+			ui.println(fmt.Sprintf("    %s", ui.formatTime(notification.CreatedAt)))
+		}
+
+		ui.println("")
+		ui.println("Commands: (O)pen #, (M)ark all read, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch {
+		case cmd == "m":
+			if err := ui.repos.Notification.MarkAllRead(user.ID, time.Now()); err != nil {
+				ui.printError(fmt.Sprintf("Failed to mark notifications read: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		case strings.HasPrefix(cmd, "o"):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+			if err != nil || num < 1 || num > len(notifications) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			ui.openNotification(notifications[num-1])
+		default:
+			if num, err := strconv.Atoi(cmd); err == nil && num > 0 && num <= len(notifications) {
+				ui.openNotification(notifications[num-1])
+				continue
+			}
+			return
+		}
+	}
+}
+
+// openNotification marks the notification read and shows its thread.
+func (ui *UI) openNotification(notification *domain.Notification) {
+	if !notification.IsRead() {
+		ui.repos.Notification.MarkRead(notification.ID, time.Now())
+	}
+
+	thread, err := ui.repos.Post.GetByID(notification.ThreadID)
+	if err != nil {
+		ui.printError("That thread no longer exists")
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.viewPost(thread)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func (ui *UI) notificationSettings() {
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		ui.printHeader("Email Notifications")
+
+		if !ui.notifier.Enabled() {
+			ui.println("Email is not configured on this BBS.")
+			ui.println("")
+			ui.readLine("Press Enter to continue...")
+			return
+		}
+
+		settings, err := ui.repos.NotificationSettings.Get(user.ID)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading settings: %v", err))
+			return
+		}
+
+		ui.println(fmt.Sprintf("Email: %s", safe(user.Email)))
+		if !user.EmailVerified {
+			ui.println("Your email address is not verified, so no email will be sent until it is.")
+		}
+		ui.println("")
+		ui.println(fmt.Sprintf("1. %-30s %s", "Replies to my threads", onOff(settings.EmailReplies)))
+		ui.println(fmt.Sprintf("2. %-30s %s", "New private messages", onOff(settings.EmailMessages)))
+		ui.println(fmt.Sprintf("3. %-30s %s", "Daily digest of new threads", onOff(settings.EmailDigest)))
+		ui.println("   (covers the boards you subscribe to from the board view)")
+		ui.println("")
+
+		if user.EmailVerified {
+			ui.println("Commands: (1-3) toggle, (B)ack")
+		} else {
+			ui.println("Commands: (1-3) toggle, (V)erify email, (B)ack")
+		}
+
+		switch strings.ToLower(strings.TrimSpace(ui.readLine("> "))) {
+		case "1":
+			settings.EmailReplies = !settings.EmailReplies
+		case "2":
+			settings.EmailMessages = !settings.EmailMessages
+		case "3":
+			settings.EmailDigest = !settings.EmailDigest
+			if settings.EmailDigest {
+				// the first digest covers threads started from now on
+				settings.DigestSentAt = time.Now()
+			}
+		case "v":
+			if !user.EmailVerified {
+				ui.sendVerification()
+				ui.verifyEmail()
+			}
+			continue
+		default:
+			return
+		}
+
+		if err := ui.repos.NotificationSettings.Save(settings); err != nil {
+			ui.printError(fmt.Sprintf("Failed to save settings: %v", err))
+			time.Sleep(2 * time.Second)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+func (ui *UI) passwordPolicy() domain.PasswordPolicy {
+	return domain.PasswordPolicy{
+		MinLength:      ui.config.PasswordMinLength,
+		RejectUsername: ui.config.PasswordRejectUsername,
+		CheckBreached:  ui.config.PasswordCheckBreached,
+	}
+}
+
+// readNewPassword prompts for a new password twice and checks it against the
+// policy. It returns false if the user gave up or the password was rejected.
+func (ui *UI) readNewPassword(username string) (string, bool) {
+	password := ui.readPassword("New password: ")
+	if password == "" {
+		return "", false
+	}
+
+	if err := ui.passwordPolicy().Validate(username, password); err != nil {
+		ui.printError(err.Error())
+		time.Sleep(2 * time.Second)
+		return "", false
+	}
+
+	if ui.readPassword("Confirm new password: ") != password {
+		ui.printError("Passwords do not match")
+		time.Sleep(2 * time.Second)
+		return "", false
+	}
+
+	return password, true
+}
+
+func (ui *UI) changePassword() {
+	user := ui.session.CurrentUser()
+
+	ui.clear()
+	ui.printHeader("Change Password")
+	ui.println("Leave the new password empty to cancel.")
+	ui.println("")
+
+	current := ui.readPassword("Current password: ")
+	if _, err := ui.repos.User.Authenticate(user.Username, current); err != nil {
+		ui.printError("Current password is incorrect")
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	password, ok := ui.readNewPassword(user.Username)
+	if !ok {
+		return
+	}
+
+	if err := ui.repos.User.UpdatePassword(user.ID, password); err != nil {
+		ui.printError(fmt.Sprintf("Failed to change password: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	// A password the user chose themselves supersedes any outstanding reset.
+	ui.repos.PasswordReset.InvalidateForUser(user.ID)
+
+	ui.printSuccess("Password changed!")
+	time.Sleep(1 * time.Second)
+}
+
+// completePasswordReset makes a user who logged in with a reset token pick a
+// new password. It keeps asking until one is accepted.
+func (ui *UI) completePasswordReset() {
+	user := ui.session.CurrentUser()
+
+	ui.clear()
+	ui.printHeader("Choose a New Password")
+	ui.println("You logged in with a password reset token.")
+	ui.println("Please choose a new password to continue.")
+	ui.println("")
+
+	for {
+		password, ok := ui.readNewPassword(user.Username)
+		if !ok {
+			continue
+		}
+
+		if err := ui.repos.User.UpdatePassword(user.ID, password); err != nil {
+			ui.printError(fmt.Sprintf("Failed to set password: %v", err))
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		break
+	}
+
+	ui.repos.PasswordReset.InvalidateForUser(user.ID)
+	ui.session.PasswordResetID = 0
+
+	ui.printSuccess("Password changed!")
+	time.Sleep(1 * time.Second)
+}
+
+func (ui *UI) manageUsers() {
+	for {
+		ui.clear()
+		ui.printHeader("Manage Users")
+
+		username := strings.TrimSpace(ui.readLine("Username (Enter to go back): "))
+		if username == "" {
+			return
+		}
+
+		user, err := ui.repos.User.GetByUsername(username)
+		if err != nil {
+			ui.printError("User not found")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		ui.manageUser(user)
+	}
+}
+
+func (ui *UI) manageUser(user *domain.User) {
+	ui.clear()
+	ui.printHeader(fmt.Sprintf("User: %s", user.Username))
+	ui.println(fmt.Sprintf("Email: %s", safe(user.Email)))
+	if !user.IsActive() {
+		ui.println(fmt.Sprintf("Account: %s", user.Status))
+	}
+	ui.printProfileDetails(user)
+	ui.println("")
+
+	self := user.ID == ui.session.CurrentUser().ID
+	commands := "(R)eset password"
+	if !self {
+		if user.IsAdmin {
+			commands += ", (S) revoke sysop"
+		} else {
+			commands += ", (S) make sysop"
+		}
+		if user.Status == domain.UserStatusBanned {
+			commands += ", (U)nban"
+		} else {
+			commands += ", (X) ban"
+		}
+	}
+	ui.println("Commands: " + commands + ", (B)ack")
+
+	cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+	switch {
+	case cmd == "r":
+		ui.issuePasswordReset(user)
+	case cmd == "s" && !self:
+		ui.toggleSysop(user)
+	case cmd == "x" && !self && user.Status != domain.UserStatusBanned:
+		ui.banUser(user)
+	case cmd == "u" && user.Status == domain.UserStatusBanned:
+		ui.unbanUser(user)
+	}
+}
+
+func (ui *UI) toggleSysop(user *domain.User) {
+	question := fmt.Sprintf("Make %s a sysop?", user.Username)
+	if user.IsAdmin {
+		question = fmt.Sprintf("Revoke sysop rights from %s?", user.Username)
+	}
+	if !ui.confirm(question) {
+		return
+	}
+
+	user.IsAdmin = !user.IsAdmin
+	if err := ui.repos.User.Update(user); err != nil {
+		ui.printError(fmt.Sprintf("Failed to change role: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditUserRole, user.Username, map[string]interface{}{"user_id": user.ID, "is_admin": user.IsAdmin})
+
+	ui.printSuccess("Role changed; it takes effect at their next login")
+	time.Sleep(2 * time.Second)
+}
+
+// banUser locks user out and hangs up any calls they are on.
+func (ui *UI) banUser(user *domain.User) {
+	if !ui.confirm(fmt.Sprintf("Ban %s?", user.Username)) {
+		return
+	}
+	reason := strings.TrimSpace(ui.readLine("Reason (shown only in the audit log): "))
+
+	if err := ui.repos.User.SetStatus(user.ID, domain.UserStatusBanned); err != nil {
+		ui.printError(fmt.Sprintf("Failed to ban user: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditUserBan, user.Username, map[string]interface{}{"user_id": user.ID, "reason": reason})
+
+	for _, session := range ui.sessions.GetActiveSessions() {
+		if current := session.CurrentUser(); current != nil && current.ID == user.ID {
+			session.Kick(domain.LogoutReasonKicked)
+		}
+	}
+
+	ui.printSuccess(fmt.Sprintf("%s has been banned", user.Username))
+	time.Sleep(2 * time.Second)
+}
+
+func (ui *UI) unbanUser(user *domain.User) {
+	if !ui.confirm(fmt.Sprintf("Lift the ban on %s?", user.Username)) {
+		return
+	}
+
+	if err := ui.repos.User.SetStatus(user.ID, domain.UserStatusActive); err != nil {
+		ui.printError(fmt.Sprintf("Failed to unban user: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditUserUnban, user.Username, map[string]interface{}{"user_id": user.ID})
+
+	ui.printSuccess(fmt.Sprintf("%s can log in again", user.Username))
+	time.Sleep(2 * time.Second)
+}
+
+func (ui *UI) issuePasswordReset(user *domain.User) {
+	if !ui.confirm(fmt.Sprintf("Issue a password reset token for %s?", safe(user.Username))) {
+		return
+	}
+
+	reset, token, err := domain.NewPasswordReset(user.ID, ui.session.CurrentUser().ID)
+	if err == nil {
+		err = ui.repos.PasswordReset.Create(reset)
+	}
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to create reset token: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditPasswordReset, user.Username, map[string]interface{}{"user_id": user.ID})
+
+	ui.println("")
+	ui.printSuccess(fmt.Sprintf("Reset token: %s", token))
+	ui.println(fmt.Sprintf("Give it to %s; they log in with it as their password.", safe(user.Username)))
+	ui.println(fmt.Sprintf("It can be used once and expires %s.", reset.ExpiresAt.Format("2006-01-02 15:04")))
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// deletedContent replaces a thread's text when it is deleted by its author
+// but other people's replies keep the thread alive.
+const deletedContent = "[This post was deleted by its author]"
+
+// canModify reports whether the current user may edit or delete post:
+// sysops can change any post, and users their own.
+func (ui *UI) canModify(post *domain.Post) bool {
+	user := ui.session.CurrentUser()
+	if user == nil || user.ID == 0 {
+		return false
+	}
+	return user.IsAdmin || user.ID == post.UserID
+}
+
+// CreatePost is how every frontend posts: it signs the post with its
+// author's signature, saves it, and tells subscribers and webhooks about
+// it. Imported posts have no local author to sign them. A frontend that
+// keeps its own record of the post does so in saved, and the post is
+// removed again if that fails.
+func (s *Services) CreatePost(author *domain.User, post *domain.Post, saved func(*domain.Post) error) error {
+	if author != nil {
+		post.Content = author.Sign(post.Content)
+	}
+	if err := s.Repos.Post.Create(post); err != nil {
+		return err
+	}
+	if saved != nil {
+		if err := saved(post); err != nil {
+			if delErr := s.Repos.Post.Delete(post.ID); delErr != nil {
+				return fmt.Errorf("%v, and post %d could not be removed: %v", err, post.ID, delErr)
+			}
+			return err
+		}
+	}
+	s.Notifier.PostCreated(post)
+	s.Webhooks.PostCreated(post)
+	return nil
+}
+
+func postTarget(post *domain.Post) string {
+	return fmt.Sprintf("post %d (%s)", post.ID, post.Username)
+}
+
+func (ui *UI) editPost(post *domain.Post) {
+	ui.clear()
+	ui.printHeader("Edit Post")
+
+	oldTitle, oldContent := post.Title, post.Content
+
+	title := post.Title
+	if post.ReplyTo == nil {
+		title = ui.readField("Title", post.Title)
+	}
+
+	ui.println("Current text:")
+	ui.printLine()
+	ui.println(safe(post.Content))
+	ui.printLine()
+	content := ui.readText("New text (leave empty to keep the current text)")
+	if content == "" {
+		content = post.Content
+	}
+
+	if title == "" && post.ReplyTo == nil {
+		ui.printError("Title cannot be empty")
+		time.Sleep(2 * time.Second)
+		return
+	}
+	if title == oldTitle && content == oldContent {
+		return
+	}
+
+	post.Title = title
+	post.Content = content
+	post.UpdatedAt = time.Now()
+	if err := ui.repos.Post.Update(post); err != nil {
+		post.Title, post.Content = oldTitle, oldContent
+		ui.printError(fmt.Sprintf("Failed to save post: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	entry := ui.audit(domain.AuditPostEdit, postTarget(post), map[string]interface{}{
+		"post_id":     post.ID,
+		"board_id":    post.BoardID,
+		"old_title":   oldTitle,
+		"old_content": oldContent,
+	})
+	if post.UserID != ui.session.CurrentUser().ID {
+		ui.webhooks.Moderation(entry)
+	}
+	ui.printSuccess("Post saved")
+	time.Sleep(1 * time.Second)
+}
+
+// deletePost deletes post and reports whether it is gone. A thread with
+// replies is removed whole when a sysop deletes it, and only blanked when
+// its author does, so other people's replies are not lost.
+func (ui *UI) deletePost(post *domain.Post, replies []*domain.Post) bool {
+	whole := ui.session.CurrentUser().IsAdmin && len(replies) > 0
+	question := "Delete this post?"
+	if whole {
+		question = fmt.Sprintf("Delete this thread and its %d replies?", len(replies))
+	}
+	if !ui.confirm(question) {
+		return false
+	}
+
+	details := map[string]interface{}{
+		"post_id":  post.ID,
+		"board_id": post.BoardID,
+		"title":    post.Title,
+		"content":  post.Content,
+	}
+
+	var err error
+	switch {
+	case len(replies) == 0:
+		err = ui.repos.Post.Delete(post.ID)
+	case whole:
+		for _, reply := range replies {
+			if err = ui.repos.Post.Delete(reply.ID); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = ui.repos.Post.Delete(post.ID)
+		}
+		details["replies"] = len(replies)
+	default:
+		post.Content = deletedContent
+		post.UpdatedAt = time.Now()
+		err = ui.repos.Post.Update(post)
+		details["blanked"] = true
+	}
+
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to delete post: %v", err))
+		time.Sleep(2 * time.Second)
+		return false
+	}
+
+	entry := ui.audit(domain.AuditPostDelete, postTarget(post), details)
+	if post.UserID != ui.session.CurrentUser().ID {
+		ui.webhooks.Moderation(entry)
+	}
+	ui.printSuccess("Post deleted")
+	time.Sleep(1 * time.Second)
+	return len(replies) == 0 || whole
+}
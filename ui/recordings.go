@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/recording"
+)
+
+const (
+	// maxRecordings is how many of the newest recordings are listed.
+	maxRecordings = 50
+	// playbackIdle is the longest pause kept when playing a recording back.
+	playbackIdle = 2 * time.Second
+)
+
+func (ui *UI) toggleRecording() {
+	user := ui.session.CurrentUser()
+	optOut := !user.RecordingOptOut
+
+	if err := ui.repos.User.SetRecordingOptOut(user.ID, optOut); err != nil {
+		ui.printError(fmt.Sprintf("Failed to save setting: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	user.RecordingOptOut = optOut
+
+	if optOut {
+		ui.printSuccess("Your calls will not be recorded, starting with your next call")
+	} else {
+		ui.printSuccess("Your calls may be recorded again")
+	}
+	time.Sleep(2 * time.Second)
+}
+
+func formatSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	if size < 1024*1024 {
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+}
+
+func (ui *UI) manageRecordings() {
+	for {
+		ui.clear()
+		ui.printHeader("Recordings")
+
+		infos, err := ui.recordings.List()
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading recordings: %v", err))
+			ui.readLine("Press Enter to continue...")
+			return
+		}
+		if len(infos) > maxRecordings {
+			infos = infos[:maxRecordings]
+		}
+
+		if len(infos) == 0 {
+			ui.println("No recordings.")
+		}
+		for i, info := range infos {
+			ui.println(fmt.Sprintf("%2d. %s  node %-3d %-16s %8s %9s",
+				i+1,
+				info.StartedAt.Format("2006-01-02 15:04"),
+				info.Node,
+				safe(info.Username),
+				formatDuration(info.Duration),
+				formatSize(info.Size)))
+		}
+
+		ui.println("")
+		ui.println("Commands: (P)lay #, (D)elete #, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		if cmd == "" || cmd == "b" {
+			return
+		}
+
+		action := byte('p')
+		if cmd[0] == 'p' || cmd[0] == 'd' {
+			action = cmd[0]
+			cmd = strings.TrimSpace(cmd[1:])
+		}
+		num, err := strconv.Atoi(cmd)
+		if err != nil || num < 1 || num > len(infos) {
+			ui.printError("Invalid selection")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		info := infos[num-1]
+		if action == 'p' {
+			ui.playRecording(info)
+			continue
+		}
+
+		if ui.confirm(fmt.Sprintf("Delete the recording of %s on node %d?", info.Username, info.Node)) {
+			ui.audit(domain.AuditRecordingDelete, info.Name, map[string]interface{}{"user": info.Username, "node": info.Node})
+			if err := ui.recordings.Delete(info.Name); err != nil {
+				ui.printError(fmt.Sprintf("Failed to delete recording: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		}
+	}
+}
+
+// playRecording plays a recording until it ends or the sysop presses Enter.
+// Long pauses are shortened to playbackIdle.
+func (ui *UI) playRecording(info *recording.Info) {
+	cast, err := ui.recordings.Load(info.Name)
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to load recording: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	ui.audit(domain.AuditRecordingPlay, info.Name, map[string]interface{}{"user": info.Username, "node": info.Node})
+
+	ui.clear()
+	ui.println(fmt.Sprintf("\033[1;33mPlaying %s (recorded at %dx%d). Press Enter to stop.\033[0m",
+		safe(cast.Title), cast.Width, cast.Height))
+	ui.printLine()
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if cast.Play(ui.term, playbackIdle, quit) {
+			ui.term.Write([]byte("\r\n\033[0m\033[1;33mEnd of recording. Press Enter to continue.\033[0m\r\n"))
+		}
+	}()
+
+	ui.readLine("")
+	close(quit)
+	<-done
+}
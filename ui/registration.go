@@ -0,0 +1,316 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/mail"
+)
+
+// resendInterval limits how often a verification code can be re-sent, so
+// the BBS cannot be used to flood someone's inbox.
+const resendInterval = time.Minute
+
+// checkAccountStatus lets active users through, has unverified users enter
+// their emailed code, and turns pending users away until a sysop approves
+// them.
+func (ui *UI) checkAccountStatus() bool {
+	user := ui.session.CurrentUser()
+	switch user.Status {
+	case domain.UserStatusUnverified:
+		return ui.verifyEmail()
+	case domain.UserStatusPending:
+		ui.clear()
+		ui.printHeader("Awaiting Approval")
+		ui.println("Your account is waiting for a sysop to approve it.")
+		ui.println("Please call back later.")
+		ui.println("")
+		ui.readLine("Press Enter to continue...")
+		return false
+	case domain.UserStatusBanned:
+		ui.clear()
+		ui.printHeader("Account Banned")
+		ui.println("This account has been banned by the sysop.")
+		ui.println("")
+		ui.readLine("Press Enter to continue...")
+		return false
+	}
+	return user.IsActive()
+}
+
+func (ui *UI) verifyEmail() bool {
+	user := ui.session.CurrentUser()
+
+	ui.clear()
+	ui.printHeader("Verify Your Email")
+	ui.println(fmt.Sprintf("A verification code was sent to %s.", safe(user.Email)))
+	ui.println("Enter it below, (R) to send a new code, or (Q) to quit.")
+	ui.println("")
+
+	for {
+		input := strings.TrimSpace(ui.readCode("Code: "))
+		switch strings.ToLower(input) {
+		case "", "q":
+			return false
+		case "r":
+			ui.sendVerification()
+			continue
+		}
+
+		if err := ui.repos.VerifyEmail(user, input); err != nil {
+			ui.printError(err.Error())
+			continue
+		}
+
+		ui.printSuccess("Email verified, thank you!")
+		time.Sleep(1 * time.Second)
+		return true
+	}
+}
+
+// sendVerification emails the user a new verification code.
+func (ui *UI) sendVerification() {
+	user := ui.session.CurrentUser()
+
+	if previous, err := ui.repos.Verification.GetByUser(user.ID); err == nil {
+		if wait := resendInterval - time.Since(previous.CreatedAt); wait > 0 {
+			ui.printError(fmt.Sprintf("Please wait %s before asking for another code", wait.Round(time.Second)))
+			return
+		}
+	}
+
+	if ui.mailer == nil {
+		ui.printError("Email is not configured on this BBS, please contact the sysop")
+		return
+	}
+
+	code, err := ui.repos.StartEmailVerification(user)
+	if err == nil {
+		err = ui.mailer.Send(&mail.Message{
+			To:      user.Email,
+			Subject: fmt.Sprintf("%s email verification", ui.config.ServerName),
+			Body: fmt.Sprintf("Your verification code for %s is: %s\n\n"+
+				"Enter it when asked after logging in as %s. It expires in %d hours.\n\n"+
+				"If you did not register, you can ignore this message.\n",
+				ui.config.ServerName, code, user.Username, int(domain.VerificationTTL.Hours())),
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to send verification code to %s: %v", user.Username, err)
+		ui.printError("Failed to send the verification code, please try again later")
+		return
+	}
+
+	ui.printSuccess(fmt.Sprintf("A verification code was sent to %s", user.Email))
+}
+
+// readInvite asks for an invite code and returns it if it can still be used.
+func (ui *UI) readInvite() *domain.Invite {
+	ui.println("Registration is by invitation only.")
+	code := ui.readCode("Invite code: ")
+
+	invite, err := ui.repos.Invite.GetByCode(code)
+	if err != nil || invite.Remaining() == 0 {
+		ui.printError("That invite code is not valid")
+		time.Sleep(2 * time.Second)
+		return nil
+	}
+	return invite
+}
+
+func (ui *UI) manageInvites() {
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		ui.printHeader("Invite Codes")
+
+		var invites []*domain.Invite
+		var err error
+		if user.IsAdmin {
+			invites, err = ui.repos.Invite.GetAll(50)
+		} else {
+			invites, err = ui.repos.Invite.GetByCreator(user.ID)
+		}
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading invites: %v", err))
+			return
+		}
+
+		open := 0
+		for i, invite := range invites {
+			if invite.CreatedBy == user.ID && invite.Remaining() > 0 {
+				open++
+			}
+
+			line := fmt.Sprintf("%2d. %s  used %d of %d  %s",
+				i+1, invite.Code, invite.Uses, invite.MaxUses, invite.CreatedAt.Format("2006-01-02"))
+			if user.IsAdmin && invite.CreatedBy != user.ID {
+				if creator, err := ui.repos.User.GetByID(invite.CreatedBy); err == nil {
+					line += "  by " + safe(creator.Username)
+				}
+			}
+			ui.println(line)
+		}
+		if len(invites) == 0 {
+			ui.println("No invite codes yet.")
+		}
+
+		ui.println("")
+		if !user.IsAdmin {
+			ui.println(fmt.Sprintf("You can have %d unused invite codes at a time.", ui.config.UserInvites))
+		}
+		ui.println("Commands: (N)ew invite, (D)elete <number>, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch {
+		case cmd == "n":
+			if !user.IsAdmin && open >= ui.config.UserInvites {
+				ui.printError("You have no invite codes left to give out")
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			ui.createInvite()
+		case strings.HasPrefix(cmd, "d"):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+			if err != nil || num < 1 || num > len(invites) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			if err := ui.repos.Invite.Delete(invites[num-1].ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to delete invite: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (ui *UI) createInvite() {
+	user := ui.session.CurrentUser()
+
+	uses := 1
+	if user.IsAdmin {
+		input := strings.TrimSpace(ui.readLine("How many times can it be used? [1]: "))
+		if input != "" {
+			n, err := strconv.Atoi(input)
+			if err != nil {
+				ui.printError("Please enter a number")
+				time.Sleep(1 * time.Second)
+				return
+			}
+			uses = n
+		}
+	}
+
+	invite, err := domain.NewInvite(user.ID, uses)
+	if err == nil {
+		err = ui.repos.Invite.Create(invite)
+	}
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to create invite: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	ui.printSuccess(fmt.Sprintf("Invite code: %s", invite.Code))
+	ui.readLine("Press Enter to continue...")
+}
+
+// pendingAccounts is the sysop's queue of registrations awaiting approval.
+func (ui *UI) pendingAccounts() {
+	for {
+		ui.clear()
+		ui.printHeader("Pending Accounts")
+
+		users, err := ui.repos.User.GetByStatus(domain.UserStatusPending)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading pending accounts: %v", err))
+			return
+		}
+
+		if len(users) == 0 {
+			ui.println("No accounts are waiting for approval.")
+			ui.println("")
+			ui.readLine("Press Enter to continue...")
+			return
+		}
+
+		for i, user := range users {
+			ui.println(fmt.Sprintf("%2d. %-20s %-30s %s",
+				i+1, safe(user.Username), safe(user.Email), ui.formatTime(user.CreatedAt)))
+		}
+
+		ui.println("")
+		ui.println("Commands: (A)pprove <number>, (R)eject <number>, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		if cmd == "" || cmd == "b" || (cmd[0] != 'a' && cmd[0] != 'r') {
+			return
+		}
+
+		num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+		if err != nil || num < 1 || num > len(users) {
+			ui.printError("Invalid selection")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		user := users[num-1]
+
+		if cmd[0] == 'a' {
+			ui.approveAccount(user)
+			continue
+		}
+
+		if !ui.confirm(fmt.Sprintf("Reject and delete the account %s?", safe(user.Username))) {
+			continue
+		}
+		if err := ui.repos.User.Delete(user.ID); err != nil {
+			ui.printError(fmt.Sprintf("Failed to reject account: %v", err))
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		ui.audit(domain.AuditUserReject, user.Username, map[string]interface{}{"user_id": user.ID, "email": user.Email})
+	}
+}
+
+func (ui *UI) approveAccount(user *domain.User) {
+	if err := ui.repos.User.SetStatus(user.ID, domain.UserStatusActive); err != nil {
+		ui.printError(fmt.Sprintf("Failed to approve account: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.audit(domain.AuditUserApprove, user.Username, map[string]interface{}{"user_id": user.ID})
+
+	if ui.mailer == nil {
+		return
+	}
+
+	err := ui.mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Your %s account was approved", ui.config.ServerName),
+		Body: fmt.Sprintf("Your account %s on %s has been approved. You can log in now.\n",
+			user.Username, ui.config.ServerName),
+	})
+	if err != nil {
+		log.Printf("Failed to send approval notice to %s: %v", user.Username, err)
+	}
+}
+
+// registrationStatus picks the status a new account starts with.
+func (ui *UI) registrationStatus() string {
+	switch ui.config.RegistrationMode {
+	case config.RegistrationEmail:
+		return domain.UserStatusUnverified
+	case config.RegistrationApproval:
+		return domain.UserStatusPending
+	}
+	return domain.UserStatusActive
+}
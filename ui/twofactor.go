@@ -0,0 +1,290 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/ansi"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/qrcode"
+	"github.com/leinonen/bbs/totp"
+)
+
+// promptSecondFactor asks for an authenticator or recovery code, allowing a
+// few attempts. Failures count towards the login throttle.
+func (ui *UI) promptSecondFactor(user *domain.User) bool {
+	ui.println("Enter the code from your authenticator app, or a recovery code.")
+	for attempt := 0; attempt < 3; attempt++ {
+		code := strings.TrimSpace(ui.readCode("Authentication code: "))
+		if code == "" {
+			ui.printError("Invalid authentication code")
+			continue
+		}
+
+		err := ui.auth.SecondFactor(user, code, ui.session.RemoteAddr)
+		if err == nil {
+			return true
+		}
+		var throttled *domain.ThrottledError
+		if errors.As(err, &throttled) {
+			ui.printError(throttled.Error())
+			return false
+		}
+		ui.printError("Invalid authentication code")
+	}
+	return false
+}
+
+// enforceTwoFactor makes users whose role requires two-factor authentication
+// enrol before they can continue.
+func (ui *UI) enforceTwoFactor() {
+	user := ui.session.CurrentUser()
+	if user.TOTPEnabled {
+		return
+	}
+
+	policy, err := ui.repos.TwoFactorPolicy()
+	if err != nil {
+		log.Printf("Failed to load two-factor policy: %v", err)
+		return
+	}
+	if !policy.Requires(user) {
+		return
+	}
+
+	ui.clear()
+	ui.printHeader("Two-Factor Authentication Required")
+	ui.println("The sysop requires two-factor authentication for your account.")
+	ui.println("You need an authenticator app, such as Aegis or Google Authenticator.")
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+
+	for !ui.enrollTwoFactor(true) {
+		// only fails if the secret could not be saved; let them try again
+	}
+}
+
+func (ui *UI) manageTwoFactor() {
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		ui.printHeader("Two-Factor Authentication")
+
+		if !user.TOTPEnabled {
+			ui.println("Two-factor authentication is off.")
+			ui.println("With it on, logging in also asks for a code from an authenticator app.")
+			ui.println("")
+			if ui.confirm("Enable two-factor authentication?") {
+				ui.enrollTwoFactor(false)
+			}
+			return
+		}
+
+		remaining, _ := ui.repos.RecoveryCode.CountUnused(user.ID)
+		ui.println("Two-factor authentication is on.")
+		ui.println(fmt.Sprintf("Recovery codes left: %d", remaining))
+		ui.println("")
+		ui.println("Commands: (R)egenerate recovery codes, (D)isable, (B)ack")
+
+		switch strings.ToLower(strings.TrimSpace(ui.readLine("> "))) {
+		case "r":
+			if ui.promptSecondFactor(user) {
+				ui.regenerateRecoveryCodes()
+			}
+		case "d":
+			ui.disableTwoFactor()
+		default:
+			return
+		}
+	}
+}
+
+// enrollTwoFactor walks the user through adding a new secret to their app.
+// When forced, the user cannot cancel.
+func (ui *UI) enrollTwoFactor(forced bool) bool {
+	user := ui.session.CurrentUser()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to create secret: %v", err))
+		time.Sleep(2 * time.Second)
+		return false
+	}
+
+	ui.clear()
+	ui.printHeader("Set Up Two-Factor Authentication")
+	ui.println("Scan this code with your authenticator app:")
+	ui.println("")
+	ui.printQRCode(totp.URI(ui.config.ServerName, user.Username, secret))
+	ui.println("")
+	ui.println(fmt.Sprintf("Or enter this secret manually: %s", groupSecret(secret)))
+	ui.println("")
+
+	prompt := "Code from the app (Enter to cancel): "
+	if forced {
+		prompt = "Code from the app: "
+	}
+
+	for {
+		code := strings.TrimSpace(ui.readCode(prompt))
+		if code == "" {
+			if forced {
+				continue
+			}
+			return false
+		}
+
+		step, ok := totp.ValidateStep(secret, code, time.Now())
+		if !ok {
+			ui.printError("That code is not valid, check your device's clock and try again")
+			continue
+		}
+
+		if err := ui.repos.User.SetTOTP(user.ID, secret, true); err != nil {
+			ui.printError(fmt.Sprintf("Failed to enable two-factor authentication: %v", err))
+			time.Sleep(2 * time.Second)
+			return false
+		}
+		ui.repos.User.UseTOTPStep(user.ID, step)
+
+		user.TOTPSecret = secret
+		user.TOTPEnabled = true
+		user.TOTPLastStep = step
+		break
+	}
+
+	ui.printSuccess("Two-factor authentication enabled!")
+	ui.regenerateRecoveryCodes()
+	return true
+}
+
+func (ui *UI) regenerateRecoveryCodes() {
+	codes, err := domain.GenerateRecoveryCodes(domain.RecoveryCodeCount)
+	if err == nil {
+		err = ui.repos.RecoveryCode.Replace(ui.session.CurrentUser().ID, codes)
+	}
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to create recovery codes: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	ui.println("")
+	ui.println("Recovery codes. Each works once in place of an authentication code")
+	ui.println("if you lose your device. Write them down now; they are not shown again.")
+	ui.println("")
+	for i := 0; i < len(codes); i += 2 {
+		line := "  " + codes[i]
+		if i+1 < len(codes) {
+			line += "    " + codes[i+1]
+		}
+		ui.println(line)
+	}
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+}
+
+func (ui *UI) disableTwoFactor() {
+	user := ui.session.CurrentUser()
+
+	if policy, err := ui.repos.TwoFactorPolicy(); err == nil && policy.Requires(user) {
+		ui.printError("Two-factor authentication is required for your account")
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	if !ui.promptSecondFactor(user) {
+		time.Sleep(1 * time.Second)
+		return
+	}
+
+	if err := ui.repos.User.SetTOTP(user.ID, "", false); err != nil {
+		ui.printError(fmt.Sprintf("Failed to disable two-factor authentication: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+	ui.repos.RecoveryCode.DeleteForUser(user.ID)
+
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	ui.printSuccess("Two-factor authentication disabled")
+	time.Sleep(1 * time.Second)
+}
+
+func (ui *UI) printQRCode(data string) {
+	code, err := qrcode.Encode(data, qrcode.Medium)
+	if err != nil {
+		ui.printError(fmt.Sprintf("Failed to draw QR code: %v", err))
+		return
+	}
+
+	if !ansi.ProfileFor(ui.session.TermType).ANSI {
+		ui.print(code.ASCII())
+		return
+	}
+
+	// force light-on-dark so the code scans whatever the terminal's colours
+	for _, line := range strings.Split(strings.TrimSuffix(code.HalfBlocks(), "\n"), "\n") {
+		ui.println("\033[97;40m" + line + "\033[0m")
+	}
+}
+
+// groupSecret splits a base32 secret into groups of four for easier typing.
+func groupSecret(secret string) string {
+	var groups []string
+	for len(secret) > 4 {
+		groups = append(groups, secret[:4])
+		secret = secret[4:]
+	}
+	return strings.Join(append(groups, secret), " ")
+}
+
+func (ui *UI) twoFactorPolicy() {
+	for {
+		ui.clear()
+		ui.printHeader("Two-Factor Policy")
+
+		policy, err := ui.repos.TwoFactorPolicy()
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading policy: %v", err))
+			return
+		}
+
+		ui.println("Roles that must use two-factor authentication:")
+		ui.println("")
+		for _, role := range domain.Roles {
+			mark := " "
+			if policy.RoleRequired(role) {
+				mark = "x"
+			}
+			ui.println(fmt.Sprintf("  [%s] %s", mark, role))
+		}
+		ui.println("")
+		ui.println("Members of a required role enrol the next time they log in.")
+		ui.println("Enter a role to toggle it (Enter to go back): ")
+
+		role := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		if role == "" {
+			return
+		}
+
+		valid := false
+		for _, r := range domain.Roles {
+			valid = valid || r == role
+		}
+		if !valid {
+			ui.printError("Unknown role")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		if err := ui.repos.SetTwoFactorPolicy(policy.Toggle(role)); err != nil {
+			ui.printError(fmt.Sprintf("Failed to save policy: %v", err))
+			time.Sleep(2 * time.Second)
+		}
+	}
+}
@@ -1,52 +1,149 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/leinonen/bbs/ansi"
+	"github.com/leinonen/bbs/auth"
+	"github.com/leinonen/bbs/config"
 	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/mail"
+	"github.com/leinonen/bbs/notify"
+	"github.com/leinonen/bbs/recording"
 	"github.com/leinonen/bbs/repository"
-	"golang.org/x/term"
+	"github.com/leinonen/bbs/webhook"
 )
 
+// Services are the parts of the BBS shared by every caller, whichever
+// transport they connected with.
+type Services struct {
+	Config     *config.Config
+	Repos      *repository.Manager
+	Sessions   *domain.SessionManager
+	Auth       *auth.Authenticator
+	Mailer     mail.Mailer
+	Notifier   *notify.Dispatcher
+	Webhooks   *webhook.Dispatcher
+	Recordings *recording.Store
+}
+
 type UI struct {
-	term    *term.Terminal
-	repos   *repository.Manager
-	session *domain.Session
+	term     domain.Terminal
+	config   *config.Config
+	repos    *repository.Manager
+	sessions *domain.SessionManager
+	auth     *auth.Authenticator
+	mailer   mail.Mailer
+	notifier *notify.Dispatcher
+	session  *domain.Session
+	art      *ansi.Loader
+	loginID  int
+
+	recordings *recording.Store
+	webhooks   *webhook.Dispatcher
+	services   *Services
+
+	// notices are shown straight away while the user sits at a prompt, and
+	// held back while a screen is being drawn.
+	noticeMu  sync.Mutex
+	prompting bool
+	pending   []string
+	lastPage  time.Time
 }
 
-func NewUI(term *term.Terminal, repos *repository.Manager, session *domain.Session) *UI {
+func NewUI(term domain.Terminal, services *Services, session *domain.Session) *UI {
 	return &UI{
-		term:    term,
-		repos:   repos,
-		session: session,
+		term:     term,
+		config:   services.Config,
+		repos:    services.Repos,
+		sessions: services.Sessions,
+		auth:     services.Auth,
+		mailer:   services.Mailer,
+		notifier: services.Notifier,
+		session:  session,
+		art:      ansi.NewLoader(services.Config.ArtDir),
+
+		recordings: services.Recordings,
+		webhooks:   services.Webhooks,
+		services:   services,
 	}
 }
 
 func (ui *UI) Run() {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(disconnected); !ok {
+				panic(r)
+			}
+			reason := ui.session.KickReason()
+			if reason == "" {
+				reason = domain.LogoutReasonDisconnect
+			}
+			ui.endCall(reason)
+		}
+	}()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go ui.showNotices(stop)
+
 	ui.clear()
 	ui.showWelcome()
 
-	for {
-		if ui.session.User == nil || ui.session.User.ID == 0 {
-			if !ui.showLoginMenu() {
-				return
-			}
-		} else {
-			if !ui.showMainMenu() {
-				return
-			}
+	if ui.session.CurrentUser() != nil && ui.session.CurrentUser().ID != 0 {
+		if !ui.untilLogout(ui.afterLogin) {
+			ui.goodbye()
+			return
 		}
 	}
+
+	for ui.untilLogout(ui.showMenu) {
+	}
+}
+
+// showMenu shows the login menu, or the main menu once someone has logged
+// in, and reports whether the call goes on.
+func (ui *UI) showMenu() bool {
+	if ui.session.CurrentUser() == nil || ui.session.CurrentUser().ID == 0 {
+		return ui.showLoginMenu()
+	}
+	return ui.showMainMenu()
+}
+
+// untilLogout runs step and returns what it does, unless a sysop logs the
+// user out meanwhile: then the call goes on at the login menu.
+func (ui *UI) untilLogout(step func() bool) (more bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(loggedOut); !ok {
+				panic(r)
+			}
+			ui.endCall(domain.LogoutReasonForced)
+			ui.session.SetUser(nil)
+			ui.println("")
+			ui.printError("You have been logged out by the sysop")
+			time.Sleep(2 * time.Second)
+			more = true
+		}
+	}()
+	return step()
 }
 
 func (ui *UI) showWelcome() {
+	if ui.showScreen(ui.config.LogonArt) {
+		return
+	}
+
 	ui.printHeader("Welcome to Go BBS System")
 	ui.println("")
 	ui.println("A modern take on the classic Bulletin Board System")
-	ui.println("Connected via SSH")
+	ui.println(fmt.Sprintf("Connected via %s", ui.session.Transport))
 	ui.println("")
 	ui.printLine()
 }
@@ -56,7 +153,9 @@ func (ui *UI) showLoginMenu() bool {
 	ui.printHeader("Login Menu")
 	ui.println("1. Login")
 	ui.println("2. Register")
-	ui.println("3. Continue as Guest")
+	if ui.config.AllowAnonymous {
+		ui.println("3. Continue as Guest")
+	}
 	ui.println("4. Exit")
 	ui.println("")
 
@@ -68,12 +167,16 @@ func (ui *UI) showLoginMenu() bool {
 	case "2":
 		ui.handleRegister()
 	case "3":
-		ui.session.User = &domain.User{
+		if !ui.config.AllowAnonymous {
+			ui.printError("Invalid option")
+			break
+		}
+		ui.session.SetUser(&domain.User{
 			ID:       0,
 			Username: "guest",
-		}
+		})
 	case "4":
-		ui.println("Goodbye!")
+		ui.goodbye()
 		return false
 	default:
 		ui.printError("Invalid option")
@@ -82,25 +185,186 @@ func (ui *UI) showLoginMenu() bool {
 	return true
 }
 
+func (ui *UI) goodbye() {
+	ui.clear()
+	ui.showScreen(ui.config.LogoffArt)
+	ui.println("Goodbye!")
+}
+
+// afterLogin runs once a user has authenticated, whether over SSH or
+// through the login menu. It returns false if the account cannot be used
+// yet, in which case the caller must not let the user in.
+func (ui *UI) afterLogin() bool {
+	if !ui.checkAccountStatus() {
+		return false
+	}
+	// a reset token lets one session in, which then has to set a password
+	if ui.session.PasswordResetID != 0 {
+		if err := ui.repos.PasswordReset.MarkUsed(ui.session.PasswordResetID, time.Now()); err != nil {
+			log.Printf("Refused password reset %d for %s: %v", ui.session.PasswordResetID, ui.session.CurrentUser().Username, err)
+			ui.printError("This reset token has already been used.")
+			time.Sleep(2 * time.Second)
+			return false
+		}
+	}
+
+	if ui.session.Recording != nil {
+		ui.session.Recording.Login(ui.session.CurrentUser())
+	}
+	ui.startCall()
+
+	if ui.session.PasswordResetID != 0 {
+		ui.completePasswordReset()
+	}
+	ui.enforceTwoFactor()
+
+	ui.clear()
+	if ui.showScreen(ui.config.NewsBulletin) {
+		ui.readLine("Press Enter to continue...")
+	}
+
+	ui.showMotd()
+	ui.showOneLiners()
+	return true
+}
+
+func (ui *UI) startCall() {
+	user := ui.session.CurrentUser()
+	login := domain.NewLogin(user.ID, user.Username, ui.session.RemoteAddr, ui.session.ClientVersion)
+	if err := ui.repos.Login.Create(login); err != nil {
+		log.Printf("Failed to record login: %v", err)
+	}
+	ui.loginID = login.ID
+	ui.audit(domain.AuditLogin, user.Username, map[string]interface{}{"client": ui.session.ClientVersion})
+}
+
+func (ui *UI) endCall(reason string) {
+	if ui.loginID == 0 {
+		return
+	}
+
+	if err := ui.repos.Login.End(ui.loginID, time.Now(), reason); err != nil {
+		log.Printf("Failed to record logout: %v", err)
+	}
+	ui.loginID = 0
+}
+
+func (ui *UI) showMotd() {
+	motd, err := ui.repos.Motd.Get()
+	if err != nil {
+		return
+	}
+
+	userID := ui.session.CurrentUser().ID
+	lastSeen, err := ui.repos.Motd.GetLastSeen(userID)
+	if err != nil {
+		log.Printf("Failed to load MOTD seen time: %v", err)
+		return
+	}
+
+	now := time.Now()
+	if !motd.ShouldShow(lastSeen, now) {
+		return
+	}
+
+	ui.clear()
+	ui.printHeader("Message of the Day")
+	ui.println("")
+	ui.println(safe(motd.Content))
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+
+	if err := ui.repos.Motd.MarkSeen(userID, now); err != nil {
+		log.Printf("Failed to mark MOTD seen: %v", err)
+	}
+}
+
+func (ui *UI) showOneLiners() {
+	for {
+		ui.clear()
+		ui.printHeader("One-Liners")
+
+		oneLiners, err := ui.repos.OneLiner.GetRecent(10)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading one-liners: %v", err))
+			return
+		}
+
+		if len(oneLiners) == 0 {
+			ui.println("The wall is empty. Leave the first one-liner!")
+		}
+
+		// Oldest first, so the newest line sits just above the prompt
+		for i := len(oneLiners) - 1; i >= 0; i-- {
+			oneLiner := oneLiners[i]
+			ui.println(fmt.Sprintf("%-16s %s", safe(oneLiner.Username), safe(oneLiner.Text)))
+		}
+
+		ui.println("")
+		cmd := ui.readLine("(A)dd a one-liner, or Enter to continue: ")
+		if strings.ToLower(strings.TrimSpace(cmd)) != "a" {
+			return
+		}
+
+		ui.addOneLiner()
+	}
+}
+
+func (ui *UI) addOneLiner() {
+	text := strings.TrimSpace(ui.readLine("Your one-liner: "))
+	if text == "" {
+		return
+	}
+
+	if len([]rune(text)) > domain.MaxOneLinerLength {
+		ui.printError(fmt.Sprintf("One-liners are limited to %d characters", domain.MaxOneLinerLength))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	oneLiner := domain.NewOneLiner(ui.session.CurrentUser().ID, ui.session.CurrentUser().Username, text)
+	if err := ui.repos.OneLiner.Create(oneLiner); err != nil {
+		ui.printError(fmt.Sprintf("Failed to add one-liner: %v", err))
+		time.Sleep(2 * time.Second)
+	}
+}
+
 func (ui *UI) showMainMenu() bool {
 	ui.clear()
-	ui.printHeader(fmt.Sprintf("Main Menu - Welcome %s", ui.session.User.Username))
+	unread, _ := ui.repos.Notification.CountUnread(ui.session.CurrentUser().ID)
+	if unread > 0 {
+		ui.printHeader(fmt.Sprintf("Main Menu - Welcome %s - %d unread notifications", ui.session.CurrentUser().Username, unread))
+	} else {
+		ui.printHeader(fmt.Sprintf("Main Menu - Welcome %s", ui.session.CurrentUser().Username))
+	}
 	ui.println("")
 	ui.println("1. Browse Boards")
 	ui.println("2. Recent Posts")
 	ui.println("3. Search")
 	ui.println("4. User Profile")
 	ui.println("5. Who's Online")
-	if ui.session.User.IsAdmin {
+	if ui.session.CurrentUser().IsAdmin {
 		ui.println("6. Admin Panel")
 	}
+	ui.println("7. Last Callers")
+	if unread, err := ui.repos.Message.CountUnread(ui.session.CurrentUser().ID); err == nil && unread > 0 {
+		ui.println(fmt.Sprintf("8. Private Messages (%d new)", unread))
+	} else {
+		ui.println("8. Private Messages")
+	}
+	if unread > 0 {
+		ui.println(fmt.Sprintf("N. Notifications (%d unread)", unread))
+	} else {
+		ui.println("N. Notifications")
+	}
+	ui.println("P. Page Sysop")
 	ui.println("9. Logout")
 	ui.println("0. Exit")
 	ui.println("")
 
 	choice := ui.readLine("Select option: ")
 
-	switch choice {
+	switch strings.ToLower(choice) {
 	case "1":
 		ui.browseBoards()
 	case "2":
@@ -112,15 +376,25 @@ func (ui *UI) showMainMenu() bool {
 	case "5":
 		ui.showOnlineUsers()
 	case "6":
-		if ui.session.User.IsAdmin {
+		if ui.session.CurrentUser().IsAdmin {
 			ui.adminPanel()
 		}
+	case "7":
+		ui.showLastCallers()
+	case "8":
+		ui.privateMessages()
+	case "n":
+		ui.notificationInbox()
+	case "p":
+		ui.pageSysop()
 	case "9":
-		ui.session.User = nil
+		ui.endCall(domain.LogoutReasonLogout)
+		ui.session.SetUser(nil)
 		ui.println("Logged out successfully")
 		time.Sleep(1 * time.Second)
 	case "0":
-		ui.println("Goodbye!")
+		ui.endCall(domain.LogoutReasonExit)
+		ui.goodbye()
 		return false
 	default:
 		ui.printError("Invalid option")
@@ -134,36 +408,74 @@ func (ui *UI) handleLogin() {
 	ui.printHeader("Login")
 
 	username := ui.readLine("Username: ")
-	ui.term.SetPrompt("Password: ")
-	password, _ := ui.term.ReadPassword("Password: ")
-	ui.term.SetPrompt("")
+	password := ui.readPassword("Password: ")
 
-	user, err := ui.repos.User.Authenticate(username, password)
+	result, err := ui.auth.Password(username, password, ui.session.RemoteAddr)
 	if err != nil {
-		ui.printError("Invalid credentials")
+		var throttled *domain.ThrottledError
+		if errors.As(err, &throttled) {
+			ui.printError(throttled.Error())
+		} else {
+			ui.printError("Invalid credentials")
+		}
+		time.Sleep(2 * time.Second)
+		return
+	}
+	user := result.User
+
+	if result.NeedsSecondFactor() && !ui.promptSecondFactor(user) {
 		time.Sleep(2 * time.Second)
 		return
 	}
 
 	ui.repos.User.UpdateLastLogin(user.ID)
-	ui.session.User = user
+	ui.session.PasswordResetID = result.PasswordResetID
+	ui.session.SetUser(user)
 	ui.printSuccess(fmt.Sprintf("Welcome back, %s!", user.Username))
 	time.Sleep(1 * time.Second)
+	if !ui.afterLogin() {
+		ui.session.SetUser(nil)
+	}
 }
 
 func (ui *UI) handleRegister() {
 	ui.clear()
 	ui.printHeader("Register New Account")
 
-	username := ui.readLine("Username: ")
-	email := ui.readLine("Email: ")
+	var invite *domain.Invite
+	if ui.config.RegistrationMode == config.RegistrationInvite {
+		if invite = ui.readInvite(); invite == nil {
+			return
+		}
+	}
 
-	ui.term.SetPrompt("Password: ")
-	password, _ := ui.term.ReadPassword("Password: ")
-	ui.term.SetPrompt("Confirm Password: ")
-	confirm, _ := ui.term.ReadPassword("Confirm Password: ")
-	ui.term.SetPrompt("")
+	username := strings.TrimSpace(ui.readLine("Username: "))
+	if err := domain.ValidateUsername(username); err != nil {
+		ui.printError(err.Error())
+		time.Sleep(2 * time.Second)
+		return
+	}
+	if _, err := ui.repos.User.GetByUsername(username); err == nil {
+		ui.printError("That username is already taken")
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	email := strings.TrimSpace(ui.readLine("Email: "))
+	if err := domain.ValidateEmail(email); err != nil {
+		ui.printError(err.Error())
+		time.Sleep(2 * time.Second)
+		return
+	}
 
+	password := ui.readPassword("Password: ")
+	if err := ui.passwordPolicy().Validate(username, password); err != nil {
+		ui.printError(err.Error())
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	confirm := ui.readPassword("Confirm Password: ")
 	if password != confirm {
 		ui.printError("Passwords do not match")
 		time.Sleep(2 * time.Second)
@@ -172,17 +484,53 @@ func (ui *UI) handleRegister() {
 
 	user := domain.NewUser(username, email)
 	user.Password = password
+	user.Status = ui.registrationStatus()
 
-	err := ui.repos.User.Create(user)
-	if err != nil {
-		ui.printError(fmt.Sprintf("Registration failed: %v", err))
+	if invite != nil {
+		user.InvitedBy = invite.CreatedBy
+	}
+
+	if err := ui.repos.User.Create(user); err != nil {
+		if errors.Is(err, domain.ErrUserExists) {
+			ui.printError("That username or email is already registered")
+		} else {
+			log.Printf("Failed to create user %s: %v", username, err)
+			ui.printError("Registration failed, please try again later")
+		}
 		time.Sleep(2 * time.Second)
 		return
 	}
+	if invite != nil {
+		// the code may have run out while the form was being filled in
+		if err := ui.repos.Invite.Use(invite.Code); err != nil {
+			if err := ui.repos.User.Delete(user.ID); err != nil {
+				log.Printf("Failed to remove user %s after their invite ran out: %v", user.Username, err)
+			}
+			ui.printError("That invite code is not valid")
+			time.Sleep(2 * time.Second)
+			return
+		}
+	}
+	ui.repos.RecordAudit(domain.NewAuditEntry(user, domain.AuditRegister, user.Username, ui.session.RemoteAddr,
+		map[string]interface{}{"status": user.Status, "invited_by": user.InvitedBy}))
+	ui.webhooks.UserRegistered(user)
+
+	if user.Status == domain.UserStatusPending {
+		ui.printSuccess("Registration received!")
+		ui.println("A sysop will review your account. You can log in once it is approved.")
+		ui.readLine("Press Enter to continue...")
+		return
+	}
 
-	ui.session.User = user
+	ui.session.SetUser(user)
 	ui.printSuccess("Registration successful!")
+	if user.Status == domain.UserStatusUnverified {
+		ui.sendVerification()
+	}
 	time.Sleep(1 * time.Second)
+	if !ui.afterLogin() {
+		ui.session.SetUser(nil)
+	}
 }
 
 func (ui *UI) browseBoards() {
@@ -198,7 +546,7 @@ func (ui *UI) browseBoards() {
 
 		for i, board := range boards {
 			ui.println(fmt.Sprintf("%d. [%s] %s (%d posts)",
-				i+1, board.Name, board.Description, board.PostCount))
+				i+1, safe(board.Name), safe(board.Description), board.PostCount))
 		}
 
 		ui.println("")
@@ -225,8 +573,10 @@ func (ui *UI) viewBoard(board *domain.Board) {
 
 	for {
 		ui.clear()
-		ui.printHeader(fmt.Sprintf("Board: %s", board.Name))
-		ui.println(board.Description)
+		if !ui.showScreen(ui.config.BoardArtFor(board.Name)) {
+			ui.printHeader(fmt.Sprintf("Board: %s", board.Name))
+		}
+		ui.println(safe(board.Description))
 		ui.printLine()
 
 		posts, err := ui.repos.Post.GetByBoard(board.ID, pageSize, page*pageSize)
@@ -240,13 +590,24 @@ func (ui *UI) viewBoard(board *domain.Board) {
 		} else {
 			for i, post := range posts {
 				ui.println(fmt.Sprintf("%d. %s - by %s (%d replies)",
-					i+1, post.Title, post.Username, post.Replies))
+					i+1, safe(post.Title), safe(post.Username), post.Replies))
 				ui.println(fmt.Sprintf("   %s", ui.formatTime(post.CreatedAt)))
 			}
 		}
 
+		loggedIn := ui.session.CurrentUser() != nil && ui.session.CurrentUser().ID != 0
+		subscribed := false
+		if loggedIn {
+			subscribed, _ = ui.repos.Subscription.IsSubscribed(ui.session.CurrentUser().ID, board.ID)
+		}
+
 		ui.println("")
-		ui.println("Commands: (N)ew post, (V)iew post #, (R)efresh, (B)ack")
+		ui.print("Commands: (N)ew post, (V)iew post #, (R)efresh, (B)ack")
+		if subscribed {
+			ui.print(", (U)nsubscribe")
+		} else if loggedIn {
+			ui.print(", (S)ubscribe")
+		}
 		if page > 0 {
 			ui.print(", (P)revious page")
 		}
@@ -262,7 +623,7 @@ func (ui *UI) viewBoard(board *domain.Board) {
 		case cmd == "b":
 			return
 		case cmd == "n":
-			if ui.session.User == nil || ui.session.User.ID == 0 {
+			if ui.session.CurrentUser() == nil || ui.session.CurrentUser().ID == 0 {
 				ui.printError("Please login to post")
 				time.Sleep(2 * time.Second)
 			} else {
@@ -270,6 +631,16 @@ func (ui *UI) viewBoard(board *domain.Board) {
 			}
 		case cmd == "r":
 			continue
+		case cmd == "s" && loggedIn && !subscribed:
+			if err := ui.repos.Subscription.Subscribe(ui.session.CurrentUser().ID, board.ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to subscribe: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		case cmd == "u" && subscribed:
+			if err := ui.repos.Subscription.Unsubscribe(ui.session.CurrentUser().ID, board.ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to unsubscribe: %v", err))
+				time.Sleep(2 * time.Second)
+			}
 		case cmd == "p" && page > 0:
 			page--
 		case cmd == "f" && len(posts) == pageSize:
@@ -290,36 +661,113 @@ func (ui *UI) viewBoard(board *domain.Board) {
 }
 
 func (ui *UI) viewPost(post *domain.Post) {
-	ui.clear()
-	ui.printHeader(post.Title)
-	ui.println(fmt.Sprintf("Posted by %s on %s", post.Username, ui.formatTime(post.CreatedAt)))
-	ui.printLine()
-	ui.println(post.Content)
-	ui.printLine()
+	for {
+		ui.clear()
+		ui.printHeader(post.Title)
+		ui.println(fmt.Sprintf("Posted by %s on %s", safe(post.Username), ui.formatTime(post.CreatedAt)))
+		ui.printLine()
+		ui.println(safe(post.Content))
+		ui.printLine()
+
+		replies, _ := ui.repos.Post.GetReplies(post.ID)
+		if len(replies) > 0 {
+			ui.println(fmt.Sprintf("--- %d Replies ---", len(replies)))
+			for i, reply := range replies {
+				ui.println("")
+				ui.println(fmt.Sprintf("%d. By %s on %s:", i+1, safe(reply.Username), ui.formatTime(reply.CreatedAt)))
+				ui.println(safe(reply.Content))
+			}
+			ui.printLine()
+		}
+		if ui.config.FeedAddr != "" {
+			ui.println(fmt.Sprintf("Feed: %s/feeds/threads/%d/atom", ui.config.FeedBaseURL(), post.ID))
+		}
+
+		loggedIn := ui.session.CurrentUser() != nil && ui.session.CurrentUser().ID != 0
+		watching := false
+		if loggedIn {
+			watching, _ = ui.repos.Subscription.IsWatching(ui.session.CurrentUser().ID, post.ID)
+		}
 
-	replies, _ := ui.repos.Post.GetReplies(post.ID)
-	if len(replies) > 0 {
-		ui.println(fmt.Sprintf("--- %d Replies ---", len(replies)))
+		moderate := ui.canModify(post)
 		for _, reply := range replies {
-			ui.println("")
-			ui.println(fmt.Sprintf("By %s on %s:", reply.Username, ui.formatTime(reply.CreatedAt)))
-			ui.println(reply.Content)
+			moderate = moderate || ui.canModify(reply)
 		}
-		ui.printLine()
-	}
 
-	ui.println("")
-	ui.println("Commands: (R)eply, (B)ack")
+		ui.println("")
+		if moderate {
+			ui.println("Your posts: (E)dit, (E #) edit reply, (D)elete, (D #) delete reply")
+		}
+		switch {
+		case watching:
+			ui.println("Commands: (R)eply, (A)uthor profile, (A #) reply author, (U)nwatch, (B)ack")
+		case loggedIn:
+			ui.println("Commands: (R)eply, (A)uthor profile, (A #) reply author, (W)atch, (B)ack")
+		default:
+			ui.println("Commands: (R)eply, (A)uthor profile, (A #) reply author, (B)ack")
+		}
 
-	cmd := ui.readLine("> ")
-	cmd = strings.ToLower(strings.TrimSpace(cmd))
+		cmd := ui.readLine("> ")
+		cmd = strings.ToLower(strings.TrimSpace(cmd))
 
-	if cmd == "r" {
-		if ui.session.User == nil || ui.session.User.ID == 0 {
-			ui.printError("Please login to reply")
-			time.Sleep(2 * time.Second)
-		} else {
+		switch {
+		case cmd == "r":
+			if ui.session.CurrentUser() == nil || ui.session.CurrentUser().ID == 0 {
+				ui.printError("Please login to reply")
+				time.Sleep(2 * time.Second)
+				return
+			}
 			ui.createPost(post.BoardID, &post.ID)
+		case cmd == "w" && loggedIn && !watching:
+			if err := ui.repos.Subscription.WatchThread(ui.session.CurrentUser().ID, post.ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to watch thread: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		case cmd == "u" && watching:
+			if err := ui.repos.Subscription.UnwatchThread(ui.session.CurrentUser().ID, post.ID); err != nil {
+				ui.printError(fmt.Sprintf("Failed to unwatch thread: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		case cmd == "a":
+			ui.viewUser(post.UserID)
+		case strings.HasPrefix(cmd, "a "):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[2:]))
+			if err != nil || num < 1 || num > len(replies) {
+				ui.printError("Invalid reply number")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			ui.viewUser(replies[num-1].UserID)
+		case moderate && (cmd == "e" || cmd == "d" || strings.HasPrefix(cmd, "e ") || strings.HasPrefix(cmd, "d ")):
+			target := post
+			if len(cmd) > 1 {
+				num, err := strconv.Atoi(strings.TrimSpace(cmd[2:]))
+				if err != nil || num < 1 || num > len(replies) {
+					ui.printError("Invalid reply number")
+					time.Sleep(1 * time.Second)
+					continue
+				}
+				target = replies[num-1]
+			}
+			if !ui.canModify(target) {
+				ui.printError("You can only change your own posts")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+
+			if cmd[0] == 'e' {
+				ui.editPost(target)
+				continue
+			}
+			var targetReplies []*domain.Post
+			if target == post {
+				targetReplies = replies
+			}
+			if ui.deletePost(target, targetReplies) && target == post {
+				return
+			}
+		default:
+			return
 		}
 	}
 }
@@ -337,17 +785,7 @@ func (ui *UI) createPost(boardID int, replyTo *int) {
 		title = ui.readLine("Title: ")
 	}
 
-	ui.println("Content (type '.' on a new line to finish):")
-	lines := []string{}
-	for {
-		line := ui.readLine("")
-		if line == "." {
-			break
-		}
-		lines = append(lines, line)
-	}
-
-	content := strings.Join(lines, "\n")
+	content := ui.readText("Content")
 
 	if title == "" && replyTo == nil {
 		ui.printError("Title cannot be empty")
@@ -361,12 +799,12 @@ func (ui *UI) createPost(boardID int, replyTo *int) {
 
 	var post *domain.Post
 	if replyTo != nil {
-		post = domain.NewReply(boardID, ui.session.User.ID, ui.session.User.Username, content, *replyTo)
+		post = domain.NewReply(boardID, ui.session.CurrentUser().ID, ui.session.CurrentUser().Username, content, *replyTo)
 	} else {
-		post = domain.NewPost(boardID, ui.session.User.ID, ui.session.User.Username, title, content)
+		post = domain.NewPost(boardID, ui.session.CurrentUser().ID, ui.session.CurrentUser().Username, title, content)
 	}
 
-	err := ui.repos.Post.Create(post)
+	err := ui.services.CreatePost(ui.session.CurrentUser(), post, nil)
 	if err != nil {
 		ui.printError(fmt.Sprintf("Failed to create post: %v", err))
 	} else {
@@ -386,7 +824,7 @@ func (ui *UI) showRecentPosts() {
 	}
 
 	for i, post := range posts {
-		ui.println(fmt.Sprintf("%d. %s - by %s", i+1, post.Title, post.Username))
+		ui.println(fmt.Sprintf("%d. %s - by %s", i+1, safe(post.Title), safe(post.Username)))
 		ui.println(fmt.Sprintf("   %s", ui.formatTime(post.CreatedAt)))
 	}
 
@@ -402,45 +840,337 @@ func (ui *UI) search() {
 }
 
 func (ui *UI) showProfile() {
-	ui.clear()
-	ui.printHeader("User Profile")
-	ui.println(fmt.Sprintf("Username: %s", ui.session.User.Username))
-	ui.println(fmt.Sprintf("Email: %s", ui.session.User.Email))
-	ui.println(fmt.Sprintf("Member since: %s", ui.formatTime(ui.session.User.CreatedAt)))
-	ui.println(fmt.Sprintf("Last login: %s", ui.formatTime(ui.session.User.LastLogin)))
-	if ui.session.User.IsAdmin {
+	for {
+		user := ui.session.CurrentUser()
+
+		ui.clear()
+		ui.printHeader("User Profile")
+		ui.println(fmt.Sprintf("Username: %s", safe(user.Username)))
+		ui.println(fmt.Sprintf("Email: %s", safe(user.Email)))
+		ui.printProfileDetails(user)
+		ui.println(fmt.Sprintf("Time zone: %s", valueOr(user.TimeZone, "server default")))
+		ui.println(fmt.Sprintf("Date format: %s", valueOr(user.DateFormat, domain.DefaultDateFormat)))
+		if user.TOTPEnabled {
+			ui.println("Two-factor authentication: on")
+		} else {
+			ui.println("Two-factor authentication: off")
+		}
+		if ui.recordings.Enabled() {
+			if user.RecordingOptOut {
+				ui.println("Call recording: opted out")
+			} else {
+				ui.println("Call recording: on")
+			}
+		}
+		if user.Signature != "" {
+			ui.println("Signature:")
+			ui.println(safe(user.Signature))
+		}
+		ui.println("")
+
+		if user.ID == 0 {
+			ui.readLine("Press Enter to continue...")
+			return
+		}
+
+		commands := "(E)dit profile, (P)assword, (T)wo-factor, (N)otifications"
+		invites := ui.config.RegistrationMode == config.RegistrationInvite &&
+			(user.IsAdmin || ui.config.UserInvites > 0)
+		if invites {
+			commands += ", (I)nvites"
+		}
+		if ui.recordings.Enabled() {
+			commands += ", (R)ecording"
+		}
+		if ui.config.APIAddr != "" {
+			commands += ", (A)PI tokens"
+		}
+		if ui.config.FeedAddr != "" {
+			commands += ", (F)eeds"
+		}
+		ui.println("Commands: " + commands + ", (B)ack")
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch cmd {
+		case "e":
+			ui.editProfile()
+		case "p":
+			ui.changePassword()
+		case "t":
+			ui.manageTwoFactor()
+		case "n":
+			ui.notificationSettings()
+		case "i":
+			if !invites {
+				return
+			}
+			ui.manageInvites()
+		case "r":
+			if !ui.recordings.Enabled() {
+				return
+			}
+			ui.toggleRecording()
+		case "a":
+			if ui.config.APIAddr == "" {
+				return
+			}
+			ui.manageAPITokens()
+		case "f":
+			if ui.config.FeedAddr == "" {
+				return
+			}
+			ui.manageFeeds()
+		default:
+			return
+		}
+	}
+}
+
+// printProfileDetails prints the parts of a profile anyone may see.
+func (ui *UI) printProfileDetails(user *domain.User) {
+	ui.println(fmt.Sprintf("Member since: %s", ui.formatTime(user.CreatedAt)))
+	ui.println(fmt.Sprintf("Last seen: %s", ui.formatTime(user.LastLogin)))
+	if calls, err := ui.repos.Login.CountByUser(user.ID); err == nil {
+		ui.println(fmt.Sprintf("Calls: %d", calls))
+	}
+	if posts, err := ui.repos.Post.CountByUser(user.ID); err == nil {
+		ui.println(fmt.Sprintf("Posts: %d", posts))
+	}
+	if user.IsAdmin {
 		ui.println("Status: Administrator")
 	}
+	if user.Location != "" {
+		ui.println(fmt.Sprintf("Location: %s", safe(user.Location)))
+	}
+	if user.Homepage != "" {
+		ui.println(fmt.Sprintf("Homepage: %s", safe(user.Homepage)))
+	}
+	if user.Bio != "" {
+		ui.println("")
+		ui.println(safe(user.Bio))
+	}
+}
+
+func (ui *UI) editProfile() {
+	ui.clear()
+	ui.printHeader("Edit Profile")
+	ui.println("Press Enter to keep the current value, or '-' to clear it.")
 	ui.println("")
-	ui.readLine("Press Enter to continue...")
+
+	updated := *ui.session.CurrentUser()
+	updated.Location = ui.readField("Location", updated.Location)
+	updated.Homepage = ui.readField("Homepage", updated.Homepage)
+	updated.TimeZone = ui.readField("Time zone (e.g. Europe/Helsinki)", updated.TimeZone)
+	updated.DateFormat = ui.readField("Date format (us, iso, eu)", updated.DateFormat)
+
+	if ui.confirm("Edit bio?") {
+		updated.Bio = ui.readText("Bio")
+	}
+	if ui.confirm("Edit signature?") {
+		updated.Signature = ui.readText(fmt.Sprintf("Signature, up to %d lines", domain.MaxSignatureLines))
+	}
+
+	if err := updated.ValidateProfile(); err != nil {
+		ui.printError(err.Error())
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	if err := ui.repos.User.Update(&updated); err != nil {
+		ui.printError(fmt.Sprintf("Failed to update profile: %v", err))
+		time.Sleep(2 * time.Second)
+		return
+	}
+
+	ui.session.SetUser(&updated)
+	ui.printSuccess("Profile updated!")
+	time.Sleep(1 * time.Second)
 }
 
-func (ui *UI) showOnlineUsers() {
+func (ui *UI) viewUser(userID int) {
+	user, err := ui.repos.User.GetByID(userID)
+	if err != nil {
+		ui.printError("User not found")
+		time.Sleep(1 * time.Second)
+		return
+	}
+
+	for {
+		ui.clear()
+		ui.printHeader(fmt.Sprintf("User: %s", user.Username))
+		ui.printProfileDetails(user)
+		ui.println("")
+
+		posts, _ := ui.repos.Post.GetByUser(user.ID, 10)
+		if len(posts) > 0 {
+			ui.println("Recent posts:")
+			for i, post := range posts {
+				ui.println(fmt.Sprintf("%2d. %s (%s)", i+1, safe(postSummary(post)), ui.formatTime(post.CreatedAt)))
+			}
+			ui.println("")
+		}
+
+		ui.println("Enter post number to read it (B to go back): ")
+		choice := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		num, err := strconv.Atoi(choice)
+		if err != nil || num < 1 || num > len(posts) {
+			return
+		}
+
+		post := posts[num-1]
+		if post.ReplyTo != nil {
+			if parent, err := ui.repos.Post.GetByID(*post.ReplyTo); err == nil {
+				post = parent
+			}
+		}
+		ui.viewPost(post)
+	}
+}
+
+func (ui *UI) showLastCallers() {
 	ui.clear()
-	ui.printHeader("Online Users")
-	ui.println("Online users functionality coming soon...")
+	ui.printHeader("Last 10 Callers")
+
+	logins, err := ui.repos.Login.GetRecent(10)
+	if err != nil {
+		ui.printError(fmt.Sprintf("Error loading callers: %v", err))
+		return
+	}
+
+	if len(logins) == 0 {
+		ui.println("Nobody has called yet.")
+	}
+
+	for _, login := range logins {
+		ui.println(fmt.Sprintf("%-16s %-16s %8s",
+			safe(login.Username), ui.formatTime(login.LoginAt), formatDuration(login.Duration(time.Now()))))
+	}
+
+	ui.println("")
 	ui.readLine("Press Enter to continue...")
 }
 
 func (ui *UI) adminPanel() {
 	ui.clear()
 	ui.printHeader("Admin Panel")
+	if pending, err := ui.repos.User.GetByStatus(domain.UserStatusPending); err == nil && len(pending) > 0 {
+		ui.println(fmt.Sprintf("%d account(s) awaiting approval", len(pending)))
+		ui.println("")
+	}
 	ui.println("1. Create Board")
 	ui.println("2. Manage Users")
 	ui.println("3. System Stats")
+	ui.println("4. Edit Message of the Day")
+	ui.println("5. Manage One-Liners")
+	ui.println("6. Caller Log")
+	ui.println("7. Two-Factor Policy")
+	ui.println("8. Failed Logins")
+	ui.println("9. Pending Accounts")
+	ui.println("A. Audit Log")
+	ui.println("B. Broadcast Message")
+	ui.println("N. Nodes")
+	if ui.recordings.Enabled() {
+		ui.println("R. Recordings")
+	}
+	if ui.webhooks.Enabled() {
+		ui.println("W. Webhooks")
+	}
 	ui.println("0. Back")
 
 	choice := ui.readLine("Select option: ")
 
-	switch choice {
+	switch strings.ToLower(choice) {
 	case "1":
 		ui.createBoard()
 	case "2":
-		ui.println("User management coming soon...")
-		ui.readLine("Press Enter to continue...")
+		ui.manageUsers()
 	case "3":
 		ui.println("System stats coming soon...")
 		ui.readLine("Press Enter to continue...")
+	case "4":
+		ui.editMotd()
+	case "5":
+		ui.manageOneLiners()
+	case "6":
+		ui.showCallerLog()
+	case "7":
+		ui.twoFactorPolicy()
+	case "8":
+		ui.showFailedLogins()
+	case "9":
+		ui.pendingAccounts()
+	case "a":
+		ui.showAuditLog()
+	case "b":
+		ui.broadcast()
+	case "n":
+		ui.manageNodes()
+	case "r":
+		if ui.recordings.Enabled() {
+			ui.manageRecordings()
+		}
+	case "w":
+		if ui.webhooks.Enabled() {
+			ui.showWebhooks()
+		}
+	}
+}
+
+func (ui *UI) editMotd() {
+	ui.clear()
+	ui.printHeader("Edit Message of the Day")
+
+	if motd, err := ui.repos.Motd.Get(); err == nil {
+		ui.println("Current message:")
+		ui.printLine()
+		ui.println(safe(motd.Content))
+		ui.printLine()
+	}
+
+	ui.println("Leave the message empty to stop showing it.")
+	content := ui.readText("New message")
+
+	motd := domain.NewMotd(content, ui.session.CurrentUser().ID)
+	if err := ui.repos.Motd.Set(motd); err != nil {
+		ui.printError(fmt.Sprintf("Failed to save message: %v", err))
+	} else {
+		ui.printSuccess("Message of the day updated!")
+	}
+	time.Sleep(2 * time.Second)
+}
+
+func (ui *UI) manageOneLiners() {
+	for {
+		ui.clear()
+		ui.printHeader("Manage One-Liners")
+
+		oneLiners, err := ui.repos.OneLiner.GetRecent(20)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading one-liners: %v", err))
+			return
+		}
+
+		for i, oneLiner := range oneLiners {
+			ui.println(fmt.Sprintf("%2d. %-16s %s", i+1, safe(oneLiner.Username), safe(oneLiner.Text)))
+		}
+
+		ui.println("")
+		ui.println("Enter number to delete (0 to go back): ")
+
+		choice := ui.readLine("> ")
+		if choice == "0" || choice == "" {
+			return
+		}
+
+		num, err := strconv.Atoi(choice)
+		if err != nil || num < 1 || num > len(oneLiners) {
+			ui.printError("Invalid selection")
+			continue
+		}
+
+		if err := ui.repos.OneLiner.Delete(oneLiners[num-1].ID); err != nil {
+			ui.printError(fmt.Sprintf("Failed to delete one-liner: %v", err))
+			time.Sleep(2 * time.Second)
+		}
 	}
 }
 
@@ -456,7 +1186,66 @@ func (ui *UI) createBoard() {
 	if err != nil {
 		ui.printError(fmt.Sprintf("Failed to create board: %v", err))
 	} else {
+		ui.audit(domain.AuditBoardCreate, board.Name, map[string]interface{}{"board_id": board.ID})
 		ui.printSuccess("Board created successfully!")
 	}
 	time.Sleep(2 * time.Second)
 }
+
+func (ui *UI) showCallerLog() {
+	filter := ""
+	for {
+		ui.clear()
+		ui.printHeader("Caller Log")
+
+		var logins []*domain.Login
+		var err error
+		switch {
+		case strings.HasPrefix(filter, "u "):
+			var user *domain.User
+			user, err = ui.repos.User.GetByUsername(strings.TrimSpace(filter[2:]))
+			if err == nil {
+				logins, err = ui.repos.Login.GetByUser(user.ID, 50)
+			}
+		case strings.HasPrefix(filter, "i "):
+			logins, err = ui.repos.Login.GetByRemoteAddr(strings.TrimSpace(filter[2:]), 50)
+		default:
+			logins, err = ui.repos.Login.GetRecent(50)
+		}
+
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading caller log: %v", err))
+		}
+
+		for _, login := range logins {
+			reason := login.LogoutReason
+			if login.LogoutAt == nil {
+				reason = "online"
+			}
+			ui.println(fmt.Sprintf("%-16s %-15s %s %8s %-10s %s",
+				safe(login.Username),
+				login.RemoteAddr,
+				login.LoginAt.Format("2006-01-02 15:04"),
+				formatDuration(login.Duration(time.Now())),
+				reason,
+				safe(login.ClientVersion)))
+		}
+
+		ui.println("")
+		ui.println("Filter: (U) <username>, (I) <ip address>, (A)ll, (B)ack")
+
+		cmd := strings.TrimSpace(ui.readLine("> "))
+		lower := strings.ToLower(cmd)
+		switch {
+		case lower == "b" || lower == "":
+			return
+		case lower == "a":
+			filter = ""
+		case strings.HasPrefix(lower, "u ") || strings.HasPrefix(lower, "i "):
+			filter = lower[:2] + cmd[2:]
+		default:
+			ui.printError("Invalid filter")
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leinonen/bbs/domain"
+)
+
+// showWebhooks lists the configured webhooks and their recent deliveries,
+// and lets the sysop look into one or send a failed one again.
+func (ui *UI) showWebhooks() {
+	for {
+		ui.clear()
+		ui.printHeader("Webhooks")
+
+		for i, hook := range ui.config.Webhooks {
+			events := "all events"
+			if len(hook.Events) > 0 {
+				events = strings.Join(hook.Events, ", ")
+			}
+			ui.println(fmt.Sprintf("[%d] %s (%s)", i+1, safe(hook.URL), events))
+		}
+		ui.printLine()
+
+		deliveries, err := ui.repos.Webhook.GetRecent(20)
+		if err != nil {
+			ui.printError(fmt.Sprintf("Error loading deliveries: %v", err))
+		}
+		if len(deliveries) == 0 && err == nil {
+			ui.println("No deliveries yet.")
+		}
+		for i, delivery := range deliveries {
+			ui.println(fmt.Sprintf("%2d. %s [%s] %-15s %-9s tries: %d  %s",
+				i+1,
+				delivery.CreatedAt.Format("2006-01-02 15:04"),
+				ui.webhookNumber(delivery.URL),
+				delivery.Event,
+				delivery.Status,
+				delivery.Attempts,
+				safe(truncate(delivery.Outcome(), 30))))
+		}
+
+		ui.println("")
+		ui.println("Commands: (V)iew #, (R)etry #, Enter to refresh, (B)ack")
+
+		cmd := strings.ToLower(strings.TrimSpace(ui.readLine("> ")))
+		switch {
+		case cmd == "":
+			continue
+		case cmd == "b":
+			return
+		case strings.HasPrefix(cmd, "v"), strings.HasPrefix(cmd, "r"):
+			num, err := strconv.Atoi(strings.TrimSpace(cmd[1:]))
+			if err != nil || num < 1 || num > len(deliveries) {
+				ui.printError("Invalid selection")
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			delivery := deliveries[num-1]
+			if cmd[0] == 'v' {
+				ui.viewWebhookDelivery(delivery)
+				continue
+			}
+			if delivery.Status != domain.WebhookFailed {
+				ui.printError("Only failed deliveries can be retried")
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			if err := ui.webhooks.Retry(delivery); err != nil {
+				ui.printError(fmt.Sprintf("Failed to retry delivery: %v", err))
+				time.Sleep(2 * time.Second)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// webhookNumber returns the number a webhook is listed under, or "-" once
+// it has been removed from the config.
+func (ui *UI) webhookNumber(url string) string {
+	for i, hook := range ui.config.Webhooks {
+		if hook.URL == url {
+			return strconv.Itoa(i + 1)
+		}
+	}
+	return "-"
+}
+
+func (ui *UI) viewWebhookDelivery(delivery *domain.WebhookDelivery) {
+	ui.clear()
+	ui.printHeader("Webhook Delivery")
+	ui.println(fmt.Sprintf("Delivery: %d", delivery.ID))
+	ui.println(fmt.Sprintf("URL: %s", safe(delivery.URL)))
+	ui.println(fmt.Sprintf("Event: %s", delivery.Event))
+	ui.println(fmt.Sprintf("Queued: %s", delivery.CreatedAt.Format("2006-01-02 15:04:05")))
+	ui.println(fmt.Sprintf("Status: %s", delivery.Status))
+	ui.println(fmt.Sprintf("Attempts: %d of %d", delivery.Attempts, domain.MaxWebhookAttempts))
+	ui.println(fmt.Sprintf("Last attempt: %s", safe(delivery.Outcome())))
+	switch {
+	case delivery.DeliveredAt != nil:
+		ui.println(fmt.Sprintf("Delivered: %s", delivery.DeliveredAt.Format("2006-01-02 15:04:05")))
+	case delivery.Status == domain.WebhookPending:
+		ui.println(fmt.Sprintf("Next attempt: %s", delivery.NextAttemptAt.Format("2006-01-02 15:04:05")))
+	}
+
+	var payload bytes.Buffer
+	if err := json.Indent(&payload, []byte(delivery.Payload), "", "  "); err != nil {
+		payload.Reset()
+		payload.WriteString(delivery.Payload)
+	}
+	ui.println("Payload:")
+	ui.println(safe(payload.String()))
+	ui.println("")
+	ui.readLine("Press Enter to continue...")
+}
@@ -0,0 +1,314 @@
+// Package webhook tells other services about events on the BBS by posting
+// signed JSON to the webhooks in the config. Deliveries are queued in the
+// database and posted by Run, so they are retried while a receiver is down
+// and survive restarts.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+)
+
+const (
+	// Interval is how often Run looks for deliveries due a retry. New
+	// events are posted straight away.
+	Interval = 30 * time.Second
+	// batchSize bounds the deliveries posted per round.
+	batchSize = 50
+	// requestTimeout bounds one attempt, so a stuck receiver cannot hold up
+	// the others.
+	requestTimeout = 10 * time.Second
+)
+
+// Headers sent with each delivery. The signature is "sha256=" and the hex
+// HMAC-SHA256 of the body, keyed with the webhook's secret.
+const (
+	SignatureHeader = "X-BBS-Signature"
+	EventHeader     = "X-BBS-Event"
+	DeliveryHeader  = "X-BBS-Delivery"
+)
+
+type Dispatcher struct {
+	config *config.Config
+	repos  *repository.Manager
+	client *http.Client
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+func NewDispatcher(cfg *config.Config, repos *repository.Manager) *Dispatcher {
+	return &Dispatcher{
+		config: cfg,
+		repos:  repos,
+		client: &http.Client{
+			Timeout: requestTimeout,
+			// a redirect would turn the POST into a GET, so it counts as
+			// a failure instead
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+		now:  time.Now,
+	}
+}
+
+// Enabled reports whether any webhooks are configured.
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.config.Webhooks) > 0
+}
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	Event     string      `json:"event"`
+	Server    string      `json:"server"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type postData struct {
+	ID          int       `json:"id"`
+	BoardID     int       `json:"board_id"`
+	Board       string    `json:"board"`
+	ThreadID    *int      `json:"thread_id,omitempty"`
+	ThreadTitle string    `json:"thread_title,omitempty"`
+	Author      string    `json:"author"`
+	AuthorID    int       `json:"author_id"`
+	Title       string    `json:"title,omitempty"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type userData struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Status    string    `json:"status"`
+	InvitedBy int       `json:"invited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type moderationData struct {
+	Actor     string          `json:"actor"`
+	ActorID   int             `json:"actor_id"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// PostCreated queues a thread.created or reply.created event.
+func (d *Dispatcher) PostCreated(post *domain.Post) {
+	if !d.Enabled() {
+		return
+	}
+
+	data := &postData{
+		ID:        post.ID,
+		BoardID:   post.BoardID,
+		ThreadID:  post.ReplyTo,
+		Author:    post.Username,
+		AuthorID:  post.UserID,
+		Title:     post.Title,
+		Content:   post.Content,
+		CreatedAt: post.CreatedAt,
+	}
+	if board, err := d.repos.Board.GetByID(post.BoardID); err == nil {
+		data.Board = board.Name
+	}
+
+	event := config.EventThreadCreated
+	if post.ReplyTo != nil {
+		event = config.EventReplyCreated
+		if thread, err := d.repos.Post.GetByID(*post.ReplyTo); err == nil {
+			data.ThreadTitle = thread.Title
+		}
+	}
+	d.enqueue(event, data)
+}
+
+// UserRegistered queues a user.registered event. Email addresses are left
+// out.
+func (d *Dispatcher) UserRegistered(user *domain.User) {
+	if !d.Enabled() {
+		return
+	}
+
+	d.enqueue(config.EventUserRegistered, &userData{
+		ID:        user.ID,
+		Username:  user.Username,
+		Status:    user.Status,
+		InvitedBy: user.InvitedBy,
+		CreatedAt: user.CreatedAt,
+	})
+}
+
+// Moderation queues a moderation event for a sysop action from the audit
+// log.
+func (d *Dispatcher) Moderation(entry *domain.AuditEntry) {
+	if !d.Enabled() {
+		return
+	}
+
+	d.enqueue(config.EventModeration, &moderationData{
+		Actor:     entry.Actor,
+		ActorID:   entry.ActorID,
+		Action:    entry.Action,
+		Target:    entry.Target,
+		Details:   json.RawMessage(entry.Details),
+		CreatedAt: entry.CreatedAt,
+	})
+}
+
+// enqueue queues one delivery of the event for each webhook that wants it,
+// and wakes Run to post them.
+func (d *Dispatcher) enqueue(event string, data interface{}) {
+	payload, err := json.Marshal(&Event{
+		Event:     event,
+		Server:    d.config.ServerName,
+		CreatedAt: d.now(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("Failed to encode %s webhook: %v", event, err)
+		return
+	}
+
+	queued := false
+	for _, hook := range d.config.Webhooks {
+		if !hook.Wants(event) {
+			continue
+		}
+		delivery := domain.NewWebhookDelivery(hook.URL, event, string(payload))
+		delivery.NextAttemptAt = d.now()
+		if err := d.repos.Webhook.Enqueue(delivery); err != nil {
+			log.Printf("Failed to queue %s webhook to %s: %v", event, hook.URL, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		d.wakeUp()
+	}
+}
+
+// Retry queues a failed delivery again with a fresh set of attempts.
+func (d *Dispatcher) Retry(delivery *domain.WebhookDelivery) error {
+	delivery.Status = domain.WebhookPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = d.now()
+	if err := d.repos.Webhook.Update(delivery); err != nil {
+		return err
+	}
+	d.wakeUp()
+	return nil
+}
+
+func (d *Dispatcher) wakeUp() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sign returns the signature header value for payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts queued deliveries that are due, rescheduling failures with
+// backoff.
+func (d *Dispatcher) Deliver() error {
+	deliveries, err := d.repos.Webhook.GetDue(d.now(), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		hook := d.config.Webhook(delivery.URL)
+		if hook == nil {
+			// removed from the config since the event was queued
+			delivery.Status = domain.WebhookFailed
+			delivery.LastError = "webhook is no longer configured"
+		} else if code, err := d.post(hook, delivery); err != nil {
+			delivery.Failed(code, err, d.now())
+			if delivery.Status == domain.WebhookFailed {
+				log.Printf("Giving up on %s webhook to %s after %d attempts: %v",
+					delivery.Event, delivery.URL, delivery.Attempts, err)
+			}
+		} else {
+			delivery.Delivered(code, d.now())
+		}
+
+		if err := d.repos.Webhook.Update(delivery); err != nil {
+			log.Printf("Failed to update webhook delivery %d: %v", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// post makes one attempt at a delivery. Any 2xx response is success.
+func (d *Dispatcher) post(hook *config.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gobbs-webhook")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// read a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Run posts deliveries as they are queued, and retries failed ones every
+// Interval, until stop is closed.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	if !d.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Deliver(); err != nil {
+			log.Printf("Failed to deliver webhooks: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
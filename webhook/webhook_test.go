@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leinonen/bbs/config"
+	"github.com/leinonen/bbs/domain"
+	"github.com/leinonen/bbs/repository"
+	"github.com/leinonen/bbs/test/mocks"
+)
+
+// receiver records the requests a webhook gets, answering with the next of
+// its statuses.
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+type fixture struct {
+	dispatcher *Dispatcher
+	repos      *repository.Manager
+	config     *config.Config
+	now        *time.Time
+	board      *domain.Board
+}
+
+func newFixture(t *testing.T, hooks ...config.Webhook) *fixture {
+	repos := &repository.Manager{
+		Board:   mocks.NewBoardRepository(),
+		Post:    mocks.NewPostRepository(),
+		Webhook: mocks.NewWebhookDeliveryRepository(),
+	}
+
+	cfg := config.Default()
+	cfg.Webhooks = hooks
+
+	f := &fixture{repos: repos, config: cfg}
+	f.board = domain.NewBoard("general", "General discussion")
+	repos.Board.Create(f.board)
+
+	now := time.Now()
+	f.now = &now
+	f.dispatcher = NewDispatcher(cfg, repos)
+	f.dispatcher.now = func() time.Time { return *f.now }
+	return f
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, string) {
+	rc := &receiver{statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+	return rc, server.URL
+}
+
+func (f *fixture) deliveries(t *testing.T) []*domain.WebhookDelivery {
+	deliveries, err := f.repos.Webhook.GetRecent(100)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	return deliveries
+}
+
+func TestDispatcher_PostCreated(t *testing.T) {
+	rc, url := newReceiver(t)
+	_, other := newReceiver(t)
+	f := newFixture(t,
+		config.Webhook{URL: url, Secret: "s3cret"},
+		config.Webhook{URL: other, Secret: "other", Events: []string{config.EventModeration}})
+
+	thread := domain.NewPost(f.board.ID, 1, "alice", "Hello", "First post")
+	f.repos.Post.Create(thread)
+	f.dispatcher.PostCreated(thread)
+	reply := domain.NewReply(f.board.ID, 2, "bob", "Welcome", thread.ID)
+	f.repos.Post.Create(reply)
+	f.dispatcher.PostCreated(reply)
+
+	if got := len(f.deliveries(t)); got != 2 {
+		t.Fatalf("Expected 2 deliveries to the webhook that wants posts, got %d", got)
+	}
+	if err := f.dispatcher.Deliver(); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	if len(rc.requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(rc.requests))
+	}
+	req, body := rc.requests[1], rc.bodies[1]
+	if req.Header.Get(SignatureHeader) != Sign("s3cret", body) {
+		t.Errorf("Expected the body to be signed with the secret, got %q", req.Header.Get(SignatureHeader))
+	}
+	if req.Header.Get(EventHeader) != config.EventReplyCreated || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers: %v", req.Header)
+	}
+
+	var event struct {
+		Event string
+		Data  postData
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("Invalid payload %s: %v", body, err)
+	}
+	if event.Event != config.EventReplyCreated || event.Data.Board != "general" ||
+		event.Data.ThreadTitle != "Hello" || event.Data.Author != "bob" {
+		t.Errorf("Unexpected payload: %s", body)
+	}
+
+	for _, delivery := range f.deliveries(t) {
+		if delivery.Status != domain.WebhookDelivered || delivery.ResponseCode != http.StatusNoContent {
+			t.Errorf("Expected delivery %d to be delivered, got %s", delivery.ID, delivery.Status)
+		}
+	}
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	rc, url := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	f := newFixture(t, config.Webhook{URL: url, Secret: "s3cret"})
+
+	user := domain.NewUser("carol", "carol@example.com")
+	user.ID = 7
+	f.dispatcher.UserRegistered(user)
+	f.dispatcher.Deliver()
+
+	delivery := f.deliveries(t)[0]
+	if delivery.Status != domain.WebhookPending || delivery.ResponseCode != 500 || delivery.Attempts != 1 {
+		t.Fatalf("Expected the delivery to be retried, got %+v", delivery)
+	}
+
+	// not due yet
+	f.dispatcher.Deliver()
+	if len(rc.requests) != 1 {
+		t.Fatalf("Expected no retry before the backoff, got %d requests", len(rc.requests))
+	}
+
+	*f.now = f.now.Add(30 * time.Second)
+	f.dispatcher.Deliver()
+	*f.now = f.now.Add(time.Minute)
+	f.dispatcher.Deliver()
+
+	delivery = f.deliveries(t)[0]
+	if len(rc.requests) != 3 || delivery.Status != domain.WebhookDelivered || delivery.Attempts != 3 {
+		t.Errorf("Expected delivery on the third attempt, got %d requests and %+v", len(rc.requests), delivery)
+	}
+	if string(rc.bodies[0]) != string(rc.bodies[2]) {
+		t.Error("Expected every attempt to send the same payload")
+	}
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	f := newFixture(t, config.Webhook{URL: "http://127.0.0.1:1/hook", Secret: "s3cret"})
+
+	f.dispatcher.Moderation(domain.NewAuditEntry(nil, domain.AuditUserBan, "mallory", "", nil))
+	for i := 0; i < domain.MaxWebhookAttempts; i++ {
+		f.dispatcher.Deliver()
+		*f.now = f.now.Add(2 * time.Hour)
+	}
+
+	delivery := f.deliveries(t)[0]
+	if delivery.Status != domain.WebhookFailed || delivery.Attempts != domain.MaxWebhookAttempts || delivery.LastError == "" {
+		t.Fatalf("Expected the delivery to fail for good, got %+v", delivery)
+	}
+
+	if err := f.dispatcher.Retry(delivery); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	delivery = f.deliveries(t)[0]
+	if delivery.Status != domain.WebhookPending || delivery.Attempts != 0 {
+		t.Errorf("Expected the delivery to be queued again, got %+v", delivery)
+	}
+}
+
+func TestDispatcher_RemovedWebhook(t *testing.T) {
+	rc, url := newReceiver(t)
+	f := newFixture(t, config.Webhook{URL: url, Secret: "s3cret"})
+
+	f.dispatcher.Moderation(domain.NewAuditEntry(nil, domain.AuditUserBan, "mallory", "", nil))
+	f.config.Webhooks = []config.Webhook{{URL: "http://localhost/elsewhere", Secret: "s3cret"}}
+	f.dispatcher.Deliver()
+
+	if delivery := f.deliveries(t)[0]; delivery.Status != domain.WebhookFailed || len(rc.requests) != 0 {
+		t.Errorf("Expected a delivery to a removed webhook to fail unsent, got %+v", delivery)
+	}
+}
+
+func TestDispatcher_Disabled(t *testing.T) {
+	f := newFixture(t)
+	f.dispatcher.PostCreated(domain.NewPost(f.board.ID, 1, "alice", "Hello", "First post"))
+	if len(f.deliveries(t)) != 0 {
+		t.Error("Expected nothing queued without webhooks")
+	}
+
+	var nilDispatcher *Dispatcher
+	nilDispatcher.PostCreated(domain.NewPost(f.board.ID, 1, "alice", "Hello", "First post"))
+}
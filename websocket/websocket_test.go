@@ -0,0 +1,201 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// the example from RFC 6455
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Expected the accept key from the RFC, got %q", got)
+	}
+}
+
+// echoServer upgrades every request and sends each message back.
+func echoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type client struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, server *httptest.Server, origin string) (*client, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET /ws HTTP/1.1\r\n" +
+		"Host: " + strings.TrimPrefix(server.URL, "http://") + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		request += "Origin: " + origin + "\r\n"
+	}
+	conn.Write([]byte(request + "\r\n"))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("Reading the handshake response failed: %v", err)
+	}
+	return &client{conn: conn, br: br}, resp
+}
+
+func (c *client) send(fin bool, opcode int, payload []byte, masked bool) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	if len(payload) < 126 {
+		frame = append(frame, maskBit|byte(len(payload)))
+	} else {
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	c.conn.Write(frame)
+}
+
+func (c *client) receive(t *testing.T) (int, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatalf("Reading a frame failed: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Error("Server frames must not be masked")
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(c.br, payload)
+	return int(header[0] & 0x0f), payload
+}
+
+func TestConn_Echo(t *testing.T) {
+	server := echoServer(t)
+	c, resp := dial(t, server, server.URL)
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 Switching Protocols, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Expected the accept key for the client's key, got %q", resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	// a fragmented message with a ping in the middle
+	c.send(false, TextMessage, []byte("hel"), true)
+	c.send(true, opPing, []byte("ping"), true)
+	c.send(true, opContinuation, []byte("lo"), true)
+
+	if op, payload := c.receive(t); op != opPong || string(payload) != "ping" {
+		t.Errorf("Expected a pong, got opcode %d %q", op, payload)
+	}
+	if op, payload := c.receive(t); op != TextMessage || string(payload) != "hello" {
+		t.Errorf("Expected the reassembled message back, got opcode %d %q", op, payload)
+	}
+
+	long := bytes.Repeat([]byte{0xff}, 300)
+	c.send(true, BinaryMessage, long, true)
+	if op, payload := c.receive(t); op != BinaryMessage || !bytes.Equal(payload, long) {
+		t.Errorf("Expected the binary message back, got opcode %d and %d bytes", op, len(payload))
+	}
+
+	c.send(true, opClose, []byte{0x03, 0xe8}, true)
+	if op, payload := c.receive(t); op != opClose || !bytes.Equal(payload, []byte{0x03, 0xe8}) {
+		t.Errorf("Expected the close status echoed, got opcode %d %v", op, payload)
+	}
+}
+
+func TestConn_ProtocolErrors(t *testing.T) {
+	server := echoServer(t)
+
+	tests := []struct {
+		name    string
+		send    func(c *client)
+		closing uint16
+	}{
+		{"unmasked frame", func(c *client) { c.send(true, TextMessage, []byte("hi"), false) }, closeProtocol},
+		{"invalid UTF-8", func(c *client) { c.send(true, TextMessage, []byte{0xff}, true) }, closeInvalidData},
+		{"stray continuation", func(c *client) { c.send(true, opContinuation, []byte("hi"), true) }, closeProtocol},
+		{"too big", func(c *client) {
+			c.conn.Write([]byte{0x82, 0xff, 0, 0, 0, 0, 0, 0x10, 0, 0})
+		}, closeTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := dial(t, server, "")
+			tt.send(c)
+			op, payload := c.receive(t)
+			if op != opClose || len(payload) != 2 || binary.BigEndian.Uint16(payload) != tt.closing {
+				t.Errorf("Expected close status %d, got opcode %d %v", tt.closing, op, payload)
+			}
+		})
+	}
+}
+
+func TestUpgrade_Refused(t *testing.T) {
+	server := echoServer(t)
+
+	_, resp := dial(t, server, "http://evil.example")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a page from another site to be refused, got %d", resp.StatusCode)
+	}
+
+	plain, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	plain.Body.Close()
+	if plain.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a plain request to be refused, got %d", plain.StatusCode)
+	}
+}